@@ -8,6 +8,7 @@ import (
 
 	"github.com/byuoitav/common/auth/activedirectory"
 	"github.com/byuoitav/common/db"
+	"github.com/byuoitav/common/log"
 )
 
 func VerifyRoleForUser(user, role string) (bool, error) {
@@ -30,8 +31,15 @@ func VerifyRoleForUser(user, role string) (bool, error) {
 	for _, permission := range auth.Permissions {
 		for _, r := range permission.Roles {
 			if strings.EqualFold(r, role) {
-				groupsWithRole = append(groupsWithRole, regexp.MustCompile(permission.Group))
-				continue
+				// a group that isn't a valid pattern can't match anyone
+				groupRegex, err := regexp.Compile(permission.Group)
+				if err != nil {
+					log.L.Warnf("Skipping permission for group %q: %s", permission.Group, err)
+					break
+				}
+
+				groupsWithRole = append(groupsWithRole, groupRegex)
+				break
 			}
 		}
 	}
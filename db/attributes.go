@@ -0,0 +1,118 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/byuoitav/common/structs"
+)
+
+/*
+DeviceFromPreset returns a new device in roomID made from the attribute preset presetName: it
+has the preset's device type (along with the type's roles and ports), the preset's attributes,
+and the preset's icon (or the type's default icon, if the preset doesn't have one). It is named
+with the preset's device name (or the type's default name), numbered after the devices already
+in the room with that name.
+
+If create is true, the device is also created.
+*/
+func DeviceFromPreset(d DB, roomID, presetName string, create bool) (structs.Device, error) {
+	var device structs.Device
+
+	groups, err := d.GetAllAttributeGroups()
+	if err != nil {
+		return device, fmt.Errorf("unable to get attribute groups: %s", err)
+	}
+
+	var preset structs.AttributeSet
+	var found []string
+
+	for i := range groups {
+		if p, ok := groups[i].FindPreset(presetName); ok {
+			preset = p
+			found = append(found, groups[i].ID)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return device, fmt.Errorf("unable to make device from preset %q: preset doesn't exist", presetName)
+	case 1:
+	default:
+		return device, fmt.Errorf("unable to make device from preset %q: more than one attribute group has it (%v)", presetName, found)
+	}
+
+	if _, err := d.GetRoom(roomID); err != nil {
+		return device, fmt.Errorf("unable to make device from preset %q: unable to get room %s: %s", presetName, roomID, err)
+	}
+
+	dt, err := d.GetDeviceType(preset.DeviceType)
+	if err != nil {
+		return device, fmt.Errorf("unable to make device from preset %q: unable to get device type %s: %s", presetName, preset.DeviceType, err)
+	}
+
+	devices, err := d.GetDevicesByRoom(roomID)
+	if err != nil {
+		return device, fmt.Errorf("unable to make device from preset %q: unable to get devices in %s: %s", presetName, roomID, err)
+	}
+
+	taken := make(map[string]bool)
+	for _, existing := range devices {
+		taken[existing.Name] = true
+	}
+
+	defaultName := preset.DeviceName
+	if len(defaultName) == 0 {
+		defaultName = dt.DefaultName
+	}
+
+	name, err := nextDeviceName(defaultName, taken)
+	if err != nil {
+		return device, fmt.Errorf("unable to make device from preset %q: unable to name the device: %s", presetName, err)
+	}
+
+	device = structs.Device{
+		ID:          roomID + "-" + name,
+		Name:        name,
+		DisplayName: dt.DisplayName,
+		Type:        structs.DeviceType{ID: dt.ID},
+		Roles:       append([]structs.Role(nil), dt.Roles...),
+		Attributes:  make(map[string]interface{}),
+	}
+
+	device.Address = device.ID
+
+	// the type's ports aren't connected to anything yet
+	for _, port := range dt.Ports {
+		port.SourceDevice = ""
+		port.DestinationDevice = ""
+		device.Ports = append(device.Ports, port)
+	}
+
+	for k, v := range preset.Attributes {
+		device.Attributes[k] = v
+	}
+
+	icon := preset.DeviceIcon
+	if len(icon) == 0 {
+		icon = dt.DefaultIcon
+	}
+
+	if len(icon) > 0 {
+		device.Attributes[structs.DeviceIconAttribute] = icon
+	}
+
+	if err := device.Validate(); err != nil {
+		return device, fmt.Errorf("unable to make device from preset %q: %s", presetName, err)
+	}
+
+	if !create {
+		return device, nil
+	}
+
+	created, err := d.CreateDevice(device)
+	if err != nil {
+		return device, fmt.Errorf("unable to create device from preset %q: %s", presetName, err)
+	}
+
+	return created, nil
+}
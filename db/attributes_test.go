@@ -0,0 +1,62 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/byuoitav/common/structs"
+)
+
+func TestDeviceFromPreset(t *testing.T) {
+	m := newSeededMemoryDB(t)
+
+	dt := structs.DeviceType{ID: "test-display", DefaultName: "D", DefaultIcon: "tv", Roles: []structs.Role{{ID: "VideoOut"}}}
+	if _, err := m.CreateDeviceType(dt); err != nil {
+		t.Fatalf("failed to create device type: %s", err)
+	}
+
+	group := structs.Group{
+		ID: "displays",
+		Subgroups: []structs.Group{{
+			ID:      "tvs",
+			Presets: []structs.AttributeSet{{Name: "Sony TV", DeviceType: "test-display", Attributes: map[string]interface{}{"brand": "sony"}}},
+		}},
+	}
+
+	if _, err := m.CreateAttributeGroup(group); err != nil {
+		t.Fatalf("failed to create attribute group: %s", err)
+	}
+
+	if _, err := m.UpdateMenuTree([]string{"displays", "missing"}); err == nil {
+		t.Fatalf("expected a menu tree with a missing group to be rejected")
+	}
+
+	if _, err := m.UpdateMenuTree([]string{"displays"}); err != nil {
+		t.Fatalf("failed to update menu tree: %s", err)
+	}
+
+	if err := m.DeleteAttributeGroup("displays"); err == nil {
+		t.Fatalf("expected deleting a group in the menu tree to fail")
+	}
+
+	first, err := DeviceFromPreset(m, "CCC-AAA", "Sony TV", true)
+	if err != nil {
+		t.Fatalf("failed to create device from preset: %s", err)
+	}
+
+	if first.ID != "CCC-AAA-D1" || first.Attributes["brand"] != "sony" || first.Attributes[structs.DeviceIconAttribute] != "tv" {
+		t.Fatalf("unexpected device: %+v", first)
+	}
+
+	second, err := DeviceFromPreset(m, "CCC-AAA", "Sony TV", false)
+	if err != nil {
+		t.Fatalf("failed to make device from preset: %s", err)
+	}
+
+	if second.ID != "CCC-AAA-D2" {
+		t.Fatalf("expected the second device to be D2, got %s", second.ID)
+	}
+
+	if _, err := m.GetDevice(second.ID); err == nil {
+		t.Fatalf("device was created without create being set")
+	}
+}
@@ -0,0 +1,1047 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/byuoitav/common/db/couch"
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/structs"
+)
+
+/*
+AuditStore is where an AuditedDB records changes. The couch and in-memory databases both
+implement it; couch keeps the entries in their own database (couch.AUDIT_LOG), which is created
+the first time a change is recorded if it doesn't exist yet.
+
+Entries are returned in the order they were made.
+*/
+type AuditStore interface {
+	AddAuditEntry(entry structs.AuditEntry) error
+	GetAuditEntry(id string) (structs.AuditEntry, error)
+	GetDocumentHistory(database, id string) ([]structs.AuditEntry, error)
+	GetRoomHistory(roomID string, since time.Time) ([]structs.AuditEntry, error)
+}
+
+/*
+AuditedDB records every change made through it to buildings, rooms, devices, device types, room
+configurations, ui configs, templates, and the option lists, along with who made it. Every other
+function passes straight through to the wrapped DB.
+
+The actor is taken from the "user" value of the context given to WithContext, which is set by
+the auth middleware:
+
+	d := db.WithContext(c.Request().Context(), auditedDB)
+
+Changes that fail aren't recorded. If a change succeeds but can't be recorded, the error is
+logged, and the change is still returned as successful.
+*/
+type AuditedDB struct {
+	DB
+
+	store AuditStore
+	actor string
+}
+
+// NewAuditedDB returns an AuditedDB wrapping d, that records changes in store.
+func NewAuditedDB(d DB, store AuditStore) *AuditedDB {
+	return &AuditedDB{
+		DB:    d,
+		store: store,
+	}
+}
+
+// Unwrap returns the DB that a records changes to.
+func (a *AuditedDB) Unwrap() DB {
+	return a.DB
+}
+
+// WithContext returns an AuditedDB whose requests to the wrapped DB are bound to ctx, and whose changes are made by the user in ctx.
+func (a *AuditedDB) WithContext(ctx context.Context) DB {
+	actor, _ := ctx.Value("user").(string)
+
+	return &AuditedDB{
+		DB:    WithContext(ctx, a.DB),
+		store: a.store,
+		actor: actor,
+	}
+}
+
+// WithActor returns an AuditedDB whose changes are made by actor, for changes that don't come from a request (e.g. a command line tool).
+func (a *AuditedDB) WithActor(actor string) *AuditedDB {
+	return &AuditedDB{
+		DB:    a.DB,
+		store: a.store,
+		actor: actor,
+	}
+}
+
+// GetAuditEntry returns a single audit entry.
+func (a *AuditedDB) GetAuditEntry(id string) (structs.AuditEntry, error) {
+	return a.store.GetAuditEntry(id)
+}
+
+// GetDocumentHistory returns each change made to a document, including the rename that gave it its id.
+func (a *AuditedDB) GetDocumentHistory(database, id string) ([]structs.AuditEntry, error) {
+	return a.store.GetDocumentHistory(database, id)
+}
+
+// GetRoomHistory returns each change made to a room, its devices, or its ui config, since since.
+func (a *AuditedDB) GetRoomHistory(roomID string, since time.Time) ([]structs.AuditEntry, error) {
+	return a.store.GetRoomHistory(roomID, since)
+}
+
+/*
+Revert puts the document changed in the audit entry id back the way it was just before that
+change: a created document is deleted, a deleted document is recreated, and an updated document
+is updated back. The revert is itself recorded as a change.
+
+Renames can't be reverted; rename the building or room back instead.
+*/
+func (a *AuditedDB) Revert(id string) error {
+	entry, err := a.store.GetAuditEntry(id)
+	if err != nil {
+		return fmt.Errorf("unable to get audit entry %s: %s", id, err)
+	}
+
+	if entry.Action == structs.AuditRenamed {
+		return fmt.Errorf("unable to revert %s: renames can't be reverted", id)
+	}
+
+	kind, ok := auditedKindOf(entry.Database, entry.DocumentID)
+	if !ok {
+		return fmt.Errorf("unable to revert %s: %s documents can't be reverted", id, entry.Database)
+	}
+
+	if len(entry.Before) == 0 {
+		if kind.remove == nil {
+			return fmt.Errorf("unable to revert %s: %s can't be deleted", id, entry.DocumentID)
+		}
+
+		if err := kind.remove(a, entry.DocumentID); err != nil {
+			return fmt.Errorf("unable to revert %s: %s", id, err)
+		}
+
+		return nil
+	}
+
+	if err := kind.restore(a, entry.DocumentID, entry.Before); err != nil {
+		return fmt.Errorf("unable to revert %s: %s", id, err)
+	}
+
+	return nil
+}
+
+/* audited writes */
+
+// CreateBuilding .
+func (a *AuditedDB) CreateBuilding(building structs.Building) (structs.Building, error) {
+	t := a.track(couch.BUILDINGS, building.ID)
+
+	toReturn, err := a.DB.CreateBuilding(building)
+	if err == nil {
+		t.record(building.ID)
+	}
+
+	return toReturn, err
+}
+
+// UpdateBuilding .
+func (a *AuditedDB) UpdateBuilding(id string, building structs.Building) (structs.Building, error) {
+	t := a.track(couch.BUILDINGS, id, building.ID)
+
+	toReturn, err := a.DB.UpdateBuilding(id, building)
+	if err == nil {
+		t.record(id, building.ID)
+	}
+
+	return toReturn, err
+}
+
+// DeleteBuilding .
+func (a *AuditedDB) DeleteBuilding(id string) error {
+	t := a.track(couch.BUILDINGS, id)
+
+	err := a.DB.DeleteBuilding(id)
+	if err == nil {
+		t.record(id)
+	}
+
+	return err
+}
+
+// RenameBuilding .
+func (a *AuditedDB) RenameBuilding(oldID, newID string) (structs.RenameResult, error) {
+	scope, _ := a.DB.DeleteBuildingCascade(oldID, true)
+	t := a.trackScope(scope)
+
+	result, err := a.DB.RenameBuilding(oldID, newID)
+	t.recordRename(result)
+
+	return result, err
+}
+
+// DeleteBuildingCascade .
+func (a *AuditedDB) DeleteBuildingCascade(id string, dryRun bool) (structs.CascadeDeleteResult, error) {
+	if dryRun {
+		return a.DB.DeleteBuildingCascade(id, dryRun)
+	}
+
+	scope, _ := a.DB.DeleteBuildingCascade(id, true)
+	t := a.trackScope(scope)
+
+	result, err := a.DB.DeleteBuildingCascade(id, dryRun)
+	t.recordCascade(result)
+
+	return result, err
+}
+
+// CreateRoom .
+func (a *AuditedDB) CreateRoom(room structs.Room) (structs.Room, error) {
+	t := a.track(couch.ROOMS, room.ID)
+
+	toReturn, err := a.DB.CreateRoom(room)
+	if err == nil {
+		t.record(room.ID)
+	}
+
+	return toReturn, err
+}
+
+// UpdateRoom .
+func (a *AuditedDB) UpdateRoom(id string, room structs.Room) (structs.Room, error) {
+	t := a.track(couch.ROOMS, id, room.ID)
+
+	toReturn, err := a.DB.UpdateRoom(id, room)
+	if err == nil {
+		t.record(id, room.ID)
+	}
+
+	return toReturn, err
+}
+
+// DeleteRoom .
+func (a *AuditedDB) DeleteRoom(id string) error {
+	t := a.track(couch.ROOMS, id)
+
+	// the room's devices are deleted with it
+	deviceIDs := a.roomDeviceIDs(id)
+	devices := a.track(couch.DEVICES, deviceIDs...)
+
+	err := a.DB.DeleteRoom(id)
+	devices.record(deviceIDs...)
+	if err == nil {
+		t.record(id)
+	}
+
+	return err
+}
+
+// RenameRoom .
+func (a *AuditedDB) RenameRoom(oldID, newID string) (structs.RenameResult, error) {
+	scope, _ := a.DB.DeleteRoomCascade(oldID, true)
+	t := a.trackScope(scope)
+
+	result, err := a.DB.RenameRoom(oldID, newID)
+	t.recordRename(result)
+
+	return result, err
+}
+
+// DeleteRoomCascade .
+func (a *AuditedDB) DeleteRoomCascade(id string, dryRun bool) (structs.CascadeDeleteResult, error) {
+	if dryRun {
+		return a.DB.DeleteRoomCascade(id, dryRun)
+	}
+
+	scope, _ := a.DB.DeleteRoomCascade(id, true)
+	t := a.trackScope(scope)
+
+	result, err := a.DB.DeleteRoomCascade(id, dryRun)
+	t.recordCascade(result)
+
+	return result, err
+}
+
+// CreateDevice .
+func (a *AuditedDB) CreateDevice(device structs.Device) (structs.Device, error) {
+	t := a.track(couch.DEVICES, device.ID)
+
+	toReturn, err := a.DB.CreateDevice(device)
+	if err == nil {
+		t.record(device.ID)
+	}
+
+	return toReturn, err
+}
+
+// UpdateDevice .
+func (a *AuditedDB) UpdateDevice(id string, device structs.Device) (structs.Device, error) {
+	t := a.track(couch.DEVICES, id, device.ID)
+
+	toReturn, err := a.DB.UpdateDevice(id, device)
+	if err == nil {
+		t.record(id, device.ID)
+	}
+
+	return toReturn, err
+}
+
+// DeleteDevice .
+func (a *AuditedDB) DeleteDevice(id string) error {
+	t := a.track(couch.DEVICES, id)
+
+	err := a.DB.DeleteDevice(id)
+	if err == nil {
+		t.record(id)
+	}
+
+	return err
+}
+
+// CreateDeviceType .
+func (a *AuditedDB) CreateDeviceType(dt structs.DeviceType) (structs.DeviceType, error) {
+	t := a.track(couch.DEVICE_TYPES, dt.ID)
+
+	toReturn, err := a.DB.CreateDeviceType(dt)
+	if err == nil {
+		t.record(dt.ID)
+	}
+
+	return toReturn, err
+}
+
+// UpdateDeviceType .
+func (a *AuditedDB) UpdateDeviceType(id string, dt structs.DeviceType) (structs.DeviceType, error) {
+	t := a.track(couch.DEVICE_TYPES, id, dt.ID)
+
+	toReturn, err := a.DB.UpdateDeviceType(id, dt)
+	if err == nil {
+		t.record(id, dt.ID)
+	}
+
+	return toReturn, err
+}
+
+// DeleteDeviceType .
+func (a *AuditedDB) DeleteDeviceType(id string) error {
+	t := a.track(couch.DEVICE_TYPES, id)
+
+	err := a.DB.DeleteDeviceType(id)
+	if err == nil {
+		t.record(id)
+	}
+
+	return err
+}
+
+// CreateRoomConfiguration .
+func (a *AuditedDB) CreateRoomConfiguration(rc structs.RoomConfiguration) (structs.RoomConfiguration, error) {
+	t := a.track(couch.ROOM_CONFIGURATIONS, rc.ID)
+
+	toReturn, err := a.DB.CreateRoomConfiguration(rc)
+	if err == nil {
+		t.record(rc.ID)
+	}
+
+	return toReturn, err
+}
+
+// UpdateRoomConfiguration .
+func (a *AuditedDB) UpdateRoomConfiguration(id string, rc structs.RoomConfiguration) (structs.RoomConfiguration, error) {
+	t := a.track(couch.ROOM_CONFIGURATIONS, id, rc.ID)
+
+	toReturn, err := a.DB.UpdateRoomConfiguration(id, rc)
+	if err == nil {
+		t.record(id, rc.ID)
+	}
+
+	return toReturn, err
+}
+
+// DeleteRoomConfiguration .
+func (a *AuditedDB) DeleteRoomConfiguration(id string) error {
+	t := a.track(couch.ROOM_CONFIGURATIONS, id)
+
+	err := a.DB.DeleteRoomConfiguration(id)
+	if err == nil {
+		t.record(id)
+	}
+
+	return err
+}
+
+// CreateUIConfig .
+func (a *AuditedDB) CreateUIConfig(roomID string, ui structs.UIConfig) (structs.UIConfig, error) {
+	t := a.track(couch.UI_CONFIGS, roomID)
+
+	toReturn, err := a.DB.CreateUIConfig(roomID, ui)
+	if err == nil {
+		t.record(roomID)
+	}
+
+	return toReturn, err
+}
+
+// UpdateUIConfig .
+func (a *AuditedDB) UpdateUIConfig(id string, ui structs.UIConfig) (structs.UIConfig, error) {
+	t := a.track(couch.UI_CONFIGS, id, ui.ID)
+
+	toReturn, err := a.DB.UpdateUIConfig(id, ui)
+	if err == nil {
+		t.record(id, ui.ID)
+	}
+
+	return toReturn, err
+}
+
+// DeleteUIConfig .
+func (a *AuditedDB) DeleteUIConfig(id string) error {
+	t := a.track(couch.UI_CONFIGS, id)
+
+	err := a.DB.DeleteUIConfig(id)
+	if err == nil {
+		t.record(id)
+	}
+
+	return err
+}
+
+// CreateBulkDevices .
+func (a *AuditedDB) CreateBulkDevices(devices []structs.Device) []structs.BulkUpdateResponse {
+	t := a.track(couch.DEVICES, deviceIDs(devices)...)
+
+	responses := a.DB.CreateBulkDevices(devices)
+	t.record(succeeded(responses)...)
+
+	return responses
+}
+
+// UpdateBulkDevices .
+func (a *AuditedDB) UpdateBulkDevices(devices []structs.Device) []structs.BulkUpdateResponse {
+	t := a.track(couch.DEVICES, deviceIDs(devices)...)
+
+	responses := a.DB.UpdateBulkDevices(devices)
+	t.record(succeeded(responses)...)
+
+	return responses
+}
+
+// DeleteBulkDevices .
+func (a *AuditedDB) DeleteBulkDevices(ids []string) []structs.BulkUpdateResponse {
+	t := a.track(couch.DEVICES, ids...)
+
+	responses := a.DB.DeleteBulkDevices(ids)
+	t.record(succeeded(responses)...)
+
+	return responses
+}
+
+// CreateBulkRooms .
+func (a *AuditedDB) CreateBulkRooms(rooms []structs.Room) []structs.BulkUpdateResponse {
+	var devices []structs.Device
+	for _, room := range rooms {
+		devices = append(devices, room.Devices...)
+	}
+
+	t := a.track(couch.ROOMS, roomIDs(rooms)...)
+	td := a.track(couch.DEVICES, deviceIDs(devices)...)
+
+	responses := a.DB.CreateBulkRooms(rooms)
+	t.record(succeeded(responses)...)
+	td.record(deviceIDs(devices)...)
+
+	return responses
+}
+
+// UpdateBulkRooms .
+func (a *AuditedDB) UpdateBulkRooms(rooms []structs.Room) []structs.BulkUpdateResponse {
+	t := a.track(couch.ROOMS, roomIDs(rooms)...)
+
+	responses := a.DB.UpdateBulkRooms(rooms)
+	t.record(succeeded(responses)...)
+
+	return responses
+}
+
+// DeleteBulkRooms .
+func (a *AuditedDB) DeleteBulkRooms(ids []string) []structs.BulkUpdateResponse {
+	t := a.track(couch.ROOMS, ids...)
+
+	// the rooms' devices are deleted with them
+	deviceIDs := a.roomDeviceIDs(ids...)
+	devices := a.track(couch.DEVICES, deviceIDs...)
+
+	responses := a.DB.DeleteBulkRooms(ids)
+	devices.record(deviceIDs...)
+	t.record(succeeded(responses)...)
+
+	return responses
+}
+
+// CreateBulkDeviceTypes .
+func (a *AuditedDB) CreateBulkDeviceTypes(types []structs.DeviceType) []structs.BulkUpdateResponse {
+	t := a.track(couch.DEVICE_TYPES, deviceTypeIDs(types)...)
+
+	responses := a.DB.CreateBulkDeviceTypes(types)
+	t.record(succeeded(responses)...)
+
+	return responses
+}
+
+// UpdateBulkDeviceTypes .
+func (a *AuditedDB) UpdateBulkDeviceTypes(types []structs.DeviceType) []structs.BulkUpdateResponse {
+	t := a.track(couch.DEVICE_TYPES, deviceTypeIDs(types)...)
+
+	responses := a.DB.UpdateBulkDeviceTypes(types)
+	t.record(succeeded(responses)...)
+
+	return responses
+}
+
+// DeleteBulkDeviceTypes .
+func (a *AuditedDB) DeleteBulkDeviceTypes(ids []string) []structs.BulkUpdateResponse {
+	t := a.track(couch.DEVICE_TYPES, ids...)
+
+	responses := a.DB.DeleteBulkDeviceTypes(ids)
+	t.record(succeeded(responses)...)
+
+	return responses
+}
+
+// UpdateTemplate .
+func (a *AuditedDB) UpdateTemplate(id string, newTemp structs.UIConfig) (structs.UIConfig, error) {
+	t := a.track(couch.OPTIONS, id, newTemp.ID)
+
+	toReturn, err := a.DB.UpdateTemplate(id, newTemp)
+	if err == nil {
+		t.record(id, newTemp.ID)
+	}
+
+	return toReturn, err
+}
+
+// UpdateIcons .
+func (a *AuditedDB) UpdateIcons(iconList []string) ([]string, error) {
+	t := a.track(couch.OPTIONS, couch.ICONS)
+
+	toReturn, err := a.DB.UpdateIcons(iconList)
+	if err == nil {
+		t.record(couch.ICONS)
+	}
+
+	return toReturn, err
+}
+
+// UpdateDeviceRoles .
+func (a *AuditedDB) UpdateDeviceRoles(roles []structs.Role) ([]structs.Role, error) {
+	t := a.track(couch.OPTIONS, couch.ROLES)
+
+	toReturn, err := a.DB.UpdateDeviceRoles(roles)
+	if err == nil {
+		t.record(couch.ROLES)
+	}
+
+	return toReturn, err
+}
+
+// UpdateRoomDesignations .
+func (a *AuditedDB) UpdateRoomDesignations(desigs []string) ([]string, error) {
+	t := a.track(couch.OPTIONS, couch.ROOM_DESIGNATIONS)
+
+	toReturn, err := a.DB.UpdateRoomDesignations(desigs)
+	if err == nil {
+		t.record(couch.ROOM_DESIGNATIONS)
+	}
+
+	return toReturn, err
+}
+
+// UpdateRoomAttributeSchemas .
+func (a *AuditedDB) UpdateRoomAttributeSchemas(schemas map[string][]structs.AttributeSchema) (map[string][]structs.AttributeSchema, error) {
+	t := a.track(couch.OPTIONS, couch.ROOM_SCHEMAS)
+
+	toReturn, err := a.DB.UpdateRoomAttributeSchemas(schemas)
+	if err == nil {
+		t.record(couch.ROOM_SCHEMAS)
+	}
+
+	return toReturn, err
+}
+
+// UpdateClosureCodes .
+func (a *AuditedDB) UpdateClosureCodes(codes []string) ([]string, error) {
+	t := a.track(couch.OPTIONS, couch.CLOSURE_CODES)
+
+	toReturn, err := a.DB.UpdateClosureCodes(codes)
+	if err == nil {
+		t.record(couch.CLOSURE_CODES)
+	}
+
+	return toReturn, err
+}
+
+// UpdateTags .
+func (a *AuditedDB) UpdateTags(newTags []string) ([]string, error) {
+	t := a.track(couch.OPTIONS, couch.TAGS)
+
+	toReturn, err := a.DB.UpdateTags(newTags)
+	if err == nil {
+		t.record(couch.TAGS)
+	}
+
+	return toReturn, err
+}
+
+// UpdateMenuTree .
+func (a *AuditedDB) UpdateMenuTree(order []string) ([]string, error) {
+	t := a.track(couch.OPTIONS, couch.MENUTREE)
+
+	toReturn, err := a.DB.UpdateMenuTree(order)
+	if err == nil {
+		t.record(couch.MENUTREE)
+	}
+
+	return toReturn, err
+}
+
+// CreateAttributeGroup .
+func (a *AuditedDB) CreateAttributeGroup(group structs.Group) (structs.Group, error) {
+	t := a.track(couch.ATTRIBUTES, group.ID)
+
+	toReturn, err := a.DB.CreateAttributeGroup(group)
+	if err == nil {
+		t.record(group.ID)
+	}
+
+	return toReturn, err
+}
+
+// UpdateAttributeGroup .
+func (a *AuditedDB) UpdateAttributeGroup(id string, group structs.Group) (structs.Group, error) {
+	t := a.track(couch.ATTRIBUTES, id)
+
+	toReturn, err := a.DB.UpdateAttributeGroup(id, group)
+	if err == nil {
+		t.record(id)
+	}
+
+	return toReturn, err
+}
+
+// DeleteAttributeGroup .
+func (a *AuditedDB) DeleteAttributeGroup(id string) error {
+	t := a.track(couch.ATTRIBUTES, id)
+
+	err := a.DB.DeleteAttributeGroup(id)
+	if err == nil {
+		t.record(id)
+	}
+
+	return err
+}
+
+// CreateLabConfig .
+func (a *AuditedDB) CreateLabConfig(config structs.LabConfig) (structs.LabConfig, error) {
+	t := a.track(couch.LAB_CONFIGS, config.ID)
+
+	toReturn, err := a.DB.CreateLabConfig(config)
+	if err == nil {
+		t.record(config.ID)
+	}
+
+	return toReturn, err
+}
+
+// UpdateLabConfig .
+func (a *AuditedDB) UpdateLabConfig(roomID string, config structs.LabConfig) (structs.LabConfig, error) {
+	t := a.track(couch.LAB_CONFIGS, roomID)
+
+	toReturn, err := a.DB.UpdateLabConfig(roomID, config)
+	if err == nil {
+		t.record(roomID)
+	}
+
+	return toReturn, err
+}
+
+// DeleteLabConfig .
+func (a *AuditedDB) DeleteLabConfig(roomID string) error {
+	t := a.track(couch.LAB_CONFIGS, roomID)
+
+	err := a.DB.DeleteLabConfig(roomID)
+	if err == nil {
+		t.record(roomID)
+	}
+
+	return err
+}
+
+// CreateScheduleConfig .
+func (a *AuditedDB) CreateScheduleConfig(config structs.ScheduleConfig) (structs.ScheduleConfig, error) {
+	t := a.track(couch.SCHEDULING_CONFIGS, config.ID)
+
+	toReturn, err := a.DB.CreateScheduleConfig(config)
+	if err == nil {
+		t.record(config.ID)
+	}
+
+	return toReturn, err
+}
+
+// UpdateScheduleConfig .
+func (a *AuditedDB) UpdateScheduleConfig(roomID string, config structs.ScheduleConfig) (structs.ScheduleConfig, error) {
+	t := a.track(couch.SCHEDULING_CONFIGS, roomID)
+
+	toReturn, err := a.DB.UpdateScheduleConfig(roomID, config)
+	if err == nil {
+		t.record(roomID)
+	}
+
+	return toReturn, err
+}
+
+// DeleteScheduleConfig .
+func (a *AuditedDB) DeleteScheduleConfig(roomID string) error {
+	t := a.track(couch.SCHEDULING_CONFIGS, roomID)
+
+	err := a.DB.DeleteScheduleConfig(roomID)
+	if err == nil {
+		t.record(roomID)
+	}
+
+	return err
+}
+
+// CreateDMPS .
+func (a *AuditedDB) CreateDMPS(dmps structs.DMPS) (structs.DMPSList, error) {
+	t := a.track(couch.DMPSLIST, couch.DMPS_LIST_ID)
+
+	toReturn, err := a.DB.CreateDMPS(dmps)
+	if err == nil {
+		t.record(couch.DMPS_LIST_ID)
+	}
+
+	return toReturn, err
+}
+
+// UpdateDMPS .
+func (a *AuditedDB) UpdateDMPS(hostname string, dmps structs.DMPS) (structs.DMPSList, error) {
+	t := a.track(couch.DMPSLIST, couch.DMPS_LIST_ID)
+
+	toReturn, err := a.DB.UpdateDMPS(hostname, dmps)
+	if err == nil {
+		t.record(couch.DMPS_LIST_ID)
+	}
+
+	return toReturn, err
+}
+
+// DeleteDMPS .
+func (a *AuditedDB) DeleteDMPS(hostname string) (structs.DMPSList, error) {
+	t := a.track(couch.DMPSLIST, couch.DMPS_LIST_ID)
+
+	toReturn, err := a.DB.DeleteDMPS(hostname)
+	if err == nil {
+		t.record(couch.DMPS_LIST_ID)
+	}
+
+	return toReturn, err
+}
+
+// CreatePermission .
+func (a *AuditedDB) CreatePermission(permission structs.Permission) (structs.Auth, error) {
+	t := a.track(couch.AUTH, couch.AUTH)
+
+	toReturn, err := a.DB.CreatePermission(permission)
+	if err == nil {
+		t.record(couch.AUTH)
+	}
+
+	return toReturn, err
+}
+
+// UpdatePermission .
+func (a *AuditedDB) UpdatePermission(group string, permission structs.Permission) (structs.Auth, error) {
+	t := a.track(couch.AUTH, couch.AUTH)
+
+	toReturn, err := a.DB.UpdatePermission(group, permission)
+	if err == nil {
+		t.record(couch.AUTH)
+	}
+
+	return toReturn, err
+}
+
+// DeletePermission .
+func (a *AuditedDB) DeletePermission(group string) (structs.Auth, error) {
+	t := a.track(couch.AUTH, couch.AUTH)
+
+	toReturn, err := a.DB.DeletePermission(group)
+	if err == nil {
+		t.record(couch.AUTH)
+	}
+
+	return toReturn, err
+}
+
+/* recording changes */
+
+// tracker holds the state of documents in a database from before they were changed.
+type tracker struct {
+	a        *AuditedDB
+	database string
+	before   map[string]json.RawMessage
+}
+
+// scopeTracker is a tracker for each database with documents in a building or room.
+type scopeTracker map[string]*tracker
+
+// track saves the current state of each of ids in database, so the change to them can be recorded.
+func (a *AuditedDB) track(database string, ids ...string) *tracker {
+	t := &tracker{
+		a:        a,
+		database: database,
+		before:   make(map[string]json.RawMessage),
+	}
+
+	for _, id := range ids {
+		if _, ok := t.before[id]; !ok && len(id) > 0 {
+			t.before[id] = a.current(database, id)
+		}
+	}
+
+	return t
+}
+
+// trackScope saves the current state of each of the documents in scope, the dry run of a cascading delete.
+func (a *AuditedDB) trackScope(scope structs.CascadeDeleteResult) scopeTracker {
+	ids := make(map[string][]string)
+	for _, doc := range scope.Documents {
+		if _, ok := auditedKindOf(doc.Database, doc.ID); ok {
+			ids[doc.Database] = append(ids[doc.Database], doc.ID)
+		}
+	}
+
+	t := make(scopeTracker)
+	for database := range ids {
+		t[database] = a.track(database, ids[database]...)
+	}
+
+	return t
+}
+
+// record adds an audit entry for each of ids that changed since it was tracked.
+func (t *tracker) record(ids ...string) {
+	for _, id := range ids {
+		before, ok := t.before[id]
+		if !ok {
+			continue
+		}
+
+		after := t.a.current(t.database, id)
+
+		entry := structs.AuditEntry{
+			Database:   t.database,
+			DocumentID: id,
+			Before:     before,
+			After:      after,
+		}
+
+		switch {
+		case before == nil && after == nil:
+			continue
+		case before == nil:
+			entry.Action = structs.AuditCreated
+		case after == nil:
+			entry.Action = structs.AuditDeleted
+		default:
+			entry.Action = structs.AuditUpdated
+			entry.Changes = diffDocuments(before, after)
+
+			if len(entry.Changes) == 0 {
+				continue
+			}
+		}
+
+		t.a.addEntry(entry)
+
+		// so that recording the same id twice only records it once
+		delete(t.before, id)
+	}
+}
+
+// recordRename adds a renamed entry for each document that was successfully moved by a rename.
+func (t scopeTracker) recordRename(result structs.RenameResult) {
+	for _, doc := range result.Documents {
+		tr, ok := t[renamedDatabase(doc.Kind)]
+		if !ok || !doc.Success || doc.OldID == doc.NewID {
+			continue
+		}
+
+		before := tr.before[doc.OldID]
+		after := tr.a.current(tr.database, doc.NewID)
+
+		tr.a.addEntry(structs.AuditEntry{
+			Database:   tr.database,
+			DocumentID: doc.NewID,
+			PreviousID: doc.OldID,
+			Action:     structs.AuditRenamed,
+			Changes:    diffDocuments(before, after),
+			Before:     before,
+			After:      after,
+		})
+	}
+}
+
+// recordCascade adds a deleted entry for each document that was successfully deleted by a cascading delete.
+func (t scopeTracker) recordCascade(result structs.CascadeDeleteResult) {
+	for _, doc := range result.Documents {
+		if tr, ok := t[doc.Database]; ok && doc.Success {
+			tr.record(doc.ID)
+		}
+	}
+}
+
+// roomDeviceIDs returns the ids of the devices in each of rooms.
+func (a *AuditedDB) roomDeviceIDs(rooms ...string) []string {
+	var ids []string
+
+	for _, room := range rooms {
+		devices, err := a.DB.GetDevicesByRoom(room)
+		if err != nil {
+			continue
+		}
+
+		for _, device := range devices {
+			ids = append(ids, device.ID)
+		}
+	}
+
+	return ids
+}
+
+// current returns the current state of a document, or nil if it doesn't exist.
+func (a *AuditedDB) current(database, id string) json.RawMessage {
+	kind, ok := auditedKindOf(database, id)
+	if !ok {
+		return nil
+	}
+
+	doc, err := kind.get(a.DB, id)
+	if err != nil {
+		return nil
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		log.L.Warnf("Unable to marshal %s %s for the audit log: %s", database, id, err)
+		return nil
+	}
+
+	return b
+}
+
+// addEntry fills in who made the change and when, and adds entry to the audit log.
+func (a *AuditedDB) addEntry(entry structs.AuditEntry) {
+	entry.Timestamp = time.Now().UTC()
+	entry.ID = fmt.Sprintf("%s-%08x", entry.Timestamp.Format(structs.AuditTimeFormat), rand.Uint32())
+	entry.Actor = a.actor
+	entry.RoomID = auditedRoomID(entry.Database, entry.DocumentID)
+
+	if len(entry.Actor) == 0 {
+		entry.Actor = "unknown"
+	}
+
+	if err := a.store.AddAuditEntry(entry); err != nil {
+		log.L.Errorf("Unable to record %s of %s %s by %s in the audit log: %s", entry.Action, entry.Database, entry.DocumentID, entry.Actor, err)
+	}
+}
+
+// diffDocuments returns each field that differs between two versions of a document, ignoring its rev.
+func diffDocuments(before, after json.RawMessage) []structs.FieldChange {
+	var old, new interface{}
+	json.Unmarshal(before, &old)
+	json.Unmarshal(after, &new)
+
+	var toReturn []structs.FieldChange
+	for _, change := range diffValues("", old, new) {
+		if change.Path != "_rev" {
+			toReturn = append(toReturn, change)
+		}
+	}
+
+	return toReturn
+}
+
+// auditedRoomID returns the room a document belongs to, if it belongs to one.
+func auditedRoomID(database, id string) string {
+	switch database {
+	case couch.ROOMS, couch.UI_CONFIGS, couch.LAB_CONFIGS, couch.SCHEDULING_CONFIGS:
+		return id
+	case couch.DEVICES:
+		if split := strings.Split(id, "-"); len(split) == 3 {
+			return split[0] + "-" + split[1]
+		}
+	}
+
+	return ""
+}
+
+// renamedDatabase returns the database of a kind of document in a RenameResult.
+func renamedDatabase(kind string) string {
+	switch kind {
+	case "building":
+		return couch.BUILDINGS
+	case "room":
+		return couch.ROOMS
+	case "device":
+		return couch.DEVICES
+	case "ui-config":
+		return couch.UI_CONFIGS
+	default:
+		return kind
+	}
+}
+
+func succeeded(responses []structs.BulkUpdateResponse) []string {
+	var ids []string
+	for _, response := range responses {
+		if response.Success {
+			ids = append(ids, response.ID)
+		}
+	}
+
+	return ids
+}
+
+func deviceIDs(devices []structs.Device) []string {
+	var ids []string
+	for _, device := range devices {
+		ids = append(ids, device.ID)
+	}
+
+	return ids
+}
+
+func roomIDs(rooms []structs.Room) []string {
+	var ids []string
+	for _, room := range rooms {
+		ids = append(ids, room.ID)
+	}
+
+	return ids
+}
+
+func deviceTypeIDs(types []structs.DeviceType) []string {
+	var ids []string
+	for _, dt := range types {
+		ids = append(ids, dt.ID)
+	}
+
+	return ids
+}
@@ -0,0 +1,489 @@
+package db
+
+import (
+	"encoding/json"
+
+	"github.com/byuoitav/common/db/couch"
+	"github.com/byuoitav/common/structs"
+)
+
+// auditedKind is how to get, put back, and delete a kind of document that an AuditedDB records changes to.
+type auditedKind struct {
+	get     func(d DB, id string) (interface{}, error)
+	restore func(d DB, id string, doc json.RawMessage) error
+	remove  func(d DB, id string) error
+}
+
+// documentKind is a kind of document with a revision. new returns a pointer to an empty document, which is what create and update are passed.
+type documentKind struct {
+	new    func() interface{}
+	get    func(d DB, id string) (interface{}, error)
+	create func(d DB, id string, doc interface{}) error
+	update func(d DB, id string, doc interface{}) error
+	remove func(d DB, id string) error
+}
+
+// kind returns the auditedKind for k. A document is put back with create if it no longer exists, or with update (at its current revision) if it does.
+func (k documentKind) kind() auditedKind {
+	return auditedKind{
+		get: k.get,
+		restore: func(d DB, id string, raw json.RawMessage) error {
+			doc := k.new()
+			if err := json.Unmarshal(raw, doc); err != nil {
+				return err
+			}
+
+			current, err := k.get(d, id)
+			if err != nil {
+				if err := setRev(doc, ""); err != nil {
+					return err
+				}
+
+				return k.create(d, id, doc)
+			}
+
+			rev, err := revOf(current)
+			if err != nil {
+				return err
+			}
+
+			if err := setRev(doc, rev); err != nil {
+				return err
+			}
+
+			return k.update(d, id, doc)
+		},
+		remove: k.remove,
+	}
+}
+
+// optionListKind is an option list, which is a single document. new returns a pointer to an empty list, which is what update is passed.
+type optionListKind struct {
+	new    func() interface{}
+	get    func(d DB) (interface{}, error)
+	update func(d DB, list interface{}) error
+}
+
+// kind returns the auditedKind for k.
+func (k optionListKind) kind() auditedKind {
+	return auditedKind{
+		get: func(d DB, id string) (interface{}, error) {
+			return k.get(d)
+		},
+		restore: func(d DB, id string, raw json.RawMessage) error {
+			list := k.new()
+			if err := json.Unmarshal(raw, list); err != nil {
+				return err
+			}
+
+			return k.update(d, list)
+		},
+	}
+}
+
+// revOf returns the revision of doc.
+func revOf(doc interface{}) (string, error) {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+
+	var rev struct {
+		Rev string `json:"_rev"`
+	}
+
+	err = json.Unmarshal(b, &rev)
+	return rev.Rev, err
+}
+
+// setRev sets the revision of the document doc points to.
+func setRev(doc interface{}, rev string) error {
+	b, err := json.Marshal(map[string]string{"_rev": rev})
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, doc)
+}
+
+// auditedKinds are keyed by database, or by database/id for the option lists that are each a single document.
+var auditedKinds = map[string]auditedKind{
+	couch.BUILDINGS: documentKind{
+		new: func() interface{} {
+			return &structs.Building{}
+		},
+		get: func(d DB, id string) (interface{}, error) {
+			return d.GetBuilding(id)
+		},
+		create: func(d DB, id string, doc interface{}) error {
+			_, err := d.CreateBuilding(*doc.(*structs.Building))
+			return err
+		},
+		update: func(d DB, id string, doc interface{}) error {
+			_, err := d.UpdateBuilding(id, *doc.(*structs.Building))
+			return err
+		},
+		remove: func(d DB, id string) error {
+			return d.DeleteBuilding(id)
+		},
+	}.kind(),
+	couch.ROOMS: documentKind{
+		new: func() interface{} {
+			return &structs.Room{}
+		},
+		get: func(d DB, id string) (interface{}, error) {
+			room, err := d.GetRoom(id)
+
+			// devices are recorded on their own
+			room.Devices = nil
+			return room, err
+		},
+		create: func(d DB, id string, doc interface{}) error {
+			_, err := d.CreateRoom(*doc.(*structs.Room))
+			return err
+		},
+		update: func(d DB, id string, doc interface{}) error {
+			_, err := d.UpdateRoom(id, *doc.(*structs.Room))
+			return err
+		},
+		remove: func(d DB, id string) error {
+			return d.DeleteRoom(id)
+		},
+	}.kind(),
+	couch.DEVICES: documentKind{
+		new: func() interface{} {
+			return &structs.Device{}
+		},
+		get: func(d DB, id string) (interface{}, error) {
+			return d.GetDevice(id)
+		},
+		create: func(d DB, id string, doc interface{}) error {
+			_, err := d.CreateDevice(*doc.(*structs.Device))
+			return err
+		},
+		update: func(d DB, id string, doc interface{}) error {
+			_, err := d.UpdateDevice(id, *doc.(*structs.Device))
+			return err
+		},
+		remove: func(d DB, id string) error {
+			return d.DeleteDevice(id)
+		},
+	}.kind(),
+	couch.DEVICE_TYPES: documentKind{
+		new: func() interface{} {
+			return &structs.DeviceType{}
+		},
+		get: func(d DB, id string) (interface{}, error) {
+			return d.GetDeviceType(id)
+		},
+		create: func(d DB, id string, doc interface{}) error {
+			_, err := d.CreateDeviceType(*doc.(*structs.DeviceType))
+			return err
+		},
+		update: func(d DB, id string, doc interface{}) error {
+			_, err := d.UpdateDeviceType(id, *doc.(*structs.DeviceType))
+			return err
+		},
+		remove: func(d DB, id string) error {
+			return d.DeleteDeviceType(id)
+		},
+	}.kind(),
+	couch.ROOM_CONFIGURATIONS: documentKind{
+		new: func() interface{} {
+			return &structs.RoomConfiguration{}
+		},
+		get: func(d DB, id string) (interface{}, error) {
+			return d.GetRoomConfiguration(id)
+		},
+		create: func(d DB, id string, doc interface{}) error {
+			_, err := d.CreateRoomConfiguration(*doc.(*structs.RoomConfiguration))
+			return err
+		},
+		update: func(d DB, id string, doc interface{}) error {
+			_, err := d.UpdateRoomConfiguration(id, *doc.(*structs.RoomConfiguration))
+			return err
+		},
+		remove: func(d DB, id string) error {
+			return d.DeleteRoomConfiguration(id)
+		},
+	}.kind(),
+	couch.UI_CONFIGS: documentKind{
+		new: func() interface{} {
+			return &structs.UIConfig{}
+		},
+		get: func(d DB, id string) (interface{}, error) {
+			return d.GetUIConfig(id)
+		},
+		create: func(d DB, id string, doc interface{}) error {
+			_, err := d.CreateUIConfig(id, *doc.(*structs.UIConfig))
+			return err
+		},
+		update: func(d DB, id string, doc interface{}) error {
+			_, err := d.UpdateUIConfig(id, *doc.(*structs.UIConfig))
+			return err
+		},
+		remove: func(d DB, id string) error {
+			return d.DeleteUIConfig(id)
+		},
+	}.kind(),
+	couch.LAB_CONFIGS: documentKind{
+		new: func() interface{} {
+			return &structs.LabConfig{}
+		},
+		get: func(d DB, id string) (interface{}, error) {
+			return d.GetLabConfig(id)
+		},
+		create: func(d DB, id string, doc interface{}) error {
+			_, err := d.CreateLabConfig(*doc.(*structs.LabConfig))
+			return err
+		},
+		update: func(d DB, id string, doc interface{}) error {
+			_, err := d.UpdateLabConfig(id, *doc.(*structs.LabConfig))
+			return err
+		},
+		remove: func(d DB, id string) error {
+			return d.DeleteLabConfig(id)
+		},
+	}.kind(),
+	couch.SCHEDULING_CONFIGS: documentKind{
+		new: func() interface{} {
+			return &structs.ScheduleConfig{}
+		},
+		get: func(d DB, id string) (interface{}, error) {
+			return d.GetScheduleConfig(id)
+		},
+		create: func(d DB, id string, doc interface{}) error {
+			_, err := d.CreateScheduleConfig(*doc.(*structs.ScheduleConfig))
+			return err
+		},
+		update: func(d DB, id string, doc interface{}) error {
+			_, err := d.UpdateScheduleConfig(id, *doc.(*structs.ScheduleConfig))
+			return err
+		},
+		remove: func(d DB, id string) error {
+			return d.DeleteScheduleConfig(id)
+		},
+	}.kind(),
+	couch.DMPSLIST + "/" + couch.DMPS_LIST_ID: {
+		get: func(d DB, id string) (interface{}, error) {
+			return d.GetDMPSList()
+		},
+		restore: func(d DB, id string, doc json.RawMessage) error {
+			var list structs.DMPSList
+			if err := json.Unmarshal(doc, &list); err != nil {
+				return err
+			}
+
+			current, err := d.GetDMPSList()
+			if err != nil {
+				return err
+			}
+
+			// the list can only be changed an entry at a time
+			keep := make(map[string]structs.DMPS)
+			for _, dmps := range list.List {
+				keep[dmps.Hostname] = dmps
+			}
+
+			exists := make(map[string]bool)
+			for _, dmps := range current.List {
+				exists[dmps.Hostname] = true
+
+				if _, ok := keep[dmps.Hostname]; !ok {
+					if _, err := d.DeleteDMPS(dmps.Hostname); err != nil {
+						return err
+					}
+				}
+			}
+
+			for _, dmps := range list.List {
+				if exists[dmps.Hostname] {
+					_, err = d.UpdateDMPS(dmps.Hostname, dmps)
+				} else {
+					_, err = d.CreateDMPS(dmps)
+				}
+
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	},
+	couch.AUTH + "/" + couch.AUTH: {
+		get: func(d DB, id string) (interface{}, error) {
+			return d.GetAuth()
+		},
+		restore: func(d DB, id string, doc json.RawMessage) error {
+			var auth structs.Auth
+			if err := json.Unmarshal(doc, &auth); err != nil {
+				return err
+			}
+
+			current, err := d.GetAuth()
+			if err != nil {
+				return err
+			}
+
+			// permissions can only be changed one at a time
+			keep := make(map[string]structs.Permission)
+			for _, permission := range auth.Permissions {
+				keep[permission.Group] = permission
+			}
+
+			exists := make(map[string]bool)
+			for _, permission := range current.Permissions {
+				exists[permission.Group] = true
+
+				if _, ok := keep[permission.Group]; !ok {
+					if _, err := d.DeletePermission(permission.Group); err != nil {
+						return err
+					}
+				}
+			}
+
+			for _, permission := range auth.Permissions {
+				if exists[permission.Group] {
+					_, err = d.UpdatePermission(permission.Group, permission)
+				} else {
+					_, err = d.CreatePermission(permission)
+				}
+
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	},
+	couch.ATTRIBUTES: documentKind{
+		new: func() interface{} {
+			return &structs.Group{}
+		},
+		get: func(d DB, id string) (interface{}, error) {
+			return d.GetAttributeGroup(id)
+		},
+		create: func(d DB, id string, doc interface{}) error {
+			_, err := d.CreateAttributeGroup(*doc.(*structs.Group))
+			return err
+		},
+		update: func(d DB, id string, doc interface{}) error {
+			_, err := d.UpdateAttributeGroup(id, *doc.(*structs.Group))
+			return err
+		},
+		remove: func(d DB, id string) error {
+			return d.DeleteAttributeGroup(id)
+		},
+	}.kind(),
+	// templates
+	couch.OPTIONS: {
+		get: func(d DB, id string) (interface{}, error) {
+			return d.GetTemplate(id)
+		},
+		restore: func(d DB, id string, doc json.RawMessage) error {
+			var ui structs.UIConfig
+			if err := json.Unmarshal(doc, &ui); err != nil {
+				return err
+			}
+
+			_, err := d.UpdateTemplate(id, ui)
+			return err
+		},
+	},
+	couch.OPTIONS + "/" + couch.ICONS: optionListKind{
+		new: func() interface{} {
+			return &[]string{}
+		},
+		get: func(d DB) (interface{}, error) {
+			return d.GetIcons()
+		},
+		update: func(d DB, list interface{}) error {
+			_, err := d.UpdateIcons(*list.(*[]string))
+			return err
+		},
+	}.kind(),
+	couch.OPTIONS + "/" + couch.ROLES: optionListKind{
+		new: func() interface{} {
+			return &[]structs.Role{}
+		},
+		get: func(d DB) (interface{}, error) {
+			return d.GetDeviceRoles()
+		},
+		update: func(d DB, list interface{}) error {
+			_, err := d.UpdateDeviceRoles(*list.(*[]structs.Role))
+			return err
+		},
+	}.kind(),
+	couch.OPTIONS + "/" + couch.ROOM_DESIGNATIONS: optionListKind{
+		new: func() interface{} {
+			return &[]string{}
+		},
+		get: func(d DB) (interface{}, error) {
+			return d.GetRoomDesignations()
+		},
+		update: func(d DB, list interface{}) error {
+			_, err := d.UpdateRoomDesignations(*list.(*[]string))
+			return err
+		},
+	}.kind(),
+	couch.OPTIONS + "/" + couch.ROOM_SCHEMAS: optionListKind{
+		new: func() interface{} {
+			return &map[string][]structs.AttributeSchema{}
+		},
+		get: func(d DB) (interface{}, error) {
+			return d.GetRoomAttributeSchemas()
+		},
+		update: func(d DB, list interface{}) error {
+			_, err := d.UpdateRoomAttributeSchemas(*list.(*map[string][]structs.AttributeSchema))
+			return err
+		},
+	}.kind(),
+	couch.OPTIONS + "/" + couch.CLOSURE_CODES: optionListKind{
+		new: func() interface{} {
+			return &[]string{}
+		},
+		get: func(d DB) (interface{}, error) {
+			return d.GetClosureCodes()
+		},
+		update: func(d DB, list interface{}) error {
+			_, err := d.UpdateClosureCodes(*list.(*[]string))
+			return err
+		},
+	}.kind(),
+	couch.OPTIONS + "/" + couch.TAGS: optionListKind{
+		new: func() interface{} {
+			return &[]string{}
+		},
+		get: func(d DB) (interface{}, error) {
+			return d.GetTags()
+		},
+		update: func(d DB, list interface{}) error {
+			_, err := d.UpdateTags(*list.(*[]string))
+			return err
+		},
+	}.kind(),
+	couch.OPTIONS + "/" + couch.MENUTREE: optionListKind{
+		new: func() interface{} {
+			return &[]string{}
+		},
+		get: func(d DB) (interface{}, error) {
+			return d.GetMenuTree()
+		},
+		update: func(d DB, list interface{}) error {
+			_, err := d.UpdateMenuTree(*list.(*[]string))
+			return err
+		},
+	}.kind(),
+}
+
+// auditedKindOf returns how to handle the document id in database, if changes to it are recorded.
+func auditedKindOf(database, id string) (auditedKind, bool) {
+	if kind, ok := auditedKinds[database+"/"+id]; ok {
+		return kind, true
+	}
+
+	kind, ok := auditedKinds[database]
+	return kind, ok
+}
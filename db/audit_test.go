@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/byuoitav/common/db/couch"
+	"github.com/byuoitav/common/structs"
+)
+
+func TestAuditedDB(t *testing.T) {
+	m := newSeededMemoryDB(t)
+
+	start := time.Now()
+	ctx := context.WithValue(context.Background(), "user", "alice")
+	d := WithContext(ctx, NewAuditedDB(m, m))
+
+	building, err := d.GetBuilding("AAA")
+	if err != nil {
+		t.Fatalf("failed to get building: %s", err)
+	}
+
+	building.Description = "changed"
+	if _, err := d.UpdateBuilding(building.ID, building); err != nil {
+		t.Fatalf("failed to update building: %s", err)
+	}
+
+	history, err := m.GetDocumentHistory(couch.BUILDINGS, "AAA")
+	if err != nil {
+		t.Fatalf("failed to get history: %s", err)
+	}
+
+	if len(history) != 1 || history[0].Action != structs.AuditUpdated || history[0].Actor != "alice" {
+		t.Fatalf("unexpected history: %+v", history)
+	}
+
+	if len(history[0].Changes) != 1 || history[0].Changes[0].Path != "description" || history[0].Changes[0].New != "changed" {
+		t.Fatalf("unexpected changes: %+v", history[0].Changes)
+	}
+
+	room, err := d.GetRoom("CCC-AAA")
+	if err != nil {
+		t.Fatalf("failed to get room: %s", err)
+	}
+
+	room.Description = "changed"
+	if _, err := d.UpdateRoom(room.ID, room); err != nil {
+		t.Fatalf("failed to update room: %s", err)
+	}
+
+	if changes, _ := m.GetRoomHistory("CCC-AAA", start); len(changes) != 1 || changes[0].DocumentID != "CCC-AAA" {
+		t.Fatalf("unexpected room history: %+v", changes)
+	}
+
+	if changes, _ := m.GetRoomHistory("CCC-AAA", time.Now()); len(changes) != 0 {
+		t.Fatalf("expected no room history since now: %+v", changes)
+	}
+
+	audited := d.(*AuditedDB)
+	if err := audited.Revert(history[0].ID); err != nil {
+		t.Fatalf("failed to revert: %s", err)
+	}
+
+	if b, _ := m.GetBuilding("AAA"); b.Description != "Antelope" {
+		t.Fatalf("building wasn't reverted: %+v", b)
+	}
+
+	if err := d.DeleteBuilding("BBB"); err != nil {
+		t.Fatalf("failed to delete building: %s", err)
+	}
+
+	deleted, _ := m.GetDocumentHistory(couch.BUILDINGS, "BBB")
+	if len(deleted) != 1 || deleted[0].Action != structs.AuditDeleted || len(deleted[0].Before) == 0 {
+		t.Fatalf("unexpected history: %+v", deleted)
+	}
+
+	if err := audited.Revert(deleted[0].ID); err != nil {
+		t.Fatalf("failed to revert delete: %s", err)
+	}
+
+	if _, err := m.GetBuilding("BBB"); err != nil {
+		t.Fatalf("deleted building wasn't recreated: %s", err)
+	}
+
+	device := structs.Device{ID: "CCC-AAA-D1", Name: "D1", Address: "ccc-aaa-d1.byu.edu", Type: structs.DeviceType{ID: "test-display"}, Roles: []structs.Role{{ID: "VideoOut"}}}
+	if _, err := d.CreateDevice(device); err != nil {
+		t.Fatalf("failed to create device: %s", err)
+	}
+
+	// the room's devices are deleted with it
+	if err := d.DeleteRoom("CCC-AAA"); err != nil {
+		t.Fatalf("failed to delete room: %s", err)
+	}
+
+	devices, _ := m.GetDocumentHistory(couch.DEVICES, device.ID)
+	if len(devices) != 2 || devices[1].Action != structs.AuditDeleted {
+		t.Fatalf("expected the device's deletion to be recorded: %+v", devices)
+	}
+}
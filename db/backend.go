@@ -0,0 +1,126 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/byuoitav/common/db/couch"
+	"github.com/byuoitav/common/db/file"
+	"github.com/byuoitav/common/db/memory"
+)
+
+// Opener opens the database described by u, whose scheme it was registered for.
+type Opener func(u *url.URL) (DB, error)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = make(map[string]Opener)
+)
+
+func init() {
+	RegisterBackend("couch", openCouch)
+	RegisterBackend("couchs", openCouch)
+	RegisterBackend("http", openCouch)
+	RegisterBackend("https", openCouch)
+	RegisterBackend("file", openFile)
+	RegisterBackend("mem", openMemory)
+}
+
+// RegisterBackend makes open available to Open (and GetDB) for urls with scheme. Registering a scheme again replaces its opener.
+func RegisterBackend(scheme string, open Opener) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	backends[strings.ToLower(scheme)] = open
+}
+
+/*
+Open returns the database described by rawurl. The scheme picks the backend:
+
+	couch://host:5984, couchs://host:6984    couch over http or https. http(s):// urls are couch too.
+	file:///path/to/bundle                   a read-only database of the documents in a directory (see package file).
+	mem://, mem:///path/to/fixtures          an in-memory database, optionally seeded with the fixtures in a directory.
+
+Couch credentials can be included in the url; otherwise DB_USERNAME and DB_PASSWORD are used.
+*/
+func Open(rawurl string) (DB, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse database url: %s", err)
+	}
+
+	if len(u.Scheme) == 0 {
+		return nil, fmt.Errorf("database url %q doesn't have a scheme", rawurl)
+	}
+
+	backendsMu.RLock()
+	open, ok := backends[strings.ToLower(u.Scheme)]
+	backendsMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("no database backend registered for %s://", u.Scheme)
+	}
+
+	return open(u)
+}
+
+func openCouch(u *url.URL) (DB, error) {
+	addr := *u
+
+	switch strings.ToLower(u.Scheme) {
+	case "couch":
+		addr.Scheme = "http"
+	case "couchs":
+		addr.Scheme = "https"
+	}
+
+	user, pass := username, password
+	if u.User != nil {
+		user = u.User.Username()
+		pass, _ = u.User.Password()
+		addr.User = nil
+	}
+
+	return couch.NewDB(addr.String(), user, pass), nil
+}
+
+func openFile(u *url.URL) (DB, error) {
+	d, err := file.NewDB(urlPath(u))
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func openMemory(u *url.URL) (DB, error) {
+	m := memory.NewDB()
+
+	if dir := urlPath(u); len(dir) > 0 {
+		if err := m.LoadFixtures(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// urlPath returns the path in u, allowing relative paths like file://bundle or file:bundle.
+func urlPath(u *url.URL) string {
+	if len(u.Opaque) > 0 {
+		return u.Opaque
+	}
+
+	return u.Host + u.Path
+}
+
+// backendURL returns the url GetDB opens. If DB_BACKEND is set, and DB_ADDRESS doesn't have a scheme, DB_BACKEND is used as its scheme.
+func backendURL() string {
+	if len(backend) > 0 && !strings.Contains(address, "://") {
+		return backend + "://" + address
+	}
+
+	return address
+}
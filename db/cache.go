@@ -0,0 +1,605 @@
+package db
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/common/db/couch"
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/structs"
+)
+
+// cache key prefixes
+const (
+	cacheBuilding      = "building/"
+	cacheRoom          = "room/"
+	cacheDevice        = "device/"
+	cacheDevicesByRoom = "devicesbyroom/"
+	cacheDeviceType    = "devicetype/"
+	cacheRoomConfig    = "roomconfig/"
+	cacheUIConfig      = "uiconfig/"
+)
+
+// CacheOptions configures a CachedDB.
+type CacheOptions struct {
+	// TTL is how long a document is cached for. Defaults to 5 minutes.
+	TTL time.Duration
+
+	// MaxEntries is the most documents that are cached at once; the least recently used are evicted first. Defaults to 1000.
+	MaxEntries int
+}
+
+// CacheStats are the hit/miss statistics of a CachedDB.
+type CacheStats struct {
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Evictions     uint64 `json:"evictions"`
+	Invalidations uint64 `json:"invalidations"`
+	Entries       int    `json:"entries"`
+}
+
+/*
+CachedDB is a read-through cache in front of another DB. GetBuilding, GetRoom, GetDevice,
+GetDevicesByRoom, GetDeviceType, GetRoomConfiguration, and GetUIConfig are cached; every
+other function passes straight through to the wrapped DB.
+
+Writes made through the CachedDB invalidate the cached documents they affect. Writes made
+by anyone else are only seen once the cached document expires, unless the cache is also
+fed the couch changes feed with InvalidateOnChanges.
+
+A cached document keeps the revision it had when it was cached, so updating it after someone
+else has changed it fails with a *couch.Conflict. Writes invalidate even when they fail, so
+getting the document again returns its current revision.
+*/
+type CachedDB struct {
+	DB
+
+	cache *cache
+}
+
+type cache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	stats   CacheStats
+}
+
+type cacheEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewCachedDB returns a CachedDB wrapping d.
+func NewCachedDB(d DB, opts CacheOptions) *CachedDB {
+	if opts.TTL <= 0 {
+		opts.TTL = 5 * time.Minute
+	}
+
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = 1000
+	}
+
+	return &CachedDB{
+		DB: d,
+		cache: &cache{
+			ttl:        opts.TTL,
+			maxEntries: opts.MaxEntries,
+			entries:    make(map[string]*list.Element),
+			lru:        list.New(),
+		},
+	}
+}
+
+// Unwrap returns the DB that c caches.
+func (c *CachedDB) Unwrap() DB {
+	return c.DB
+}
+
+// WithContext returns a CachedDB sharing c's cache, whose requests to the wrapped DB are bound to ctx.
+func (c *CachedDB) WithContext(ctx context.Context) DB {
+	return &CachedDB{
+		DB:    WithContext(ctx, c.DB),
+		cache: c.cache,
+	}
+}
+
+// Stats returns the cache's statistics, suitable for including in a status response.
+func (c *CachedDB) Stats() CacheStats {
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+
+	stats := c.cache.stats
+	stats.Entries = c.cache.lru.Len()
+	return stats
+}
+
+// Flush removes every document from the cache.
+func (c *CachedDB) Flush() {
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+
+	c.cache.stats.Invalidations += uint64(c.cache.lru.Len())
+	c.cache.entries = make(map[string]*list.Element)
+	c.cache.lru.Init()
+}
+
+/*
+InvalidateOnChanges invalidates cached documents as changes come in from a couch changes
+feed (see couch.CouchDB.Watch). It returns once changes is closed, so it is usually run in
+its own goroutine, once for each watched database:
+
+	changes, err := couchDB.WithContext(ctx).Watch(couch.DEVICES, "")
+	...
+	go cachedDB.InvalidateOnChanges(changes)
+*/
+func (c *CachedDB) InvalidateOnChanges(changes <-chan couch.Change) {
+	for change := range changes {
+		switch change.Database {
+		case couch.DEVICES:
+			c.invalidateDevice(change.ID)
+		case couch.ROOMS:
+			c.invalidateRoom(change.ID)
+		case couch.UI_CONFIGS:
+			c.cache.invalidate(cacheUIConfig + change.ID)
+		case couch.BUILDINGS:
+			c.cache.invalidate(cacheBuilding + change.ID)
+		case couch.DEVICE_TYPES:
+			c.invalidateDeviceType(change.ID)
+		case couch.ROOM_CONFIGURATIONS:
+			c.invalidateRoomConfiguration(change.ID)
+		default:
+			log.L.Debugf("Ignoring change to %s/%s; it isn't cached", change.Database, change.ID)
+		}
+	}
+}
+
+// get fills toFill from the cache if key is cached, otherwise from fetch.
+func (c *cache) get(key string, toFill interface{}, fetch func() (interface{}, error)) error {
+	if b, ok := c.lookup(key); ok {
+		return json.Unmarshal(b, toFill)
+	}
+
+	v, err := fetch()
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	c.store(key, b)
+
+	// the caller gets its own copy, so changing it doesn't change what is cached
+	return json.Unmarshal(b, toFill)
+}
+
+func (c *cache) lookup(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(elem)
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	c.stats.Hits++
+	return entry.value, true
+}
+
+func (c *cache) store(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+
+	c.entries[key] = c.lru.PushFront(&cacheEntry{
+		key:     key,
+		value:   value,
+		expires: time.Now().Add(c.ttl),
+	})
+
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+// remove must be called with c.mu held.
+func (c *cache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry).key)
+}
+
+func (c *cache) invalidate(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if elem, ok := c.entries[key]; ok {
+			c.remove(elem)
+			c.stats.Invalidations++
+		}
+	}
+}
+
+func (c *cache) invalidatePrefix(prefixes ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, elem := range c.entries {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				c.remove(elem)
+				c.stats.Invalidations++
+				break
+			}
+		}
+	}
+}
+
+// invalidateBuilding invalidates a building, and everything in it.
+func (c *CachedDB) invalidateBuilding(id string) {
+	c.cache.invalidate(cacheBuilding + id)
+	c.cache.invalidatePrefix(cacheRoom+id+"-", cacheDevicesByRoom+id+"-", cacheDevice+id+"-", cacheUIConfig+id+"-")
+}
+
+// invalidateRoom invalidates a room, and everything in it.
+func (c *CachedDB) invalidateRoom(id string) {
+	c.cache.invalidate(cacheRoom+id, cacheDevicesByRoom+id, cacheUIConfig+id)
+	c.cache.invalidatePrefix(cacheDevice + id + "-")
+}
+
+// invalidateDevice invalidates a device, and the room it is in.
+func (c *CachedDB) invalidateDevice(id string) {
+	c.cache.invalidate(cacheDevice + id)
+
+	if split := strings.Split(id, "-"); len(split) == 3 {
+		roomID := split[0] + "-" + split[1]
+		c.cache.invalidate(cacheRoom+roomID, cacheDevicesByRoom+roomID)
+	}
+}
+
+// invalidateDeviceType invalidates a device type, and every device (and room) that includes it.
+func (c *CachedDB) invalidateDeviceType(id string) {
+	c.cache.invalidate(cacheDeviceType + id)
+	c.cache.invalidatePrefix(cacheDevice, cacheDevicesByRoom, cacheRoom)
+}
+
+// invalidateRoomConfiguration invalidates a room configuration, and every room that includes it.
+func (c *CachedDB) invalidateRoomConfiguration(id string) {
+	c.cache.invalidate(cacheRoomConfig + id)
+	c.cache.invalidatePrefix(cacheRoom)
+}
+
+/* cached reads */
+
+// GetBuilding .
+func (c *CachedDB) GetBuilding(id string) (structs.Building, error) {
+	var toReturn structs.Building
+	err := c.cache.get(cacheBuilding+id, &toReturn, func() (interface{}, error) {
+		return c.DB.GetBuilding(id)
+	})
+
+	return toReturn, err
+}
+
+// GetRoom .
+func (c *CachedDB) GetRoom(id string) (structs.Room, error) {
+	var toReturn structs.Room
+	err := c.cache.get(cacheRoom+id, &toReturn, func() (interface{}, error) {
+		return c.DB.GetRoom(id)
+	})
+
+	return toReturn, err
+}
+
+// GetDevice .
+func (c *CachedDB) GetDevice(id string) (structs.Device, error) {
+	var toReturn structs.Device
+	err := c.cache.get(cacheDevice+id, &toReturn, func() (interface{}, error) {
+		return c.DB.GetDevice(id)
+	})
+
+	return toReturn, err
+}
+
+// GetDevicesByRoom .
+func (c *CachedDB) GetDevicesByRoom(roomID string) ([]structs.Device, error) {
+	var toReturn []structs.Device
+	err := c.cache.get(cacheDevicesByRoom+roomID, &toReturn, func() (interface{}, error) {
+		return c.DB.GetDevicesByRoom(roomID)
+	})
+
+	return toReturn, err
+}
+
+// GetDeviceType .
+func (c *CachedDB) GetDeviceType(id string) (structs.DeviceType, error) {
+	var toReturn structs.DeviceType
+	err := c.cache.get(cacheDeviceType+id, &toReturn, func() (interface{}, error) {
+		return c.DB.GetDeviceType(id)
+	})
+
+	return toReturn, err
+}
+
+// GetRoomConfiguration .
+func (c *CachedDB) GetRoomConfiguration(id string) (structs.RoomConfiguration, error) {
+	var toReturn structs.RoomConfiguration
+	err := c.cache.get(cacheRoomConfig+id, &toReturn, func() (interface{}, error) {
+		return c.DB.GetRoomConfiguration(id)
+	})
+
+	return toReturn, err
+}
+
+// GetUIConfig .
+func (c *CachedDB) GetUIConfig(roomID string) (structs.UIConfig, error) {
+	var toReturn structs.UIConfig
+	err := c.cache.get(cacheUIConfig+roomID, &toReturn, func() (interface{}, error) {
+		return c.DB.GetUIConfig(roomID)
+	})
+
+	return toReturn, err
+}
+
+/* invalidating writes; each invalidates even if the write fails, e.g. because the cached revision was stale */
+
+// CreateBuilding .
+func (c *CachedDB) CreateBuilding(building structs.Building) (structs.Building, error) {
+	defer c.invalidateBuilding(building.ID)
+	return c.DB.CreateBuilding(building)
+}
+
+// UpdateBuilding .
+func (c *CachedDB) UpdateBuilding(id string, building structs.Building) (structs.Building, error) {
+	defer c.invalidateBuilding(building.ID)
+	defer c.invalidateBuilding(id)
+	return c.DB.UpdateBuilding(id, building)
+}
+
+// DeleteBuilding .
+func (c *CachedDB) DeleteBuilding(id string) error {
+	defer c.invalidateBuilding(id)
+	return c.DB.DeleteBuilding(id)
+}
+
+// RenameBuilding .
+func (c *CachedDB) RenameBuilding(oldID, newID string) (structs.RenameResult, error) {
+	defer c.invalidateBuilding(newID)
+	defer c.invalidateBuilding(oldID)
+	return c.DB.RenameBuilding(oldID, newID)
+}
+
+// DeleteBuildingCascade .
+func (c *CachedDB) DeleteBuildingCascade(id string, dryRun bool) (structs.CascadeDeleteResult, error) {
+	defer c.invalidateBuilding(id)
+	return c.DB.DeleteBuildingCascade(id, dryRun)
+}
+
+// CreateRoom .
+func (c *CachedDB) CreateRoom(room structs.Room) (structs.Room, error) {
+	defer c.invalidateRoom(room.ID)
+	defer c.cache.invalidate(cacheRoomConfig + room.Configuration.ID)
+	return c.DB.CreateRoom(room)
+}
+
+// UpdateRoom .
+func (c *CachedDB) UpdateRoom(id string, room structs.Room) (structs.Room, error) {
+	defer c.invalidateRoom(room.ID)
+	defer c.invalidateRoom(id)
+	defer c.cache.invalidate(cacheRoomConfig + room.Configuration.ID)
+	return c.DB.UpdateRoom(id, room)
+}
+
+// RenameRoom .
+func (c *CachedDB) RenameRoom(oldID, newID string) (structs.RenameResult, error) {
+	defer c.invalidateRoom(newID)
+	defer c.invalidateRoom(oldID)
+	return c.DB.RenameRoom(oldID, newID)
+}
+
+// DeleteRoom .
+func (c *CachedDB) DeleteRoom(id string) error {
+	defer c.invalidateRoom(id)
+	return c.DB.DeleteRoom(id)
+}
+
+// DeleteRoomCascade .
+func (c *CachedDB) DeleteRoomCascade(id string, dryRun bool) (structs.CascadeDeleteResult, error) {
+	defer c.invalidateRoom(id)
+	return c.DB.DeleteRoomCascade(id, dryRun)
+}
+
+// CreateDevice .
+func (c *CachedDB) CreateDevice(device structs.Device) (structs.Device, error) {
+	defer c.invalidateDevice(device.ID)
+	defer c.cache.invalidate(cacheDeviceType + device.Type.ID)
+	return c.DB.CreateDevice(device)
+}
+
+// UpdateDevice .
+func (c *CachedDB) UpdateDevice(id string, device structs.Device) (structs.Device, error) {
+	defer c.invalidateDevice(device.ID)
+	defer c.invalidateDevice(id)
+	return c.DB.UpdateDevice(id, device)
+}
+
+// DeleteDevice .
+func (c *CachedDB) DeleteDevice(id string) error {
+	defer c.invalidateDevice(id)
+	return c.DB.DeleteDevice(id)
+}
+
+// CreateBulkDevices .
+func (c *CachedDB) CreateBulkDevices(devices []structs.Device) []structs.BulkUpdateResponse {
+	for _, device := range devices {
+		defer c.invalidateDevice(device.ID)
+		defer c.cache.invalidate(cacheDeviceType + device.Type.ID)
+	}
+
+	return c.DB.CreateBulkDevices(devices)
+}
+
+// UpdateBulkDevices .
+func (c *CachedDB) UpdateBulkDevices(devices []structs.Device) []structs.BulkUpdateResponse {
+	for _, device := range devices {
+		defer c.invalidateDevice(device.ID)
+		defer c.cache.invalidate(cacheDeviceType + device.Type.ID)
+	}
+
+	return c.DB.UpdateBulkDevices(devices)
+}
+
+// DeleteBulkDevices .
+func (c *CachedDB) DeleteBulkDevices(ids []string) []structs.BulkUpdateResponse {
+	for _, id := range ids {
+		defer c.invalidateDevice(id)
+	}
+
+	return c.DB.DeleteBulkDevices(ids)
+}
+
+// CreateBulkRooms .
+func (c *CachedDB) CreateBulkRooms(rooms []structs.Room) []structs.BulkUpdateResponse {
+	for _, room := range rooms {
+		defer c.invalidateRoom(room.ID)
+		defer c.cache.invalidate(cacheRoomConfig + room.Configuration.ID)
+
+		for _, device := range room.Devices {
+			defer c.cache.invalidate(cacheDeviceType + device.Type.ID)
+		}
+	}
+
+	return c.DB.CreateBulkRooms(rooms)
+}
+
+// UpdateBulkRooms .
+func (c *CachedDB) UpdateBulkRooms(rooms []structs.Room) []structs.BulkUpdateResponse {
+	for _, room := range rooms {
+		defer c.invalidateRoom(room.ID)
+		defer c.cache.invalidate(cacheRoomConfig + room.Configuration.ID)
+	}
+
+	return c.DB.UpdateBulkRooms(rooms)
+}
+
+// DeleteBulkRooms .
+func (c *CachedDB) DeleteBulkRooms(ids []string) []structs.BulkUpdateResponse {
+	for _, id := range ids {
+		defer c.invalidateRoom(id)
+	}
+
+	return c.DB.DeleteBulkRooms(ids)
+}
+
+// CreateDeviceType .
+func (c *CachedDB) CreateDeviceType(dt structs.DeviceType) (structs.DeviceType, error) {
+	defer c.cache.invalidate(cacheDeviceType + dt.ID)
+	return c.DB.CreateDeviceType(dt)
+}
+
+// UpdateDeviceType .
+func (c *CachedDB) UpdateDeviceType(id string, dt structs.DeviceType) (structs.DeviceType, error) {
+	defer c.invalidateDeviceType(dt.ID)
+	defer c.invalidateDeviceType(id)
+	return c.DB.UpdateDeviceType(id, dt)
+}
+
+// DeleteDeviceType .
+func (c *CachedDB) DeleteDeviceType(id string) error {
+	defer c.invalidateDeviceType(id)
+	return c.DB.DeleteDeviceType(id)
+}
+
+// CreateBulkDeviceTypes .
+func (c *CachedDB) CreateBulkDeviceTypes(types []structs.DeviceType) []structs.BulkUpdateResponse {
+	for _, dt := range types {
+		defer c.cache.invalidate(cacheDeviceType + dt.ID)
+	}
+
+	return c.DB.CreateBulkDeviceTypes(types)
+}
+
+// UpdateBulkDeviceTypes .
+func (c *CachedDB) UpdateBulkDeviceTypes(types []structs.DeviceType) []structs.BulkUpdateResponse {
+	defer c.cache.invalidatePrefix(cacheDevice, cacheDevicesByRoom, cacheRoom)
+	for _, dt := range types {
+		defer c.cache.invalidate(cacheDeviceType + dt.ID)
+	}
+
+	return c.DB.UpdateBulkDeviceTypes(types)
+}
+
+// DeleteBulkDeviceTypes .
+func (c *CachedDB) DeleteBulkDeviceTypes(ids []string) []structs.BulkUpdateResponse {
+	for _, id := range ids {
+		defer c.cache.invalidate(cacheDeviceType + id)
+	}
+
+	return c.DB.DeleteBulkDeviceTypes(ids)
+}
+
+// CreateRoomConfiguration .
+func (c *CachedDB) CreateRoomConfiguration(rc structs.RoomConfiguration) (structs.RoomConfiguration, error) {
+	defer c.cache.invalidate(cacheRoomConfig + rc.ID)
+	return c.DB.CreateRoomConfiguration(rc)
+}
+
+// UpdateRoomConfiguration .
+func (c *CachedDB) UpdateRoomConfiguration(id string, rc structs.RoomConfiguration) (structs.RoomConfiguration, error) {
+	defer c.invalidateRoomConfiguration(rc.ID)
+	defer c.invalidateRoomConfiguration(id)
+	return c.DB.UpdateRoomConfiguration(id, rc)
+}
+
+// DeleteRoomConfiguration .
+func (c *CachedDB) DeleteRoomConfiguration(id string) error {
+	defer c.invalidateRoomConfiguration(id)
+	return c.DB.DeleteRoomConfiguration(id)
+}
+
+// CreateUIConfig .
+func (c *CachedDB) CreateUIConfig(roomID string, ui structs.UIConfig) (structs.UIConfig, error) {
+	defer c.cache.invalidate(cacheUIConfig + roomID)
+	return c.DB.CreateUIConfig(roomID, ui)
+}
+
+// UpdateUIConfig .
+func (c *CachedDB) UpdateUIConfig(id string, ui structs.UIConfig) (structs.UIConfig, error) {
+	defer c.cache.invalidate(cacheUIConfig+id, cacheUIConfig+ui.ID)
+	return c.DB.UpdateUIConfig(id, ui)
+}
+
+// DeleteUIConfig .
+func (c *CachedDB) DeleteUIConfig(id string) error {
+	defer c.cache.invalidate(cacheUIConfig + id)
+	return c.DB.DeleteUIConfig(id)
+}
+
+// ImportSnapshot flushes the cache, since any of the documents may have changed.
+func (c *CachedDB) ImportSnapshot(snapshot structs.Snapshot, strategy structs.ConflictStrategy) (structs.ImportResult, error) {
+	defer c.Flush()
+	return c.DB.ImportSnapshot(snapshot, strategy)
+}
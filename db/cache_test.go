@@ -0,0 +1,146 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/byuoitav/common/db/couch"
+	"github.com/byuoitav/common/db/memory"
+)
+
+// the same fixtures that the couch tests use
+var testDir = `couch/test-data`
+
+func newSeededMemoryDB(t *testing.T) *memory.MemoryDB {
+	m := memory.NewDB()
+	if err := m.LoadFixtures(testDir); err != nil {
+		t.Fatalf("failed to load fixtures: %s", err)
+	}
+
+	return m
+}
+
+func TestCachedDB(t *testing.T) {
+	m := newSeededMemoryDB(t)
+	c := NewCachedDB(m, CacheOptions{MaxEntries: 2})
+
+	room, err := c.GetRoom("CCC-AAA")
+	if err != nil {
+		t.Fatalf("failed to get room: %s", err)
+	}
+
+	// changing what was returned shouldn't change what is cached
+	room.Name = "changed"
+
+	if room, err = c.GetRoom("CCC-AAA"); err != nil {
+		t.Fatalf("failed to get room: %s", err)
+	}
+
+	if room.Name == "changed" {
+		t.Fatalf("cached room was changed by the caller")
+	}
+
+	if stats := c.Stats(); stats.Hits != 1 || stats.Misses != 1 || stats.Entries != 1 {
+		t.Fatalf("unexpected stats after getting a room twice: %+v", stats)
+	}
+
+	// writes through the cache invalidate it
+	room.Description = "new description"
+	if _, err := c.UpdateRoom(room.ID, room); err != nil {
+		t.Fatalf("failed to update room: %s", err)
+	}
+
+	if room, err = c.GetRoom("CCC-AAA"); err != nil {
+		t.Fatalf("failed to get room: %s", err)
+	}
+
+	if room.Description != "new description" {
+		t.Fatalf("got a stale room after updating it: %+v", room)
+	}
+
+	// the least recently used entry is evicted
+	c.GetBuilding("AAA")
+	c.GetBuilding("BBB")
+
+	if stats := c.Stats(); stats.Evictions != 1 || stats.Entries != 2 {
+		t.Fatalf("expected the room to be evicted: %+v", stats)
+	}
+
+	// writes made around the cache are seen once the change comes in
+	building, _ := c.GetBuilding("BBB")
+	building.Description = "changed directly"
+	if _, err := m.UpdateBuilding(building.ID, building); err != nil {
+		t.Fatalf("failed to update building: %s", err)
+	}
+
+	if b, _ := c.GetBuilding("BBB"); b.Description == building.Description {
+		t.Fatalf("didn't get the cached building")
+	}
+
+	changes := make(chan couch.Change, 1)
+	changes <- couch.Change{Database: couch.BUILDINGS, ID: "BBB", Type: couch.Updated}
+	close(changes)
+	c.InvalidateOnChanges(changes)
+
+	if b, _ := c.GetBuilding("BBB"); b.Description != building.Description {
+		t.Fatalf("got a stale building after it changed: %+v", b)
+	}
+}
+
+func TestCachedDBConflict(t *testing.T) {
+	m := newSeededMemoryDB(t)
+	c := NewCachedDB(m, CacheOptions{})
+
+	building, err := c.GetBuilding("AAA")
+	if err != nil {
+		t.Fatalf("failed to get building: %s", err)
+	}
+
+	// someone else changes the building, so the cached revision is stale
+	changed := building
+	changed.Description = "changed directly"
+	if _, err := m.UpdateBuilding(changed.ID, changed); err != nil {
+		t.Fatalf("failed to update building: %s", err)
+	}
+
+	building.Description = "changed through the cache"
+	_, err = c.UpdateBuilding(building.ID, building)
+	if _, ok := err.(*couch.Conflict); !ok {
+		t.Fatalf("expected a conflict updating a stale building, got %v", err)
+	}
+
+	// the conflict invalidated the stale building, so updating it again works
+	if building, err = c.GetBuilding("AAA"); err != nil {
+		t.Fatalf("failed to get building: %s", err)
+	}
+
+	building.Description = "changed through the cache"
+	if _, err := c.UpdateBuilding(building.ID, building); err != nil {
+		t.Fatalf("failed to update building after a conflict: %s", err)
+	}
+
+	if b, _ := c.GetBuilding("AAA"); b.Description != building.Description {
+		t.Fatalf("got a stale building after updating it: %+v", b)
+	}
+}
+
+func TestCachedDBExpires(t *testing.T) {
+	c := NewCachedDB(newSeededMemoryDB(t), CacheOptions{TTL: time.Millisecond})
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetBuilding("AAA"); err != nil {
+			t.Fatalf("failed to get building: %s", err)
+		}
+
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	// errors aren't cached
+	if dt, err := c.GetDeviceType("missing"); err == nil {
+		t.Fatalf("got a device type that doesn't exist: %+v", dt)
+	}
+
+	if stats := c.Stats(); stats.Hits != 0 || stats.Misses != 3 {
+		t.Fatalf("expected every get to miss: %+v", stats)
+	}
+}
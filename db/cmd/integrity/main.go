@@ -0,0 +1,74 @@
+/*
+Command integrity checks the configuration documents in a database for dangling references,
+orphaned documents, and invalid ids, and prints a report of each issue found.
+
+	integrity [-db url] [-fix] [-json]
+
+With -fix, the issues that can be fixed safely are fixed (see db.CheckIntegrity). It exits with
+status 1 if any issues are left unfixed.
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/byuoitav/common/db"
+)
+
+func main() {
+	address := flag.String("db", "", "the url of the database to use, e.g. couch://localhost:5984 or file:///srv/bundle")
+	fix := flag.Bool("fix", false, "fix the issues that can be fixed safely")
+	asJSON := flag.Bool("json", false, "print the report as json")
+	flag.Parse()
+
+	d := db.GetDB
+	if len(*address) > 0 {
+		d = func() db.DB {
+			opened, err := db.Open(*address)
+			if err != nil {
+				fail("unable to open %s: %s", *address, err)
+			}
+
+			return opened
+		}
+	}
+
+	report, err := db.CheckIntegrity(d(), *fix)
+	if err != nil {
+		fail("%s", err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		enc.Encode(report)
+	} else {
+		for _, issue := range report.Issues {
+			status := ""
+			switch {
+			case issue.Fixed:
+				status = " (fixed)"
+			case len(issue.FixError) > 0:
+				status = fmt.Sprintf(" (unable to fix: %s)", issue.FixError)
+			case issue.Fixable:
+				status = " (fixable)"
+			}
+
+			fmt.Printf("%s %s/%s %s: %s%s\n", issue.Kind, issue.Database, issue.ID, issue.Field, issue.Message, status)
+		}
+
+		fmt.Printf("found %v issues, %v left unfixed\n", len(report.Issues), len(report.Unfixed()))
+	}
+
+	if len(report.Unfixed()) > 0 {
+		os.Exit(1)
+	}
+}
+
+func fail(format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", a...)
+	os.Exit(1)
+}
@@ -0,0 +1,112 @@
+/*
+Command snapshot exports a database to a snapshot archive, or imports an archive into a database.
+
+	snapshot [-db url] export backup.json.gz
+	snapshot [-db url] [-strategy skip|overwrite|fail] import backup.json.gz
+
+The database is picked the same way as db.GetDB (from DB_ADDRESS, etc.) unless -db is given (see db.Open).
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/byuoitav/common/db"
+	"github.com/byuoitav/common/structs"
+)
+
+func main() {
+	address := flag.String("db", "", "the url of the database to use, e.g. couch://localhost:5984 or file:///srv/bundle")
+	strategy := flag.String("strategy", string(structs.ConflictFail), "what to do with documents that already exist when importing: skip, overwrite, or fail")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [flags] export|import <archive>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	d := db.GetDB
+	if len(*address) > 0 {
+		d = func() db.DB {
+			opened, err := db.Open(*address)
+			if err != nil {
+				fail("unable to open %s: %s", *address, err)
+			}
+
+			return opened
+		}
+	}
+
+	switch flag.Arg(0) {
+	case "export":
+		export(d(), flag.Arg(1))
+	case "import":
+		importArchive(d(), flag.Arg(1), structs.ConflictStrategy(*strategy))
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func export(d db.DB, path string) {
+	snapshot, err := d.ExportSnapshot()
+	if err != nil {
+		fail("unable to export snapshot: %s", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		fail("unable to create %s: %s", path, err)
+	}
+
+	if err := db.WriteSnapshot(f, snapshot); err != nil {
+		fail("%s", err)
+	}
+
+	if err := f.Close(); err != nil {
+		fail("unable to write %s: %s", path, err)
+	}
+
+	fmt.Printf("exported %v buildings, %v rooms, and %v devices to %s\n", len(snapshot.Buildings), len(snapshot.Rooms), len(snapshot.Devices), path)
+}
+
+func importArchive(d db.DB, path string, strategy structs.ConflictStrategy) {
+	f, err := os.Open(path)
+	if err != nil {
+		fail("unable to open %s: %s", path, err)
+	}
+	defer f.Close()
+
+	snapshot, err := db.ReadSnapshot(f)
+	if err != nil {
+		fail("%s", err)
+	}
+
+	result, err := d.ImportSnapshot(snapshot, strategy)
+
+	counts := make(map[structs.ImportAction]int)
+	for _, doc := range result.Documents {
+		counts[doc.Action]++
+
+		if doc.Action == structs.ImportFailed {
+			fmt.Fprintf(os.Stderr, "%s/%s: %s\n", doc.Database, doc.ID, doc.Message)
+		}
+	}
+
+	fmt.Printf("created %v, overwrote %v, skipped %v, and failed to import %v documents\n", counts[structs.ImportCreated], counts[structs.ImportOverwritten], counts[structs.ImportSkipped], counts[structs.ImportFailed])
+
+	if err != nil {
+		fail("%s", err)
+	}
+}
+
+func fail(format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", a...)
+	os.Exit(1)
+}
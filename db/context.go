@@ -0,0 +1,35 @@
+package db
+
+import (
+	"context"
+
+	"github.com/byuoitav/common/db/couch"
+)
+
+// ContextBinder is implemented by databases (or wrappers around them) that can bind their requests to a context.
+type ContextBinder interface {
+	WithContext(ctx context.Context) DB
+}
+
+/*
+WithContext returns a DB whose requests are bound to ctx, so that cancellation and deadlines
+(e.g. from an echo handler's request) flow down to the database. d itself is not modified.
+
+	room, err := db.WithContext(c.Request().Context(), db.GetDB()).GetRoom(id)
+
+Databases that don't make requests (like the in-memory one) are returned as is.
+*/
+func WithContext(ctx context.Context, d DB) DB {
+	if ctx == nil {
+		panic("nil context")
+	}
+
+	switch d := d.(type) {
+	case *couch.CouchDB:
+		return d.WithContext(ctx)
+	case ContextBinder:
+		return d.WithContext(ctx)
+	default:
+		return d
+	}
+}
@@ -1,7 +1,6 @@
 package couch
 
 import (
-	"encoding/json"
 	"fmt"
 
 	"github.com/byuoitav/common/structs"
@@ -16,7 +15,7 @@ func (c *CouchDB) GetAttributeGroup(groupID string) (structs.Group, error) {
 func (c *CouchDB) getAttributeGroup(groupID string) (attributeGroup, error) {
 	var toReturn attributeGroup
 
-	err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", ATTRIBUTES, groupID), "", nil, toReturn)
+	err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", ATTRIBUTES, groupID), "", nil, &toReturn)
 	if err != nil {
 		err = fmt.Errorf("failed to get attribute group %s: %s", groupID, err)
 	}
@@ -31,21 +30,86 @@ func (c *CouchDB) GetAllAttributeGroups() ([]structs.Group, error) {
 	query.Selector.ID.GT = "\x00"
 	query.Limit = 1000
 
-	b, err := json.Marshal(query)
-	if err != nil {
-		return toReturn, fmt.Errorf("failed to marshal query to get all attribute groups: %s", err)
-	}
-
-	var resp attributeQueryResponse
+	var docs []attributeGroup
 
-	err = c.MakeRequest("POST", fmt.Sprintf("%v/_find", ATTRIBUTES), "application/json", b, &resp)
+	err := c.findAll(ATTRIBUTES, query, &docs)
 	if err != nil {
 		return toReturn, fmt.Errorf("failed to get all attribute groups: %s", err)
 	}
 
-	for _, doc := range resp.Docs {
+	for _, doc := range docs {
 		toReturn = append(toReturn, doc.Group)
 	}
 
 	return toReturn, err
 }
+
+// CreateAttributeGroup adds an attribute group to the database.
+func (c *CouchDB) CreateAttributeGroup(group structs.Group) (structs.Group, error) {
+	var toReturn structs.Group
+
+	if err := group.Validate(); err != nil {
+		return toReturn, err
+	}
+
+	err := c.createDoc(ATTRIBUTES, group.ID, group)
+	if err != nil {
+		if _, ok := err.(*Conflict); ok {
+			return toReturn, fmt.Errorf("unable to create attribute group, because it already exists. error: %s", err)
+		}
+
+		return toReturn, fmt.Errorf("failed to create attribute group %s: %s", group.ID, err)
+	}
+
+	return c.GetAttributeGroup(group.ID)
+}
+
+// UpdateAttributeGroup updates an attribute group. If group.Rev is set, it is only updated if it hasn't changed since that revision.
+func (c *CouchDB) UpdateAttributeGroup(id string, group structs.Group) (structs.Group, error) {
+	var toReturn structs.Group
+
+	if err := group.Validate(); err != nil {
+		return toReturn, err
+	}
+
+	if group.ID != id {
+		return toReturn, fmt.Errorf("unable to update attribute group %s: the id of an attribute group can't be changed", id)
+	}
+
+	_, err := c.updateDoc(ATTRIBUTES, id, group.Rev, group)
+	if err != nil {
+		if _, ok := err.(*Conflict); ok {
+			return toReturn, err
+		}
+
+		return toReturn, fmt.Errorf("failed to update attribute group %s: %s", id, err)
+	}
+
+	return c.GetAttributeGroup(id)
+}
+
+// DeleteAttributeGroup deletes an attribute group. Deletion is refused while the group is in the menu tree.
+func (c *CouchDB) DeleteAttributeGroup(id string) error {
+	group, err := c.getAttributeGroup(id)
+	if err != nil {
+		return fmt.Errorf("unable to get attribute group %s to delete: %s", id, err)
+	}
+
+	tree, err := c.currentMenuTree()
+	if err != nil {
+		return fmt.Errorf("unable to check the menu tree for %s: %s", id, err)
+	}
+
+	for _, groupID := range tree.Order {
+		if groupID == id {
+			return fmt.Errorf("attribute group %s is still in the menu tree. remove it from the menu tree first.", id)
+		}
+	}
+
+	err = c.MakeRequest("DELETE", fmt.Sprintf("%v/%v?rev=%v", ATTRIBUTES, id, group.Rev), "", nil, nil)
+	if err != nil {
+		return fmt.Errorf("unable to delete attribute group %s: %s", id, err)
+	}
+
+	return nil
+}
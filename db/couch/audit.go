@@ -0,0 +1,105 @@
+package couch
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/byuoitav/common/structs"
+)
+
+/*
+AUDIT_LOG is the database that changes made through an audited database are recorded in. It
+is created, along with its indexes, the first time an entry is added if it doesn't exist yet;
+CreateAuditLog creates it ahead of time.
+*/
+const AUDIT_LOG = "audit-log"
+
+// AddAuditEntry adds entry to the audit log, creating the audit log if it doesn't exist.
+func (c *CouchDB) AddAuditEntry(entry structs.AuditEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("unable to add audit entry: unable to marshal %s: %s", entry.ID, err)
+	}
+
+	put := func() error {
+		return c.MakeRequest("PUT", fmt.Sprintf("%v/%v", AUDIT_LOG, entry.ID), "application/json", b, &CouchUpsertResponse{})
+	}
+
+	err = put()
+	if _, ok := err.(*NotFound); ok {
+		if err := c.CreateAuditLog(); err != nil {
+			return fmt.Errorf("unable to add audit entry: %s", err)
+		}
+
+		err = put()
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to add audit entry: %s", err)
+	}
+
+	return nil
+}
+
+// CreateAuditLog creates the audit log database and its indexes, if they don't already exist.
+func (c *CouchDB) CreateAuditLog() error {
+	err := c.MakeRequest("GET", AUDIT_LOG, "", nil, nil)
+	switch err.(type) {
+	case nil:
+	case *NotFound:
+		if err := c.MakeRequest("PUT", AUDIT_LOG, "", nil, nil); err != nil {
+			return fmt.Errorf("unable to create %s: %s", AUDIT_LOG, err)
+		}
+	default:
+		return fmt.Errorf("unable to check if %s exists: %s", AUDIT_LOG, err)
+	}
+
+	return c.CreateIndexes(AuditDocumentIndex, AuditRoomIndex)
+}
+
+// GetAuditEntry returns a single audit entry.
+func (c *CouchDB) GetAuditEntry(id string) (structs.AuditEntry, error) {
+	var toReturn structs.AuditEntry
+
+	err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", AUDIT_LOG, id), "", nil, &toReturn)
+	if err != nil {
+		return toReturn, fmt.Errorf("failed to get audit entry %s: %s", id, err)
+	}
+
+	return toReturn, nil
+}
+
+// GetDocumentHistory returns each change made to a document in database, including the rename that gave it its id, in the order they were made.
+func (c *CouchDB) GetDocumentHistory(database, id string) ([]structs.AuditEntry, error) {
+	var toReturn []structs.AuditEntry
+
+	query := NewQuery().Where("database", database).Or(Selector{"document-id": id}, Selector{"previous-id": id})
+	if err := c.Find(AUDIT_LOG, query, &toReturn); err != nil {
+		return toReturn, fmt.Errorf("failed to get history of %s %s: %s", database, id, err)
+	}
+
+	sortAuditEntries(toReturn)
+	return toReturn, nil
+}
+
+// GetRoomHistory returns each change made to a room, its devices, or its ui config since since, in the order they were made.
+func (c *CouchDB) GetRoomHistory(roomID string, since time.Time) ([]structs.AuditEntry, error) {
+	var toReturn []structs.AuditEntry
+
+	// audit entry ids start with the time they were made
+	query := NewQuery().Where("room-id", roomID).Where("_id", Gte(since.UTC().Format(structs.AuditTimeFormat))).UseIndex(AuditRoomIndex.DesignDoc, AuditRoomIndex.Name)
+	if err := c.Find(AUDIT_LOG, query, &toReturn); err != nil {
+		return toReturn, fmt.Errorf("failed to get history of room %s: %s", roomID, err)
+	}
+
+	sortAuditEntries(toReturn)
+	return toReturn, nil
+}
+
+func sortAuditEntries(entries []structs.AuditEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID < entries[j].ID
+	})
+}
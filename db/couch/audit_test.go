@@ -0,0 +1,53 @@
+package couch
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/byuoitav/common/structs"
+)
+
+func TestAddAuditEntryCreatesAuditLog(t *testing.T) {
+	exists := false
+	indexes := 0
+
+	missingDB := respond(http.StatusNotFound, `{"error": "not_found", "reason": "Database does not exist."}`)
+
+	c, srv := newFakeCouch(t, fakeRoutes{
+		"GET /" + AUDIT_LOG: func(w http.ResponseWriter, r *http.Request) {
+			if !exists {
+				missingDB(w, r)
+				return
+			}
+
+			w.Write([]byte(`{"db_name": "audit-log"}`))
+		},
+		"PUT /" + AUDIT_LOG: func(w http.ResponseWriter, r *http.Request) {
+			exists = true
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"ok": true}`))
+		},
+		"POST /" + AUDIT_LOG + "/_index": func(w http.ResponseWriter, r *http.Request) {
+			indexes++
+			w.Write([]byte(`{"result": "created"}`))
+		},
+		"PUT /" + AUDIT_LOG + "/entry-1": func(w http.ResponseWriter, r *http.Request) {
+			if !exists {
+				missingDB(w, r)
+				return
+			}
+
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"ok": true, "id": "entry-1", "rev": "1-aaa"}`))
+		},
+	})
+	defer srv.Close()
+
+	if err := c.AddAuditEntry(structs.AuditEntry{ID: "entry-1"}); err != nil {
+		t.Fatalf("failed to add audit entry: %s", err)
+	}
+
+	if !exists || indexes != 2 {
+		t.Fatalf("expected the audit log and its 2 indexes to be created (created: %v, indexes: %v)", exists, indexes)
+	}
+}
@@ -1,9 +1,7 @@
 package couch
 
 import (
-	"encoding/json"
 	"fmt"
-	"strings"
 
 	"github.com/byuoitav/common/structs"
 )
@@ -30,19 +28,14 @@ func (c *CouchDB) GetAllBuildings() ([]structs.Building, error) {
 	query.Selector.ID.GT = "\x00"
 	query.Limit = 1000
 
-	b, err := json.Marshal(query)
-	if err != nil {
-		return toReturn, fmt.Errorf("failed to marshal query to get all buildings: %s", err)
-	}
+	var docs []building
 
-	var resp buildingQueryResponse
-
-	err = c.MakeRequest("POST", fmt.Sprintf("%v/_find", BUILDINGS), "application/json", b, &resp)
+	err := c.findAll(BUILDINGS, query, &docs)
 	if err != nil {
 		return toReturn, fmt.Errorf("failed to get all buildings: %s", err)
 	}
 
-	for _, doc := range resp.Docs {
+	for _, doc := range docs {
 		toReturn = append(toReturn, *doc.Building)
 	}
 
@@ -81,14 +74,8 @@ func (c *CouchDB) CreateBuilding(toAdd structs.Building) (structs.Building, erro
 		return toReturn, err
 	}
 
-	b, err := json.Marshal(toAdd)
-	if err != nil {
-		return toReturn, fmt.Errorf("failed to marshal building %s: %s", toAdd.ID, err)
-	}
-
 	// post new building
-	var resp CouchUpsertResponse
-	err = c.MakeRequest("POST", BUILDINGS, "application/json", b, &resp)
+	err = c.createDoc(BUILDINGS, toAdd.ID, toAdd)
 	if err != nil {
 		if _, ok := err.(*Conflict); ok { // a building with the same ID already exists
 			return toReturn, fmt.Errorf("building already exists, please update this building or change id's. error: %s", err)
@@ -133,21 +120,6 @@ func (c *CouchDB) DeleteBuilding(id string) error {
 	return nil
 }
 
-// delete a building without checking if rooms will be affected
-func (c *CouchDB) deleteBuildingWithoutCascade(id string) error {
-	building, err := c.getBuilding(id)
-	if err != nil {
-		return fmt.Errorf("unable to get building %s to delete: %s", id, err)
-	}
-
-	err = c.MakeRequest("DELETE", fmt.Sprintf("%s/%s?rev=%v", BUILDINGS, id, building.Rev), "", nil, nil)
-	if err != nil {
-		return fmt.Errorf("unable to delete building %s: %s", id, err)
-	}
-
-	return nil
-}
-
 func (c *CouchDB) UpdateBuilding(id string, building structs.Building) (structs.Building, error) {
 	var toReturn structs.Building
 
@@ -158,53 +130,33 @@ func (c *CouchDB) UpdateBuilding(id string, building structs.Building) (structs.
 	}
 
 	if id == building.ID { // the building ID isn't changing
-		// get the rev of the building
-		bld, err := c.getBuilding(id)
+		// update the building, as long as it hasn't changed since building.Rev
+		rev, err := c.updateDoc(BUILDINGS, id, building.Rev, building)
 		if err != nil {
-			return toReturn, fmt.Errorf("unable to get building %s to update: %s", id, err)
-		}
-
-		// marshal the new building
-		b, err := json.Marshal(building)
-		if err != nil {
-			return toReturn, fmt.Errorf("unable to unmarshal new building: %s", err)
-		}
+			if _, ok := err.(*Conflict); ok {
+				return toReturn, err
+			}
 
-		// update the building
-		err = c.MakeRequest("PUT", fmt.Sprintf("%s/%s?rev=%v", BUILDINGS, id, bld.Rev), "application/json", b, &toReturn)
-		if err != nil {
 			return toReturn, fmt.Errorf("failed to update building %s: %s", id, err)
 		}
-	} else { // the building ID is changing :|
-		// get rooms that are in the building
-		rooms, err := c.GetRoomsByBuilding(id)
-		if err != nil {
-			return toReturn, fmt.Errorf("unable to get rooms assocated with old building: %s", id)
-		}
 
-		// delete the old building
-		err = c.deleteBuildingWithoutCascade(id)
-		if err != nil {
-			return toReturn, fmt.Errorf("unable to delete old building %s: %s", id, err)
+		toReturn = building
+		toReturn.Rev = rev
+	} else { // the building ID is changing :|
+		// make sure the building hasn't changed before moving it
+		if err := c.checkRev(BUILDINGS, id, building.Rev); err != nil {
+			return toReturn, err
 		}
 
-		// create the new building
-		_, err = c.CreateBuilding(building)
+		// move the building, along with everything in it
+		_, err = c.RenameBuilding(id, building.ID)
 		if err != nil {
-			return toReturn, fmt.Errorf("unable to create new building %s: %s", id, err)
+			return toReturn, fmt.Errorf("unable to move building %s to %s: %s", id, building.ID, err)
 		}
 
-		// update each of the rooms to be in the new building
-		for index := range rooms {
-			go func(i int) {
-				// create the new room id
-				oldID := rooms[i].ID
-				rooms[i].ID = strings.Replace(rooms[i].ID, id, building.ID, 1)
-
-				// update the room
-				c.UpdateRoom(oldID, rooms[i])
-			}(index)
-		}
+		// then update it with any other changes
+		building.Rev = ""
+		return c.UpdateBuilding(building.ID, building)
 	}
 
 	return toReturn, nil
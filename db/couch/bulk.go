@@ -0,0 +1,412 @@
+package couch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/byuoitav/common/structs"
+)
+
+// BulkBatchSize is the most documents written by a single _bulk_docs request.
+var BulkBatchSize = 500
+
+type bulkDocsRequest struct {
+	Docs []interface{} `json:"docs"`
+}
+
+type bulkDocsResult struct {
+	ID     string `json:"id"`
+	Rev    string `json:"rev"`
+	OK     bool   `json:"ok"`
+	Error  string `json:"error"`
+	Reason string `json:"reason"`
+}
+
+type allDocsRequest struct {
+	Keys []string `json:"keys"`
+}
+
+type allDocsResponse struct {
+	Rows []struct {
+		ID    string `json:"id"`
+		Key   string `json:"key"`
+		Error string `json:"error"`
+		Value struct {
+			Rev     string `json:"rev"`
+			Deleted bool   `json:"deleted"`
+		} `json:"value"`
+	} `json:"rows"`
+}
+
+type deletedDoc struct {
+	ID      string `json:"_id"`
+	Rev     string `json:"_rev"`
+	Deleted bool   `json:"_deleted"`
+}
+
+// bulkWrite is a document to write with bulkDocs, and the index of its response.
+type bulkWrite struct {
+	index int
+	doc   interface{}
+}
+
+// newBulkResponses returns a (failed) response for each of ids.
+func newBulkResponses(ids []string) []structs.BulkUpdateResponse {
+	toReturn := make([]structs.BulkUpdateResponse, len(ids))
+	for i := range ids {
+		toReturn[i].ID = ids[i]
+	}
+
+	return toReturn
+}
+
+// bulkDocs writes each of writes to database, BulkBatchSize at a time, and fills in the matching response with couch's result for each document.
+func (c *CouchDB) bulkDocs(database string, writes []bulkWrite, responses []structs.BulkUpdateResponse) {
+	for start := 0; start < len(writes); start += BulkBatchSize {
+		end := start + BulkBatchSize
+		if end > len(writes) {
+			end = len(writes)
+		}
+
+		batch := writes[start:end]
+
+		var req bulkDocsRequest
+		for _, write := range batch {
+			req.Docs = append(req.Docs, write.doc)
+		}
+
+		var results []bulkDocsResult
+
+		b, err := json.Marshal(req)
+		if err == nil {
+			err = c.MakeRequest("POST", fmt.Sprintf("%v/_bulk_docs", database), "application/json", b, &results)
+		}
+
+		if err == nil && len(results) != len(batch) {
+			err = fmt.Errorf("expected %v results, but got %v", len(batch), len(results))
+		}
+
+		if err != nil {
+			for _, write := range batch {
+				responses[write.index].Message = fmt.Sprintf("failed to write to %s: %s", database, err)
+			}
+
+			continue
+		}
+
+		for i, result := range results {
+			response := &responses[batch[i].index]
+
+			if len(result.Error) > 0 {
+				response.Message = CheckCouchErrors(CouchError{Error: result.Error, Reason: result.Reason}).Error()
+				continue
+			}
+
+			response.Success = true
+		}
+	}
+}
+
+// getRevs returns the current rev of each of ids that exists in database.
+func (c *CouchDB) getRevs(database string, ids []string) (map[string]string, error) {
+	toReturn := make(map[string]string)
+
+	for start := 0; start < len(ids); start += BulkBatchSize {
+		end := start + BulkBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		b, err := json.Marshal(allDocsRequest{Keys: ids[start:end]})
+		if err != nil {
+			return toReturn, fmt.Errorf("failed to marshal keys: %s", err)
+		}
+
+		var resp allDocsResponse
+		err = c.MakeRequest("POST", fmt.Sprintf("%v/_all_docs", database), "application/json", b, &resp)
+		if err != nil {
+			return toReturn, fmt.Errorf("failed to get revs from %s: %s", database, err)
+		}
+
+		for _, row := range resp.Rows {
+			if len(row.Error) == 0 && !row.Value.Deleted {
+				toReturn[row.Key] = row.Value.Rev
+			}
+		}
+	}
+
+	return toReturn, nil
+}
+
+// bulkDelete deletes each of ids from database, and returns a response for each of them.
+func (c *CouchDB) bulkDelete(database string, ids []string, responses []structs.BulkUpdateResponse) {
+	revs, err := c.getRevs(database, ids)
+	if err != nil {
+		for i := range ids {
+			responses[i].Message = err.Error()
+		}
+
+		return
+	}
+
+	var writes []bulkWrite
+	for i, id := range ids {
+		rev, ok := revs[id]
+		if !ok {
+			if len(responses[i].Message) == 0 {
+				responses[i].Message = fmt.Sprintf("%s doesn't exist", id)
+			}
+
+			continue
+		}
+
+		if len(responses[i].Message) == 0 {
+			writes = append(writes, bulkWrite{index: i, doc: deletedDoc{ID: id, Rev: rev, Deleted: true}})
+		}
+	}
+
+	c.bulkDocs(database, writes, responses)
+}
+
+// bulkValidator validates documents for a bulk write, remembering which rooms, device types, etc. it has already looked up.
+type bulkValidator struct {
+	c *CouchDB
+
+	buildings   map[string]error
+	rooms       map[string]error
+	types       map[string]error
+	configs     map[string]error
+	validDevice map[string]bool
+
+	// attribute schemas of device types, and of rooms (which are loaded the first time they're needed)
+	typeSchemas map[string][]structs.AttributeSchema
+	roomSchemas *roomSchemas
+
+	// device types and room configurations that don't exist yet; they are created by createNew
+	newTypes   []structs.DeviceType
+	newConfigs []structs.RoomConfiguration
+}
+
+func newBulkValidator(c *CouchDB) *bulkValidator {
+	return &bulkValidator{
+		c:           c,
+		buildings:   make(map[string]error),
+		rooms:       make(map[string]error),
+		types:       make(map[string]error),
+		configs:     make(map[string]error),
+		validDevice: make(map[string]bool),
+		typeSchemas: make(map[string][]structs.AttributeSchema),
+	}
+}
+
+// exists returns whether id exists in database.
+func (c *CouchDB) exists(database, id string) (bool, error) {
+	err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", database, id), "", nil, nil)
+	switch err.(type) {
+	case nil:
+		return true, nil
+	case *NotFound:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// building checks that building id exists.
+func (v *bulkValidator) building(id string) error {
+	if err, ok := v.buildings[id]; ok {
+		return err
+	}
+
+	exists, err := v.c.exists(BUILDINGS, id)
+	switch {
+	case err != nil:
+		err = fmt.Errorf("unable to validate building %s exists: %s", id, err)
+	case !exists:
+		err = fmt.Errorf("building %s doesn't exist", id)
+	}
+
+	v.buildings[id] = err
+	return err
+}
+
+// room checks that room id exists.
+func (v *bulkValidator) room(id string) error {
+	if err, ok := v.rooms[id]; ok {
+		return err
+	}
+
+	exists, err := v.c.exists(ROOMS, id)
+	switch {
+	case err != nil:
+		err = fmt.Errorf("unable to validate room %s exists: %s", id, err)
+	case !exists:
+		err = fmt.Errorf("room %s doesn't exist", id)
+	}
+
+	v.rooms[id] = err
+	return err
+}
+
+// deviceType checks that dt exists, or that it can be created if it doesn't.
+func (v *bulkValidator) deviceType(dt structs.DeviceType) error {
+	if err, ok := v.types[dt.ID]; ok {
+		return err
+	}
+
+	exists, err := v.c.exists(DEVICE_TYPES, dt.ID)
+	switch {
+	case err != nil:
+		err = fmt.Errorf("unable to validate if device type %s exists or not: %s", dt.ID, err)
+	case !exists:
+		if err = dt.Validate(true); err != nil {
+			err = fmt.Errorf("device type %s doesn't exist yet, and not enough information was included to create it. (error: %s)", dt.ID, err)
+			break
+		}
+
+		v.newTypes = append(v.newTypes, dt)
+		v.typeSchemas[dt.ID] = dt.AttributeSchema
+	}
+
+	v.types[dt.ID] = err
+	return err
+}
+
+// roomConfiguration checks that rc exists, or that it can be created if it doesn't.
+func (v *bulkValidator) roomConfiguration(rc structs.RoomConfiguration) error {
+	if err, ok := v.configs[rc.ID]; ok {
+		return err
+	}
+
+	exists, err := v.c.exists(ROOM_CONFIGURATIONS, rc.ID)
+	switch {
+	case err != nil:
+		err = fmt.Errorf("unable to validate if room configuration %s exists or not: %s", rc.ID, err)
+	case !exists:
+		if err = rc.Validate(true); err != nil {
+			err = fmt.Errorf("room configuration %s doesn't exist yet, and it couldn't be created: %s", rc.ID, err)
+			break
+		}
+
+		v.newConfigs = append(v.newConfigs, rc)
+	}
+
+	v.configs[rc.ID] = err
+	return err
+}
+
+/*
+createNew creates each of the device types and room configurations that didn't exist when they
+were validated, and are used by a document that was written (used is keyed by their ids). They
+aren't created any earlier, so that a failed write doesn't leave them behind. A response is
+returned for each of them.
+*/
+func (v *bulkValidator) createNew(used map[string]bool) []structs.BulkUpdateResponse {
+	var toReturn []structs.BulkUpdateResponse
+
+	for _, dt := range v.newTypes {
+		if !used[dt.ID] {
+			continue
+		}
+
+		response := structs.BulkUpdateResponse{ID: dt.ID, Success: true}
+		if _, err := v.c.CreateDeviceType(dt); err != nil {
+			response.Success = false
+			response.Message = err.Error()
+		}
+
+		toReturn = append(toReturn, response)
+	}
+
+	for _, rc := range v.newConfigs {
+		if !used[rc.ID] {
+			continue
+		}
+
+		response := structs.BulkUpdateResponse{ID: rc.ID, Success: true}
+		if _, err := v.c.CreateRoomConfiguration(rc); err != nil {
+			response.Success = false
+			response.Message = err.Error()
+		}
+
+		toReturn = append(toReturn, response)
+	}
+
+	return toReturn
+}
+
+// deviceAttributeSchema returns the attribute schema of device type id.
+func (v *bulkValidator) deviceAttributeSchema(id string) ([]structs.AttributeSchema, error) {
+	if schema, ok := v.typeSchemas[id]; ok {
+		return schema, nil
+	}
+
+	schema, err := v.c.deviceAttributeSchema(id)
+	if err != nil {
+		return nil, err
+	}
+
+	v.typeSchemas[id] = schema
+	return schema, nil
+}
+
+// roomAttributeSchema returns the attribute schema of the rooms with designation.
+func (v *bulkValidator) roomAttributeSchema(designation string) ([]structs.AttributeSchema, error) {
+	if v.roomSchemas == nil {
+		schemas, err := v.c.currentRoomSchemas()
+		if err != nil {
+			return nil, fmt.Errorf("unable to get room attribute schemas: %s", err)
+		}
+
+		v.roomSchemas = &schemas
+	}
+
+	return v.roomSchemas.Schemas[designation], nil
+}
+
+// device validates a device, its room and type, and the devices its ports reference. The defaults of its type's attribute schema are applied to it.
+func (v *bulkValidator) device(device *structs.Device) error {
+	if err := device.Validate(); err != nil {
+		return err
+	}
+
+	split := strings.Split(device.ID, "-")
+	if err := v.room(split[0] + "-" + split[1]); err != nil {
+		return err
+	}
+
+	if err := v.deviceType(device.Type); err != nil {
+		return err
+	}
+
+	schema, err := v.deviceAttributeSchema(device.Type.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := device.ApplyAttributeSchema(schema); err != nil {
+		return err
+	}
+
+	// check that the ports contain valid devices
+	for _, port := range device.Ports {
+		if len(port.SourceDevice) > 0 && !v.validDevice[port.SourceDevice] {
+			if _, err := v.c.getDevice(port.SourceDevice); err != nil {
+				return fmt.Errorf("invalid port %v. source device %s doesn't exist, create it before adding it to a port.", port.ID, port.SourceDevice)
+			}
+
+			v.validDevice[port.SourceDevice] = true
+		}
+
+		if len(port.DestinationDevice) > 0 && !v.validDevice[port.DestinationDevice] {
+			if _, err := v.c.getDevice(port.DestinationDevice); err != nil {
+				return fmt.Errorf("invalid port %v. destination device %s doesn't exist, create it before adding it to a port.", port.ID, port.DestinationDevice)
+			}
+
+			v.validDevice[port.DestinationDevice] = true
+		}
+	}
+
+	return nil
+}
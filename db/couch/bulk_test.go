@@ -0,0 +1,114 @@
+package couch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/byuoitav/common/structs"
+)
+
+func TestCreateBulkDevices(t *testing.T) {
+	var bulkRequests int
+	var posted bulkDocsRequest
+
+	c, srv := newFakeCouch(t, fakeRoutes{
+		"GET /rooms/AAA-ZZZ":            respond(http.StatusOK, `{"_id": "exists"}`),
+		"GET /device_types/test_type_1": respond(http.StatusOK, `{"_id": "exists"}`),
+		"GET /rooms/AAA-YYY":            respondNotFound,
+		"POST /devices/_bulk_docs": func(w http.ResponseWriter, r *http.Request) {
+			bulkRequests++
+
+			b, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(b, &posted)
+
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`[
+				{"ok": true, "id": "AAA-ZZZ-D1", "rev": "1-aaa"},
+				{"id": "AAA-ZZZ-D2", "error": "conflict", "reason": "Document update conflict."}
+			]`))
+		},
+	})
+	defer srv.Close()
+
+	roles := []structs.Role{{ID: "VideoOut"}}
+	devices := []structs.Device{
+		{ID: "AAA-ZZZ-D1", Name: "D1", Roles: roles, Type: structs.DeviceType{ID: "test_type_1", Description: "dropped"}, Ports: []structs.Port{{ID: "IN1", SourceDevice: "AAA-ZZZ-D2"}}},
+		{ID: "AAA-YYY-D1", Name: "D1", Roles: roles, Type: structs.DeviceType{ID: "test_type_1"}},
+		{ID: "AAA-ZZZ-D2", Name: "D2", Roles: roles, Type: structs.DeviceType{ID: "test_type_1"}},
+	}
+
+	responses := c.CreateBulkDevices(devices)
+	if len(responses) != len(devices) {
+		t.Fatalf("expected %v responses, got %+v", len(devices), responses)
+	}
+
+	if bulkRequests != 1 || len(posted.Docs) != 2 {
+		t.Fatalf("expected the two valid devices to be posted in one request; got %v requests, and %+v", bulkRequests, posted.Docs)
+	}
+
+	if doc := posted.Docs[0].(map[string]interface{}); doc["type"].(map[string]interface{})["description"] != nil {
+		t.Fatalf("the device type wasn't stripped: %+v", doc)
+	}
+
+	if !responses[0].Success {
+		t.Fatalf("%s should have been created: %s", responses[0].ID, responses[0].Message)
+	}
+
+	if responses[1].Success || responses[1].Message != "room AAA-YYY doesn't exist" {
+		t.Fatalf("%s should have failed validation: %+v", responses[1].ID, responses[1])
+	}
+
+	if responses[2].Success || len(responses[2].Message) == 0 {
+		t.Fatalf("%s should have had a conflict: %+v", responses[2].ID, responses[2])
+	}
+}
+
+func TestCreateBulkDevicesNewType(t *testing.T) {
+	fail := true
+	var created []string
+
+	c, srv := newFakeCouch(t, fakeRoutes{
+		"GET /rooms/AAA-ZZZ": respond(http.StatusOK, `{"_id": "AAA-ZZZ"}`),
+		"GET /device_types/new_type": func(w http.ResponseWriter, r *http.Request) {
+			if len(created) == 0 {
+				respondNotFound(w, r)
+				return
+			}
+
+			w.Write([]byte(`{"_id": "new_type", "_rev": "1-aaa"}`))
+		},
+		"POST /device_types": func(w http.ResponseWriter, r *http.Request) {
+			created = append(created, "new_type")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"ok": true, "id": "new_type", "rev": "1-aaa"}`))
+		},
+		"POST /devices/_bulk_docs": func(w http.ResponseWriter, r *http.Request) {
+			if fail {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"error": "internal_server_error", "reason": "down"}`))
+				return
+			}
+
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`[{"ok": true, "id": "AAA-ZZZ-D1", "rev": "1-aaa"}]`))
+		},
+	})
+	defer srv.Close()
+
+	devices := []structs.Device{{ID: "AAA-ZZZ-D1", Name: "D1", Roles: []structs.Role{{ID: "VideoOut"}}, Type: structs.DeviceType{ID: "new_type"}}}
+
+	// the device type isn't created if the devices using it aren't
+	responses := c.CreateBulkDevices(devices)
+	if len(responses) != 1 || responses[0].Success || len(created) != 0 {
+		t.Fatalf("expected the write to fail without creating the device type (created: %v): %+v", created, responses)
+	}
+
+	fail = false
+
+	responses = c.CreateBulkDevices(devices)
+	if len(responses) != 2 || !responses[0].Success || responses[1].ID != "new_type" || !responses[1].Success || len(created) != 1 {
+		t.Fatalf("expected the device, and then its type, to be created (created: %v): %+v", created, responses)
+	}
+}
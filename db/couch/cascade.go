@@ -0,0 +1,161 @@
+package couch
+
+import (
+	"fmt"
+
+	"github.com/byuoitav/common/structs"
+)
+
+// deletePlan is a document to delete, along with each of the documents that have to be deleted before it.
+type deletePlan struct {
+	database string
+	id       string
+	rev      string
+	children []deletePlan
+}
+
+type docRev struct {
+	ID  string `json:"_id"`
+	Rev string `json:"_rev"`
+}
+
+/*
+DeleteRoomCascade deletes a room along with its devices, their device states, its ui config,
+and its room attachments. If dryRun is true, nothing is deleted, and the result is the plan of
+every document that would be.
+
+Everything in the room is deleted before the room itself; if any of those documents can't be
+deleted, the room is left in place so that it can be retried.
+*/
+func (c *CouchDB) DeleteRoomCascade(id string, dryRun bool) (structs.CascadeDeleteResult, error) {
+	result := structs.CascadeDeleteResult{ID: id, DryRun: dryRun}
+
+	plan, err := c.planRoomDelete(id)
+	if err != nil {
+		return result, fmt.Errorf("unable to plan delete of room %s: %s", id, err)
+	}
+
+	return c.cascadeDelete(result, plan)
+}
+
+/*
+DeleteBuildingCascade deletes a building along with each of its rooms, and everything in them
+(see DeleteRoomCascade). If dryRun is true, nothing is deleted, and the result is the plan of
+every document that would be.
+
+The building is only deleted if every one of its rooms was.
+*/
+func (c *CouchDB) DeleteBuildingCascade(id string, dryRun bool) (structs.CascadeDeleteResult, error) {
+	result := structs.CascadeDeleteResult{ID: id, DryRun: dryRun}
+
+	plan := deletePlan{database: BUILDINGS, id: id}
+
+	var bld docRev
+	err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", BUILDINGS, id), "", nil, &bld)
+	if err != nil {
+		return result, fmt.Errorf("unable to get building %s to delete: %s", id, err)
+	}
+
+	plan.rev = bld.Rev
+
+	rooms, err := c.findRooms(NewQuery().Where("_id", Cond{"$gt": id + "-", "$lt": id + "."}).Select("_id"))
+	if err != nil {
+		return result, fmt.Errorf("unable to get rooms in building %s to delete: %s", id, err)
+	}
+
+	for _, r := range rooms {
+		roomPlan, err := c.planRoomDelete(r.ID)
+		if err != nil {
+			return result, fmt.Errorf("unable to plan delete of building %s: %s", id, err)
+		}
+
+		plan.children = append(plan.children, roomPlan)
+	}
+
+	return c.cascadeDelete(result, plan)
+}
+
+// planRoomDelete returns the plan to delete a room, and everything in it.
+func (c *CouchDB) planRoomDelete(id string) (deletePlan, error) {
+	plan := deletePlan{database: ROOMS, id: id}
+
+	var rm docRev
+	err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", ROOMS, id), "", nil, &rm)
+	if err != nil {
+		return plan, fmt.Errorf("unable to get room %s: %s", id, err)
+	}
+
+	plan.rev = rm.Rev
+
+	// device states, then the devices they belong to
+	for _, database := range []string{DEVICE_STATES, DEVICES} {
+		var docs []docRev
+
+		query := NewQuery().Where("_id", Cond{"$gt": id + "-", "$lt": id + "."}).Select("_id", "_rev")
+		if err := c.Find(database, query, &docs); err != nil {
+			return plan, fmt.Errorf("unable to get %s in room %s: %s", database, id, err)
+		}
+
+		for _, doc := range docs {
+			plan.children = append(plan.children, deletePlan{database: database, id: doc.ID, rev: doc.Rev})
+		}
+	}
+
+	// documents with the same id as the room, if the room has them
+	for _, database := range []string{UI_CONFIGS, ROOM_ATTACHMENTS} {
+		var doc docRev
+
+		err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", database, id), "", nil, &doc)
+		switch err.(type) {
+		case nil:
+			plan.children = append(plan.children, deletePlan{database: database, id: doc.ID, rev: doc.Rev})
+		case *NotFound:
+		default:
+			return plan, fmt.Errorf("unable to get %s %s: %s", database, id, err)
+		}
+	}
+
+	return plan, nil
+}
+
+// cascadeDelete runs (or, for a dry run, lists) plan, and adds the result of each document to result.
+func (c *CouchDB) cascadeDelete(result structs.CascadeDeleteResult, plan deletePlan) (structs.CascadeDeleteResult, error) {
+	c.runDeletePlan(&result, plan)
+
+	if failed := result.Failed(); len(failed) > 0 {
+		return result, fmt.Errorf("unable to delete %v of the %v documents in %s", len(failed), len(result.Documents), result.ID)
+	}
+
+	return result, nil
+}
+
+// runDeletePlan deletes each of plan's children, and then plan's document if all of them were deleted. It returns whether plan's document was deleted.
+func (c *CouchDB) runDeletePlan(result *structs.CascadeDeleteResult, plan deletePlan) bool {
+	ok := true
+	for _, child := range plan.children {
+		if !c.runDeletePlan(result, child) {
+			ok = false
+		}
+	}
+
+	doc := structs.DeletedDocument{
+		Database: plan.database,
+		ID:       plan.id,
+	}
+
+	switch {
+	case result.DryRun:
+		doc.Message = "would be deleted"
+	case !ok:
+		doc.Message = "not deleted, because some of the documents in it couldn't be deleted"
+	default:
+		if err := c.deleteDoc(plan.database, plan.id, plan.rev); err != nil {
+			doc.Message = err.Error()
+		} else {
+			doc.Success = true
+		}
+	}
+
+	result.Documents = append(result.Documents, doc)
+	return doc.Success
+}
@@ -0,0 +1,60 @@
+package couch
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestDeleteRoomCascade(t *testing.T) {
+	var mu sync.Mutex
+	var deleted []string
+
+	c, srv := newFakeCouch(t, fakeRoutes{
+		"GET /rooms/AAA-ZZZ":            respond(http.StatusOK, `{"_id": "AAA-ZZZ", "_rev": "1-aaa"}`),
+		"POST /device-state/_find":      respond(http.StatusOK, `{"docs": [{"_id": "AAA-ZZZ-D1", "_rev": "1-bbb"}]}`),
+		"POST /devices/_find":           respond(http.StatusOK, `{"docs": [{"_id": "AAA-ZZZ-D1", "_rev": "1-ccc"}, {"_id": "AAA-ZZZ-D2", "_rev": "1-ddd"}]}`),
+		"GET /ui-configuration/AAA-ZZZ": respond(http.StatusOK, `{"_id": "AAA-ZZZ", "_rev": "1-eee"}`),
+		"GET /room_attachments/AAA-ZZZ": respondNotFound,
+		"DELETE /devices/AAA-ZZZ-D2":    respondConflict,
+		"DELETE": func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			deleted = append(deleted, r.URL.Path)
+			mu.Unlock()
+
+			w.Write([]byte(`{"ok": true}`))
+		},
+	})
+	defer srv.Close()
+
+	plan, err := c.DeleteRoomCascade("AAA-ZZZ", true)
+	if err != nil {
+		t.Fatalf("failed to plan delete: %s", err)
+	}
+
+	if len(plan.Documents) != 5 || len(deleted) != 0 {
+		t.Fatalf("bad dry run\nplan: %+v\ndeleted: %v", plan.Documents, deleted)
+	}
+
+	result, err := c.DeleteRoomCascade("AAA-ZZZ", false)
+	if err == nil {
+		t.Fatalf("delete should have failed")
+	}
+
+	failed := result.Failed()
+	if len(failed) != 2 || failed[0].ID != "AAA-ZZZ-D2" || failed[1].Database != ROOMS {
+		t.Fatalf("expected the device and the room to fail, got %+v", failed)
+	}
+
+	// the room isn't deleted, since one of its devices wasn't
+	expected := []string{"/device-state/AAA-ZZZ-D1", "/devices/AAA-ZZZ-D1", "/ui-configuration/AAA-ZZZ"}
+	if len(deleted) != len(expected) {
+		t.Fatalf("expected %v to be deleted, got %v", expected, deleted)
+	}
+
+	for i := range expected {
+		if deleted[i] != expected[i] {
+			t.Fatalf("expected %v to be deleted, got %v", expected, deleted)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package couch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/byuoitav/common/log"
+	sd "github.com/byuoitav/common/state/statedefinition"
+	"github.com/byuoitav/common/structs"
+)
+
+// ChangeType is the kind of change that was made to a document.
+type ChangeType string
+
+const (
+	// Created means the document was added. The changes feed doesn't say whether a document is new, so this is
+	// only a heuristic: a change is Created if its revision is the document's first (1-...). A document that is
+	// deleted and added again continues from its old revision, so it is reported as Updated.
+	Created ChangeType = "created"
+
+	// Updated means an existing document was changed.
+	Updated ChangeType = "updated"
+
+	// Deleted means the document was removed.
+	Deleted ChangeType = "deleted"
+)
+
+// how long to wait before reconnecting to a changes feed that dropped
+const watchRetryInterval = 5 * time.Second
+
+/*
+Change is a single event from a database's changes feed. Only the field matching the
+database that is being watched is filled, and it is nil for deleted documents.
+
+Seq can be passed back into Watch to resume the feed after this change.
+*/
+type Change struct {
+	Database string     `json:"database"`
+	ID       string     `json:"id"`
+	Rev      string     `json:"rev"`
+	Seq      string     `json:"seq"`
+	Type     ChangeType `json:"type"` // Created is inferred from Rev; see Created
+
+	Device      *structs.Device   `json:"device,omitempty"`
+	Room        *structs.Room     `json:"room,omitempty"`
+	UIConfig    *structs.UIConfig `json:"uiConfig,omitempty"`
+	DeviceState *sd.StaticDevice  `json:"deviceState,omitempty"`
+}
+
+type changesLine struct {
+	Seq     json.RawMessage `json:"seq"`
+	LastSeq json.RawMessage `json:"last_seq"`
+	ID      string          `json:"id"`
+	Deleted bool            `json:"deleted"`
+	Changes []struct {
+		Rev string `json:"rev"`
+	} `json:"changes"`
+	Doc json.RawMessage `json:"doc"`
+}
+
+/*
+Watch follows the changes feed of database, which must be one of DEVICES, ROOMS,
+UI_CONFIGS or DEVICE_STATES, and sends each change on the returned channel.
+
+Changes after since are sent; an empty since starts with the next change made. If the
+connection to couch drops, Watch reconnects and resumes from the last change it read.
+Documents that can't be decoded are logged and skipped.
+The channel is closed once c's context (see WithContext) is done, so a context must be
+bound to stop watching.
+*/
+func (c *CouchDB) Watch(database, since string) (<-chan Change, error) {
+	switch database {
+	case DEVICES, ROOMS, UI_CONFIGS, DEVICE_STATES:
+	default:
+		return nil, fmt.Errorf("unable to watch %s: only %s, %s, %s, and %s can be watched", database, DEVICES, ROOMS, UI_CONFIGS, DEVICE_STATES)
+	}
+
+	if len(c.address) == 0 {
+		return nil, fmt.Errorf("unable to watch %s: couch address not set", database)
+	}
+
+	changes := make(chan Change)
+
+	go func() {
+		defer close(changes)
+
+		ctx := c.Context()
+		for {
+			var err error
+
+			since, err = c.followChanges(database, since, changes)
+			if ctx.Err() != nil {
+				return
+			}
+
+			log.L.Warnf("Changes feed for %s stopped (error: %v); Reconnecting in %v", database, err, watchRetryInterval)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(watchRetryInterval):
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+/*
+followChanges reads the changes feed of database until it ends, returning the sequence to resume from.
+An empty since is the current sequence of database, so that nothing written while reconnecting is missed.
+*/
+func (c *CouchDB) followChanges(database, since string, changes chan<- Change) (string, error) {
+	ctx := c.Context()
+
+	if !c.IgnoreReadyChecks {
+		if err := c.waitUntilReady(); err != nil {
+			return since, err
+		}
+	}
+
+	if len(since) == 0 {
+		seq, err := c.currentSeq(database)
+		if err != nil {
+			return since, err
+		}
+
+		since = seq
+	}
+
+	params := url.Values{}
+	params.Set("feed", "continuous")
+	params.Set("include_docs", "true")
+	params.Set("heartbeat", "30000")
+	params.Set("since", since)
+
+	// the feed is long lived, so it doesn't get the default request timeout
+	resp, err := c.send(ctx, "GET", fmt.Sprintf("%s/%s/_changes?%s", c.address, database, params.Encode()), "", nil)
+	if err != nil {
+		return since, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		var ce CouchError
+		if err := json.NewDecoder(resp.Body).Decode(&ce); err != nil {
+			return since, fmt.Errorf("received a non-200 response from the %s changes feed", database)
+		}
+
+		return since, CheckCouchErrors(ce)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue // heartbeat
+		}
+
+		change, ok, err := parseChange(database, line)
+		switch {
+		case err != nil && len(change.Seq) == 0:
+			return since, err
+		case err != nil:
+			// skip it, rather than getting stuck on it every time the feed reconnects
+			log.L.Warnf("Skipping change %s from the %s changes feed: %s", change.Seq, database, err)
+			since = change.Seq
+			continue
+		case !ok:
+			if len(change.Seq) > 0 {
+				since = change.Seq
+			}
+
+			continue
+		}
+
+		select {
+		case changes <- change:
+			since = change.Seq
+		case <-ctx.Done():
+			return since, ctx.Err()
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return since, err
+	}
+
+	return since, fmt.Errorf("feed closed")
+}
+
+// currentSeq returns the sequence of the latest change to database.
+func (c *CouchDB) currentSeq(database string) (string, error) {
+	var info struct {
+		UpdateSeq json.RawMessage `json:"update_seq"`
+	}
+
+	if err := c.MakeRequest("GET", database, "", nil, &info); err != nil {
+		return "", fmt.Errorf("unable to get the current sequence of %s: %s", database, err)
+	}
+
+	return seqString(info.UpdateSeq), nil
+}
+
+/*
+parseChange decodes a single line of a changes feed. ok is false for lines that aren't document changes.
+The returned change's Seq is set for every line that has one, even if ok is false or the document
+couldn't be decoded, so that the feed can move past it.
+*/
+func parseChange(database string, line []byte) (Change, bool, error) {
+	var cl changesLine
+	if err := json.Unmarshal(line, &cl); err != nil {
+		return Change{}, false, fmt.Errorf("unable to parse change from %s: %s", database, err)
+	}
+
+	change := Change{
+		Database: database,
+		ID:       cl.ID,
+		Type:     Updated,
+	}
+
+	switch {
+	case len(cl.Seq) > 0:
+		change.Seq = seqString(cl.Seq)
+	case len(cl.LastSeq) > 0:
+		change.Seq = seqString(cl.LastSeq)
+	}
+
+	if len(cl.ID) == 0 || strings.HasPrefix(cl.ID, "_design/") {
+		return change, false, nil
+	}
+
+	if len(cl.Changes) > 0 {
+		change.Rev = cl.Changes[0].Rev
+	}
+
+	switch {
+	case cl.Deleted:
+		change.Type = Deleted
+		return change, true, nil
+	case strings.HasPrefix(change.Rev, "1-"):
+		// only a guess; see Created
+		change.Type = Created
+	}
+
+	if len(cl.Doc) == 0 {
+		return change, true, nil
+	}
+
+	var err error
+	switch database {
+	case DEVICES:
+		change.Device = &structs.Device{}
+		err = json.Unmarshal(cl.Doc, change.Device)
+	case ROOMS:
+		change.Room = &structs.Room{}
+		err = json.Unmarshal(cl.Doc, change.Room)
+	case UI_CONFIGS:
+		change.UIConfig = &structs.UIConfig{}
+		err = json.Unmarshal(cl.Doc, change.UIConfig)
+	case DEVICE_STATES:
+		change.DeviceState = &sd.StaticDevice{}
+		err = json.Unmarshal(cl.Doc, change.DeviceState)
+	}
+
+	if err != nil {
+		return change, false, fmt.Errorf("unable to decode %s from %s: %s", cl.ID, database, err)
+	}
+
+	return change, true, nil
+}
+
+// seqString returns a sequence as a string. couch 1.x uses numbers for sequences, and 2.x uses strings.
+func seqString(seq json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(seq, &s); err == nil {
+		return s
+	}
+
+	return string(seq)
+}
@@ -0,0 +1,86 @@
+package couch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// changes feed from couch 2.x, which has string sequences
+var testChanges = []string{
+	`{"seq":"1-abc","id":"AAA-ZZZ-D1","changes":[{"rev":"1-aaa"}],"doc":{"_id":"AAA-ZZZ-D1","_rev":"1-aaa","name":"D1","type":{"_id":"test_type_1"}}}`,
+	`{"seq":"2-abb","id":"AAA-ZZZ-D2","changes":[{"rev":"1-abb"}],"doc":{"_id":"AAA-ZZZ-D2","_rev":"1-abb","name":2}}`,
+	``,
+	`{"seq":"2-abc","id":"_design/devices","changes":[{"rev":"1-bbb"}],"doc":{"_id":"_design/devices"}}`,
+	`{"seq":"3-abc","id":"AAA-ZZZ-D1","changes":[{"rev":"2-ccc"}],"doc":{"_id":"AAA-ZZZ-D1","_rev":"2-ccc","name":"D1","description":"updated","type":{"_id":"test_type_1"}}}`,
+	`{"seq":"4-abc","id":"AAA-ZZZ-D1","changes":[{"rev":"3-ddd"}],"deleted":true,"doc":{"_id":"AAA-ZZZ-D1","_rev":"3-ddd","_deleted":true}}`,
+}
+
+func TestWatch(t *testing.T) {
+	var since string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.URL.Path == "/"+DEVICES {
+			fmt.Fprint(w, `{"db_name":"devices","update_seq":"0-abc"}`)
+			return
+		}
+
+		since = r.URL.Query().Get("since")
+
+		for _, line := range testChanges {
+			fmt.Fprintln(w, line)
+		}
+
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c := NewDB(srv.URL, "", "")
+	c.IgnoreReadyChecks = true
+
+	changes, err := c.WithContext(ctx).Watch(DEVICES, "")
+	if err != nil {
+		t.Fatalf("failed to watch devices: %s", err)
+	}
+
+	var got []Change
+	for change := range changes {
+		got = append(got, change)
+		if len(got) == 3 {
+			cancel()
+		}
+	}
+
+	if since != "0-abc" {
+		t.Fatalf("expected the feed to start at the current sequence, but started at %q", since)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("expected 3 changes, got %v: %+v", len(got), got)
+	}
+
+	// the device that couldn't be decoded is skipped
+	if got[0].Type != Created || got[0].Device == nil || got[0].Device.Name != "D1" {
+		t.Fatalf("first change should have created D1: %+v", got[0])
+	}
+
+	if got[1].Type != Updated || got[1].Device == nil || got[1].Device.Description != "updated" || got[1].Seq != "3-abc" {
+		t.Fatalf("second change should have updated D1: %+v", got[1])
+	}
+
+	if got[2].Type != Deleted || got[2].Device != nil {
+		t.Fatalf("third change should have deleted D1: %+v", got[2])
+	}
+
+	if _, err := c.Watch(BUILDINGS, ""); err == nil {
+		t.Fatalf("shouldn't be able to watch %s", BUILDINGS)
+	}
+}
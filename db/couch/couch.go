@@ -1,12 +1,10 @@
 package couch
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"os"
 	"strings"
 	"sync"
@@ -22,6 +20,7 @@ const (
 	ROOM_ATTACHMENTS    = "room_attachments"
 	DEVICES             = "devices"
 	DEVICE_STATES       = "device-state"
+	ROOM_STATES         = "room-state"
 	DEVICE_TYPES        = "device_types"
 	ROOM_CONFIGURATIONS = "room_configurations"
 	UI_CONFIGS          = "ui-configuration"
@@ -29,9 +28,12 @@ const (
 	ICONS               = "Icons"
 	ROLES               = "DeviceRoles"
 	ROOM_DESIGNATIONS   = "RoomDesignations"
+	ROOM_SCHEMAS        = "RoomAttributeSchemas"
 	CLOSURE_CODES       = "ClosureCodes"
 	TAGS                = "Tags"
 	DMPSLIST            = "dmps"
+	DMPS_LIST_ID        = "dmps_list"
+	AUTH                = "auth"
 	LAB_CONFIGS         = "lab-attendance-config"
 	SCHEDULING_CONFIGS  = "scheduling-configs"
 
@@ -50,91 +52,146 @@ type CouchDB struct {
 	password string
 
 	IgnoreReadyChecks bool
+
+	ctx     context.Context
+	options Options
+	breaker *breaker
 }
 
+// defaultTimeout is how long a request against couch is allowed to take if its context doesn't have a deadline, and its options don't set a timeout.
+const defaultTimeout = 5 * time.Second
+
 // NewDB .
 func NewDB(address, username, password string) *CouchDB {
+	return NewDBWithOptions(address, username, password, DefaultOptions())
+}
+
+// NewDBWithOptions returns a CouchDB that makes its requests as described by options.
+func NewDBWithOptions(address, username, password string, options Options) *CouchDB {
+	address = strings.Trim(address, "/")
+
 	return &CouchDB{
-		address:  strings.Trim(address, "/"),
+		address:  address,
 		username: username,
 		password: password,
+		options:  options,
+		breaker:  breakerFor(address, options.BreakerThreshold, options.BreakerCooldown),
 	}
 }
 
-func (c *CouchDB) req(method, endpoint, contentType string, body []byte) (string, []byte, error) {
-	errMsg := "unable to make request against couch"
-
-	if len(c.address) == 0 {
-		return "", nil, fmt.Errorf("%s: couch address not set", errMsg)
+/*
+WithContext returns a shallow copy of c whose requests are bound to ctx. Requests made
+through the copy are canceled when ctx is canceled, and waiting for couch to be ready
+stops when ctx is done. If ctx doesn't have a deadline, each request still times out
+after the timeout in c's options.
+*/
+func (c *CouchDB) WithContext(ctx context.Context) *CouchDB {
+	if ctx == nil {
+		panic("nil context")
 	}
 
-	url := fmt.Sprintf("%s/%s", c.address, endpoint)
-	url = strings.TrimSpace(url)
+	c2 := *c
+	c2.ctx = ctx
+	return &c2
+}
 
-	// build request
-	req, err := http.NewRequest(method, url, bytes.NewReader(body))
-	if err != nil {
-		return "", nil, fmt.Errorf("%s: %s", errMsg, err)
+// Context returns the context requests from c are bound to. It defaults to context.Background.
+func (c *CouchDB) Context() context.Context {
+	if c.ctx != nil {
+		return c.ctx
 	}
 
-	// add auth
-	if len(c.username) > 0 && len(c.password) > 0 {
-		req.SetBasicAuth(c.username, c.password)
+	return context.Background()
+}
+
+// requestContext returns the context to use for a single request, adding the default timeout if c's context doesn't have a deadline.
+func (c *CouchDB) requestContext() (context.Context, context.CancelFunc) {
+	ctx := c.Context()
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
 	}
 
-	// add headers
-	if len(contentType) > 0 {
-		req.Header.Add("Content-Type", contentType)
+	timeout := c.options.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
 	}
 
-	client := &http.Client{
-		Timeout: 5 * time.Second,
+	return context.WithTimeout(ctx, timeout)
+}
+
+func (c *CouchDB) req(method, endpoint, contentType string, body []byte) (string, []byte, error) {
+	errMsg := "unable to make request against couch"
+
+	if len(c.address) == 0 {
+		return "", nil, fmt.Errorf("%s: couch address not set", errMsg)
 	}
 
+	url := fmt.Sprintf("%s/%s", c.address, endpoint)
+	url = strings.TrimSpace(url)
+
 	// validate that couch is ready, wait if it isn't
 	if !c.IgnoreReadyChecks {
-		c.waitUntilReady()
+		if err := c.waitUntilReady(); err != nil {
+			return "", nil, fmt.Errorf("%s: %s", errMsg, err)
+		}
 	}
 
 	// execute request
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", nil, fmt.Errorf("%s: %s", errMsg, err)
-	}
-	defer resp.Body.Close()
-
-	b, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
+	resp, err := c.do(method, url, contentType, body)
+	switch err.(type) {
+	case nil:
+	case *Unavailable:
+		return "", nil, err
+	default:
 		return "", nil, fmt.Errorf("%s: %s", errMsg, err)
 	}
 
-	if resp.StatusCode/100 != 2 {
+	if resp.status/100 != 2 {
 		ce := CouchError{}
-		err = json.Unmarshal(b, &ce)
+		err = json.Unmarshal(resp.body, &ce)
 		if err != nil {
-			return "", nil, fmt.Errorf("%s: received a non-200 response from %s. body: %s", errMsg, url, b)
+			return "", nil, fmt.Errorf("%s: received a non-200 response from %s. body: %s", errMsg, url, resp.body)
 		}
 
 		return "", nil, CheckCouchErrors(ce)
 	}
 
-	return resp.Header.Get("content-type"), b, nil
+	return resp.contentType, resp.body, nil
 }
 
 var (
-	checkReady sync.Once
-	readyMu    sync.Mutex
+	readyMu sync.Mutex
+	readies = make(map[string]chan struct{})
 )
 
-func (c *CouchDB) waitUntilReady() {
-	checkReady.Do(func() {
-		readyMu.Lock()
-		defer readyMu.Unlock()
+// readyFor returns a channel that is closed once the couch at address has finished replicating. The check is started by the first CouchDB for an address to ask, and shared by every other.
+func (c *CouchDB) readyFor(address string) <-chan struct{} {
+	readyMu.Lock()
+	defer readyMu.Unlock()
+
+	if ready, ok := readies[address]; ok {
+		return ready
+	}
+
+	ready := make(chan struct{})
+	readies[address] = ready
+
+	// the check isn't tied to whichever request happened to start it
+	bg := c.WithContext(context.Background())
+
+	go func() {
+		defer close(ready)
 
 		// +deployment not-required
 		for len(os.Getenv("STOP_REPLICATION")) == 0 {
 			// wait until database is ready
-			state, err := c.GetStatus()
+			// other replications failing doesn't keep requests from being allowed
+			state, err := bg.GetStatus()
+			if _, ok := err.(*ReplicationFailed); ok {
+				log.L.Warnf("Database replication in state %v, but %s", state, err)
+				err = nil
+			}
+
 			if err != nil || state != "completed" {
 				log.L.Warnf("Database replication in state %v (error: %s); Retrying in 5 seconds", state, err)
 				time.Sleep(5 * time.Second)
@@ -144,10 +201,19 @@ func (c *CouchDB) waitUntilReady() {
 			log.L.Infof("Database replication in state %v. Allowing CouchDB requests now.", state)
 			break
 		}
-	})
+	}()
 
-	readyMu.Lock()
-	readyMu.Unlock()
+	return ready
+}
+
+// waitUntilReady blocks until couch has finished replicating, or until c's context is done.
+func (c *CouchDB) waitUntilReady() error {
+	select {
+	case <-c.readyFor(c.address):
+		return nil
+	case <-c.Context().Done():
+		return fmt.Errorf("stopped waiting for couch to be ready: %s", c.Context().Err())
+	}
 }
 
 // MakeRequest .
@@ -209,9 +275,11 @@ func CheckCouchErrors(ce CouchError) error {
 	case "not_found":
 		return &NotFound{fmt.Sprintf("The ID requested was unknown. Message: %v.", ce.Reason)}
 	case "conflict":
-		return &Conflict{fmt.Sprintf("There was a conflict updating/creating the document: %v", ce.Reason)}
+		return &Conflict{msg: fmt.Sprintf("There was a conflict updating/creating the document: %v", ce.Reason)}
 	case "bad_request":
 		return &BadRequest{fmt.Sprintf("The request was bad: %v", ce.Reason)}
+	case "forbidden":
+		return &Forbidden{fmt.Sprintf("The document was rejected: %v", ce.Reason)}
 	default:
 		return errors.New(fmt.Sprintf("unknown error type: %v. Message: %v", ce.Error, ce.Reason))
 	}
@@ -225,7 +293,8 @@ type IDPrefixQuery struct {
 			Regex string `json:"$regex,omitempty"`
 		} `json:"_id"`
 	} `json:"selector"`
-	Limit int `json:"limit"`
+	Limit    int    `json:"limit"`
+	Bookmark string `json:"bookmark,omitempty"`
 }
 
 type CouchUpsertResponse struct {
@@ -247,8 +316,19 @@ func (n NotFound) Error() string {
 	return n.msg
 }
 
+// NewNotFound returns a *NotFound with msg, for other backends to return when a document is missing.
+func NewNotFound(msg string) *NotFound {
+	return &NotFound{msg}
+}
+
+// Conflict is returned when a document can't be created because it already exists, or can't be updated because it has changed since the revision the update was based on.
 type Conflict struct {
 	msg string
+
+	// set when an update was based on an out of date revision
+	ID          string
+	ExpectedRev string
+	CurrentRev  string
 }
 
 func (c Conflict) Error() string {
@@ -262,3 +342,11 @@ type BadRequest struct {
 func (br BadRequest) Error() string {
 	return br.msg
 }
+
+type Forbidden struct {
+	msg string
+}
+
+func (f Forbidden) Error() string {
+	return f.msg
+}
@@ -3,9 +3,7 @@ package couch
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
-	"time"
 
 	"github.com/byuoitav/common/log"
 	"github.com/byuoitav/common/structs"
@@ -46,34 +44,13 @@ func (c *CouchDB) getServiceInfo(serviceID string) (structs.ServiceConfigWrapper
 func (c *CouchDB) GetServiceAttachment(service, designation string) ([]byte, error) {
 	url := fmt.Sprintf("%v/%v/%v/%v", c.address, DEPLOY, service, fmt.Sprintf("%v-%v", service, designation))
 
-	// start building the request
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	resp, err := c.do(http.MethodGet, url, "", nil)
 	if err != nil {
 		return nil, err
 	}
 
-	// add auth
-	if len(c.username) > 0 && len(c.password) > 0 {
-		req.SetBasicAuth(c.username, c.password)
-	}
-
-	// build client with a timeout
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	b, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.StatusCode/100 != 2 {
+	b := resp.body
+	if resp.status/100 != 2 {
 		var ce CouchError
 		err = json.Unmarshal(b, &ce)
 		if err != nil {
@@ -91,34 +68,13 @@ func (c *CouchDB) GetServiceAttachment(service, designation string) ([]byte, err
 func (c *CouchDB) GetServiceZip(service, designation string) ([]byte, error) {
 	url := fmt.Sprintf("%v/%v/%v/%v", c.address, DEPLOY, service, fmt.Sprintf("%v.tar.gz", designation))
 
-	// start building the request
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	// add auth
-	if len(c.username) > 0 && len(c.password) > 0 {
-		req.SetBasicAuth(c.username, c.password)
-	}
-
-	// build client with a timeout
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	b, err := ioutil.ReadAll(resp.Body)
+	resp, err := c.do(http.MethodGet, url, "", nil)
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode/100 != 2 {
+	b := resp.body
+	if resp.status/100 != 2 {
 		var ce CouchError
 		err = json.Unmarshal(b, &ce)
 		if err != nil {
@@ -1,7 +1,6 @@
 package couch
 
 import (
-	"encoding/json"
 	"fmt"
 
 	sd "github.com/byuoitav/common/state/statedefinition"
@@ -27,22 +26,11 @@ func (c *CouchDB) getDeviceState(id string) (sd.StaticDevice, error) {
 func (c *CouchDB) getDeviceStatesByQuery(query IDPrefixQuery) ([]sd.StaticDevice, error) {
 	var toReturn []sd.StaticDevice
 
-	// marshal query
-	b, err := json.Marshal(query)
-	if err != nil {
-		return toReturn, fmt.Errorf("failed to marshal device state query: %s", err)
-	}
-
-	// make query for devices
-	var resp deviceStateQueryResponse
-	err = c.MakeRequest("POST", fmt.Sprintf("%s/_find", DEVICE_STATES), "application/json", b, &resp)
+	// make query for device states, a page at a time
+	err := c.findAll(DEVICE_STATES, query, &toReturn)
 	if err != nil {
 		return toReturn, fmt.Errorf("failed to query device state: %s", err)
 	}
-	// return each document
-	for _, doc := range resp.Docs {
-		toReturn = append(toReturn, doc)
-	}
 
 	return toReturn, nil
 }
@@ -1,7 +1,6 @@
 package couch
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -46,15 +45,9 @@ func (c *CouchDB) getDevice(id string) (device, error) {
 func (c *CouchDB) getDevicesByQuery(query IDPrefixQuery, includeType bool) ([]device, error) {
 	var toReturn []device
 
-	// marshal query
-	b, err := json.Marshal(query)
-	if err != nil {
-		return toReturn, fmt.Errorf("failed to marshal devices query: %s", err)
-	}
-
-	// make query for devices
-	var resp deviceQueryResponse
-	err = c.MakeRequest("POST", fmt.Sprintf("%s/_find", DEVICES), "application/json", b, &resp)
+	// make query for devices, a page at a time
+	var docs []device
+	err := c.findAll(DEVICES, query, &docs)
 	if err != nil {
 		return toReturn, fmt.Errorf("failed to query devices: %s", err)
 	}
@@ -73,13 +66,13 @@ func (c *CouchDB) getDevicesByQuery(query IDPrefixQuery, includeType bool) ([]de
 		}
 
 		// fill in device types
-		for _, d := range resp.Docs {
+		for _, d := range docs {
 			d.Type = typesMap[d.Type.ID]
 		}
 	}
 
 	// return each document
-	for _, doc := range resp.Docs {
+	for _, doc := range docs {
 		toReturn = append(toReturn, doc)
 	}
 
@@ -200,6 +193,11 @@ func (c *CouchDB) CreateDevice(toAdd structs.Device) (structs.Device, error) {
 		}
 	}
 
+	// check the device's attributes against its type's attribute schema
+	if err := toAdd.ApplyAttributeSchema(deviceType.AttributeSchema); err != nil {
+		return toReturn, err
+	}
+
 	// the device document should only include the type ID
 	toAdd.Type = structs.DeviceType{ID: deviceType.ID}
 
@@ -212,15 +210,8 @@ func (c *CouchDB) CreateDevice(toAdd structs.Device) (structs.Device, error) {
 		}
 	*/
 
-	// marshal the device
-	b, err := json.Marshal(toAdd)
-	if err != nil {
-		return toReturn, fmt.Errorf("failed to marshal device: %s", err)
-	}
-
 	// post up device
-	var resp CouchUpsertResponse
-	err = c.MakeRequest("POST", fmt.Sprintf("%v", DEVICES), "application/json", b, &resp)
+	err = c.createDoc(DEVICES, toAdd.ID, toAdd)
 	if err != nil {
 		if _, ok := err.(*Conflict); ok { // device with same id already in database
 			return toReturn, fmt.Errorf("unable to create device, because it already exists. error: %s", err)
@@ -270,25 +261,34 @@ func (c *CouchDB) UpdateDevice(id string, device structs.Device) (structs.Device
 	}
 
 	if id == device.ID { // the device ID isn't changing
-		// get the rev of the device
-		dev, err := c.getDevice(id)
+		// check the device's attributes against its type's attribute schema
+		schema, err := c.deviceAttributeSchema(device.Type.ID)
 		if err != nil {
-			return toReturn, fmt.Errorf("unable to get device %s to update: %s", id, err)
+			return toReturn, fmt.Errorf("failed to update device %s: %s", id, err)
 		}
 
-		// marshal the new device
-		b, err := json.Marshal(device)
-		if err != nil {
-			return toReturn, fmt.Errorf("unable to unmarshal new device: %s", err)
+		if err := device.ApplyAttributeSchema(schema); err != nil {
+			return toReturn, err
 		}
 
-		// update the device
-		err = c.MakeRequest("PUT", fmt.Sprintf("%s/%s?rev=%v", DEVICES, id, dev.Rev), "application/json", b, &toReturn)
+		// update the device, as long as it hasn't changed since device.Rev
+		rev, err := c.updateDoc(DEVICES, id, device.Rev, device)
 		if err != nil {
+			if _, ok := err.(*Conflict); ok {
+				return toReturn, err
+			}
+
 			return toReturn, fmt.Errorf("failed to update device %s: %s", id, err)
 		}
 
+		toReturn = device
+		toReturn.Rev = rev
 	} else {
+		// make sure the device hasn't changed before replacing it
+		if err := c.checkRev(DEVICES, id, device.Rev); err != nil {
+			return toReturn, err
+		}
+
 		// delete the old struct
 		err = c.DeleteDevice(id)
 		if err != nil {
@@ -367,231 +367,213 @@ func (c *CouchDB) GetDevicesByRoomAndType(roomID, typeID string) ([]structs.Devi
 	return toReturn, nil
 }
 
-// TODO could actually use a query to be faster
-
-// GetDevicesByType .
+// GetDevicesByType returns each of the devices with the given type. The type is matched ignoring case.
 func (c *CouchDB) GetDevicesByType(deviceType string) ([]structs.Device, error) {
-	var toReturn []structs.Device
-
-	// get all devices
-	devs, err := c.GetAllDevices()
+	toReturn, err := c.FindDevices(structs.DeviceFilter{
+		Type:       deviceType,
+		IgnoreCase: true,
+	})
 	if err != nil {
 		return toReturn, fmt.Errorf("failed to get devices by type: %s", err)
 	}
 
-	// filter for ones that have correct type
-	for _, d := range devs {
-		if strings.EqualFold(d.Type.ID, deviceType) {
-			toReturn = append(toReturn, d)
-		}
-	}
-
 	return toReturn, nil
 }
 
-// GetDevicesByRoleAndTypeAndDesignation .
+// GetDevicesByRoleAndTypeAndDesignation returns each of the devices with the given role and type, that are in a room with the given designation. Like HasRole, each is matched ignoring case.
 func (c *CouchDB) GetDevicesByRoleAndTypeAndDesignation(role, deviceType, designation string) ([]structs.Device, *nerr.E) {
-
-	devs, err := c.GetDevicesByRoleAndType(role, deviceType)
+	toReturn, err := c.FindDevices(structs.DeviceFilter{
+		Type:        deviceType,
+		Roles:       []string{role},
+		Designation: designation,
+		IgnoreCase:  true,
+	})
 	if err != nil {
-		return devs, err.Addf("Couldn't get device by role and type and designation")
+		return toReturn, nerr.Translate(err).Addf("Couldn't get device by role and type and designation")
 	}
-	log.L.Debugf("Found %v devices with type %v and role %v", len(devs), deviceType, role)
 
-	//we have to go get all the rooms with the given designation
-	rooms, err := c.GetRoomsByDesignation(designation)
+	log.L.Debugf("Found %v devices with type %v, role %v, and designation %v", len(toReturn), deviceType, role, designation)
+	return toReturn, nil
+}
+
+// GetDevicesByRoleAndType returns each of the devices with the given role and type. Like HasRole, both are matched ignoring case.
+func (c *CouchDB) GetDevicesByRoleAndType(role, deviceType string) ([]structs.Device, *nerr.E) {
+	toReturn, err := c.FindDevices(structs.DeviceFilter{
+		Type:       deviceType,
+		Roles:      []string{role},
+		IgnoreCase: true,
+	})
 	if err != nil {
-		return devs, err.Addf("Couldn't get rooms by designation.")
+		return toReturn, nerr.Translate(err).Addf("failed to get devices by role and type")
 	}
 
-	//make a map of roomIDs for fast lookup
-	roomSet := make(map[string]bool)
+	return toReturn, nil
+}
+
+/*
+FindDevices returns each of the devices that match filter. The filter is evaluated by couch,
+so only matching devices are sent back. If filter has a Designation, the rooms with that
+designation are looked up first. As with GetAllDevices, only the ID of each device's type
+is included.
+*/
+func (c *CouchDB) FindDevices(filter structs.DeviceFilter) ([]structs.Device, error) {
+	var toReturn []structs.Device
+
+	query := NewQuery().Limit(1000)
+
+	// match a string field exactly, or ignoring case if the filter says to
+	match := func(value string) Cond {
+		if filter.IgnoreCase {
+			return EqualFold(value)
+		}
 
-	for r := range rooms {
-		roomSet[rooms[r].ID] = true
+		return Eq(value)
 	}
-	log.L.Debugf("Found %v rooms with designation %v", len(rooms), designation)
 
-	//filter on the match
-	var toReturn []structs.Device
-	for _, d := range devs {
-		idx := strings.LastIndex(d.ID, "-")
+	if len(filter.RoomID) > 0 {
+		query.Where("_id", Cond{"$gt": filter.RoomID + "-", "$lt": filter.RoomID + "."})
+	}
 
-		if idx > 0 {
-			roomID := d.ID[:idx]
-			if _, ok := roomSet[roomID]; ok {
-				toReturn = append(toReturn, d)
-			}
+	if len(filter.BuildingID) > 0 {
+		query.Where("_id", Cond{"$gt": filter.BuildingID + "-", "$lt": filter.BuildingID + "."})
+	}
+
+	if len(filter.Type) > 0 {
+		query.Where("type._id", match(filter.Type))
+
+		if !filter.IgnoreCase {
+			query.UseIndex(DeviceTypeIndex.DesignDoc, DeviceTypeIndex.Name)
 		}
 	}
 
-	return toReturn, nil
-}
+	for _, role := range filter.Roles {
+		query.Where("roles", ElemMatch(Selector{"_id": match(role)}))
+	}
 
-// TODO a real query would probably be faster again
+	if len(filter.Tags) > 0 {
+		var tags []interface{}
+		for _, tag := range filter.Tags {
+			tags = append(tags, tag)
+		}
 
-// GetDevicesByRoleAndType .
-func (c *CouchDB) GetDevicesByRoleAndType(role, deviceType string) ([]structs.Device, *nerr.E) {
-	var toReturn []structs.Device
+		query.Where("tags", All(tags...))
+	}
 
-	// get all devices
-	devs, err := c.GetAllDevices()
-	if err != nil {
-		return toReturn, nerr.Translate(err).Addf("failed to get devices by role and type")
+	for key, val := range filter.Attributes {
+		query.Where(Field("attributes", key), Eq(val))
 	}
 
-	// filter for ones that have the role and type
-	for _, d := range devs {
-		if structs.HasRole(d, role) && strings.EqualFold(d.Type.ID, deviceType) {
-			toReturn = append(toReturn, d)
+	if len(filter.Designation) > 0 {
+		rooms, err := c.findRooms(NewQuery().Where("designation", match(filter.Designation)).Select("_id"))
+		if err != nil {
+			return toReturn, fmt.Errorf("failed to find rooms with designation %s: %s", filter.Designation, err)
+		}
+
+		if len(rooms) == 0 {
+			return toReturn, nil
 		}
+
+		// only devices in one of those rooms
+		var inRooms []Selector
+		for _, room := range rooms {
+			inRooms = append(inRooms, Selector{"_id": Cond{"$gt": room.ID + "-", "$lt": room.ID + "."}})
+		}
+
+		query.Or(inRooms...)
+	}
+
+	var docs []device
+	err := c.Find(DEVICES, query, &docs)
+	if err != nil {
+		return toReturn, fmt.Errorf("failed to find devices: %s", err)
+	}
+
+	for _, doc := range docs {
+		toReturn = append(toReturn, *doc.Device)
 	}
 
 	return toReturn, nil
 }
 
 // CreateBulkDevices does validating and adding for a list of devices to be added to the database.
+// The devices are validated one at a time, and then created with a single _bulk_docs request per batch.
+// Device types that don't exist yet are created once a device using them is; their responses follow the devices'.
 func (c *CouchDB) CreateBulkDevices(devices []structs.Device) []structs.BulkUpdateResponse {
-	var toReturn []structs.BulkUpdateResponse
-	checkedRooms := make(map[string]bool)
-	checkedTypes := make(map[string]bool)
-	validPortIDs := make(map[string]bool)
+	return c.writeBulkDevices(devices, false)
+}
 
+// UpdateBulkDevices validates and updates each of the devices, which must already exist.
+func (c *CouchDB) UpdateBulkDevices(devices []structs.Device) []structs.BulkUpdateResponse {
+	return c.writeBulkDevices(devices, true)
+}
+
+func (c *CouchDB) writeBulkDevices(devices []structs.Device, update bool) []structs.BulkUpdateResponse {
+	var ids []string
 	for i := range devices {
-		validPortIDs[devices[i].ID] = true
+		ids = append(ids, devices[i].ID)
 	}
 
-	for _, device := range devices {
-		response := structs.BulkUpdateResponse{
-			ID:      device.ID,
-			Success: false,
-		}
-
-		// validate device struct
-		err := device.Validate()
-		if err != nil {
-			response.Message = err.Error()
-			toReturn = append(toReturn, response)
-			continue
-		}
+	toReturn := newBulkResponses(ids)
 
-		// validate the room exists
-		split := strings.Split(device.ID, "-")
-		roomID := fmt.Sprintf("%s-%s", split[0], split[1])
-		valid, ok := checkedRooms[roomID]
-		if ok && !valid {
-			response.Message = fmt.Sprintf("room %s doesn't exist", roomID)
-			toReturn = append(toReturn, response)
-			continue
-		}
-
-		if !ok {
-			_, err = c.GetRoom(roomID)
-			if err != nil {
-				if _, ok := err.(*NotFound); ok {
-					response.Message = fmt.Sprintf("room %s doesn't exist", roomID)
-					toReturn = append(toReturn, response)
-					checkedRooms[roomID] = false
-					continue
-				}
+	v := newBulkValidator(c)
+	for i := range devices {
+		v.validDevice[devices[i].ID] = true
+	}
 
-				response.Message = err.Error()
-				toReturn = append(toReturn, response)
-				checkedRooms[roomID] = false
-				continue
+	revs := make(map[string]string)
+	if update {
+		var err error
+		if revs, err = c.getRevs(DEVICES, ids); err != nil {
+			for i := range toReturn {
+				toReturn[i].Message = err.Error()
 			}
 
-			checkedRooms[roomID] = true // mark this room as having been checked so we don't do it again
+			return toReturn
 		}
+	}
+
+	var writes []bulkWrite
+	for i := range devices {
+		d := devices[i]
 
-		// validate the device type
-		valid, ok = checkedTypes[device.Type.ID]
-		if ok && !valid {
-			response.Message = fmt.Sprintf("device type %s doesn't exist", device.Type.ID)
-			toReturn = append(toReturn, response)
+		if err := v.device(&d); err != nil {
+			toReturn[i].Message = err.Error()
 			continue
 		}
 
-		if !ok {
-			_, err := c.GetDeviceType(device.Type.ID)
-			if err != nil {
-				if _, ok := err.(*NotFound); ok { // device type doesn't exist
-					// try to create device type
-					_, err := c.CreateDeviceType(device.Type)
-					if err != nil {
-						response.Message = fmt.Sprintf("device type %s doesn't exist yet, and not enough information was included to create it. (error: %s)", device.Type.ID, err)
-						toReturn = append(toReturn, response)
-						checkedTypes[device.Type.ID] = false
-						continue
-					}
-				} else { // unknown error getting device type
-					response.Message = err.Error()
-					toReturn = append(toReturn, response)
-					checkedTypes[device.Type.ID] = false
-					continue
-				}
+		rev, ok := revs[d.ID]
+		if update && !ok {
+			toReturn[i].Message = fmt.Sprintf("unable to update device %s, because it doesn't exist", d.ID)
+			continue
+		}
 
-				checkedTypes[device.Type.ID] = true
-			}
+		// updates without an expected revision overwrite whatever the current revision is
+		if !update || len(d.Rev) == 0 {
+			d.Rev = rev
 		}
 
 		// clear out extra data in the device type
-		device.Type = structs.DeviceType{ID: device.Type.ID}
-
-		// check that the ports contain valid devices
-		for _, port := range device.Ports {
-			if len(port.SourceDevice) > 0 {
-				if !validPortIDs[port.SourceDevice] {
-					if _, err := c.GetDevice(port.SourceDevice); err != nil {
-						response.Message = fmt.Sprintf("invalid port %v. source device %s doesn't exist, create it before adding it to a port.", port.ID, port.SourceDevice)
-						toReturn = append(toReturn, response)
-						break
-					}
-				}
-			}
+		d.Type = structs.DeviceType{ID: d.Type.ID}
 
-			if len(port.DestinationDevice) > 0 {
-				if !validPortIDs[port.DestinationDevice] {
-					if _, err := c.GetDevice(port.DestinationDevice); err != nil {
-						response.Message = fmt.Sprintf("invalid port %v. destination device %s doesn't exist, create it before adding it to a port.", port.ID, port.DestinationDevice)
-						toReturn = append(toReturn, response)
-						break
-					}
-				}
-			}
-		}
+		writes = append(writes, bulkWrite{index: i, doc: d})
+	}
 
-		if len(response.Message) > 0 {
-			toReturn = append(toReturn, response)
-			continue
-		}
+	c.bulkDocs(DEVICES, writes, toReturn)
 
-		// marshal the device
-		b, err := json.Marshal(device)
-		if err != nil {
-			response.Message = fmt.Sprintf("failed to marshal: %s", err)
-			toReturn = append(toReturn, response)
-			continue
+	used := make(map[string]bool)
+	for _, write := range writes {
+		if toReturn[write.index].Success {
+			used[devices[write.index].Type.ID] = true
 		}
+	}
 
-		// post it up
-		var resp CouchUpsertResponse
-		err = c.MakeRequest("POST", fmt.Sprintf("%v", DEVICES), "application/json", b, &resp)
-		if err != nil {
-			if _, ok := err.(*Conflict); ok { // device with same id already in database
-				response.Message = fmt.Sprintf("unable to create device, because it already exists. error: %s", err)
-				toReturn = append(toReturn, response)
-				continue
-			}
-
-			response.Message = err.Error()
-			toReturn = append(toReturn, response)
-			continue
-		}
+	return append(toReturn, v.createNew(used)...)
+}
 
-		response.Success = true
-		toReturn = append(toReturn, response)
-	}
+// DeleteBulkDevices deletes each of the devices.
+func (c *CouchDB) DeleteBulkDevices(ids []string) []structs.BulkUpdateResponse {
+	toReturn := newBulkResponses(ids)
+	c.bulkDelete(DEVICES, ids, toReturn)
 
 	return toReturn
 }
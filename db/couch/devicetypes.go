@@ -1,7 +1,6 @@
 package couch
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 
@@ -27,24 +26,12 @@ func (c *CouchDB) getDeviceType(id string) (deviceType, error) {
 func (c *CouchDB) getDeviceTypesByQuery(query IDPrefixQuery) ([]deviceType, error) {
 	var toReturn []deviceType
 
-	// marshal query
-	b, err := json.Marshal(query)
-	if err != nil {
-		return toReturn, errors.New(fmt.Sprintf("failed to marshal device types query: %s", err))
-	}
-
-	// make query for types
-	var resp deviceTypeQueryResponse
-	err = c.MakeRequest("POST", fmt.Sprintf("%s/_find", DEVICE_TYPES), "application/json", b, &resp)
+	// make query for types, a page at a time
+	err := c.findAll(DEVICE_TYPES, query, &toReturn)
 	if err != nil {
 		return toReturn, errors.New(fmt.Sprintf("failed to query device types: %s", err))
 	}
 
-	// return each document
-	for _, doc := range resp.Docs {
-		toReturn = append(toReturn, doc)
-	}
-
 	return toReturn, nil
 }
 
@@ -104,15 +91,8 @@ func (c *CouchDB) CreateDeviceType(toAdd structs.DeviceType) (structs.DeviceType
 		return toReturn, err
 	}
 
-	// marshal device type
-	b, err := json.Marshal(toAdd)
-	if err != nil {
-		return toReturn, errors.New(fmt.Sprintf("failed to marshal building %s: %s", toAdd.ID, err))
-	}
-
 	// post device type
-	var resp CouchUpsertResponse
-	err = c.MakeRequest("POST", DEVICE_TYPES, "application/json", b, &resp)
+	err = c.createDoc(DEVICE_TYPES, toAdd.ID, toAdd)
 	if err != nil {
 		if _, ok := err.(*Conflict); ok { // a device type with this id already exists
 			return toReturn, errors.New(fmt.Sprintf("device type already exists, please update this type or change id's. error: %s", err))
@@ -156,6 +136,148 @@ func (c *CouchDB) DeleteDeviceType(id string) error {
 	return nil
 }
 
+// deviceAttributeSchema returns the attribute schema of the device type id. A device type that doesn't exist doesn't have one.
+func (c *CouchDB) deviceAttributeSchema(id string) ([]structs.AttributeSchema, error) {
+	var dt structs.DeviceType
+
+	err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", DEVICE_TYPES, id), "", nil, &dt)
+	switch err.(type) {
+	case nil:
+		return dt.AttributeSchema, nil
+	case *NotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unable to get attribute schema of device type %s: %s", id, err)
+	}
+}
+
+/*
+UpdateDeviceType replaces the device type id with dt, as long as it hasn't changed since dt.Rev.
+The id of a device type can only be changed if no devices depend on it.
+*/
 func (c *CouchDB) UpdateDeviceType(id string, dt structs.DeviceType) (structs.DeviceType, error) {
-	return structs.DeviceType{}, errors.New("not implemented")
+	var toReturn structs.DeviceType
+
+	if err := dt.Validate(true); err != nil {
+		return toReturn, err
+	}
+
+	if id == dt.ID { // the id isn't changing
+		rev, err := c.updateDoc(DEVICE_TYPES, id, dt.Rev, dt)
+		if err != nil {
+			if _, ok := err.(*Conflict); ok {
+				return toReturn, err
+			}
+
+			return toReturn, fmt.Errorf("failed to update device type %s: %s", id, err)
+		}
+
+		toReturn = dt
+		toReturn.Rev = rev
+		return toReturn, nil
+	}
+
+	// make sure the device type hasn't changed before moving it
+	if err := c.checkRev(DEVICE_TYPES, id, dt.Rev); err != nil {
+		return toReturn, err
+	}
+
+	devices, err := c.GetDevicesByType(id)
+	if err != nil {
+		return toReturn, fmt.Errorf("unable to validate no devices depend on device type %s: %s", id, err)
+	}
+
+	if len(devices) != 0 {
+		return toReturn, fmt.Errorf("can't change the id of device type %s. %v devices still depend on it.", id, len(devices))
+	}
+
+	toReturn, err = c.CreateDeviceType(dt)
+	if err != nil {
+		return toReturn, fmt.Errorf("unable to move device type %s to %s: %s", id, dt.ID, err)
+	}
+
+	if err := c.DeleteDeviceType(id); err != nil {
+		return toReturn, fmt.Errorf("moved device type %s to %s, but unable to delete the old one: %s", id, dt.ID, err)
+	}
+
+	return toReturn, nil
+}
+
+// CreateBulkDeviceTypes validates and creates each of the device types with a single _bulk_docs request per batch.
+func (c *CouchDB) CreateBulkDeviceTypes(types []structs.DeviceType) []structs.BulkUpdateResponse {
+	return c.writeBulkDeviceTypes(types, false)
+}
+
+// UpdateBulkDeviceTypes validates and updates each of the device types, which must already exist.
+func (c *CouchDB) UpdateBulkDeviceTypes(types []structs.DeviceType) []structs.BulkUpdateResponse {
+	return c.writeBulkDeviceTypes(types, true)
+}
+
+func (c *CouchDB) writeBulkDeviceTypes(types []structs.DeviceType, update bool) []structs.BulkUpdateResponse {
+	var ids []string
+	for i := range types {
+		ids = append(ids, types[i].ID)
+	}
+
+	toReturn := newBulkResponses(ids)
+
+	revs := make(map[string]string)
+	if update {
+		var err error
+		if revs, err = c.getRevs(DEVICE_TYPES, ids); err != nil {
+			for i := range toReturn {
+				toReturn[i].Message = err.Error()
+			}
+
+			return toReturn
+		}
+	}
+
+	var writes []bulkWrite
+	for i := range types {
+		dt := types[i]
+
+		if err := dt.Validate(true); err != nil {
+			toReturn[i].Message = err.Error()
+			continue
+		}
+
+		rev, ok := revs[dt.ID]
+		if update && !ok {
+			toReturn[i].Message = fmt.Sprintf("unable to update device type %s, because it doesn't exist", dt.ID)
+			continue
+		}
+
+		// updates without an expected revision overwrite whatever the current revision is
+		if !update || len(dt.Rev) == 0 {
+			dt.Rev = rev
+		}
+
+		writes = append(writes, bulkWrite{index: i, doc: dt})
+	}
+
+	c.bulkDocs(DEVICE_TYPES, writes, toReturn)
+	return toReturn
+}
+
+// DeleteBulkDeviceTypes deletes each of the device types. As with DeleteDeviceType, a type can't be deleted while devices still depend on it.
+func (c *CouchDB) DeleteBulkDeviceTypes(ids []string) []structs.BulkUpdateResponse {
+	toReturn := newBulkResponses(ids)
+
+	for i, id := range ids {
+		var devices []docRev
+
+		query := NewQuery().Where("type._id", Eq(id)).Select("_id").UseIndex(DeviceTypeIndex.DesignDoc, DeviceTypeIndex.Name)
+		if err := c.Find(DEVICES, query, &devices); err != nil {
+			toReturn[i].Message = fmt.Sprintf("unable to validate no devices depend on this type: %s", err)
+			continue
+		}
+
+		if len(devices) != 0 {
+			toReturn[i].Message = fmt.Sprintf("can't delete device type %s. %v devices still depend on it.", id, len(devices))
+		}
+	}
+
+	c.bulkDelete(DEVICE_TYPES, ids, toReturn)
+	return toReturn
 }
@@ -1,6 +1,7 @@
 package couch
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/byuoitav/common/structs"
@@ -10,7 +11,7 @@ import (
 func (c *CouchDB) GetDMPSList() (structs.DMPSList, error) {
 	var toReturn structs.DMPSList
 
-	err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", DMPSLIST, "dmps_list"), "", nil, &toReturn)
+	err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", DMPSLIST, DMPS_LIST_ID), "", nil, &toReturn)
 
 	if err != nil {
 		err = fmt.Errorf("failed to get DMPSList: %s", err)
@@ -31,3 +32,89 @@ func (c *CouchDB) GetOtherCrestronList() (structs.DMPSList, error) {
 
 	return toReturn, err
 }
+
+// CreateDMPS adds a DMPS to the list of DMPSes to pull events from, and returns the new list.
+func (c *CouchDB) CreateDMPS(dmps structs.DMPS) (structs.DMPSList, error) {
+	return c.updateDMPSList(func(list *structs.DMPSList) error {
+		for _, d := range list.List {
+			if d.Hostname == dmps.Hostname {
+				return fmt.Errorf("%s is already in the list", dmps.Hostname)
+			}
+		}
+
+		list.List = append(list.List, dmps)
+		return nil
+	})
+}
+
+// UpdateDMPS replaces the DMPS hostname in the list of DMPSes to pull events from, and returns the new list.
+func (c *CouchDB) UpdateDMPS(hostname string, dmps structs.DMPS) (structs.DMPSList, error) {
+	return c.updateDMPSList(func(list *structs.DMPSList) error {
+		for i := range list.List {
+			if list.List[i].Hostname == hostname {
+				list.List[i] = dmps
+				return nil
+			}
+		}
+
+		return &NotFound{fmt.Sprintf("%s isn't in the list", hostname)}
+	})
+}
+
+// DeleteDMPS removes the DMPS hostname from the list of DMPSes to pull events from, and returns the new list.
+func (c *CouchDB) DeleteDMPS(hostname string) (structs.DMPSList, error) {
+	return c.updateDMPSList(func(list *structs.DMPSList) error {
+		for i := range list.List {
+			if list.List[i].Hostname == hostname {
+				list.List = append(list.List[:i], list.List[i+1:]...)
+				return nil
+			}
+		}
+
+		return &NotFound{fmt.Sprintf("%s isn't in the list", hostname)}
+	})
+}
+
+// updateDMPSList applies change to the list of DMPSes (creating it if it doesn't exist yet), validates it, and writes it back.
+func (c *CouchDB) updateDMPSList(change func(list *structs.DMPSList) error) (structs.DMPSList, error) {
+	var list structs.DMPSList
+
+	err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", DMPSLIST, DMPS_LIST_ID), "", nil, &list)
+	switch err.(type) {
+	case nil:
+	case *NotFound:
+		list = structs.DMPSList{ID: DMPS_LIST_ID}
+	default:
+		return list, fmt.Errorf("unable to get DMPSList: %s", err)
+	}
+
+	if err := change(&list); err != nil {
+		return list, fmt.Errorf("unable to update DMPSList: %s", err)
+	}
+
+	if err := list.Validate(); err != nil {
+		return list, err
+	}
+
+	if err := c.putListDoc(DMPSLIST, DMPS_LIST_ID, list.Rev, list); err != nil {
+		return list, fmt.Errorf("unable to update DMPSList: %s", err)
+	}
+
+	return c.GetDMPSList()
+}
+
+// putListDoc writes doc, which is a single document holding a list; it is created if rev is empty, and otherwise only updated if it is still at revision rev.
+func (c *CouchDB) putListDoc(database, id, rev string, doc interface{}) error {
+	if len(rev) > 0 {
+		_, err := c.updateDoc(database, id, rev, doc)
+		return err
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("unable to marshal %s: %s", id, err)
+	}
+
+	var resp CouchUpsertResponse
+	return c.MakeRequest("PUT", fmt.Sprintf("%v/%v", database, id), "application/json", b, &resp)
+}
@@ -0,0 +1,53 @@
+package couch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeRoutes maps "METHOD /path" to what a fake couch does for that request. A route can also be just a method, which handles every other request with that method.
+type fakeRoutes map[string]http.HandlerFunc
+
+/*
+newFakeCouch starts a server that handles routes like couch would, and fails the test on any
+request that isn't routed. It returns the server, which must be closed, and a CouchDB that uses
+it without waiting for replication.
+*/
+func newFakeCouch(t *testing.T, routes fakeRoutes) (*CouchDB, *httptest.Server) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		handler, ok := routes[r.Method+" "+r.URL.Path]
+		if !ok {
+			handler, ok = routes[r.Method]
+		}
+
+		if !ok {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		handler(w, r)
+	}))
+
+	c := NewDB(srv.URL, "", "")
+	c.IgnoreReadyChecks = true
+
+	return c, srv
+}
+
+// respond returns a route that always responds with status and body.
+func respond(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+}
+
+// routes that respond like couch does when a document is missing, or has changed since the revision given.
+var (
+	respondNotFound = respond(http.StatusNotFound, `{"error": "not_found", "reason": "missing"}`)
+	respondConflict = respond(http.StatusConflict, `{"error": "conflict", "reason": "Document update conflict."}`)
+)
@@ -0,0 +1,109 @@
+package couch
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Index is a Mango (json) index on one or more fields of the documents in a database.
+type Index struct {
+	Database  string
+	DesignDoc string
+	Name      string
+	Fields    []string
+}
+
+type indexRequest struct {
+	Index struct {
+		Fields []string `json:"fields"`
+	} `json:"index"`
+	DesignDoc string `json:"ddoc,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Type      string `json:"type"`
+}
+
+type indexResponse struct {
+	Result string `json:"result"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+}
+
+// Indexes used by the built-in queries.
+var (
+	DeviceTypeIndex = Index{
+		Database:  DEVICES,
+		DesignDoc: "device-type",
+		Name:      "device-type",
+		Fields:    []string{"type._id"},
+	}
+
+	RoomDesignationIndex = Index{
+		Database:  ROOMS,
+		DesignDoc: "room-designation",
+		Name:      "room-designation",
+		Fields:    []string{"designation"},
+	}
+
+	AuditDocumentIndex = Index{
+		Database:  AUDIT_LOG,
+		DesignDoc: "audit-document",
+		Name:      "audit-document",
+		Fields:    []string{"document-id"},
+	}
+
+	AuditRoomIndex = Index{
+		Database:  AUDIT_LOG,
+		DesignDoc: "audit-room",
+		Name:      "audit-room",
+		Fields:    []string{"room-id"},
+	}
+)
+
+// BuiltinIndexes are each of the indexes used by the built-in queries. They can be created with CreateIndexes.
+var BuiltinIndexes = []Index{
+	DeviceTypeIndex,
+	RoomDesignationIndex,
+	AuditDocumentIndex,
+	AuditRoomIndex,
+}
+
+// CreateIndex creates idx. Creating an index that already exists succeeds without changing it.
+func (c *CouchDB) CreateIndex(idx Index) error {
+	if len(idx.Database) == 0 || len(idx.Fields) == 0 {
+		return fmt.Errorf("unable to create index %s: a database and at least one field are required", idx.Name)
+	}
+
+	var req indexRequest
+	req.Index.Fields = idx.Fields
+	req.DesignDoc = idx.DesignDoc
+	req.Name = idx.Name
+	req.Type = "json"
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("unable to marshal index %s: %s", idx.Name, err)
+	}
+
+	var resp indexResponse
+	err = c.MakeRequest("POST", fmt.Sprintf("%v/_index", idx.Database), "application/json", b, &resp)
+	if err != nil {
+		return fmt.Errorf("unable to create index %s on %s: %s", idx.Name, idx.Database, err)
+	}
+
+	return nil
+}
+
+// CreateIndexes creates each of indexes, or each of the BuiltinIndexes if none are given.
+func (c *CouchDB) CreateIndexes(indexes ...Index) error {
+	if len(indexes) == 0 {
+		indexes = BuiltinIndexes
+	}
+
+	for _, idx := range indexes {
+		if err := c.CreateIndex(idx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
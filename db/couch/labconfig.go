@@ -18,3 +18,74 @@ func (c *CouchDB) GetLabConfig(roomID string) (structs.LabConfig, error) {
 
 	return config, nil
 }
+
+// GetAllLabConfigs returns the lab attendance configuration of every room that has one.
+func (c *CouchDB) GetAllLabConfigs() ([]structs.LabConfig, error) {
+	var toReturn []structs.LabConfig
+
+	err := c.Find(LAB_CONFIGS, NewQuery().Where("_id", Gt("")), &toReturn)
+	if err != nil {
+		return toReturn, fmt.Errorf("failed to get all lab configs: %s", err)
+	}
+
+	return toReturn, nil
+}
+
+// CreateLabConfig adds the lab attendance configuration for a room.
+func (c *CouchDB) CreateLabConfig(config structs.LabConfig) (structs.LabConfig, error) {
+	var toReturn structs.LabConfig
+
+	if err := config.Validate(); err != nil {
+		return toReturn, err
+	}
+
+	err := c.createDoc(LAB_CONFIGS, config.ID, config)
+	if err != nil {
+		if _, ok := err.(*Conflict); ok {
+			return toReturn, fmt.Errorf("unable to create lab config, because it already exists. error: %s", err)
+		}
+
+		return toReturn, fmt.Errorf("failed to create lab config for %s: %s", config.ID, err)
+	}
+
+	return c.GetLabConfig(config.ID)
+}
+
+// UpdateLabConfig updates the lab attendance configuration for a room. If config.Rev is set, it is only updated if it hasn't changed since that revision.
+func (c *CouchDB) UpdateLabConfig(roomID string, config structs.LabConfig) (structs.LabConfig, error) {
+	var toReturn structs.LabConfig
+
+	if err := config.Validate(); err != nil {
+		return toReturn, err
+	}
+
+	if config.ID != roomID {
+		return toReturn, fmt.Errorf("unable to update lab config for %s: the room of a lab config can't be changed", roomID)
+	}
+
+	_, err := c.updateDoc(LAB_CONFIGS, roomID, config.Rev, config)
+	if err != nil {
+		if _, ok := err.(*Conflict); ok {
+			return toReturn, err
+		}
+
+		return toReturn, fmt.Errorf("failed to update lab config for %s: %s", roomID, err)
+	}
+
+	return c.GetLabConfig(roomID)
+}
+
+// DeleteLabConfig deletes the lab attendance configuration for a room.
+func (c *CouchDB) DeleteLabConfig(roomID string) error {
+	config, err := c.GetLabConfig(roomID)
+	if err != nil {
+		return fmt.Errorf("failed to get lab config %s to delete: %s", roomID, err)
+	}
+
+	err = c.MakeRequest("DELETE", fmt.Sprintf("%v/%v?rev=%v", LAB_CONFIGS, roomID, config.Rev), "", nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete lab config for %s: %s", roomID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package couch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/byuoitav/common/structs"
+)
+
+// SCHEMA_VERSION is the id of the local (unreplicated) document in each database that holds its schema version.
+const SCHEMA_VERSION = "_local/schema-version"
+
+// GetSchemaVersion returns the schema version of database. A database that has never been migrated is at version 0.
+func (c *CouchDB) GetSchemaVersion(database string) (structs.SchemaVersion, error) {
+	toReturn := structs.SchemaVersion{Database: database}
+
+	err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", database, SCHEMA_VERSION), "", nil, &toReturn)
+	if _, ok := err.(*NotFound); ok {
+		return structs.SchemaVersion{Database: database}, nil
+	}
+
+	if err != nil {
+		return toReturn, fmt.Errorf("unable to get schema version of %s: %s", database, err)
+	}
+
+	return toReturn, nil
+}
+
+// PutSchemaVersion writes the schema version of database. version.Rev must be the rev returned by GetSchemaVersion.
+func (c *CouchDB) PutSchemaVersion(database string, version structs.SchemaVersion) (structs.SchemaVersion, error) {
+	version.Database = database
+
+	b, err := json.Marshal(version)
+	if err != nil {
+		return version, fmt.Errorf("unable to marshal schema version: %s", err)
+	}
+
+	var resp CouchUpsertResponse
+	err = c.MakeRequest("PUT", fmt.Sprintf("%v/%v", database, SCHEMA_VERSION), "application/json", b, &resp)
+	if err != nil {
+		if _, ok := err.(*Conflict); ok {
+			return version, NewConflict(database+"/"+SCHEMA_VERSION, version.Rev, "")
+		}
+
+		return version, fmt.Errorf("unable to put schema version of %s: %s", database, err)
+	}
+
+	version.Rev = resp.Rev
+	return version, nil
+}
+
+// GetRawDocuments returns every document in database (except design documents), decoded as plain JSON.
+func (c *CouchDB) GetRawDocuments(database string) ([]map[string]interface{}, error) {
+	var docs []map[string]interface{}
+
+	err := c.Find(database, NewQuery().Where("_id", Gt("\x00")), &docs)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get documents in %s: %s", database, err)
+	}
+
+	var toReturn []map[string]interface{}
+	for _, doc := range docs {
+		if id, _ := doc["_id"].(string); strings.HasPrefix(id, "_design/") {
+			continue
+		}
+
+		toReturn = append(toReturn, doc)
+	}
+
+	return toReturn, nil
+}
+
+// PutRawDocuments writes each of docs back to database. Each document must have the _rev it was read with.
+func (c *CouchDB) PutRawDocuments(database string, docs []map[string]interface{}) []structs.BulkUpdateResponse {
+	var ids []string
+	var writes []bulkWrite
+
+	for i, doc := range docs {
+		id, _ := doc["_id"].(string)
+		ids = append(ids, id)
+		writes = append(writes, bulkWrite{index: i, doc: doc})
+	}
+
+	responses := newBulkResponses(ids)
+	c.bulkDocs(database, writes, responses)
+
+	return responses
+}
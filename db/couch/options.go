@@ -17,19 +17,14 @@ func (c *CouchDB) GetAllTemplates() ([]structs.Template, error) {
 	query.Selector.ID.GT = "\x00"
 	query.Limit = 100
 
-	b, err := json.Marshal(query)
-	if err != nil {
-		return toReturn, fmt.Errorf("failed to marshal query to get all templates: %s", err)
-	}
+	var docs []template
 
-	var resp templateQueryResponse
-
-	err = c.MakeRequest("POST", fmt.Sprintf("%v/_find", OPTIONS), "application/json", b, &resp)
+	err := c.findAll(OPTIONS, query, &docs)
 	if err != nil {
 		return toReturn, fmt.Errorf("failed to get all templates: %s", err)
 	}
 
-	for _, doc := range resp.Docs {
+	for _, doc := range docs {
 		if len(doc.Template.UIConfig.Api) > 0 {
 			toReturn = append(toReturn, *doc.Template)
 		}
@@ -42,7 +37,15 @@ func (c *CouchDB) GetAllTemplates() ([]structs.Template, error) {
 func (c *CouchDB) GetTemplate(id string) (structs.UIConfig, error) {
 	log.L.Info(id)
 	template, err := c.getTemplate(id)
-	return *template.UIConfig, err
+	if err != nil {
+		return structs.UIConfig{}, err
+	}
+
+	if template.UIConfig == nil {
+		return structs.UIConfig{}, fmt.Errorf("failed to get template %s: it isn't a template", id)
+	}
+
+	return *template.UIConfig, nil
 }
 
 func (c *CouchDB) getTemplate(id string) (uiconfig, error) {
@@ -255,6 +258,62 @@ func (c *CouchDB) UpdateRoomDesignations(desigs []string) ([]string, error) {
 	return toReturn, nil
 }
 
+// ROOM ATTRIBUTE SCHEMAS
+
+// GetRoomAttributeSchemas returns the attribute schema of the rooms with each designation.
+func (c *CouchDB) GetRoomAttributeSchemas() (map[string][]structs.AttributeSchema, error) {
+	schemas, err := c.currentRoomSchemas()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room attribute schemas : %s", err)
+	}
+
+	return schemas.Schemas, nil
+}
+
+// UpdateRoomAttributeSchemas replaces the attribute schema of the rooms with each designation, creating them if they don't exist yet.
+func (c *CouchDB) UpdateRoomAttributeSchemas(schemas map[string][]structs.AttributeSchema) (map[string][]structs.AttributeSchema, error) {
+	for designation, schema := range schemas {
+		if err := structs.ValidateAttributeSchemas(schema, designation).Err("room attribute schemas"); err != nil {
+			return nil, err
+		}
+	}
+
+	current, err := c.currentRoomSchemas()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get room attribute schemas to update: %s", err)
+	}
+
+	current.Schemas = schemas
+
+	if err := c.putListDoc(OPTIONS, ROOM_SCHEMAS, current.Rev, current); err != nil {
+		return nil, fmt.Errorf("failed to update room attribute schemas : %s", err)
+	}
+
+	return c.GetRoomAttributeSchemas()
+}
+
+// currentRoomSchemas returns the room attribute schemas, which are empty if they don't exist yet.
+func (c *CouchDB) currentRoomSchemas() (roomSchemas, error) {
+	var toReturn roomSchemas
+
+	err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", OPTIONS, ROOM_SCHEMAS), "", nil, &toReturn)
+	if _, ok := err.(*NotFound); ok {
+		return roomSchemas{}, nil
+	}
+
+	return toReturn, err
+}
+
+// roomAttributeSchema returns the attribute schema of the rooms with designation.
+func (c *CouchDB) roomAttributeSchema(designation string) ([]structs.AttributeSchema, error) {
+	schemas, err := c.currentRoomSchemas()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get room attribute schemas: %s", err)
+	}
+
+	return schemas.Schemas[designation], nil
+}
+
 // CLOSURE CODES
 
 // GetClosureCodes returns a list of the possible closure codes for ServiceNow.
@@ -359,3 +418,44 @@ func (c *CouchDB) getMenuTree() (menu, error) {
 
 	return toReturn, err
 }
+
+// UpdateMenuTree replaces the order of the attribute groups in the menu tree, creating it if it doesn't exist yet. Each group must exist, and can only be in it once.
+func (c *CouchDB) UpdateMenuTree(order []string) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, id := range order {
+		if seen[id] {
+			return nil, fmt.Errorf("invalid menu tree: %s is in it more than once", id)
+		}
+
+		seen[id] = true
+
+		if _, err := c.getAttributeGroup(id); err != nil {
+			return nil, fmt.Errorf("invalid menu tree: %s", err)
+		}
+	}
+
+	current, err := c.currentMenuTree()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get menu tree to update: %s", err)
+	}
+
+	current.Order = order
+
+	if err := c.putListDoc(OPTIONS, MENUTREE, current.Rev, current); err != nil {
+		return nil, fmt.Errorf("failed to update the menu tree : %s", err)
+	}
+
+	return c.GetMenuTree()
+}
+
+// currentMenuTree returns the menu tree, which is empty if it doesn't exist yet.
+func (c *CouchDB) currentMenuTree() (menu, error) {
+	var toReturn menu
+
+	err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", OPTIONS, MENUTREE), "", nil, &toReturn)
+	if _, ok := err.(*NotFound); ok {
+		return menu{}, nil
+	}
+
+	return toReturn, err
+}
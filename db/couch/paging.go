@@ -0,0 +1,216 @@
+package couch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/byuoitav/common/log"
+	sd "github.com/byuoitav/common/state/statedefinition"
+	"github.com/byuoitav/common/structs"
+)
+
+// DefaultPageSize is the page size used by the List functions when pageSize isn't positive.
+const DefaultPageSize = 100
+
+type rawQueryResponse struct {
+	Docs     []json.RawMessage `json:"docs"`
+	Bookmark string            `json:"bookmark"`
+	Warning  string            `json:"warning"`
+}
+
+// pageQuery returns a query for every document in a database, one page at a time.
+func pageQuery(pageSize int, bookmark string) IDPrefixQuery {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	var query IDPrefixQuery
+	query.Selector.ID.GT = "\x00"
+	query.Limit = pageSize
+	query.Bookmark = bookmark
+
+	return query
+}
+
+/*
+Find runs q against database, following the bookmark from each page of results to the next
+until every matching document has been found; q's Limit is used as the page size. toFill
+must be a pointer to a slice of the documents' type (or of a struct with the fields selected).
+*/
+func (c *CouchDB) Find(database string, q *Query, toFill interface{}) error {
+	page := *q
+	if page.limit <= 0 {
+		page.limit = DefaultPageSize
+	}
+
+	var docs []json.RawMessage
+	for {
+		found, bookmark, err := c.findRawPage(database, &page)
+		if err != nil {
+			return err
+		}
+
+		docs = append(docs, found...)
+
+		if len(bookmark) == 0 || bookmark == page.bookmark {
+			break
+		}
+
+		page.bookmark = bookmark
+	}
+
+	return unmarshalDocs(docs, toFill)
+}
+
+// FindPage runs a single page of q against database, filling toFill (a pointer to a slice) with the documents found. It returns the bookmark for the next page, which is empty if there are no more pages.
+func (c *CouchDB) FindPage(database string, q *Query, toFill interface{}) (string, error) {
+	page := *q
+	if page.limit <= 0 {
+		page.limit = DefaultPageSize
+	}
+
+	docs, bookmark, err := c.findRawPage(database, &page)
+	if err != nil {
+		return "", err
+	}
+
+	return bookmark, unmarshalDocs(docs, toFill)
+}
+
+// findAll is Find for an IDPrefixQuery.
+func (c *CouchDB) findAll(database string, query IDPrefixQuery, toFill interface{}) error {
+	return c.Find(database, query.query(), toFill)
+}
+
+// findPage is FindPage for an IDPrefixQuery.
+func (c *CouchDB) findPage(database string, query IDPrefixQuery, toFill interface{}) (string, error) {
+	return c.FindPage(database, query.query(), toFill)
+}
+
+func (c *CouchDB) findRawPage(database string, q *Query) ([]json.RawMessage, string, error) {
+	b, err := json.Marshal(q)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal query: %s", err)
+	}
+
+	var resp rawQueryResponse
+	err = c.MakeRequest("POST", fmt.Sprintf("%s/_find", database), "application/json", b, &resp)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(resp.Warning) > 0 {
+		log.L.Debugf("Query against %s returned a warning: %s", database, resp.Warning)
+	}
+
+	// couch always returns a bookmark, so a short page is how we know there aren't any more
+	if len(resp.Docs) == 0 || len(resp.Docs) < q.limit {
+		return resp.Docs, "", nil
+	}
+
+	return resp.Docs, resp.Bookmark, nil
+}
+
+func unmarshalDocs(docs []json.RawMessage, toFill interface{}) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, doc := range docs {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		buf.Write(doc)
+	}
+	buf.WriteByte(']')
+
+	if err := json.Unmarshal(buf.Bytes(), toFill); err != nil {
+		return fmt.Errorf("failed to unmarshal documents: %s", err)
+	}
+
+	return nil
+}
+
+// ListBuildings returns a page of up to pageSize buildings starting at bookmark (empty for the first page), and the bookmark for the next page. The returned bookmark is empty once there are no more pages.
+func (c *CouchDB) ListBuildings(pageSize int, bookmark string) ([]structs.Building, string, error) {
+	var toReturn []structs.Building
+	var docs []building
+
+	next, err := c.findPage(BUILDINGS, pageQuery(pageSize, bookmark), &docs)
+	if err != nil {
+		return toReturn, "", fmt.Errorf("failed to list buildings: %s", err)
+	}
+
+	for _, doc := range docs {
+		toReturn = append(toReturn, *doc.Building)
+	}
+
+	return toReturn, next, nil
+}
+
+// ListRooms returns a page of up to pageSize rooms starting at bookmark (empty for the first page), and the bookmark for the next page. The returned bookmark is empty once there are no more pages.
+func (c *CouchDB) ListRooms(pageSize int, bookmark string) ([]structs.Room, string, error) {
+	var toReturn []structs.Room
+	var docs []room
+
+	next, err := c.findPage(ROOMS, pageQuery(pageSize, bookmark), &docs)
+	if err != nil {
+		return toReturn, "", fmt.Errorf("failed to list rooms: %s", err)
+	}
+
+	for _, doc := range docs {
+		toReturn = append(toReturn, *doc.Room)
+	}
+
+	return toReturn, next, nil
+}
+
+// ListDevices returns a page of up to pageSize devices starting at bookmark (empty for the first page), and the bookmark for the next page. The returned bookmark is empty once there are no more pages. As with GetAllDevices, only the ID of each device's type is included.
+func (c *CouchDB) ListDevices(pageSize int, bookmark string) ([]structs.Device, string, error) {
+	var toReturn []structs.Device
+	var docs []device
+
+	next, err := c.findPage(DEVICES, pageQuery(pageSize, bookmark), &docs)
+	if err != nil {
+		return toReturn, "", fmt.Errorf("failed to list devices: %s", err)
+	}
+
+	for _, doc := range docs {
+		toReturn = append(toReturn, *doc.Device)
+	}
+
+	return toReturn, next, nil
+}
+
+// ListDeviceStates returns a page of up to pageSize device states starting at bookmark (empty for the first page), and the bookmark for the next page. The returned bookmark is empty once there are no more pages.
+func (c *CouchDB) ListDeviceStates(pageSize int, bookmark string) ([]sd.StaticDevice, string, error) {
+	var toReturn []sd.StaticDevice
+
+	next, err := c.findPage(DEVICE_STATES, pageQuery(pageSize, bookmark), &toReturn)
+	if err != nil {
+		return toReturn, "", fmt.Errorf("failed to list device states: %s", err)
+	}
+
+	return toReturn, next, nil
+}
+
+// ListUIConfigs returns a page of up to pageSize ui configs starting at bookmark (empty for the first page), and the bookmark for the next page. The returned bookmark is empty once there are no more pages.
+func (c *CouchDB) ListUIConfigs(pageSize int, bookmark string) ([]structs.UIConfig, string, error) {
+	var toReturn []structs.UIConfig
+	var docs []uiconfig
+
+	next, err := c.findPage(UI_CONFIGS, pageQuery(pageSize, bookmark), &docs)
+	if err != nil {
+		return toReturn, "", fmt.Errorf("failed to list ui configs: %s", err)
+	}
+
+	for _, doc := range docs {
+		toReturn = append(toReturn, *doc.UIConfig)
+	}
+
+	return toReturn, next, nil
+}
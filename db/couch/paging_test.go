@@ -0,0 +1,102 @@
+package couch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/byuoitav/common/structs"
+)
+
+// newPagingServer returns a server whose buildings database has n buildings, which it returns a page at a time.
+func newPagingServer(t *testing.T, n int) (*httptest.Server, *int) {
+	requests := new(int)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+
+		var query IDPrefixQuery
+		if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+			t.Fatalf("failed to decode query: %s", err)
+		}
+
+		start := 0
+		if len(query.Bookmark) > 0 {
+			start, _ = strconv.Atoi(query.Bookmark)
+		}
+
+		end := start + query.Limit
+		if end > n {
+			end = n
+		}
+
+		var resp rawQueryResponse
+		for i := start; i < end; i++ {
+			b, _ := json.Marshal(structs.Building{ID: fmt.Sprintf("B%03d", i), Name: "building"})
+			resp.Docs = append(resp.Docs, b)
+		}
+
+		resp.Bookmark = strconv.Itoa(end)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+
+	return srv, requests
+}
+
+func TestFindAll(t *testing.T) {
+	srv, requests := newPagingServer(t, 2500)
+	defer srv.Close()
+
+	c := NewDB(srv.URL, "", "")
+	c.IgnoreReadyChecks = true
+
+	buildings, err := c.GetAllBuildings()
+	if err != nil {
+		t.Fatalf("failed to get all buildings: %s", err)
+	}
+
+	if len(buildings) != 2500 {
+		t.Fatalf("expected 2500 buildings, got %v", len(buildings))
+	}
+
+	if buildings[2499].ID != "B2499" || *requests != 3 {
+		t.Fatalf("expected 3 pages ending with B2499, got %v pages ending with %s", *requests, buildings[2499].ID)
+	}
+}
+
+func TestListBuildings(t *testing.T) {
+	srv, _ := newPagingServer(t, 25)
+	defer srv.Close()
+
+	c := NewDB(srv.URL, "", "")
+	c.IgnoreReadyChecks = true
+
+	var all []structs.Building
+	var pages int
+
+	bookmark := ""
+	for {
+		buildings, next, err := c.ListBuildings(10, bookmark)
+		if err != nil {
+			t.Fatalf("failed to list buildings: %s", err)
+		}
+
+		all = append(all, buildings...)
+		pages++
+
+		if len(next) == 0 {
+			break
+		}
+
+		bookmark = next
+	}
+
+	if len(all) != 25 || pages != 3 {
+		t.Fatalf("expected 25 buildings in 3 pages, got %v in %v pages", len(all), pages)
+	}
+}
@@ -10,10 +10,80 @@ import (
 func (c *CouchDB) GetAuth() (structs.Auth, error) {
 	var toReturn structs.Auth
 
-	err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", "auth", "auth"), "", nil, &toReturn)
+	err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", AUTH, AUTH), "", nil, &toReturn)
 	if err != nil {
 		err = errors.New(fmt.Sprintf("failed to get permissions: %s", err))
 	}
 
 	return toReturn, err
 }
+
+// CreatePermission adds a permission, and returns the updated permissions.
+func (c *CouchDB) CreatePermission(permission structs.Permission) (structs.Auth, error) {
+	return c.updateAuth(func(auth *structs.Auth) error {
+		for _, p := range auth.Permissions {
+			if p.Group == permission.Group {
+				return fmt.Errorf("there is already a permission for %s", permission.Group)
+			}
+		}
+
+		auth.Permissions = append(auth.Permissions, permission)
+		return nil
+	})
+}
+
+// UpdatePermission replaces the permission for group, and returns the updated permissions.
+func (c *CouchDB) UpdatePermission(group string, permission structs.Permission) (structs.Auth, error) {
+	return c.updateAuth(func(auth *structs.Auth) error {
+		for i := range auth.Permissions {
+			if auth.Permissions[i].Group == group {
+				auth.Permissions[i] = permission
+				return nil
+			}
+		}
+
+		return &NotFound{fmt.Sprintf("there isn't a permission for %s", group)}
+	})
+}
+
+// DeletePermission removes the permission for group, and returns the updated permissions.
+func (c *CouchDB) DeletePermission(group string) (structs.Auth, error) {
+	return c.updateAuth(func(auth *structs.Auth) error {
+		for i := range auth.Permissions {
+			if auth.Permissions[i].Group == group {
+				auth.Permissions = append(auth.Permissions[:i], auth.Permissions[i+1:]...)
+				return nil
+			}
+		}
+
+		return &NotFound{fmt.Sprintf("there isn't a permission for %s", group)}
+	})
+}
+
+// updateAuth applies change to the permissions document (creating it if it doesn't exist yet), validates it, and writes it back.
+func (c *CouchDB) updateAuth(change func(auth *structs.Auth) error) (structs.Auth, error) {
+	var auth structs.Auth
+
+	err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", AUTH, AUTH), "", nil, &auth)
+	switch err.(type) {
+	case nil:
+	case *NotFound:
+		auth = structs.Auth{ID: AUTH}
+	default:
+		return auth, fmt.Errorf("unable to get permissions: %s", err)
+	}
+
+	if err := change(&auth); err != nil {
+		return auth, fmt.Errorf("unable to update permissions: %s", err)
+	}
+
+	if err := auth.Validate(); err != nil {
+		return auth, err
+	}
+
+	if err := c.putListDoc(AUTH, AUTH, auth.Rev, auth); err != nil {
+		return auth, fmt.Errorf("unable to update permissions: %s", err)
+	}
+
+	return c.GetAuth()
+}
@@ -0,0 +1,205 @@
+package couch
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// Selector is a Mango selector, mapping fields (or operators) to conditions.
+type Selector map[string]interface{}
+
+// Cond is a condition on a single field of a document, built with Eq, Gt, In, etc.
+type Cond map[string]interface{}
+
+// Eq matches fields equal to v.
+func Eq(v interface{}) Cond { return Cond{"$eq": v} }
+
+// Ne matches fields not equal to v.
+func Ne(v interface{}) Cond { return Cond{"$ne": v} }
+
+// Gt matches fields greater than v.
+func Gt(v interface{}) Cond { return Cond{"$gt": v} }
+
+// Gte matches fields greater than or equal to v.
+func Gte(v interface{}) Cond { return Cond{"$gte": v} }
+
+// Lt matches fields less than v.
+func Lt(v interface{}) Cond { return Cond{"$lt": v} }
+
+// Lte matches fields less than or equal to v.
+func Lte(v interface{}) Cond { return Cond{"$lte": v} }
+
+// In matches fields equal to one of values.
+func In(values ...interface{}) Cond { return Cond{"$in": values} }
+
+// All matches array fields that contain all of values.
+func All(values ...interface{}) Cond { return Cond{"$all": values} }
+
+// Exists matches documents that have (or don't have) the field.
+func Exists(exists bool) Cond { return Cond{"$exists": exists} }
+
+// Regex matches string fields against pattern, which uses Erlang (PCRE) syntax.
+func Regex(pattern string) Cond { return Cond{"$regex": pattern} }
+
+// EqualFold matches string fields equal to s, ignoring case. An index can't be used for it.
+func EqualFold(s string) Cond { return Regex("(?i)^" + regexp.QuoteMeta(s) + "$") }
+
+// HasPrefix matches string fields that start with prefix.
+func HasPrefix(prefix string) Cond { return Regex("^" + regexp.QuoteMeta(prefix)) }
+
+// ElemMatch matches array fields that have at least one element matching sel.
+func ElemMatch(sel Selector) Cond { return Cond{"$elemMatch": sel} }
+
+// Field escapes the dots in each part of a field name, and joins them into a path. For example, Field("attributes", "a.b") is "attributes.a\.b".
+func Field(parts ...string) string {
+	escaped := make([]string, len(parts))
+	for i := range parts {
+		escaped[i] = strings.Replace(parts[i], ".", `\.`, -1)
+	}
+
+	return strings.Join(escaped, ".")
+}
+
+/*
+Query is a Mango query, built up by chaining its methods:
+
+	query := couch.NewQuery().
+		Where("type._id", couch.Eq("SonyXBR")).
+		Where("roles", couch.ElemMatch(couch.Selector{"_id": couch.Eq("VideoOut")})).
+		Select("_id", "name").
+		Sort("_id", false)
+
+Each call to Where adds a clause that documents must match. A query with no clauses
+matches every document.
+*/
+type Query struct {
+	clauses  []Selector
+	fields   []string
+	sort     []map[string]string
+	limit    int
+	skip     int
+	useIndex []string
+	bookmark string
+}
+
+type mangoQuery struct {
+	Selector Selector            `json:"selector"`
+	Fields   []string            `json:"fields,omitempty"`
+	Sort     []map[string]string `json:"sort,omitempty"`
+	Limit    int                 `json:"limit,omitempty"`
+	Skip     int                 `json:"skip,omitempty"`
+	UseIndex []string            `json:"use_index,omitempty"`
+	Bookmark string              `json:"bookmark,omitempty"`
+}
+
+// NewQuery returns a query that matches every document.
+func NewQuery() *Query {
+	return &Query{}
+}
+
+// Where adds a clause requiring field to match cond (a Cond, or a value to match exactly).
+func (q *Query) Where(field string, cond interface{}) *Query {
+	q.clauses = append(q.clauses, Selector{field: cond})
+	return q
+}
+
+// Or adds a clause requiring documents to match at least one of sels.
+func (q *Query) Or(sels ...Selector) *Query {
+	q.clauses = append(q.clauses, Selector{"$or": sels})
+	return q
+}
+
+// Select limits the fields returned in each document.
+func (q *Query) Select(fields ...string) *Query {
+	q.fields = append(q.fields, fields...)
+	return q
+}
+
+// Sort orders the results by field. Couch requires an index on every field sorted on.
+func (q *Query) Sort(field string, descending bool) *Query {
+	dir := "asc"
+	if descending {
+		dir = "desc"
+	}
+
+	q.sort = append(q.sort, map[string]string{field: dir})
+	return q
+}
+
+// Limit sets how many documents are returned by each request; see CouchDB.Find and CouchDB.FindPage.
+func (q *Query) Limit(limit int) *Query {
+	q.limit = limit
+	return q
+}
+
+// Skip skips the first n results.
+func (q *Query) Skip(n int) *Query {
+	q.skip = n
+	return q
+}
+
+// UseIndex hints which index couch should use, by its design document and (optionally) name.
+func (q *Query) UseIndex(designDoc, name string) *Query {
+	q.useIndex = []string{designDoc}
+	if len(name) > 0 {
+		q.useIndex = append(q.useIndex, name)
+	}
+
+	return q
+}
+
+// Bookmark starts the query at bookmark, which was returned by a previous page of the same query.
+func (q *Query) Bookmark(bookmark string) *Query {
+	q.bookmark = bookmark
+	return q
+}
+
+// Selector returns the selector documents must match.
+func (q *Query) Selector() Selector {
+	switch len(q.clauses) {
+	case 0:
+		return Selector{"_id": Gt(nil)}
+	case 1:
+		return q.clauses[0]
+	default:
+		return Selector{"$and": q.clauses}
+	}
+}
+
+// MarshalJSON returns the query as the body of a request to _find.
+func (q *Query) MarshalJSON() ([]byte, error) {
+	return json.Marshal(mangoQuery{
+		Selector: q.Selector(),
+		Fields:   q.fields,
+		Sort:     q.sort,
+		Limit:    q.limit,
+		Skip:     q.skip,
+		UseIndex: q.useIndex,
+		Bookmark: q.bookmark,
+	})
+}
+
+// query converts an IDPrefixQuery into a Query.
+func (q IDPrefixQuery) query() *Query {
+	query := NewQuery().Limit(q.Limit).Bookmark(q.Bookmark)
+
+	id := Cond{}
+	if len(q.Selector.ID.GT) > 0 {
+		id["$gt"] = q.Selector.ID.GT
+	}
+
+	if len(q.Selector.ID.LT) > 0 {
+		id["$lt"] = q.Selector.ID.LT
+	}
+
+	if len(q.Selector.ID.Regex) > 0 {
+		id["$regex"] = q.Selector.ID.Regex
+	}
+
+	if len(id) > 0 {
+		query.Where("_id", id)
+	}
+
+	return query
+}
@@ -0,0 +1,108 @@
+package couch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/byuoitav/common/structs"
+)
+
+func TestQuery(t *testing.T) {
+	query := NewQuery().
+		Where("type._id", Eq("SonyXBR")).
+		Where("roles", ElemMatch(Selector{"_id": EqualFold("VideoOut")})).
+		Where(Field("attributes", "a.b"), Gt(3)).
+		Select("_id").
+		Sort("type._id", true).
+		UseIndex("device-type", "").
+		Limit(10)
+
+	b, err := json.Marshal(query)
+	if err != nil {
+		t.Fatalf("failed to marshal query: %s", err)
+	}
+
+	var got map[string]interface{}
+	var expected map[string]interface{}
+
+	json.Unmarshal(b, &got)
+	json.Unmarshal([]byte(`{
+		"selector": {"$and": [
+			{"type._id": {"$eq": "SonyXBR"}},
+			{"roles": {"$elemMatch": {"_id": {"$regex": "(?i)^VideoOut$"}}}},
+			{"attributes.a\\.b": {"$gt": 3}}
+		]},
+		"fields": ["_id"],
+		"sort": [{"type._id": "desc"}],
+		"use_index": ["device-type"],
+		"limit": 10
+	}`), &expected)
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("query didn't marshal correctly\ngot: %s", b)
+	}
+
+	// an empty query matches everything
+	b, _ = json.Marshal(NewQuery())
+	if string(b) != `{"selector":{"_id":{"$gt":null}}}` {
+		t.Fatalf("empty query didn't marshal correctly: %s", b)
+	}
+}
+
+func TestFindDevices(t *testing.T) {
+	var deviceQuery string
+
+	c, srv := newFakeCouch(t, fakeRoutes{
+		"POST /rooms/_find": respond(http.StatusOK, `{"docs": [{"_id": "ITB-1101"}, {"_id": "ITB-1108"}]}`),
+		"POST /devices/_find": func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			deviceQuery = string(b)
+			w.Write([]byte(`{"docs": [{"_id": "ITB-1101-D1", "name": "D1", "type": {"_id": "SonyXBR"}}]}`))
+		},
+	})
+	defer srv.Close()
+
+	devices, err := c.FindDevices(structs.DeviceFilter{
+		Type:        "SonyXBR",
+		Roles:       []string{"VideoOut"},
+		Tags:        []string{"hdmi"},
+		Designation: "production",
+	})
+	if err != nil {
+		t.Fatalf("failed to find devices: %s", err)
+	}
+
+	if len(devices) != 1 || devices[0].ID != "ITB-1101-D1" {
+		t.Fatalf("got the wrong devices: %+v", devices)
+	}
+
+	for _, expected := range []string{
+		`{"type._id":{"$eq":"SonyXBR"}}`,
+		`{"roles":{"$elemMatch":{"_id":{"$eq":"VideoOut"}}}}`,
+		`{"tags":{"$all":["hdmi"]}}`,
+		`{"_id":{"$gt":"ITB-1108-","$lt":"ITB-1108."}}`,
+		`"use_index":["device-type","device-type"]`,
+	} {
+		if !strings.Contains(deviceQuery, expected) {
+			t.Fatalf("device query doesn't include %s\nquery: %s", expected, deviceQuery)
+		}
+	}
+
+	// getting devices by role and type has always ignored case
+	if _, err := c.GetDevicesByRoleAndType("videoout", "sonyxbr"); err != nil {
+		t.Fatalf("failed to get devices by role and type: %s", err)
+	}
+
+	for _, expected := range []string{
+		`{"type._id":{"$regex":"(?i)^sonyxbr$"}}`,
+		`{"roles":{"$elemMatch":{"_id":{"$regex":"(?i)^videoout$"}}}}`,
+	} {
+		if !strings.Contains(deviceQuery, expected) {
+			t.Fatalf("device query doesn't include %s\nquery: %s", expected, deviceQuery)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package couch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/structs"
+)
+
+// renameStep moves a single document from its old id to its new id.
+type renameStep struct {
+	kind     string
+	database string
+
+	oldID  string
+	oldRev string
+	oldDoc interface{}
+
+	newID  string
+	newRev string
+	newDoc interface{}
+
+	created     bool
+	deleted     bool
+	err         error
+	rollbackErr error
+}
+
+/*
+RenameBuilding moves building oldID to newID, along with each of its rooms, their devices, and
+their ui configs (with their attachments). Ports that reference a device being moved are updated
+to its new id.
+
+Every document is created under its new id before any of the old documents are deleted.
+If any step fails, each document that was already created is deleted and each document that
+was already deleted is restored, so that the database is left as it was. The result has an
+entry for each document that was (or would have been) moved.
+*/
+func (c *CouchDB) RenameBuilding(oldID, newID string) (structs.RenameResult, error) {
+	result := structs.RenameResult{OldID: oldID, NewID: newID}
+
+	var old building
+	err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", BUILDINGS, oldID), "", nil, &old)
+	if err != nil {
+		return result, fmt.Errorf("unable to get building %s to rename: %s", oldID, err)
+	}
+
+	original := *old.Building
+	original.Rev = ""
+
+	renamed := original
+	renamed.ID = newID
+
+	if err := renamed.Validate(); err != nil {
+		return result, fmt.Errorf("unable to rename building %s: %s", oldID, err)
+	}
+
+	if err := c.checkRenameTarget(BUILDINGS, newID); err != nil {
+		return result, err
+	}
+
+	steps := []*renameStep{{
+		kind:     "building",
+		database: BUILDINGS,
+		oldID:    oldID,
+		oldRev:   old.Rev,
+		oldDoc:   original,
+		newID:    newID,
+		newDoc:   renamed,
+	}}
+
+	rooms, err := c.findRooms(NewQuery().Where("_id", Cond{"$gt": oldID + "-", "$lt": oldID + "."}))
+	if err != nil {
+		return result, fmt.Errorf("unable to get rooms in building %s to rename: %s", oldID, err)
+	}
+
+	for _, r := range rooms {
+		roomSteps, err := c.planRoomRename(r, oldID, newID)
+		if err != nil {
+			return result, err
+		}
+
+		steps = append(steps, roomSteps...)
+	}
+
+	return c.rename(result, steps)
+}
+
+/*
+RenameRoom moves room oldID to newID, along with its devices and its ui config (with its
+attachments). Ports that reference a device being moved are updated to its new id. The building
+of newID must already exist.
+
+As with RenameBuilding, either every document is moved or, if any step fails, the changes
+already made are rolled back.
+*/
+func (c *CouchDB) RenameRoom(oldID, newID string) (structs.RenameResult, error) {
+	result := structs.RenameResult{OldID: oldID, NewID: newID}
+
+	var old room
+	err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", ROOMS, oldID), "", nil, &old)
+	if err != nil {
+		return result, fmt.Errorf("unable to get room %s to rename: %s", oldID, err)
+	}
+
+	renamed := *old.Room
+	renamed.ID = newID
+
+	if err := renamed.Validate(); err != nil {
+		return result, fmt.Errorf("unable to rename room %s: %s", oldID, err)
+	}
+
+	buildingID := strings.Split(newID, "-")[0]
+	if _, err := c.getBuilding(buildingID); err != nil {
+		return result, fmt.Errorf("unable to rename room %s: building %s doesn't exist: %s", oldID, buildingID, err)
+	}
+
+	if err := c.checkRenameTarget(ROOMS, newID); err != nil {
+		return result, err
+	}
+
+	steps, err := c.planRoomRename(old, oldID, newID)
+	if err != nil {
+		return result, err
+	}
+
+	return c.rename(result, steps)
+}
+
+// checkRenameTarget returns an error unless id is free to be renamed to.
+func (c *CouchDB) checkRenameTarget(database, id string) error {
+	err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", database, id), "", nil, nil)
+	switch err.(type) {
+	case nil:
+		return fmt.Errorf("unable to rename to %s: it already exists", id)
+	case *NotFound:
+		return nil
+	default:
+		return fmt.Errorf("unable to check if %s already exists: %s", id, err)
+	}
+}
+
+// planRoomRename returns the steps to move r, its devices, and its ui config, for oldID (r, or its building) being renamed to newID.
+func (c *CouchDB) planRoomRename(r room, oldID, newID string) ([]*renameStep, error) {
+	original := *r.Room
+	original.Rev = ""
+
+	renamed := original
+	renamed.ID = structs.RenameID(r.ID, oldID, newID)
+
+	steps := []*renameStep{{
+		kind:     "room",
+		database: ROOMS,
+		oldID:    r.ID,
+		oldRev:   r.Rev,
+		oldDoc:   original,
+		newID:    renamed.ID,
+		newDoc:   renamed,
+	}}
+
+	var query IDPrefixQuery
+	query.Selector.ID.GT = fmt.Sprintf("%v-", r.ID)
+	query.Selector.ID.LT = fmt.Sprintf("%v.", r.ID)
+	query.Limit = 1000
+
+	devices, err := c.getDevicesByQuery(query, false)
+	if err != nil {
+		return steps, fmt.Errorf("unable to get devices in room %s to rename: %s", r.ID, err)
+	}
+
+	for _, d := range devices {
+		original := *d.Device
+		original.Rev = ""
+
+		renamed := original
+		renamed.Ports = append([]structs.Port(nil), d.Ports...)
+		renamed.Rename(oldID, newID)
+
+		steps = append(steps, &renameStep{
+			kind:     "device",
+			database: DEVICES,
+			oldID:    d.ID,
+			oldRev:   d.Rev,
+			oldDoc:   original,
+			newID:    renamed.ID,
+			newDoc:   renamed,
+		})
+	}
+
+	// the attachments' data is needed to create them again under the new id
+	var raw json.RawMessage
+	err = c.MakeRequest("GET", fmt.Sprintf("%v/%v?attachments=true", UI_CONFIGS, r.ID), "", nil, &raw)
+	switch err.(type) {
+	case nil:
+		var ui uiconfig
+		if err := json.Unmarshal(raw, &ui); err != nil {
+			return steps, fmt.Errorf("unable to decode ui config %s to rename: %s", r.ID, err)
+		}
+
+		original := *ui.UIConfig
+		original.Rev = ""
+
+		renamed := original
+		renamed.Panels = append([]structs.Panel(nil), ui.Panels...)
+		renamed.Rename(oldID, newID)
+
+		step := &renameStep{
+			kind:     "ui-config",
+			database: UI_CONFIGS,
+			oldID:    ui.ID,
+			oldRev:   ui.Rev,
+			newID:    renamed.ID,
+		}
+
+		if step.oldDoc, err = withAttachments(original, raw); err != nil {
+			return steps, fmt.Errorf("unable to get the attachments of ui config %s to rename: %s", r.ID, err)
+		}
+
+		if step.newDoc, err = withAttachments(renamed, raw); err != nil {
+			return steps, fmt.Errorf("unable to get the attachments of ui config %s to rename: %s", r.ID, err)
+		}
+
+		steps = append(steps, step)
+	case *NotFound:
+		// not every room has a ui config
+	default:
+		return steps, fmt.Errorf("unable to get ui config %s to rename: %s", r.ID, err)
+	}
+
+	return steps, nil
+}
+
+// withAttachments returns doc with the attachments of stored (a document fetched with its attachments' data), so that they are kept when doc is created from scratch.
+func withAttachments(doc interface{}, stored json.RawMessage) (interface{}, error) {
+	var attachments struct {
+		Attachments map[string]struct {
+			ContentType string `json:"content_type"`
+			Data        string `json:"data"`
+		} `json:"_attachments"`
+	}
+
+	if err := json.Unmarshal(stored, &attachments); err != nil {
+		return doc, err
+	}
+
+	if len(attachments.Attachments) == 0 {
+		return doc, nil
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return doc, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return doc, err
+	}
+
+	fields["_attachments"] = attachments.Attachments
+	return fields, nil
+}
+
+// rename runs each of the steps, rolling all of them back if any of them fail.
+func (c *CouchDB) rename(result structs.RenameResult, steps []*renameStep) (structs.RenameResult, error) {
+	var failed *renameStep
+
+	// create everything under its new id
+	for _, step := range steps {
+		step.newRev, step.err = c.putDoc(step.database, step.newID, step.newDoc)
+		if step.err != nil {
+			failed = step
+			break
+		}
+
+		step.created = true
+	}
+
+	// then delete the old documents, inside out
+	for i := len(steps) - 1; i >= 0 && failed == nil; i-- {
+		step := steps[i]
+
+		step.err = c.deleteDoc(step.database, step.oldID, step.oldRev)
+		if step.err != nil {
+			failed = step
+			break
+		}
+
+		step.deleted = true
+	}
+
+	if failed != nil {
+		result.RolledBack = true
+		c.rollbackRename(steps)
+	}
+
+	for _, step := range steps {
+		doc := structs.RenamedDocument{
+			Kind:    step.kind,
+			OldID:   step.oldID,
+			NewID:   step.newID,
+			Success: failed == nil,
+		}
+
+		switch {
+		case step.err != nil:
+			doc.Message = step.err.Error()
+		case step.rollbackErr != nil:
+			doc.Message = fmt.Sprintf("failed to roll back: %s", step.rollbackErr)
+		case failed != nil && (step.created || step.deleted):
+			doc.Message = "rolled back"
+		case failed != nil:
+			doc.Message = "not moved"
+		}
+
+		result.Documents = append(result.Documents, doc)
+	}
+
+	if failed != nil {
+		return result, fmt.Errorf("unable to move %s %s to %s, so the rename of %s to %s was rolled back: %s", failed.kind, failed.oldID, failed.newID, result.OldID, result.NewID, failed.err)
+	}
+
+	return result, nil
+}
+
+// rollbackRename undoes each of the steps that have been done, in the reverse order they were done in.
+func (c *CouchDB) rollbackRename(steps []*renameStep) {
+	// restore the old documents
+	for _, step := range steps {
+		if !step.deleted {
+			continue
+		}
+
+		// a deleted document can be created again without a rev, which oldDoc doesn't have
+		if _, err := c.putDoc(step.database, step.oldID, step.oldDoc); err != nil {
+			log.L.Errorf("Failed to restore %s %s while rolling back a rename: %s", step.kind, step.oldID, err)
+			step.rollbackErr = err
+		}
+	}
+
+	// delete the new documents
+	for i := len(steps) - 1; i >= 0; i-- {
+		step := steps[i]
+		if !step.created {
+			continue
+		}
+
+		if err := c.deleteDoc(step.database, step.newID, step.newRev); err != nil {
+			log.L.Errorf("Failed to delete %s %s while rolling back a rename: %s", step.kind, step.newID, err)
+			step.rollbackErr = err
+		}
+	}
+}
+
+// putDoc creates doc with the given id, returning its rev.
+func (c *CouchDB) putDoc(database, id string, doc interface{}) (string, error) {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal %s: %s", id, err)
+	}
+
+	var resp CouchUpsertResponse
+	err = c.MakeRequest("PUT", fmt.Sprintf("%v/%v", database, id), "application/json", b, &resp)
+	if err != nil {
+		return "", fmt.Errorf("unable to create %s: %s", id, err)
+	}
+
+	return resp.Rev, nil
+}
+
+// deleteDoc deletes revision rev of a document.
+func (c *CouchDB) deleteDoc(database, id, rev string) error {
+	err := c.MakeRequest("DELETE", fmt.Sprintf("%v/%v?rev=%v", database, id, rev), "", nil, nil)
+	if err != nil {
+		return fmt.Errorf("unable to delete %s: %s", id, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package couch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+const renameRoomDoc = `{"_id": "AAA-ZZZ", "_rev": "1-aaa", "name": "AAA-ZZZ", "designation": "production", "configuration": {"_id": "AAA"}}`
+
+func TestRenameRoom(t *testing.T) {
+	var mu sync.Mutex
+	created := make(map[string]map[string]interface{})
+
+	create := func(w http.ResponseWriter, r *http.Request) {
+		var doc map[string]interface{}
+		b, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &doc); err != nil {
+			t.Errorf("invalid document: %s", err)
+		}
+
+		mu.Lock()
+		created[r.URL.Path] = doc
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"ok": true, "rev": "1-bbb"}`))
+	}
+
+	c, srv := newFakeCouch(t, fakeRoutes{
+		"GET /rooms/AAA-ZZZ":            respond(http.StatusOK, renameRoomDoc),
+		"GET /buildings/AAA":            respond(http.StatusOK, `{"_id": "AAA", "_rev": "1-aaa"}`),
+		"GET /rooms/AAA-YYY":            respondNotFound,
+		"POST /devices/_find":           respond(http.StatusOK, `{"docs": [{"_id": "AAA-ZZZ-D1", "_rev": "1-aaa", "name": "D1", "type": {"_id": "test_type_1"}, "ports": [{"_id": "IN1", "source_device": "AAA-ZZZ-D2", "destination_device": "AAA-ZZZ-D1"}, {"_id": "IN2", "source_device": "BBB-ZZZ-D1"}]}]}`),
+		"PUT /rooms/AAA-YYY":            create,
+		"PUT /devices/AAA-YYY-D1":       create,
+		"PUT /ui-configuration/AAA-YYY": create,
+		"GET /ui-configuration/AAA-ZZZ": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("attachments") != "true" {
+				t.Errorf("expected the ui config to be fetched with its attachments")
+			}
+
+			w.Write([]byte(`{"_id": "AAA-ZZZ", "_rev": "2-aaa", "panels": [{"hostname": "AAA-ZZZ-CP1"}], "_attachments": {"layout.json": {"content_type": "application/json", "revpos": 2, "digest": "md5-abc", "data": "e30="}}}`))
+		},
+		"DELETE": respond(http.StatusOK, `{"ok": true}`),
+	})
+	defer srv.Close()
+
+	result, err := c.RenameRoom("AAA-ZZZ", "AAA-YYY")
+	if err != nil {
+		t.Fatalf("failed to rename room: %s (%+v)", err, result)
+	}
+
+	var device struct {
+		Ports []struct {
+			SourceDevice      string `json:"source_device"`
+			DestinationDevice string `json:"destination_device"`
+		} `json:"ports"`
+	}
+
+	b, _ := json.Marshal(created["/devices/AAA-YYY-D1"])
+	json.Unmarshal(b, &device)
+
+	if len(device.Ports) != 2 || device.Ports[0].SourceDevice != "AAA-YYY-D2" || device.Ports[0].DestinationDevice != "AAA-YYY-D1" || device.Ports[1].SourceDevice != "BBB-ZZZ-D1" {
+		t.Fatalf("the device's ports weren't renamed: %+v", device.Ports)
+	}
+
+	var ui struct {
+		Attachments map[string]map[string]interface{} `json:"_attachments"`
+	}
+
+	b, _ = json.Marshal(created["/ui-configuration/AAA-YYY"])
+	json.Unmarshal(b, &ui)
+
+	if layout := ui.Attachments["layout.json"]; layout["data"] != "e30=" || layout["content_type"] != "application/json" || layout["stub"] != nil {
+		t.Fatalf("the ui config's attachments weren't kept: %+v", ui.Attachments)
+	}
+}
+
+func TestRenameRoomRollback(t *testing.T) {
+	var mu sync.Mutex
+	var deleted []string
+
+	c, srv := newFakeCouch(t, fakeRoutes{
+		"GET /rooms/AAA-ZZZ":            respond(http.StatusOK, renameRoomDoc),
+		"GET /buildings/AAA":            respond(http.StatusOK, `{"_id": "AAA", "_rev": "1-aaa"}`),
+		"GET /rooms/AAA-YYY":            respondNotFound,
+		"GET /ui-configuration/AAA-ZZZ": respondNotFound,
+		"POST /devices/_find":           respond(http.StatusOK, `{"docs": [{"_id": "AAA-ZZZ-D1", "_rev": "1-aaa", "name": "D1", "type": {"_id": "test_type_1"}, "ports": [{"_id": "IN1", "source_device": "AAA-ZZZ-D2", "destination_device": "AAA-ZZZ-D1"}]}]}`),
+		"PUT /rooms/AAA-YYY":            respond(http.StatusCreated, `{"ok": true, "id": "AAA-YYY", "rev": "1-bbb"}`),
+		"PUT /devices/AAA-YYY-D1":       respond(http.StatusBadRequest, `{"error": "bad_request", "reason": "no"}`),
+		"DELETE /rooms/AAA-YYY": func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			deleted = append(deleted, r.URL.Path+"?rev="+r.URL.Query().Get("rev"))
+			mu.Unlock()
+
+			w.Write([]byte(`{"ok": true}`))
+		},
+	})
+	defer srv.Close()
+
+	result, err := c.RenameRoom("AAA-ZZZ", "AAA-YYY")
+	if err == nil {
+		t.Fatalf("rename should have failed")
+	}
+
+	if !result.RolledBack {
+		t.Fatalf("rename wasn't rolled back")
+	}
+
+	if len(deleted) != 1 || deleted[0] != "/rooms/AAA-YYY?rev=1-bbb" {
+		t.Fatalf("the new room wasn't deleted: %v", deleted)
+	}
+
+	if len(result.Documents) != 2 {
+		t.Fatalf("expected a result for the room and the device, got %+v", result.Documents)
+	}
+
+	for _, doc := range result.Documents {
+		if doc.Success {
+			t.Fatalf("%s %s shouldn't have succeeded", doc.Kind, doc.OldID)
+		}
+	}
+
+	if result.Documents[1].NewID != "AAA-YYY-D1" {
+		t.Fatalf("device was moved to the wrong id: %s", result.Documents[1].NewID)
+	}
+}
@@ -0,0 +1,459 @@
+package couch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// REPLICATOR is the database couch reads replication documents from.
+const REPLICATOR = "_replicator"
+
+// ManagedReplicationPrefix is the prefix of the ids of replications managed by ReconcileReplications. Replications without it are never changed by it.
+const ManagedReplicationPrefix = "managed_"
+
+// Replication is a replication document, describing a replication of one database from another couch into this one.
+type Replication struct {
+	ID           string   `json:"_id"`
+	Rev          string   `json:"_rev,omitempty"`
+	Source       string   `json:"source"`
+	Target       string   `json:"target"`
+	CreateTarget bool     `json:"create_target"`
+	Continuous   bool     `json:"continuous"`
+	Selector     Selector `json:"selector,omitempty"`
+}
+
+// ReplicationStatus is the state of a scheduled replication, as reported by couch's replication scheduler.
+type ReplicationStatus struct {
+	ID          string    `json:"_id"`
+	Database    string    `json:"database"`
+	Source      string    `json:"source"`
+	Target      string    `json:"target"`
+	State       string    `json:"state"`
+	Info        string    `json:"info,omitempty"`
+	ErrorCount  int       `json:"error-count"`
+	StartTime   time.Time `json:"start-time"`
+	LastUpdated time.Time `json:"last-updated"`
+}
+
+// Failed returns true if the replication has failed, or is repeatedly crashing.
+func (s ReplicationStatus) Failed() bool {
+	switch s.State {
+	case "failed", "crashed", "crashing", "error":
+		return true
+	default:
+		return false
+	}
+}
+
+// ReplicationFailed is returned by FailedReplications (and GetStatus) when a replication has failed.
+type ReplicationFailed struct {
+	Replications []ReplicationStatus
+}
+
+func (r ReplicationFailed) Error() string {
+	var failed []string
+	for _, status := range r.Replications {
+		msg := fmt.Sprintf("replication of %s (%s) is %s", status.Database, status.ID, status.State)
+		if len(status.Info) > 0 {
+			msg += ": " + status.Info
+		}
+
+		failed = append(failed, msg)
+	}
+
+	return strings.Join(failed, "; ")
+}
+
+// ReplicationChanges is what ReconcileReplications changed.
+type ReplicationChanges struct {
+	Created  []string `json:"created,omitempty"`
+	Updated  []string `json:"updated,omitempty"`
+	Canceled []string `json:"canceled,omitempty"`
+}
+
+// RoomSelector returns the selector matching the documents in database that belong to roomID.
+func RoomSelector(database, roomID string) Selector {
+	switch database {
+	case BUILDINGS:
+		return Selector{"_id": Eq(strings.SplitN(roomID, "-", 2)[0])}
+	case DEVICES, DEVICE_STATES:
+		return Selector{"_id": HasPrefix(roomID + "-")}
+	default:
+		return Selector{"_id": Eq(roomID)}
+	}
+}
+
+// DesignationSelector returns the selector matching the rooms with designation.
+func DesignationSelector(designation string) Selector {
+	return Selector{"designation": Eq(designation)}
+}
+
+// RoomReplicationDatabases are the databases replicated to a room's couch by RoomReplications. Databases with a RoomSelector are filtered to the room; the others are replicated whole.
+var RoomReplicationDatabases = map[string]bool{
+	BUILDINGS:           true,
+	ROOMS:               true,
+	DEVICES:             true,
+	UI_CONFIGS:          true,
+	DEVICE_TYPES:        false,
+	ROOM_CONFIGURATIONS: false,
+}
+
+/*
+RoomReplications returns the replications a room's couch needs: a continuous replication from
+the couch at source of each of RoomReplicationDatabases, filtered to roomID where that makes
+sense. Pass them to ReconcileReplications to make sure they are exactly the replications c has.
+*/
+func (c *CouchDB) RoomReplications(source, roomID string) ([]Replication, error) {
+	var replications []Replication
+
+	for database, filtered := range RoomReplicationDatabases {
+		var selector Selector
+		if filtered {
+			selector = RoomSelector(database, roomID)
+		}
+
+		r, err := c.NewReplication(ManagedReplicationPrefix+database, source, database, selector)
+		if err != nil {
+			return nil, err
+		}
+
+		replications = append(replications, r)
+	}
+
+	sort.Slice(replications, func(i, j int) bool {
+		return replications[i].ID < replications[j].ID
+	})
+
+	return replications, nil
+}
+
+// NewReplication returns a continuous replication of database from the couch at source into c, of the documents matching selector (or every document, if selector is nil).
+func (c *CouchDB) NewReplication(id, source, database string, selector Selector) (Replication, error) {
+	sourceURL, err := databaseURL(source, "", "", database)
+	if err != nil {
+		return Replication{}, fmt.Errorf("invalid source %q: %s", source, err)
+	}
+
+	targetURL, err := databaseURL(c.address, c.username, c.password, database)
+	if err != nil {
+		return Replication{}, fmt.Errorf("invalid couch address: %s", err)
+	}
+
+	return Replication{
+		ID:           id,
+		Source:       sourceURL,
+		Target:       targetURL,
+		CreateTarget: true,
+		Continuous:   true,
+		Selector:     selector,
+	}, nil
+}
+
+// CreateReplication creates the replication document r, which couch starts replicating.
+func (c *CouchDB) CreateReplication(r Replication) (Replication, error) {
+	if err := r.validate(); err != nil {
+		return r, fmt.Errorf("unable to create replication: %s", err)
+	}
+
+	r.Rev = ""
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return r, fmt.Errorf("unable to marshal replication %s: %s", r.ID, err)
+	}
+
+	var resp CouchUpsertResponse
+	err = c.replicator().MakeRequest("PUT", fmt.Sprintf("%v/%v", REPLICATOR, r.ID), "application/json", b, &resp)
+	if err != nil {
+		if _, ok := err.(*Conflict); ok {
+			return r, fmt.Errorf("unable to create replication %s: it already exists", r.ID)
+		}
+
+		return r, fmt.Errorf("unable to create replication %s: %s", r.ID, err)
+	}
+
+	r.Rev = resp.Rev
+	return r, nil
+}
+
+// GetReplication returns the replication document id.
+func (c *CouchDB) GetReplication(id string) (Replication, error) {
+	var toReturn Replication
+
+	err := c.replicator().MakeRequest("GET", fmt.Sprintf("%v/%v", REPLICATOR, id), "", nil, &toReturn)
+	if err != nil {
+		return toReturn, fmt.Errorf("failed to get replication %s: %s", id, err)
+	}
+
+	return toReturn, nil
+}
+
+// GetReplications returns every replication document.
+func (c *CouchDB) GetReplications() ([]Replication, error) {
+	var resp struct {
+		Rows []struct {
+			ID  string      `json:"id"`
+			Doc Replication `json:"doc"`
+		} `json:"rows"`
+	}
+
+	err := c.replicator().MakeRequest("GET", fmt.Sprintf("%v/_all_docs?include_docs=true", REPLICATOR), "", nil, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get replications: %s", err)
+	}
+
+	var toReturn []Replication
+	for _, row := range resp.Rows {
+		if strings.HasPrefix(row.ID, "_design/") {
+			continue
+		}
+
+		toReturn = append(toReturn, row.Doc)
+	}
+
+	return toReturn, nil
+}
+
+// UpdateReplication replaces the replication document id with r, which restarts the replication. If r.Rev is set, it is only updated if it hasn't changed since that revision.
+func (c *CouchDB) UpdateReplication(id string, r Replication) (Replication, error) {
+	r.ID = id
+	if err := r.validate(); err != nil {
+		return r, fmt.Errorf("unable to update replication: %s", err)
+	}
+
+	rev, err := c.replicator().updateDoc(REPLICATOR, id, r.Rev, r)
+	if err != nil {
+		return r, fmt.Errorf("unable to update replication %s: %s", id, err)
+	}
+
+	r.Rev = rev
+	return r, nil
+}
+
+// CancelReplication deletes the replication document id, which stops the replication.
+func (c *CouchDB) CancelReplication(id string) error {
+	r, err := c.GetReplication(id)
+	if err != nil {
+		return fmt.Errorf("unable to get replication %s to cancel: %s", id, err)
+	}
+
+	err = c.replicator().MakeRequest("DELETE", fmt.Sprintf("%v/%v?rev=%v", REPLICATOR, id, r.Rev), "", nil, nil)
+	if err != nil {
+		return fmt.Errorf("unable to cancel replication %s: %s", id, err)
+	}
+
+	return nil
+}
+
+// FailedReplications returns a *ReplicationFailed describing each replication that has failed, by database, or nil if none have.
+func (c *CouchDB) FailedReplications() error {
+	statuses, err := c.GetReplicationStatuses()
+	if err != nil {
+		return err
+	}
+
+	var failed []ReplicationStatus
+	for _, status := range statuses {
+		if status.Failed() {
+			failed = append(failed, status)
+		}
+	}
+
+	if len(failed) > 0 {
+		return &ReplicationFailed{Replications: failed}
+	}
+
+	return nil
+}
+
+// GetReplicationStatuses returns the status of every replication document.
+func (c *CouchDB) GetReplicationStatuses() ([]ReplicationStatus, error) {
+	var resp struct {
+		Docs []couchReplicationState `json:"docs"`
+	}
+
+	err := c.replicator().MakeRequest("GET", fmt.Sprintf("_scheduler/docs/%v", REPLICATOR), "", nil, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get replication statuses: %s", err)
+	}
+
+	var toReturn []ReplicationStatus
+	for _, state := range resp.Docs {
+		toReturn = append(toReturn, ReplicationStatus{
+			ID:          state.DocID,
+			Database:    targetDatabase(state.Target),
+			Source:      state.Source,
+			Target:      state.Target,
+			State:       state.State,
+			Info:        replicationInfo(state.Info),
+			ErrorCount:  state.ErrorCount,
+			StartTime:   state.StartTime,
+			LastUpdated: state.LastUpdated,
+		})
+	}
+
+	return toReturn, nil
+}
+
+/*
+ReconcileReplications makes the replications managed by c (those whose ids start with
+ManagedReplicationPrefix) exactly desired: missing replications are created, ones that differ
+from desired are updated, and ones that aren't desired are canceled. Replications that aren't
+managed are left alone.
+
+Every change is attempted; the returned error describes each that failed.
+*/
+func (c *CouchDB) ReconcileReplications(desired []Replication) (ReplicationChanges, error) {
+	var changes ReplicationChanges
+
+	for _, r := range desired {
+		if !strings.HasPrefix(r.ID, ManagedReplicationPrefix) {
+			return changes, fmt.Errorf("unable to reconcile replications: %s isn't managed (its id must start with %q)", r.ID, ManagedReplicationPrefix)
+		}
+	}
+
+	current, err := c.GetReplications()
+	if err != nil {
+		return changes, fmt.Errorf("unable to reconcile replications: %s", err)
+	}
+
+	existing := make(map[string]Replication)
+	for _, r := range current {
+		if strings.HasPrefix(r.ID, ManagedReplicationPrefix) {
+			existing[r.ID] = r
+		}
+	}
+
+	var errs []string
+
+	for _, r := range desired {
+		cur, ok := existing[r.ID]
+		delete(existing, r.ID)
+
+		switch {
+		case !ok:
+			if _, err := c.CreateReplication(r); err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+
+			changes.Created = append(changes.Created, r.ID)
+		case !r.sameAs(cur):
+			r.Rev = cur.Rev
+			if _, err := c.UpdateReplication(r.ID, r); err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+
+			changes.Updated = append(changes.Updated, r.ID)
+		}
+	}
+
+	var extra []string
+	for id := range existing {
+		extra = append(extra, id)
+	}
+
+	sort.Strings(extra)
+
+	for _, id := range extra {
+		if err := c.CancelReplication(id); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		changes.Canceled = append(changes.Canceled, id)
+	}
+
+	if len(errs) > 0 {
+		return changes, fmt.Errorf("unable to reconcile every replication: %s", strings.Join(errs, "; "))
+	}
+
+	return changes, nil
+}
+
+// replicator returns a copy of c that doesn't wait for couch to be ready, since becoming ready can depend on the replications being managed.
+func (c *CouchDB) replicator() *CouchDB {
+	c2 := *c
+	c2.IgnoreReadyChecks = true
+	return &c2
+}
+
+var validReplicationID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_\-]*$`)
+
+func (r Replication) validate() error {
+	switch {
+	case !validReplicationID.MatchString(r.ID):
+		return fmt.Errorf("invalid replication id %q", r.ID)
+	case len(r.Source) == 0:
+		return errors.New("replication must have a source")
+	case len(r.Target) == 0:
+		return errors.New("replication must have a target")
+	}
+
+	return nil
+}
+
+// sameAs returns true if r and o describe the same replication, ignoring their revisions.
+func (r Replication) sameAs(o Replication) bool {
+	r.Rev, o.Rev = "", ""
+
+	b1, err1 := json.Marshal(r)
+	b2, err2 := json.Marshal(o)
+
+	return err1 == nil && err2 == nil && string(b1) == string(b2)
+}
+
+// databaseURL returns the url of database on the couch at address, adding username and password to it if they are set.
+func databaseURL(address, username, password, database string) (string, error) {
+	u, err := url.Parse(strings.TrimRight(address, "/"))
+	if err != nil {
+		return "", err
+	}
+
+	if len(u.Scheme) == 0 || len(u.Host) == 0 {
+		return "", errors.New("address must include a scheme and host")
+	}
+
+	if len(username) > 0 {
+		u.User = url.UserPassword(username, password)
+	}
+
+	u.Path = path.Join(u.Path, database)
+	return u.String(), nil
+}
+
+// targetDatabase returns the name of the database a replication's target url points to.
+func targetDatabase(target string) string {
+	u, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+
+	return path.Base("/" + strings.Trim(u.Path, "/"))
+}
+
+// replicationInfo returns the error a replication's info describes. Couch reports it as either a string, or an object with an error field.
+func replicationInfo(info json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(info, &s); err == nil {
+		return s
+	}
+
+	var obj struct {
+		Error interface{} `json:"error"`
+	}
+
+	if err := json.Unmarshal(info, &obj); err == nil && obj.Error != nil {
+		return fmt.Sprintf("%v", obj.Error)
+	}
+
+	return ""
+}
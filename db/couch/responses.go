@@ -6,7 +6,6 @@ import (
 )
 
 type building struct {
-	Rev string `json:"_rev,omitempty"`
 	*structs.Building
 }
 
@@ -17,7 +16,6 @@ type buildingQueryResponse struct {
 }
 
 type room struct {
-	Rev string `json:"_rev,omitempty"`
 	*structs.Room
 }
 
@@ -28,7 +26,6 @@ type roomQueryResponse struct {
 }
 
 type roomConfiguration struct {
-	Rev string `json:"_rev,omitempty"`
 	*structs.RoomConfiguration
 }
 
@@ -39,7 +36,6 @@ type roomConfigurationQueryResponse struct {
 }
 
 type device struct {
-	Rev string `json:"_rev,omitempty"`
 	*structs.Device
 }
 
@@ -56,7 +52,6 @@ type deviceStateQueryResponse struct {
 }
 
 type deviceType struct {
-	Rev string `json:"_rev,omitempty"`
 	*structs.DeviceType
 }
 
@@ -67,7 +62,6 @@ type deviceTypeQueryResponse struct {
 }
 
 type uiconfig struct {
-	Rev string `json:"_rev,omitempty"`
 	*structs.UIConfig
 }
 
@@ -92,6 +86,11 @@ type roomDesignations struct {
 	DesigList []string `json:"designations"`
 }
 
+type roomSchemas struct {
+	Rev     string                               `json:"_rev,omitempty"`
+	Schemas map[string][]structs.AttributeSchema `json:"schemas"`
+}
+
 type closureCodes struct {
 	Rev   string   `json:"_rev,omitempty"`
 	Codes []string `json:"closure_codes"`
@@ -119,7 +118,6 @@ type menu struct {
 }
 
 type attributeGroup struct {
-	Rev string `json:"_rev,omitempty"`
 	structs.Group
 }
 
@@ -0,0 +1,106 @@
+package couch
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// NewConflict returns the error for updating id based on revision expected, when it has since changed to revision current.
+func NewConflict(id, expected, current string) *Conflict {
+	return &Conflict{
+		msg:         fmt.Sprintf("%s has been changed by someone else since revision %s (it is now at revision %s)", id, expected, current),
+		ID:          id,
+		ExpectedRev: expected,
+		CurrentRev:  current,
+	}
+}
+
+// currentRev returns the current revision of id.
+func (c *CouchDB) currentRev(database, id string) (string, error) {
+	var doc docRev
+
+	err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", database, id), "", nil, &doc)
+	if err != nil {
+		return "", err
+	}
+
+	return doc.Rev, nil
+}
+
+// createDoc adds doc to database as the new document id. A new document doesn't have a revision yet, so doc's _rev is left out.
+func (c *CouchDB) createDoc(database, id string, doc interface{}) error {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("unable to marshal %s: %s", id, err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return fmt.Errorf("unable to marshal %s: %s", id, err)
+	}
+
+	delete(fields, "_rev")
+	fields["_id"], _ = json.Marshal(id)
+
+	b, err = json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("unable to marshal %s: %s", id, err)
+	}
+
+	var resp CouchUpsertResponse
+	return c.MakeRequest("POST", database, "application/json", b, &resp)
+}
+
+/*
+updateDoc replaces document id with doc, and returns its new revision.
+
+If rev is empty, the document is updated regardless of its current revision. Otherwise, it is
+only updated if it is still at revision rev; if it isn't, a *Conflict is returned. doc's own
+revision must be the same as rev.
+*/
+func (c *CouchDB) updateDoc(database, id, rev string, doc interface{}) (string, error) {
+	if len(rev) == 0 {
+		current, err := c.currentRev(database, id)
+		if err != nil {
+			return "", fmt.Errorf("unable to get %s to update: %s", id, err)
+		}
+
+		rev = current
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal %s: %s", id, err)
+	}
+
+	var resp CouchUpsertResponse
+	err = c.MakeRequest("PUT", fmt.Sprintf("%v/%v?rev=%v", database, id, rev), "application/json", b, &resp)
+	if err != nil {
+		if _, ok := err.(*Conflict); ok {
+			current, _ := c.currentRev(database, id)
+			return "", NewConflict(id, rev, current)
+		}
+
+		return "", fmt.Errorf("failed to update %s: %s", id, err)
+	}
+
+	return resp.Rev, nil
+}
+
+// checkRev returns a *Conflict if rev is set, and id isn't still at revision rev.
+func (c *CouchDB) checkRev(database, id, rev string) error {
+	if len(rev) == 0 {
+		return nil
+	}
+
+	current, err := c.currentRev(database, id)
+	if err != nil {
+		return fmt.Errorf("unable to get %s: %s", id, err)
+	}
+
+	if current != rev {
+		return NewConflict(id, rev, current)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package couch
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/byuoitav/common/structs"
+)
+
+func TestUpdateBuildingConflict(t *testing.T) {
+	c, srv := newFakeCouch(t, fakeRoutes{
+		"GET /buildings/AAA": respond(http.StatusOK, `{"_id": "AAA", "_rev": "2-bbb", "name": "AAA"}`),
+		"PUT /buildings/AAA": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("rev") != "2-bbb" {
+				respondConflict(w, r)
+				return
+			}
+
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"ok": true, "id": "AAA", "rev": "3-ccc"}`))
+		},
+	})
+	defer srv.Close()
+
+	building := structs.Building{ID: "AAA", Rev: "1-aaa", Name: "AAA"}
+
+	_, err := c.UpdateBuilding(building.ID, building)
+	conflict, ok := err.(*Conflict)
+	if !ok {
+		t.Fatalf("expected a conflict updating with a stale rev, got %v", err)
+	}
+
+	if conflict.ExpectedRev != "1-aaa" || conflict.CurrentRev != "2-bbb" {
+		t.Fatalf("unexpected conflict: %+v", conflict)
+	}
+
+	building.Rev = conflict.CurrentRev
+	updated, err := c.UpdateBuilding(building.ID, building)
+	if err != nil {
+		t.Fatalf("failed to update building: %s", err)
+	}
+
+	if updated.Rev != "3-ccc" {
+		t.Fatalf("expected the new rev to be returned, got %q", updated.Rev)
+	}
+}
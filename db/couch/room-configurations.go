@@ -1,7 +1,6 @@
 package couch
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 
@@ -35,24 +34,12 @@ func (c *CouchDB) getRoomConfiguration(id string) (roomConfiguration, error) {
 func (c *CouchDB) getRoomConfigurationsByQuery(query IDPrefixQuery) ([]roomConfiguration, error) {
 	var toReturn []roomConfiguration
 
-	// marshal query
-	b, err := json.Marshal(query)
-	if err != nil {
-		return toReturn, errors.New(fmt.Sprintf("failed to marshal room configurations query: %s", err))
-	}
-
-	// make query for room configs
-	var resp roomConfigurationQueryResponse
-	err = c.MakeRequest("POST", fmt.Sprintf("%s/_find", ROOM_CONFIGURATIONS), "application/json", b, &resp)
+	// make query for room configs, a page at a time
+	err := c.findAll(ROOM_CONFIGURATIONS, query, &toReturn)
 	if err != nil {
 		return toReturn, errors.New(fmt.Sprintf("failed to query room configurations: %s", err))
 	}
 
-	// return each document
-	for _, doc := range resp.Docs {
-		toReturn = append(toReturn, doc)
-	}
-
 	return toReturn, nil
 }
 
@@ -100,15 +87,8 @@ func (c *CouchDB) CreateRoomConfiguration(toAdd structs.RoomConfiguration) (stru
 
 	// TODO figure out how to check if the evalutaor key is valid
 
-	// marshal room config
-	b, err := json.Marshal(toAdd)
-	if err != nil {
-		return toReturn, errors.New(fmt.Sprintf("failed to marshal room configuration %s: %s", toAdd.ID, err))
-	}
-
 	// post room configuration
-	var resp CouchUpsertResponse
-	err = c.MakeRequest("POST", ROOM_CONFIGURATIONS, "application/json", b, &resp)
+	err = c.createDoc(ROOM_CONFIGURATIONS, toAdd.ID, toAdd)
 	if err != nil {
 		if _, ok := err.(*Conflict); ok {
 			return toReturn, errors.New(fmt.Sprintf("room configuration already exists; please update this configuration or change id's. error: %s", err))
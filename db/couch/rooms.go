@@ -1,7 +1,6 @@
 package couch
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
@@ -76,21 +75,15 @@ func (c *CouchDB) GetAllRooms() ([]structs.Room, error) {
 	query.Selector.ID.GT = "\x00"
 	query.Limit = 1000
 
-	// marshal query
-	b, err := json.Marshal(query)
-	if err != nil {
-		return toReturn, errors.New(fmt.Sprintf("failed to marshal query to get all rooms: %s", err))
-	}
-
-	// make request to get rooms
-	var resp roomQueryResponse
-	err = c.MakeRequest("POST", fmt.Sprintf("%v/_find", ROOMS), "application/json", b, &resp)
+	// make request to get rooms, a page at a time
+	var docs []room
+	err := c.findAll(ROOMS, query, &docs)
 	if err != nil {
 		return toReturn, errors.New(fmt.Sprintf("failed to get all rooms: %s", err))
 	}
 
 	// add each doc to toReturn
-	for _, doc := range resp.Docs {
+	for _, doc := range docs {
 		toReturn = append(toReturn, *doc.Room)
 	}
 
@@ -106,21 +99,15 @@ func (c *CouchDB) GetRoomsByBuilding(id string) ([]structs.Room, error) {
 	query.Selector.ID.LT = fmt.Sprintf("%v.", id)
 	query.Limit = 1000
 
-	// marshal query
-	b, err := json.Marshal(query)
-	if err != nil {
-		return toReturn, errors.New(fmt.Sprintf("failed to marshal query to get rooms in building %s: %s", id, err))
-	}
-
-	// make request to get rooms
-	var resp roomQueryResponse
-	err = c.MakeRequest("POST", fmt.Sprintf("%v/_find", ROOMS), "application/json", b, &resp)
+	// make request to get rooms, a page at a time
+	var docs []room
+	err := c.findAll(ROOMS, query, &docs)
 	if err != nil {
 		return toReturn, errors.New(fmt.Sprintf("failed to get rooms in building %s: %s", id, err))
 	}
 
 	// add each doc to toReturn
-	for _, doc := range resp.Docs {
+	for _, doc := range docs {
 		toReturn = append(toReturn, *doc.Room)
 	}
 
@@ -147,6 +134,16 @@ func (c *CouchDB) CreateRoom(toAdd structs.Room) (structs.Room, error) {
 		return toReturn, err
 	}
 
+	// check the room's attributes against its designation's attribute schema
+	schema, err := c.roomAttributeSchema(toAdd.Designation)
+	if err != nil {
+		return toReturn, fmt.Errorf("unable to create room %s: %s", toAdd.ID, err)
+	}
+
+	if err := toAdd.ApplyAttributeSchema(schema); err != nil {
+		return toReturn, err
+	}
+
 	// ensure it's in a real building
 	_, err = c.GetBuilding(strings.Split(toAdd.ID, "-")[0])
 	if err != nil {
@@ -181,15 +178,8 @@ func (c *CouchDB) CreateRoom(toAdd structs.Room) (structs.Room, error) {
 	// don't post devices to room table
 	toAdd.Devices = []structs.Device{}
 
-	// marshal room
-	b, err := json.Marshal(toAdd)
-	if err != nil {
-		return toReturn, errors.New(fmt.Sprintf("failed to marshal room %s: %s", toAdd.ID, err))
-	}
-
 	// post up room!
-	var resp CouchUpsertResponse
-	err = c.MakeRequest("POST", fmt.Sprintf("%v", ROOMS), "application/json", b, &resp)
+	err = c.createDoc(ROOMS, toAdd.ID, toAdd)
 	if err != nil {
 		if _, ok := err.(*Conflict); ok { // there was a conflict creating room
 			return toReturn, errors.New(fmt.Sprintf("unable to create new room, because it already exists. error: %s", err))
@@ -260,6 +250,16 @@ func (c *CouchDB) UpdateRoom(id string, room structs.Room) (structs.Room, error)
 		return toReturn, err
 	}
 
+	// check the room's attributes against its designation's attribute schema
+	schema, err := c.roomAttributeSchema(room.Designation)
+	if err != nil {
+		return toReturn, fmt.Errorf("failed to update room %s: %s", id, err)
+	}
+
+	if err := room.ApplyAttributeSchema(schema); err != nil {
+		return toReturn, err
+	}
+
 	// verify the room configuration is real, if it isn't, then create it
 	config, err := c.GetRoomConfiguration(room.Configuration.ID)
 	if err != nil {
@@ -278,62 +278,65 @@ func (c *CouchDB) UpdateRoom(id string, room structs.Room) (structs.Room, error)
 	room.Devices = nil
 	room.Configuration = structs.RoomConfiguration{ID: config.ID}
 
-	// get the current room
-	r, err := c.getRoom(id)
-	if err != nil {
-		return toReturn, errors.New(fmt.Sprintf("unable to get room %s to update: %s'", id, err))
-	}
-
 	if id == room.ID { // the room ID isn't changing
-		// marshal the updated room
-		b, err := json.Marshal(room)
+		// update the room, as long as it hasn't changed since room.Rev
+		rev, err := c.updateDoc(ROOMS, id, room.Rev, room)
 		if err != nil {
-			return toReturn, errors.New(fmt.Sprintf("failed to unmarshal new room %s: %s", id, err))
-		}
+			if _, ok := err.(*Conflict); ok {
+				return toReturn, err
+			}
 
-		// update the room
-		err = c.MakeRequest("PUT", fmt.Sprintf("%v/%s?rev=%v", ROOMS, id, r.Rev), "application/json", b, &toReturn)
-		if err != nil {
 			return toReturn, errors.New(fmt.Sprintf("failed to update room %s: %s", id, err))
 		}
-	} else { // the room ID is changing
-		// delete the old room
-		err = c.DeleteRoom(id)
-		if err != nil {
-			return toReturn, errors.New(fmt.Sprintf("failed to delete old room %s: %s", id, err))
-		}
 
-		// move the old room's devices into the new room's devices, and edit their ID's
-		for _, device := range room.Devices {
-			device.ID = strings.Replace(device.ID, id, room.ID, 1)
-			room.Devices = append(room.Devices, device)
+		toReturn = room
+		toReturn.Rev = rev
+	} else { // the room ID is changing
+		// make sure the room hasn't changed before moving it
+		if err := c.checkRev(ROOMS, id, room.Rev); err != nil {
+			return toReturn, err
 		}
 
-		// create new room
-		toReturn, err = c.CreateRoom(room)
+		// move the room, along with its devices and ui config
+		_, err = c.RenameRoom(id, room.ID)
 		if err != nil {
-			return toReturn, errors.New(fmt.Sprintf("failed to update room %s: %s", id, err))
+			return toReturn, errors.New(fmt.Sprintf("failed to move room %s to %s: %s", id, room.ID, err))
 		}
+
+		// then update it with any other changes
+		room.Rev = ""
+		return c.UpdateRoom(room.ID, room)
 	}
 
 	return toReturn, nil
 }
 
 func (c *CouchDB) GetRoomsByDesignation(designation string) ([]structs.Room, *nerr.E) {
-
 	var toReturn []structs.Room
 
-	// get all rooms
-	rooms, err := c.GetAllRooms()
+	rooms, err := c.findRooms(NewQuery().Where("designation", EqualFold(designation)))
 	if err != nil {
 		return toReturn, nerr.Translate(err).Addf("failed to get rooms by room designation.")
 	}
 
-	// filter for ones that have the room configuration
 	for _, room := range rooms {
-		if strings.EqualFold(room.Designation, designation) {
-			toReturn = append(toReturn, room)
-		}
+		toReturn = append(toReturn, *room.Room)
+	}
+
+	return toReturn, nil
+}
+
+// findRooms returns each of the rooms matching query.
+func (c *CouchDB) findRooms(query *Query) ([]room, error) {
+	var toReturn []room
+
+	if query.limit <= 0 {
+		query.Limit(1000)
+	}
+
+	err := c.Find(ROOMS, query, &toReturn)
+	if err != nil {
+		return toReturn, fmt.Errorf("failed to find rooms: %s", err)
 	}
 
 	return toReturn, nil
@@ -397,3 +400,143 @@ func (c *CouchDB) GetRoomAttachments(room string) ([]string, error) {
 	log.L.Infof("This is the data now: ", toReturn)
 	return toReturn, nil
 }
+
+// CreateBulkRooms validates and creates each of the rooms with a single _bulk_docs request per batch.
+// Room configurations that don't exist yet are created once a room using them is. As with CreateRoom,
+// any devices included in a room are created after the room. Their responses, and then the devices', follow the rooms'.
+func (c *CouchDB) CreateBulkRooms(rooms []structs.Room) []structs.BulkUpdateResponse {
+	toReturn := c.writeBulkRooms(rooms, false)
+
+	var devices []structs.Device
+	for i := range rooms {
+		if toReturn[i].Success {
+			devices = append(devices, rooms[i].Devices...)
+		}
+	}
+
+	if len(devices) > 0 {
+		toReturn = append(toReturn, c.CreateBulkDevices(devices)...)
+	}
+
+	return toReturn
+}
+
+// UpdateBulkRooms validates and updates each of the rooms, which must already exist. The devices in each room are not changed.
+func (c *CouchDB) UpdateBulkRooms(rooms []structs.Room) []structs.BulkUpdateResponse {
+	return c.writeBulkRooms(rooms, true)
+}
+
+func (c *CouchDB) writeBulkRooms(rooms []structs.Room, update bool) []structs.BulkUpdateResponse {
+	var ids []string
+	for i := range rooms {
+		ids = append(ids, rooms[i].ID)
+	}
+
+	toReturn := newBulkResponses(ids)
+	v := newBulkValidator(c)
+
+	revs := make(map[string]string)
+	if update {
+		var err error
+		if revs, err = c.getRevs(ROOMS, ids); err != nil {
+			for i := range toReturn {
+				toReturn[i].Message = err.Error()
+			}
+
+			return toReturn
+		}
+	}
+
+	var writes []bulkWrite
+	for i := range rooms {
+		r := rooms[i]
+
+		if err := r.Validate(); err != nil {
+			toReturn[i].Message = err.Error()
+			continue
+		}
+
+		schema, err := v.roomAttributeSchema(r.Designation)
+		if err != nil {
+			toReturn[i].Message = err.Error()
+			continue
+		}
+
+		if err := r.ApplyAttributeSchema(schema); err != nil {
+			toReturn[i].Message = err.Error()
+			continue
+		}
+
+		if err := v.building(strings.Split(r.ID, "-")[0]); err != nil {
+			toReturn[i].Message = err.Error()
+			continue
+		}
+
+		if err := v.roomConfiguration(r.Configuration); err != nil {
+			toReturn[i].Message = err.Error()
+			continue
+		}
+
+		rev, ok := revs[r.ID]
+		if update && !ok {
+			toReturn[i].Message = fmt.Sprintf("unable to update room %s, because it doesn't exist", r.ID)
+			continue
+		}
+
+		// updates without an expected revision overwrite whatever the current revision is
+		if !update || len(r.Rev) == 0 {
+			r.Rev = rev
+		}
+
+		// only the room configuration's ID is stored with the room, and devices aren't stored with it at all
+		r.Configuration = structs.RoomConfiguration{ID: r.Configuration.ID}
+		r.Devices = nil
+
+		writes = append(writes, bulkWrite{index: i, doc: r})
+	}
+
+	c.bulkDocs(ROOMS, writes, toReturn)
+
+	used := make(map[string]bool)
+	for _, write := range writes {
+		if toReturn[write.index].Success {
+			used[rooms[write.index].Configuration.ID] = true
+		}
+	}
+
+	return append(toReturn, v.createNew(used)...)
+}
+
+// DeleteBulkRooms deletes each of the rooms. As with DeleteRoom, the devices in each room are deleted first; a room is only deleted if all of its devices were.
+func (c *CouchDB) DeleteBulkRooms(ids []string) []structs.BulkUpdateResponse {
+	toReturn := newBulkResponses(ids)
+
+	for i, id := range ids {
+		var devices []docRev
+
+		query := NewQuery().Where("_id", Cond{"$gt": id + "-", "$lt": id + "."}).Select("_id")
+		if err := c.Find(DEVICES, query, &devices); err != nil {
+			toReturn[i].Message = fmt.Sprintf("unable to get devices in room %s to delete: %s", id, err)
+			continue
+		}
+
+		if len(devices) == 0 {
+			continue
+		}
+
+		var deviceIDs []string
+		for _, d := range devices {
+			deviceIDs = append(deviceIDs, d.ID)
+		}
+
+		for _, resp := range c.DeleteBulkDevices(deviceIDs) {
+			if !resp.Success {
+				toReturn[i].Message = fmt.Sprintf("unable to delete device %s: %s", resp.ID, resp.Message)
+				break
+			}
+		}
+	}
+
+	c.bulkDelete(ROOMS, ids, toReturn)
+	return toReturn
+}
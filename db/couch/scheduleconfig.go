@@ -17,3 +17,74 @@ func (c *CouchDB) GetScheduleConfig(roomID string) (structs.ScheduleConfig, erro
 
 	return config, nil
 }
+
+// GetAllScheduleConfigs returns the scheduling panel configuration of every room that has one.
+func (c *CouchDB) GetAllScheduleConfigs() ([]structs.ScheduleConfig, error) {
+	var toReturn []structs.ScheduleConfig
+
+	err := c.Find(SCHEDULING_CONFIGS, NewQuery().Where("_id", Gt("")), &toReturn)
+	if err != nil {
+		return toReturn, fmt.Errorf("failed to get all schedule configs: %s", err)
+	}
+
+	return toReturn, nil
+}
+
+// CreateScheduleConfig adds the scheduling panel configuration for a room.
+func (c *CouchDB) CreateScheduleConfig(config structs.ScheduleConfig) (structs.ScheduleConfig, error) {
+	var toReturn structs.ScheduleConfig
+
+	if err := config.Validate(); err != nil {
+		return toReturn, err
+	}
+
+	err := c.createDoc(SCHEDULING_CONFIGS, config.ID, config)
+	if err != nil {
+		if _, ok := err.(*Conflict); ok {
+			return toReturn, fmt.Errorf("unable to create schedule config, because it already exists. error: %s", err)
+		}
+
+		return toReturn, fmt.Errorf("failed to create schedule config for %s: %s", config.ID, err)
+	}
+
+	return c.GetScheduleConfig(config.ID)
+}
+
+// UpdateScheduleConfig updates the scheduling panel configuration for a room. If config.Rev is set, it is only updated if it hasn't changed since that revision.
+func (c *CouchDB) UpdateScheduleConfig(roomID string, config structs.ScheduleConfig) (structs.ScheduleConfig, error) {
+	var toReturn structs.ScheduleConfig
+
+	if err := config.Validate(); err != nil {
+		return toReturn, err
+	}
+
+	if config.ID != roomID {
+		return toReturn, fmt.Errorf("unable to update schedule config for %s: the room of a schedule config can't be changed", roomID)
+	}
+
+	_, err := c.updateDoc(SCHEDULING_CONFIGS, roomID, config.Rev, config)
+	if err != nil {
+		if _, ok := err.(*Conflict); ok {
+			return toReturn, err
+		}
+
+		return toReturn, fmt.Errorf("failed to update schedule config for %s: %s", roomID, err)
+	}
+
+	return c.GetScheduleConfig(roomID)
+}
+
+// DeleteScheduleConfig deletes the scheduling panel configuration for a room.
+func (c *CouchDB) DeleteScheduleConfig(roomID string) error {
+	config, err := c.GetScheduleConfig(roomID)
+	if err != nil {
+		return fmt.Errorf("failed to get schedule config %s to delete: %s", roomID, err)
+	}
+
+	err = c.MakeRequest("DELETE", fmt.Sprintf("%v/%v?rev=%v", SCHEDULING_CONFIGS, roomID, config.Rev), "", nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete schedule config for %s: %s", roomID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,377 @@
+package couch
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/byuoitav/common/structs"
+)
+
+// snapshotDoc is a document to import, and the database it goes in.
+type snapshotDoc struct {
+	database string
+	id       string
+	doc      interface{}
+}
+
+// ExportSnapshot returns every configuration document in the database. Revisions aren't included, so the snapshot can be imported into any database.
+func (c *CouchDB) ExportSnapshot() (structs.Snapshot, error) {
+	snapshot := structs.Snapshot{
+		Version:   structs.SnapshotVersion,
+		CreatedAt: time.Now(),
+	}
+
+	exports := []struct {
+		database string
+		toFill   interface{}
+	}{
+		{BUILDINGS, &snapshot.Buildings},
+		{ROOMS, &snapshot.Rooms},
+		{DEVICES, &snapshot.Devices},
+		{DEVICE_TYPES, &snapshot.DeviceTypes},
+		{ROOM_CONFIGURATIONS, &snapshot.RoomConfigurations},
+		{UI_CONFIGS, &snapshot.UIConfigs},
+		{ATTRIBUTES, &snapshot.AttributeGroups},
+		{DEPLOY, &snapshot.DeploymentInfo},
+		{CAMPUS, &snapshot.DeviceDeploymentInfo},
+	}
+
+	for _, export := range exports {
+		if err := c.Find(export.database, NewQuery().Where("_id", Gt("\x00")), export.toFill); err != nil {
+			return snapshot, fmt.Errorf("unable to export %s: %s", export.database, err)
+		}
+	}
+
+	for i := range snapshot.Buildings {
+		snapshot.Buildings[i].Rev = ""
+	}
+
+	for i := range snapshot.Rooms {
+		snapshot.Rooms[i].Rev = ""
+	}
+
+	for i := range snapshot.Devices {
+		snapshot.Devices[i].Rev = ""
+	}
+
+	for i := range snapshot.DeviceTypes {
+		snapshot.DeviceTypes[i].Rev = ""
+	}
+
+	for i := range snapshot.RoomConfigurations {
+		snapshot.RoomConfigurations[i].Rev = ""
+	}
+
+	for i := range snapshot.UIConfigs {
+		snapshot.UIConfigs[i].Rev = ""
+	}
+
+	for i := range snapshot.AttributeGroups {
+		snapshot.AttributeGroups[i].Rev = ""
+	}
+
+	templates, err := c.GetAllTemplates()
+	if err != nil {
+		return snapshot, fmt.Errorf("unable to export templates: %s", err)
+	}
+
+	snapshot.Options.Templates = templates
+
+	var (
+		icons       icons
+		roles       deviceRoles
+		desigs      roomDesignations
+		codes       closureCodes
+		tagList     tags
+		menuTreeDoc menu
+		schemaDoc   roomSchemas
+	)
+
+	options := map[string]interface{}{
+		ICONS:             &icons,
+		ROLES:             &roles,
+		ROOM_DESIGNATIONS: &desigs,
+		CLOSURE_CODES:     &codes,
+		TAGS:              &tagList,
+		MENUTREE:          &menuTreeDoc,
+		ROOM_SCHEMAS:      &schemaDoc,
+	}
+
+	for id, toFill := range options {
+		err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", OPTIONS, id), "", nil, toFill)
+		switch err.(type) {
+		case nil, *NotFound:
+		default:
+			return snapshot, fmt.Errorf("unable to export %s: %s", id, err)
+		}
+	}
+
+	snapshot.Options.Icons = icons.IconList
+	snapshot.Options.Roles = roles.RoleList
+	snapshot.Options.RoomDesignations = desigs.DesigList
+	snapshot.Options.ClosureCodes = codes.Codes
+	snapshot.Options.Tags = tagList.TagList
+	snapshot.Options.MenuTree = menuTreeDoc.Order
+	snapshot.Options.RoomAttributeSchemas = schemaDoc.Schemas
+
+	return snapshot, nil
+}
+
+/*
+ImportSnapshot writes each of the documents in snapshot. Documents that already exist are
+skipped or overwritten, depending on strategy; with structs.ConflictFail, nothing is written
+if any of them already exist.
+
+Documents are written as they are in the snapshot, without being validated, and in an order
+that keeps references between them intact (e.g. buildings before rooms, rooms before devices).
+Snapshots don't include attachments, so overwritten documents keep the attachments they have.
+*/
+func (c *CouchDB) ImportSnapshot(snapshot structs.Snapshot, strategy structs.ConflictStrategy) (structs.ImportResult, error) {
+	result := structs.ImportResult{Strategy: strategy}
+
+	if err := snapshot.Check(strategy); err != nil {
+		return result, err
+	}
+
+	docs := snapshotDocs(snapshot)
+
+	// find which documents already exist, database by database
+	var order []string
+	byDatabase := make(map[string][]snapshotDoc)
+	for _, doc := range docs {
+		if _, ok := byDatabase[doc.database]; !ok {
+			order = append(order, doc.database)
+		}
+
+		byDatabase[doc.database] = append(byDatabase[doc.database], doc)
+	}
+
+	revs := make(map[string]map[string]string)
+	for _, database := range order {
+		var ids []string
+		for _, doc := range byDatabase[database] {
+			ids = append(ids, doc.id)
+		}
+
+		existing, err := c.getRevs(database, ids)
+		if err != nil {
+			return result, fmt.Errorf("unable to check for existing documents: %s", err)
+		}
+
+		revs[database] = existing
+	}
+
+	if strategy == structs.ConflictFail {
+		for _, doc := range docs {
+			if _, ok := revs[doc.database][doc.id]; ok {
+				result.Documents = append(result.Documents, structs.ImportedDocument{
+					Database: doc.database,
+					ID:       doc.id,
+					Action:   structs.ImportFailed,
+					Message:  "already exists",
+				})
+			}
+		}
+
+		if len(result.Documents) > 0 {
+			return result, fmt.Errorf("unable to import snapshot: %v documents already exist", len(result.Documents))
+		}
+	}
+
+	for _, database := range order {
+		dbDocs := byDatabase[database]
+
+		// overwriting a document without its attachments would delete them
+		var attachments map[string]json.RawMessage
+		if strategy == structs.ConflictOverwrite && len(revs[database]) > 0 {
+			var existing []string
+			for id := range revs[database] {
+				existing = append(existing, id)
+			}
+
+			var err error
+			attachments, err = c.getAttachmentStubs(database, existing)
+			if err != nil {
+				return result, fmt.Errorf("unable to get attachments of existing documents: %s", err)
+			}
+		}
+
+		var ids []string
+		var writes []bulkWrite
+		actions := make([]structs.ImportAction, len(dbDocs))
+
+		for i, doc := range dbDocs {
+			ids = append(ids, doc.id)
+
+			b, err := json.Marshal(doc.doc)
+			if err != nil {
+				return result, fmt.Errorf("unable to marshal %s: %s", doc.id, err)
+			}
+
+			var raw map[string]interface{}
+			if err := json.Unmarshal(b, &raw); err != nil {
+				return result, fmt.Errorf("unable to marshal %s: %s", doc.id, err)
+			}
+
+			raw["_id"] = doc.id
+			delete(raw, "_rev")
+
+			actions[i] = structs.ImportCreated
+			if rev, ok := revs[database][doc.id]; ok {
+				if strategy == structs.ConflictSkip {
+					actions[i] = structs.ImportSkipped
+					continue
+				}
+
+				raw["_rev"] = rev
+				actions[i] = structs.ImportOverwritten
+
+				if stubs, ok := attachments[doc.id]; ok {
+					raw["_attachments"] = stubs
+				}
+			}
+
+			writes = append(writes, bulkWrite{index: i, doc: raw})
+		}
+
+		responses := newBulkResponses(ids)
+		c.bulkDocs(database, writes, responses)
+
+		for i, resp := range responses {
+			imported := structs.ImportedDocument{
+				Database: database,
+				ID:       resp.ID,
+				Action:   actions[i],
+			}
+
+			if actions[i] != structs.ImportSkipped && !resp.Success {
+				imported.Action = structs.ImportFailed
+				imported.Message = resp.Message
+			}
+
+			result.Documents = append(result.Documents, imported)
+		}
+	}
+
+	if failed := result.Failed(); len(failed) > 0 {
+		return result, fmt.Errorf("unable to import %v of the %v documents in the snapshot", len(failed), len(result.Documents))
+	}
+
+	return result, nil
+}
+
+// getAttachmentStubs returns the _attachments stubs of each of ids in database that has attachments.
+func (c *CouchDB) getAttachmentStubs(database string, ids []string) (map[string]json.RawMessage, error) {
+	toReturn := make(map[string]json.RawMessage)
+
+	for start := 0; start < len(ids); start += BulkBatchSize {
+		end := start + BulkBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		b, err := json.Marshal(allDocsRequest{Keys: ids[start:end]})
+		if err != nil {
+			return toReturn, fmt.Errorf("failed to marshal keys: %s", err)
+		}
+
+		var resp struct {
+			Rows []struct {
+				Key string `json:"key"`
+				Doc *struct {
+					Attachments json.RawMessage `json:"_attachments"`
+				} `json:"doc"`
+			} `json:"rows"`
+		}
+
+		err = c.MakeRequest("POST", fmt.Sprintf("%v/_all_docs?include_docs=true", database), "application/json", b, &resp)
+		if err != nil {
+			return toReturn, fmt.Errorf("failed to get attachments from %s: %s", database, err)
+		}
+
+		for _, row := range resp.Rows {
+			if row.Doc != nil && len(row.Doc.Attachments) > 0 && string(row.Doc.Attachments) != "null" {
+				toReturn[row.Key] = row.Doc.Attachments
+			}
+		}
+	}
+
+	return toReturn, nil
+}
+
+// snapshotDocs returns each of the documents in snapshot, in the order they should be imported.
+func snapshotDocs(snapshot structs.Snapshot) []snapshotDoc {
+	var docs []snapshotDoc
+
+	for _, rc := range snapshot.RoomConfigurations {
+		docs = append(docs, snapshotDoc{ROOM_CONFIGURATIONS, rc.ID, rc})
+	}
+
+	for _, dt := range snapshot.DeviceTypes {
+		docs = append(docs, snapshotDoc{DEVICE_TYPES, dt.ID, dt})
+	}
+
+	for _, b := range snapshot.Buildings {
+		docs = append(docs, snapshotDoc{BUILDINGS, b.ID, b})
+	}
+
+	for _, r := range snapshot.Rooms {
+		docs = append(docs, snapshotDoc{ROOMS, r.ID, r})
+	}
+
+	for _, d := range snapshot.Devices {
+		docs = append(docs, snapshotDoc{DEVICES, d.ID, d})
+	}
+
+	for _, ui := range snapshot.UIConfigs {
+		docs = append(docs, snapshotDoc{UI_CONFIGS, ui.ID, ui})
+	}
+
+	for i := range snapshot.Options.Templates {
+		t := snapshot.Options.Templates[i]
+		docs = append(docs, snapshotDoc{OPTIONS, t.ID, template{Template: &t}})
+	}
+
+	if len(snapshot.Options.Icons) > 0 {
+		docs = append(docs, snapshotDoc{OPTIONS, ICONS, icons{IconList: snapshot.Options.Icons}})
+	}
+
+	if len(snapshot.Options.Roles) > 0 {
+		docs = append(docs, snapshotDoc{OPTIONS, ROLES, deviceRoles{RoleList: snapshot.Options.Roles}})
+	}
+
+	if len(snapshot.Options.RoomDesignations) > 0 {
+		docs = append(docs, snapshotDoc{OPTIONS, ROOM_DESIGNATIONS, roomDesignations{DesigList: snapshot.Options.RoomDesignations}})
+	}
+
+	if len(snapshot.Options.ClosureCodes) > 0 {
+		docs = append(docs, snapshotDoc{OPTIONS, CLOSURE_CODES, closureCodes{Codes: snapshot.Options.ClosureCodes}})
+	}
+
+	if len(snapshot.Options.Tags) > 0 {
+		docs = append(docs, snapshotDoc{OPTIONS, TAGS, tags{TagList: snapshot.Options.Tags}})
+	}
+
+	if len(snapshot.Options.MenuTree) > 0 {
+		docs = append(docs, snapshotDoc{OPTIONS, MENUTREE, menu{Order: snapshot.Options.MenuTree}})
+	}
+
+	if len(snapshot.Options.RoomAttributeSchemas) > 0 {
+		docs = append(docs, snapshotDoc{OPTIONS, ROOM_SCHEMAS, roomSchemas{Schemas: snapshot.Options.RoomAttributeSchemas}})
+	}
+
+	for _, g := range snapshot.AttributeGroups {
+		docs = append(docs, snapshotDoc{ATTRIBUTES, g.ID, g})
+	}
+
+	for _, d := range snapshot.DeploymentInfo {
+		docs = append(docs, snapshotDoc{DEPLOY, d.ID, d})
+	}
+
+	for _, d := range snapshot.DeviceDeploymentInfo {
+		docs = append(docs, snapshotDoc{CAMPUS, d.ID, d})
+	}
+
+	return docs
+}
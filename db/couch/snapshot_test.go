@@ -0,0 +1,68 @@
+package couch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/byuoitav/common/structs"
+)
+
+func TestImportSnapshot(t *testing.T) {
+	var written []map[string]interface{}
+
+	c, srv := newFakeCouch(t, fakeRoutes{
+		"POST /buildings/_all_docs": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("include_docs") == "true" {
+				w.Write([]byte(`{"rows": [{"key": "AAA", "id": "AAA", "doc": {"_id": "AAA", "_rev": "1-aaa", "_attachments": {"map.png": {"content_type": "image/png", "stub": true}}}}]}`))
+				return
+			}
+
+			w.Write([]byte(`{"rows": [{"key": "AAA", "id": "AAA", "value": {"rev": "1-aaa"}}, {"key": "BBB", "error": "not_found"}]}`))
+		},
+		"POST /buildings/_bulk_docs": func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+
+			var req struct {
+				Docs []map[string]interface{} `json:"docs"`
+			}
+			json.Unmarshal(b, &req)
+			written = append(written, req.Docs...)
+
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`[{"ok": true, "id": "AAA", "rev": "2-aaa"}, {"ok": true, "id": "BBB", "rev": "1-bbb"}]`))
+		},
+	})
+	defer srv.Close()
+
+	snapshot := structs.Snapshot{
+		Version:   structs.SnapshotVersion,
+		Buildings: []structs.Building{{ID: "AAA", Name: "AAA"}, {ID: "BBB", Name: "BBB"}},
+	}
+
+	if _, err := c.ImportSnapshot(snapshot, structs.ConflictFail); err == nil {
+		t.Fatalf("expected the import to fail, because AAA already exists")
+	}
+
+	if len(written) != 0 {
+		t.Fatalf("nothing should be written when the import fails: %v", written)
+	}
+
+	result, err := c.ImportSnapshot(snapshot, structs.ConflictOverwrite)
+	if err != nil {
+		t.Fatalf("failed to import snapshot: %s", err)
+	}
+
+	if len(result.Documents) != 2 || result.Documents[0].Action != structs.ImportOverwritten || result.Documents[1].Action != structs.ImportCreated {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	if len(written) != 2 || written[0]["_rev"] != "1-aaa" || written[1]["_rev"] != nil {
+		t.Fatalf("expected AAA to be overwritten, and BBB to be created: %v", written)
+	}
+
+	if attachments, ok := written[0]["_attachments"].(map[string]interface{}); !ok || attachments["map.png"] == nil {
+		t.Fatalf("expected AAA to keep its attachments: %v", written[0])
+	}
+}
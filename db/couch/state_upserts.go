@@ -0,0 +1,258 @@
+package couch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	sd "github.com/byuoitav/common/state/statedefinition"
+	"github.com/byuoitav/common/structs"
+)
+
+// StateUpsertAttempts is how many times a state document is read, merged, and written before giving up because it keeps being changed by someone else.
+var StateUpsertAttempts = 5
+
+type deviceStateDoc struct {
+	ID  string `json:"_id"`
+	Rev string `json:"_rev,omitempty"`
+	sd.StaticDevice
+}
+
+type roomStateDoc struct {
+	ID  string `json:"_id"`
+	Rev string `json:"_rev,omitempty"`
+	sd.StaticRoom
+}
+
+/*
+UpsertDeviceState merges state into the current state of its device (see statedefinition.MergeDevices),
+creating it if the device doesn't have one yet. The document is only written if the merge
+changed something, which is what the returned bool reports.
+
+If the document is changed by someone else between being read and written, it is read and
+merged again, up to StateUpsertAttempts times.
+*/
+func (c *CouchDB) UpsertDeviceState(state sd.StaticDevice) (sd.StaticDevice, bool, error) {
+	var merged sd.StaticDevice
+
+	if len(state.DeviceID) == 0 {
+		return merged, false, errors.New("unable to upsert device state: missing deviceID")
+	}
+
+	changed, err := c.upsertState(DEVICE_STATES, state.DeviceID, func(current []byte) (interface{}, bool, error) {
+		doc := deviceStateDoc{ID: state.DeviceID}
+		if current != nil {
+			if err := json.Unmarshal(current, &doc); err != nil {
+				return nil, false, err
+			}
+		}
+
+		var changed bool
+		merged, changed = sd.MergeDevices(doc.StaticDevice, state)
+		doc.StaticDevice = merged
+
+		return doc, changed || current == nil, nil
+	})
+	if err != nil {
+		return merged, false, fmt.Errorf("unable to upsert device state for %s: %s", state.DeviceID, err)
+	}
+
+	return merged, changed, nil
+}
+
+// UpsertBulkDeviceStates upserts each of states (see UpsertDeviceState). A state that didn't change anything is successful, with the message "unchanged".
+func (c *CouchDB) UpsertBulkDeviceStates(states []sd.StaticDevice) []structs.BulkUpdateResponse {
+	var ids []string
+	for _, state := range states {
+		ids = append(ids, state.DeviceID)
+	}
+
+	var current []deviceStateDoc
+	responses := c.upsertBulkStates(DEVICE_STATES, ids, &current, func(i int) (interface{}, bool) {
+		doc := deviceStateDoc{ID: ids[i]}
+		for _, cur := range current {
+			if cur.ID == ids[i] {
+				doc = cur
+			}
+		}
+
+		var changed bool
+		doc.StaticDevice, changed = sd.MergeDevices(doc.StaticDevice, states[i])
+		return doc, changed || len(doc.Rev) == 0
+	})
+
+	// anything that couldn't be written in bulk is retried on its own, which re-reads it
+	for i := range responses {
+		if !responses[i].Success && len(ids[i]) > 0 {
+			_, changed, err := c.UpsertDeviceState(states[i])
+			setUpsertResponse(&responses[i], changed, err)
+		}
+	}
+
+	return responses
+}
+
+// GetRoomState returns the state document of a room.
+func (c *CouchDB) GetRoomState(roomID string) (sd.StaticRoom, error) {
+	var toReturn roomStateDoc
+
+	err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", ROOM_STATES, roomID), "", nil, &toReturn)
+	if err != nil {
+		return toReturn.StaticRoom, fmt.Errorf("failed to get room state for %s: %s", roomID, err)
+	}
+
+	return toReturn.StaticRoom, nil
+}
+
+// UpsertRoomState merges state into the current state of its room (see statedefinition.MergeRooms), in the same way as UpsertDeviceState.
+func (c *CouchDB) UpsertRoomState(state sd.StaticRoom) (sd.StaticRoom, bool, error) {
+	var merged sd.StaticRoom
+
+	if len(state.RoomID) == 0 {
+		return merged, false, errors.New("unable to upsert room state: missing roomID")
+	}
+
+	changed, err := c.upsertState(ROOM_STATES, state.RoomID, func(current []byte) (interface{}, bool, error) {
+		doc := roomStateDoc{ID: state.RoomID}
+		if current != nil {
+			if err := json.Unmarshal(current, &doc); err != nil {
+				return nil, false, err
+			}
+		}
+
+		var changed bool
+		merged, changed = sd.MergeRooms(doc.StaticRoom, state)
+		doc.StaticRoom = merged
+
+		return doc, changed || current == nil, nil
+	})
+	if err != nil {
+		return merged, false, fmt.Errorf("unable to upsert room state for %s: %s", state.RoomID, err)
+	}
+
+	return merged, changed, nil
+}
+
+// UpsertBulkRoomStates upserts each of states (see UpsertRoomState). A state that didn't change anything is successful, with the message "unchanged".
+func (c *CouchDB) UpsertBulkRoomStates(states []sd.StaticRoom) []structs.BulkUpdateResponse {
+	var ids []string
+	for _, state := range states {
+		ids = append(ids, state.RoomID)
+	}
+
+	var current []roomStateDoc
+	responses := c.upsertBulkStates(ROOM_STATES, ids, &current, func(i int) (interface{}, bool) {
+		doc := roomStateDoc{ID: ids[i]}
+		for _, cur := range current {
+			if cur.ID == ids[i] {
+				doc = cur
+			}
+		}
+
+		var changed bool
+		doc.StaticRoom, changed = sd.MergeRooms(doc.StaticRoom, states[i])
+		return doc, changed || len(doc.Rev) == 0
+	})
+
+	for i := range responses {
+		if !responses[i].Success && len(ids[i]) > 0 {
+			_, changed, err := c.UpsertRoomState(states[i])
+			setUpsertResponse(&responses[i], changed, err)
+		}
+	}
+
+	return responses
+}
+
+// upsertState reads id from database, passes it (or nil if it doesn't exist) to merge, and writes the document merge returns if it changed. Conflicting writes are retried from the read.
+func (c *CouchDB) upsertState(database, id string, merge func(current []byte) (interface{}, bool, error)) (bool, error) {
+	for attempt := 1; ; attempt++ {
+		var current json.RawMessage
+
+		err := c.MakeRequest("GET", fmt.Sprintf("%v/%v", database, id), "", nil, &current)
+		switch err.(type) {
+		case nil:
+		case *NotFound:
+			current = nil
+		default:
+			return false, err
+		}
+
+		doc, changed, err := merge(current)
+		if err != nil {
+			return false, err
+		}
+
+		if !changed {
+			return false, nil
+		}
+
+		b, err := json.Marshal(doc)
+		if err != nil {
+			return false, fmt.Errorf("unable to marshal %s: %s", id, err)
+		}
+
+		var resp CouchUpsertResponse
+		err = c.MakeRequest("PUT", fmt.Sprintf("%v/%v", database, id), "application/json", b, &resp)
+		if _, ok := err.(*Conflict); ok && attempt < StateUpsertAttempts {
+			continue
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
+	}
+}
+
+// upsertBulkStates reads each of ids from database into current (a pointer to a slice of state documents), and writes each of the documents that merge changes in a single bulk request.
+func (c *CouchDB) upsertBulkStates(database string, ids []string, current interface{}, merge func(i int) (interface{}, bool)) []structs.BulkUpdateResponse {
+	responses := newBulkResponses(ids)
+
+	var keys []interface{}
+	for _, id := range ids {
+		keys = append(keys, id)
+	}
+
+	if err := c.Find(database, NewQuery().Where("_id", In(keys...)), current); err != nil {
+		for i := range responses {
+			responses[i].Message = fmt.Sprintf("failed to get current states: %s", err)
+		}
+
+		return responses
+	}
+
+	var writes []bulkWrite
+	for i, id := range ids {
+		if len(id) == 0 {
+			responses[i].Message = "missing id"
+			continue
+		}
+
+		doc, changed := merge(i)
+		if !changed {
+			responses[i].Success = true
+			responses[i].Message = "unchanged"
+			continue
+		}
+
+		writes = append(writes, bulkWrite{index: i, doc: doc})
+	}
+
+	c.bulkDocs(database, writes, responses)
+	return responses
+}
+
+func setUpsertResponse(response *structs.BulkUpdateResponse, changed bool, err error) {
+	switch {
+	case err != nil:
+		response.Message = err.Error()
+	case changed:
+		response.Success = true
+		response.Message = ""
+	default:
+		response.Success = true
+		response.Message = "unchanged"
+	}
+}
@@ -0,0 +1,74 @@
+package couch
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	sd "github.com/byuoitav/common/state/statedefinition"
+)
+
+func TestUpsertDeviceStateConflict(t *testing.T) {
+	then := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := then.Add(time.Hour)
+
+	current := []string{
+		`{"_id": "AAA-1-D1", "_rev": "1-aaa", "deviceID": "AAA-1-D1", "power": "standby", "field-state-received": {"power": "2019-01-01T00:00:00Z"}}`,
+		`{"_id": "AAA-1-D1", "_rev": "2-bbb", "deviceID": "AAA-1-D1", "power": "standby", "input": "hdmi1", "field-state-received": {"power": "2019-01-01T00:00:00Z", "input": "2019-01-01T00:00:00Z"}}`,
+	}
+
+	var gets, puts int
+	var written deviceStateDoc
+
+	c, srv := newFakeCouch(t, fakeRoutes{
+		"GET /device-state/AAA-1-D1": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(current[gets]))
+			gets++
+		},
+		"PUT /device-state/AAA-1-D1": func(w http.ResponseWriter, r *http.Request) {
+			puts++
+			if err := json.NewDecoder(r.Body).Decode(&written); err != nil {
+				t.Errorf("unable to decode device state: %s", err)
+			}
+
+			// someone else wrote the document between the first read and write
+			if written.Rev != "2-bbb" {
+				respondConflict(w, r)
+				return
+			}
+
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"ok": true, "id": "AAA-1-D1", "rev": "3-ccc"}`))
+		},
+	})
+	defer srv.Close()
+
+	state := sd.StaticDevice{
+		DeviceID:    "AAA-1-D1",
+		Power:       "on",
+		UpdateTimes: map[string]time.Time{"power": now},
+	}
+
+	merged, changed, err := c.UpsertDeviceState(state)
+	if err != nil {
+		t.Fatalf("failed to upsert device state: %s", err)
+	}
+
+	if !changed || gets != 2 || puts != 2 {
+		t.Fatalf("expected the conflict to be retried (changed: %v, gets: %v, puts: %v)", changed, gets, puts)
+	}
+
+	if merged.Power != "on" || merged.Input != "hdmi1" || written.Power != "on" || written.Input != "hdmi1" {
+		t.Fatalf("state wasn't merged into the current document: %+v", written)
+	}
+
+	// an older update doesn't change anything, so nothing is written
+	gets, puts = 1, 0
+	state.Power = "standby"
+	state.UpdateTimes["power"] = then.Add(-time.Hour)
+
+	if _, changed, err := c.UpsertDeviceState(state); err != nil || changed || puts != 0 {
+		t.Fatalf("expected an older update to be ignored (changed: %v, puts: %v, err: %v)", changed, puts, err)
+	}
+}
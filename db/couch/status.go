@@ -3,8 +3,6 @@ package couch
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"time"
 
 	l "github.com/byuoitav/common/log"
@@ -13,60 +11,58 @@ import (
 )
 
 type couchReplicationState struct {
-	Database    string    `json:"database"`
-	DocID       string    `json:"doc_id"`
-	ID          string    `json:"id"`
-	Source      string    `json:"source"`
-	Target      string    `json:"target"`
-	State       string    `json:"state"`
-	ErrorCount  int       `json:"error_count"`
-	StartTime   time.Time `json:"start_time"`
-	LastUpdated time.Time `json:"last_updated"`
+	Database    string          `json:"database"`
+	DocID       string          `json:"doc_id"`
+	ID          string          `json:"id"`
+	Source      string          `json:"source"`
+	Target      string          `json:"target"`
+	State       string          `json:"state"`
+	Info        json.RawMessage `json:"info"`
+	ErrorCount  int             `json:"error_count"`
+	StartTime   time.Time       `json:"start_time"`
+	LastUpdated time.Time       `json:"last_updated"`
 }
 
-type couchReplicationPayload struct {
-	ID           string      `json:"_id"`
-	Rev          string      `json:"_rev,omitempty"`
-	Source       string      `json:"source"`
-	Target       string      `json:"target"`
-	CreateTarget bool        `json:"create_target"`
-	Continuous   bool        `json:"continous"`
-	Selector     interface{} `json:"selector,omitempty"`
-}
+/*
+GetStatus returns the state of the auto_devices replication, which is what requests wait on
+before being allowed. While couch is down and requests are failing fast, it returns
+"circuit-open" instead.
 
-//Simply returns the replication state.
+Other replications don't affect the state. If any of them have failed, the state is still
+returned, along with a *ReplicationFailed describing each failed replication by database.
+*/
 func (c *CouchDB) GetStatus() (string, error) {
+	if c.BreakerState() == BreakerOpen {
+		return "circuit-open", &Unavailable{"requests to couch are failing fast, because too many requests in a row have failed"}
+	}
+
 	//check the state of the devices index to see if it's replication or ready.
 	state, err := c.CheckReplication("auto_devices")
 	if err != nil {
 		return "not-ready", err
 	}
 
+	if err := c.FailedReplications(); err != nil {
+		if _, ok := err.(*ReplicationFailed); ok {
+			return state, err
+		}
+
+		l.L.Warnf("unable to check the state of the other replications: %s", err)
+	}
+
 	return state, nil
 }
 
 func (c *CouchDB) CheckReplication(replID string) (string, *nerr.E) {
 	l.L.Debugf("Checking to see if replication document %v is already scheduled", replID)
 
-	req, err := http.NewRequest("GET", fmt.Sprintf("%v/_scheduler/docs/_replicator/%v", c.address, replID), nil)
-	if err != nil {
-		return "", nerr.Translate(err).Addf("Couldn't create request to check replication of %v", replID)
-	}
-
-	req.SetBasicAuth(c.username, c.password)
-	client := http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do("GET", fmt.Sprintf("%v/_scheduler/docs/_replicator/%v", c.address, replID), "", nil)
 	if err != nil {
 		return "", nerr.Translate(err).Addf("Couldn't make request to check replication of %v", replID)
 	}
 
-	defer resp.Body.Close()
-	b, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", nerr.Translate(err).Addf("Couldn't read error response from couch server while checking for replication %v", replID)
-	}
-
-	if resp.StatusCode/100 != 2 {
+	b := resp.body
+	if resp.status/100 != 2 {
 		ce := CouchError{}
 		err = json.Unmarshal(b, &ce)
 		if err != nil {
@@ -74,7 +70,7 @@ func (c *CouchDB) CheckReplication(replID string) (string, *nerr.E) {
 		}
 
 		err = CheckCouchErrors(ce)
-		if _, ok := err.(*NotFound); resp.StatusCode == 404 && ok {
+		if _, ok := err.(*NotFound); resp.status == 404 && ok {
 			return "not_started", nil
 		}
 
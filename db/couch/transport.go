@@ -0,0 +1,360 @@
+package couch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/common/log"
+)
+
+// Options configures how a CouchDB talks to couch.
+type Options struct {
+	// Client makes the requests against couch. By default, every CouchDB shares a client, so that connections are reused.
+	Client *http.Client
+
+	// Timeout is how long a single request is allowed to take, if its context doesn't have a deadline.
+	Timeout time.Duration
+
+	// MaxRetries is how many times a failed request is retried. Only requests that are safe to repeat are retried.
+	MaxRetries int
+
+	// MinBackoff and MaxBackoff bound how long to wait before each retry. The wait doubles after each attempt, and is jittered.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// BreakerThreshold is how many requests in a row can fail before requests to couch start failing fast.
+	// A threshold of 0 disables the circuit breaker.
+	BreakerThreshold int
+
+	// BreakerCooldown is how long requests fail fast before another request is allowed through to check if couch is back.
+	BreakerCooldown time.Duration
+}
+
+// DefaultOptions returns the options used by NewDB.
+func DefaultOptions() Options {
+	return Options{
+		Client:           sharedClient,
+		Timeout:          5 * time.Second,
+		MaxRetries:       3,
+		MinBackoff:       100 * time.Millisecond,
+		MaxBackoff:       2 * time.Second,
+		BreakerThreshold: 5,
+		BreakerCooldown:  10 * time.Second,
+	}
+}
+
+// sharedClient is used by every CouchDB that isn't given its own client.
+var sharedClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   20,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	},
+}
+
+// Unavailable is returned without making a request while the circuit breaker for couch is open.
+type Unavailable struct {
+	msg string
+}
+
+func (u Unavailable) Error() string {
+	return u.msg
+}
+
+// BreakerState is the state of the circuit breaker in front of couch.
+type BreakerState string
+
+const (
+	// BreakerClosed means requests are being made normally.
+	BreakerClosed BreakerState = "closed"
+
+	// BreakerOpen means couch is down, and requests are failing fast.
+	BreakerOpen BreakerState = "open"
+
+	// BreakerHalfOpen means the cooldown has passed, and the next request will check if couch is back.
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+/*
+breaker fails requests fast after threshold requests in a row fail. Once cooldown has passed,
+a single request is let through; if it succeeds, the breaker closes again, and if it fails,
+requests fail fast for another cooldown.
+*/
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = make(map[string]*breaker)
+)
+
+// breakerFor returns the breaker for address, so that every CouchDB talking to the same couch shares one. The options of the first CouchDB for an address are used.
+func breakerFor(address string, threshold int, cooldown time.Duration) *breaker {
+	if threshold <= 0 {
+		return nil
+	}
+
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	if b, ok := breakers[address]; ok {
+		return b
+	}
+
+	b := &breaker{threshold: threshold, cooldown: cooldown}
+	breakers[address] = b
+	return b
+}
+
+// allow returns an error if the request should fail fast.
+func (b *breaker) allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return nil
+	}
+
+	if time.Since(b.openedAt) < b.cooldown {
+		return &Unavailable{fmt.Sprintf("couch is unavailable after %v failed requests in a row; retrying after %v", b.failures, b.openedAt.Add(b.cooldown).Format(time.RFC3339))}
+	}
+
+	// let this request through, and keep failing the others until it is done
+	b.openedAt = time.Now()
+	return nil
+}
+
+func (b *breaker) success() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures >= b.threshold {
+		log.L.Infof("Couch is available again; closing the circuit breaker")
+	}
+
+	b.failures = 0
+}
+
+func (b *breaker) failure() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.failures == b.threshold {
+		log.L.Warnf("%v requests in a row to couch failed; failing requests for the next %v", b.failures, b.cooldown)
+	}
+
+	if b.failures >= b.threshold {
+		b.openedAt = time.Now()
+	}
+}
+
+func (b *breaker) state() BreakerState {
+	if b == nil {
+		return BreakerClosed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case b.failures < b.threshold:
+		return BreakerClosed
+	case time.Since(b.openedAt) < b.cooldown:
+		return BreakerOpen
+	default:
+		return BreakerHalfOpen
+	}
+}
+
+// BreakerState returns the state of the circuit breaker in front of c's couch.
+func (c *CouchDB) BreakerState() BreakerState {
+	return c.breaker.state()
+}
+
+func (c *CouchDB) httpClient() *http.Client {
+	if c.options.Client != nil {
+		return c.options.Client
+	}
+
+	return sharedClient
+}
+
+// send makes a single request against couch through the circuit breaker. The caller must close the response body.
+func (c *CouchDB) send(ctx context.Context, method, url, contentType string, body []byte) (*http.Response, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.username) > 0 && len(c.password) > 0 {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	if len(contentType) > 0 {
+		req.Header.Add("Content-Type", contentType)
+	}
+
+	resp, err := c.httpClient().Do(req.WithContext(ctx))
+	switch {
+	case err != nil && ctx.Err() == context.Canceled:
+		// the caller gave up; that doesn't say anything about couch
+	case err != nil || unavailableStatus(resp.StatusCode):
+		c.breaker.failure()
+	default:
+		c.breaker.success()
+	}
+
+	return resp, err
+}
+
+// response is a response from couch that has been read in full.
+type response struct {
+	status      int
+	contentType string
+	body        []byte
+}
+
+// do makes a request against couch, retrying it with a jittered backoff if it fails in a way that might not happen again.
+func (c *CouchDB) do(method, url, contentType string, body []byte) (response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.attempt(method, url, contentType, body)
+		if attempt >= c.options.MaxRetries || !c.retryable(method, url, resp, err) {
+			return resp, err
+		}
+
+		wait := c.backoff(attempt)
+		log.L.Debugf("Request %s %s failed (status: %v, error: %v); retrying in %v", method, url, resp.status, err, wait)
+
+		select {
+		case <-time.After(wait):
+		case <-c.Context().Done():
+			return resp, err
+		}
+	}
+}
+
+func (c *CouchDB) attempt(method, url, contentType string, body []byte) (response, error) {
+	var toReturn response
+
+	ctx, cancel := c.requestContext()
+	defer cancel()
+
+	resp, err := c.send(ctx, method, url, contentType, body)
+	if err != nil {
+		return toReturn, err
+	}
+	defer resp.Body.Close()
+
+	toReturn.status = resp.StatusCode
+	toReturn.contentType = resp.Header.Get("content-type")
+	toReturn.body, err = ioutil.ReadAll(resp.Body)
+	return toReturn, err
+}
+
+// retryable returns whether a request that got resp and err should be tried again.
+func (c *CouchDB) retryable(method, url string, resp response, err error) bool {
+	if c.Context().Err() != nil {
+		return false
+	}
+
+	if err != nil {
+		if _, ok := err.(*Unavailable); ok {
+			return false
+		}
+
+		// a request that never reached couch is always safe to repeat
+		if dialFailed(err) {
+			return true
+		}
+
+		return idempotent(method, url)
+	}
+
+	return idempotent(method, url) && (unavailableStatus(resp.status) || resp.status == http.StatusTooManyRequests)
+}
+
+// dialFailed returns whether err is from failing to connect to couch.
+func dialFailed(err error) bool {
+	if urlErr, ok := err.(*neturl.Error); ok {
+		err = urlErr.Err
+	}
+
+	opErr, ok := err.(*net.OpError)
+	return ok && opErr.Op == "dial"
+}
+
+// idempotent returns whether a request can be repeated without changing its result. Queries are POSTed, but don't change anything.
+func idempotent(method, url string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	case http.MethodPost:
+		path := strings.SplitN(url, "?", 2)[0]
+		return strings.HasSuffix(path, "/_find") || strings.HasSuffix(path, "/_all_docs")
+	default:
+		return false
+	}
+}
+
+// unavailableStatus returns whether status means couch (or the proxy in front of it) is down.
+func unavailableStatus(status int) bool {
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// backoff returns how long to wait before retry attempt+1: an exponentially increasing wait, half of which is random.
+func (c *CouchDB) backoff(attempt int) time.Duration {
+	wait := c.options.MinBackoff << uint(attempt)
+	if wait > c.options.MaxBackoff || wait <= 0 {
+		wait = c.options.MaxBackoff
+	}
+
+	if wait <= 0 {
+		return 0
+	}
+
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
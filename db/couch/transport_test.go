@@ -0,0 +1,167 @@
+package couch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testOptions() Options {
+	options := DefaultOptions()
+	options.MinBackoff = time.Millisecond
+	options.MaxBackoff = 5 * time.Millisecond
+	return options
+}
+
+func TestRetry(t *testing.T) {
+	var requests int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		// couch is restarting for the first two requests
+		if atomic.AddInt32(&requests, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write([]byte(`{"_id": "AAA", "_rev": "1-aaa"}`))
+	}))
+	defer srv.Close()
+
+	c := NewDBWithOptions(srv.URL, "", "", testOptions())
+	c.IgnoreReadyChecks = true
+
+	var doc docRev
+	if err := c.MakeRequest("GET", "buildings/AAA", "", nil, &doc); err != nil {
+		t.Fatalf("request wasn't retried: %s", err)
+	}
+
+	if atomic.LoadInt32(&requests) != 3 || doc.ID != "AAA" {
+		t.Fatalf("expected 3 requests, got %v (doc: %+v)", requests, doc)
+	}
+
+	// writes aren't retried
+	atomic.StoreInt32(&requests, 0)
+	if err := c.MakeRequest("PUT", "buildings/AAA", "application/json", []byte(`{}`), nil); err == nil {
+		t.Fatalf("expected the write to fail")
+	}
+
+	if atomic.LoadInt32(&requests) != 1 {
+		t.Fatalf("expected the write to be tried once, got %v requests", requests)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var requests int32
+	var down int32 = 1
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write([]byte(`{"state": "completed"}`))
+	}))
+	defer srv.Close()
+
+	options := testOptions()
+	options.MaxRetries = 0
+	options.BreakerThreshold = 2
+	options.BreakerCooldown = 50 * time.Millisecond
+
+	c := NewDBWithOptions(srv.URL, "", "", options)
+	c.IgnoreReadyChecks = true
+
+	for i := 0; i < 2; i++ {
+		c.MakeRequest("GET", "buildings/AAA", "", nil, nil)
+	}
+
+	if state := c.BreakerState(); state != BreakerOpen {
+		t.Fatalf("expected the breaker to be open, got %s", state)
+	}
+
+	err := c.MakeRequest("GET", "buildings/AAA", "", nil, nil)
+	if _, ok := err.(*Unavailable); !ok || atomic.LoadInt32(&requests) != 2 {
+		t.Fatalf("expected the request to fail fast, got %v after %v requests", err, requests)
+	}
+
+	if state, err := c.GetStatus(); state != "circuit-open" || err == nil {
+		t.Fatalf("expected status to report the open breaker, got %s (%v)", state, err)
+	}
+
+	// once couch is back, the first request after the cooldown closes the breaker
+	atomic.StoreInt32(&down, 0)
+	time.Sleep(options.BreakerCooldown)
+
+	if state := c.BreakerState(); state != BreakerHalfOpen {
+		t.Fatalf("expected the breaker to be half-open, got %s", state)
+	}
+
+	if state, err := c.GetStatus(); state != "completed" || err != nil {
+		t.Fatalf("expected status to be completed, got %s (%v)", state, err)
+	}
+
+	if state := c.BreakerState(); state != BreakerClosed {
+		t.Fatalf("expected the breaker to be closed, got %s", state)
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	// a request that never gets a response
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer slow.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	c := NewDBWithOptions(slow.URL, "", "", testOptions())
+	c.IgnoreReadyChecks = true
+
+	start := time.Now()
+	if err := c.WithContext(ctx).MakeRequest("GET", "buildings/AAA", "", nil, nil); err == nil {
+		t.Fatalf("expected the request to fail when its context was canceled")
+	}
+
+	if time.Since(start) > time.Second {
+		t.Fatalf("canceling the context didn't stop the request (took %v)", time.Since(start))
+	}
+}
+
+func TestWaitUntilReady(t *testing.T) {
+	replicating := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"state": "running"}`))
+	}))
+	defer replicating.Close()
+
+	ready := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"state": "completed"}`))
+	}))
+	defer ready.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := NewDBWithOptions(replicating.URL, "", "", testOptions()).WithContext(ctx).waitUntilReady(); err == nil {
+		t.Fatalf("expected waiting on a couch that is still replicating to stop when the context is done")
+	}
+
+	// each couch has its own readiness check
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := NewDBWithOptions(ready.URL, "", "", testOptions()).WithContext(ctx).waitUntilReady(); err != nil {
+		t.Fatalf("a couch that is ready wasn't waited on separately: %s", err)
+	}
+}
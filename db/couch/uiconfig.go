@@ -4,9 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
-	"time"
 
 	"github.com/byuoitav/common/log"
 	"github.com/byuoitav/common/structs"
@@ -40,14 +38,8 @@ func (c *CouchDB) getUIConfig(roomID string) (uiconfig, error) {
 func (c *CouchDB) CreateUIConfig(roomID string, toAdd structs.UIConfig) (structs.UIConfig, error) {
 	var toReturn structs.UIConfig
 
-	b, err := json.Marshal(toAdd)
-	if err != nil {
-		return toReturn, fmt.Errorf("failed to marshal the config file for %s: %s", roomID, err)
-	}
-
 	// Send up the UIConfig
-	var resp CouchUpsertResponse
-	err = c.MakeRequest("PUT", fmt.Sprintf("%v/%v", UI_CONFIGS, roomID), "application/json", b, &resp)
+	err := c.createDoc(UI_CONFIGS, roomID, toAdd)
 	if err != nil {
 		if _, ok := err.(*Conflict); ok { // UIConfig with same ID already in database
 			return toReturn, fmt.Errorf("unable to create ui config, because it already exists. error: %s", err)
@@ -86,24 +78,23 @@ func (c *CouchDB) UpdateUIConfig(id string, update structs.UIConfig) (structs.UI
 	var toReturn structs.UIConfig
 
 	if id == update.ID { // the template ID isn't changing
-		// get the rev of the template
-		oldConfig, err := c.getUIConfig(id)
-		if err != nil {
-			return toReturn, fmt.Errorf("unable to get ui config %s to update: %s", id, err)
-		}
-
-		// marshal the updated UIConfig
-		b, err := json.Marshal(update)
+		// update the UIConfig, as long as it hasn't changed since update.Rev
+		_, err := c.updateDoc(UI_CONFIGS, id, update.Rev, update)
 		if err != nil {
-			return toReturn, fmt.Errorf("unable to marshal updated ui config for %s : %s", update.ID, err)
-		}
+			if _, ok := err.(*Conflict); ok {
+				return toReturn, err
+			}
 
-		// update the UIConfig
-		err = c.MakeRequest("PUT", fmt.Sprintf("%s/%s?rev=%v", UI_CONFIGS, id, oldConfig.Rev), "application/json", b, &toReturn)
-		if err != nil {
 			return toReturn, fmt.Errorf("failed to update ui config for %s: %s", id, err)
 		}
 	} else { // the UIConfig ID is changing :|
+		// make sure the UIConfig hasn't changed before replacing it
+		if err := c.checkRev(UI_CONFIGS, id, update.Rev); err != nil {
+			return toReturn, err
+		}
+
+		update.Rev = ""
+
 		// delete the old UIConfig
 		err := c.DeleteUIConfig(id)
 		if err != nil {
@@ -118,7 +109,7 @@ func (c *CouchDB) UpdateUIConfig(id string, update structs.UIConfig) (structs.UI
 
 		// post new UIConfig
 		var resp CouchUpsertResponse
-		err = c.MakeRequest("PUT", fmt.Sprintf("%v/%v", UI_CONFIGS, id), "", b, &resp)
+		err = c.MakeRequest("PUT", fmt.Sprintf("%v/%v", UI_CONFIGS, update.ID), "application/json", b, &resp)
 		if err != nil {
 			if _, ok := err.(*Conflict); ok { // a UIConfig with the same ID already exists
 				return toReturn, fmt.Errorf("ui config already exists, please update this ui config or change IDs. error: %s", err)
@@ -129,7 +120,7 @@ func (c *CouchDB) UpdateUIConfig(id string, update structs.UIConfig) (structs.UI
 		}
 	}
 
-	toReturn, err := c.GetUIConfig(id)
+	toReturn, err := c.GetUIConfig(update.ID)
 	if err != nil {
 		return structs.UIConfig{}, err
 	}
@@ -142,34 +133,13 @@ func (c *CouchDB) UpdateUIConfig(id string, update structs.UIConfig) (structs.UI
 func (c *CouchDB) GetUIAttachment(ui, attachment string) (string, []byte, error) {
 	url := fmt.Sprintf("%v/%v/%v/%v", c.address, UI_CONFIGS, ui, attachment)
 
-	// start building the request
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return "", nil, err
-	}
-
-	// add auth
-	if len(c.username) > 0 && len(c.password) > 0 {
-		req.SetBasicAuth(c.username, c.password)
-	}
-
-	// build client with a timeout
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-	}
-
-	resp, err := client.Do(req)
+	resp, err := c.do(http.MethodGet, url, "", nil)
 	if err != nil {
 		return "", nil, err
 	}
-	defer resp.Body.Close()
 
-	b, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", nil, err
-	}
-
-	if resp.StatusCode/100 != 2 {
+	b := resp.body
+	if resp.status/100 != 2 {
 		var ce CouchError
 		err = json.Unmarshal(b, &ce)
 		if err != nil {
@@ -180,7 +150,7 @@ func (c *CouchDB) GetUIAttachment(ui, attachment string) (string, []byte, error)
 		return "", nil, CheckCouchErrors(ce)
 	}
 
-	return resp.Header.Get("content-type"), b, nil
+	return resp.contentType, b, nil
 }
 
 // GetAllUIConfigs returns a list of all the UI Config documents in the database
@@ -190,19 +160,14 @@ func (c *CouchDB) GetAllUIConfigs() ([]structs.UIConfig, error) {
 	query.Selector.ID.GT = "\x00"
 	query.Limit = 1000
 
-	b, err := json.Marshal(query)
-	if err != nil {
-		return toReturn, fmt.Errorf("failed to marshal query to get all UI configs: %s", err)
-	}
-
-	var resp uiconfigQueryResponse
+	var docs []uiconfig
 
-	err = c.MakeRequest("POST", fmt.Sprintf("%v/_find", UI_CONFIGS), "application/json", b, &resp)
+	err := c.findAll(UI_CONFIGS, query, &docs)
 	if err != nil {
 		return toReturn, fmt.Errorf("failed to get all UI configs: %s", err)
 	}
 
-	for _, doc := range resp.Docs {
+	for _, doc := range docs {
 		toReturn = append(toReturn, *doc.UIConfig)
 	}
 
@@ -0,0 +1,205 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/byuoitav/common/db/couch"
+	"github.com/byuoitav/common/structs"
+)
+
+/*
+AnalyzeDeviceTypeUpdate returns what replacing the device type id with dt would change: the
+commands, ports, roles, and power states added, removed, or changed, and everything that depends
+on something removed.
+
+Every device of the type loses removed commands and power states. A device also depends on a
+removed port or role if it has a port or role with that id. A ui config depends on a removed item
+wherever it names a device that depends on it.
+*/
+func AnalyzeDeviceTypeUpdate(d DB, id string, dt structs.DeviceType) (structs.DeviceTypeImpact, error) {
+	var impact structs.DeviceTypeImpact
+
+	current, err := d.GetDeviceType(id)
+	if err != nil {
+		return impact, fmt.Errorf("unable to get device type %s: %s", id, err)
+	}
+
+	impact.Diff = structs.DiffDeviceTypes(current, dt)
+
+	devices, err := d.GetDevicesByType(id)
+	if err != nil {
+		return impact, fmt.Errorf("unable to get devices of type %s: %s", id, err)
+	}
+
+	// the removed items each device depends on, by room and then device name
+	affected := make(map[string]map[string][]string)
+
+	for _, device := range devices {
+		impact.Devices = append(impact.Devices, device.ID)
+
+		refs := deviceTypeReferences(device, impact.Diff)
+		if len(refs) == 0 {
+			continue
+		}
+
+		impact.References = append(impact.References, refs...)
+
+		roomID := device.GetDeviceRoomID()
+		if affected[roomID] == nil {
+			affected[roomID] = make(map[string][]string)
+		}
+
+		for _, ref := range refs {
+			affected[roomID][device.Name] = append(affected[roomID][device.Name], ref.Removed)
+		}
+	}
+
+	if len(affected) == 0 {
+		return impact, nil
+	}
+
+	configs, err := d.GetAllUIConfigs()
+	if err != nil {
+		return impact, fmt.Errorf("unable to get ui configs: %s", err)
+	}
+
+	for _, ui := range configs {
+		if names, ok := affected[ui.ID]; ok {
+			impact.References = append(impact.References, uiConfigTypeReferences(ui, names)...)
+		}
+	}
+
+	return impact, nil
+}
+
+// deviceTypeReferences returns each of device's references to something removed in diff.
+func deviceTypeReferences(device structs.Device, diff structs.DeviceTypeDiff) []structs.DeviceTypeReference {
+	var refs []structs.DeviceTypeReference
+
+	add := func(field, removed, message string) {
+		refs = append(refs, structs.DeviceTypeReference{
+			Database: couch.DEVICES,
+			ID:       device.ID,
+			Field:    field,
+			Removed:  removed,
+			Message:  message,
+		})
+	}
+
+	for _, command := range diff.Commands.Removed {
+		add("type.commands", command, fmt.Sprintf("device %s loses command %s", device.ID, command))
+	}
+
+	for _, state := range diff.PowerStates.Removed {
+		add("type.power_states", state, fmt.Sprintf("device %s loses power state %s", device.ID, state))
+	}
+
+	ports := make(map[string]bool)
+	for _, port := range diff.Ports.Removed {
+		ports[port] = true
+	}
+
+	for i, port := range device.Ports {
+		if ports[port.ID] {
+			add(fmt.Sprintf("ports[%d]._id", i), port.ID, fmt.Sprintf("device %s has port %s, which was removed", device.ID, port.ID))
+		}
+	}
+
+	roles := make(map[string]bool)
+	for _, role := range diff.Roles.Removed {
+		roles[role] = true
+	}
+
+	for i, role := range device.Roles {
+		if roles[role.ID] {
+			add(fmt.Sprintf("roles[%d]._id", i), role.ID, fmt.Sprintf("device %s has role %s, which was removed", device.ID, role.ID))
+		}
+	}
+
+	return refs
+}
+
+// uiConfigTypeReferences returns a reference for each place ui names one of devices (by name), which maps each device to the removed items it depends on.
+func uiConfigTypeReferences(ui structs.UIConfig, devices map[string][]string) []structs.DeviceTypeReference {
+	var refs []structs.DeviceTypeReference
+
+	add := func(field, name string) {
+		for _, removed := range devices[name] {
+			refs = append(refs, structs.DeviceTypeReference{
+				Database: couch.UI_CONFIGS,
+				ID:       ui.ID,
+				Field:    field,
+				Removed:  removed,
+				Message:  fmt.Sprintf("%s names %s, which depends on %s", field, name, removed),
+			})
+		}
+	}
+
+	for i, panel := range ui.Panels {
+		add(fmt.Sprintf("panels[%d].hostname", i), strings.TrimPrefix(panel.Hostname, ui.ID+"-"))
+	}
+
+	for i, preset := range ui.Presets {
+		lists := []struct {
+			field string
+			list  []string
+		}{
+			{"displays", preset.Displays},
+			{"shareableDisplays", preset.ShareableDisplays},
+			{"audioDevices", preset.AudioDevices},
+			{"inputs", preset.Inputs},
+			{"independentAudioDevices", preset.IndependentAudioDevices},
+			{"screens", preset.Screens},
+		}
+
+		for _, l := range lists {
+			for j, name := range l.list {
+				add(fmt.Sprintf("presets[%d].%s[%d]", i, l.field, j), name)
+			}
+		}
+	}
+
+	for i, audio := range ui.AudioConfiguration {
+		add(fmt.Sprintf("audioConfiguration[%d].display", i), audio.Display)
+
+		for j, name := range audio.AudioDevices {
+			add(fmt.Sprintf("audioConfiguration[%d].audioDevices[%d]", i, j), name)
+		}
+	}
+
+	return refs
+}
+
+/*
+UpdateDeviceTypeGuarded replaces the device type id with dt, unless the update is breaking (it
+removes something that is still depended on, see AnalyzeDeviceTypeUpdate), in which case a
+*structs.BreakingChange is returned instead. If force is true, the update is made either way.
+
+If dt doesn't have a revision, the update is only made if the type hasn't changed since it was
+analyzed. The impact of the update is always returned.
+*/
+func UpdateDeviceTypeGuarded(d DB, id string, dt structs.DeviceType, force bool) (structs.DeviceType, structs.DeviceTypeImpact, error) {
+	var toReturn structs.DeviceType
+
+	if len(dt.Rev) == 0 {
+		current, err := d.GetDeviceType(id)
+		if err != nil {
+			return toReturn, structs.DeviceTypeImpact{}, fmt.Errorf("unable to get device type %s: %s", id, err)
+		}
+
+		dt.Rev = current.Rev
+	}
+
+	impact, err := AnalyzeDeviceTypeUpdate(d, id, dt)
+	if err != nil {
+		return toReturn, impact, fmt.Errorf("unable to analyze update to device type %s: %s", id, err)
+	}
+
+	if impact.Breaking() && !force {
+		return toReturn, impact, &structs.BreakingChange{Impact: impact}
+	}
+
+	toReturn, err = d.UpdateDeviceType(id, dt)
+	return toReturn, impact, err
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/byuoitav/common/structs"
+)
+
+func TestUpdateDeviceTypeGuarded(t *testing.T) {
+	m := newSeededMemoryDB(t)
+
+	dt, err := m.CreateDeviceType(structs.DeviceType{
+		ID:    "test-display",
+		Roles: []structs.Role{{ID: "VideoOut"}, {ID: "AudioOut"}},
+		Ports: []structs.Port{{ID: "hdmi!1"}, {ID: "hdmi!2"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create device type: %s", err)
+	}
+
+	device := structs.Device{
+		ID:    "CCC-AAA-D1",
+		Name:  "D1",
+		Type:  structs.DeviceType{ID: dt.ID},
+		Roles: []structs.Role{{ID: "VideoOut"}},
+		Ports: []structs.Port{{ID: "hdmi!2", SourceDevice: "CCC-AAA-VIA1", DestinationDevice: "CCC-AAA-D1"}},
+	}
+
+	if _, err := m.CreateDevice(device); err != nil {
+		t.Fatalf("failed to create device: %s", err)
+	}
+
+	if _, err := m.CreateUIConfig("CCC-AAA", structs.UIConfig{Presets: []structs.Preset{{Name: "main", Displays: []string{"D1"}}}}); err != nil {
+		t.Fatalf("failed to create ui config: %s", err)
+	}
+
+	// the device doesn't have AudioOut, so removing it isn't breaking
+	update := dt
+	update.Roles = []structs.Role{{ID: "VideoOut"}}
+
+	dt, impact, err := UpdateDeviceTypeGuarded(m, dt.ID, update, false)
+	if err != nil {
+		t.Fatalf("failed to make a non-breaking update: %s", err)
+	}
+
+	if len(impact.Diff.Roles.Removed) != 1 || len(impact.Devices) != 1 || impact.Breaking() {
+		t.Fatalf("unexpected impact: %+v", impact)
+	}
+
+	update = dt
+	update.Ports = []structs.Port{{ID: "hdmi!1"}, {ID: "hdmi!3"}}
+
+	_, impact, err = UpdateDeviceTypeGuarded(m, dt.ID, update, false)
+	if _, ok := err.(*structs.BreakingChange); !ok {
+		t.Fatalf("expected a breaking change, got %v", err)
+	}
+
+	if len(impact.References) != 2 || impact.References[0].Field != "ports[0]._id" || impact.References[1].ID != "CCC-AAA" || impact.References[1].Field != "presets[0].displays[0]" {
+		t.Fatalf("unexpected references: %+v", impact.References)
+	}
+
+	if current, _ := m.GetDeviceType(dt.ID); len(current.Ports) != 2 || current.Ports[1].ID != "hdmi!2" {
+		t.Fatalf("device type was updated without force: %+v", current)
+	}
+
+	if _, _, err := UpdateDeviceTypeGuarded(m, dt.ID, update, true); err != nil {
+		t.Fatalf("failed to force a breaking update: %s", err)
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Package file is a read-only database loaded from a directory of JSON or YAML documents (a
+config bundle), so that a room can keep running when the central database is unreachable.
+
+Documents are grouped by kind (see memory.FixtureKinds), either in a file named after the
+kind, or in a directory named after it:
+
+	bundle/
+		buildings.yaml
+		rooms.json
+		devices/
+			ITB-1101-CP1.yaml
+			ITB-1101-D1.json
+
+Each file may hold a single document or a list of them.
+*/
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/byuoitav/common/db/memory"
+	"gopkg.in/yaml.v2"
+)
+
+// DB is a read-only database backed by the documents in a directory. Everything that would change a document returns ErrReadOnly.
+type DB struct {
+	*memory.MemoryDB
+
+	dir string
+}
+
+// NewDB loads the bundle in dir.
+func NewDB(dir string) (*DB, error) {
+	d := &DB{
+		MemoryDB: memory.NewDB(),
+		dir:      dir,
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open bundle %s: %s", dir, err)
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("unable to open bundle %s: it isn't a directory", dir)
+	}
+
+	for _, kind := range memory.FixtureKinds() {
+		files, err := kindFiles(dir, kind)
+		if err != nil {
+			return nil, fmt.Errorf("unable to find %s in bundle %s: %s", kind, dir, err)
+		}
+
+		for _, file := range files {
+			b, err := readDocuments(file)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read %s: %s", file, err)
+			}
+
+			if err := d.MemoryDB.LoadFixture(kind, b); err != nil {
+				return nil, fmt.Errorf("unable to load %s: %s", file, err)
+			}
+		}
+	}
+
+	return d, nil
+}
+
+// Dir returns the directory the bundle was loaded from.
+func (d *DB) Dir() string {
+	return d.dir
+}
+
+// kindFiles returns the files in dir holding documents of kind, in the order they should be loaded.
+func kindFiles(dir, kind string) ([]string, error) {
+	var files []string
+
+	for _, ext := range extensions {
+		path := filepath.Join(dir, kind+ext)
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+
+	entries, err := ioutil.ReadDir(filepath.Join(dir, kind))
+	switch {
+	case os.IsNotExist(err):
+		return files, nil
+	case err != nil:
+		return files, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && isDocument(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		files = append(files, filepath.Join(dir, kind, name))
+	}
+
+	return files, nil
+}
+
+var extensions = []string{".json", ".yaml", ".yml"}
+
+func isDocument(name string) bool {
+	for _, ext := range extensions {
+		if strings.EqualFold(filepath.Ext(name), ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readDocuments returns the documents in path as JSON.
+func readDocuments(path string) ([]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return b, nil
+	}
+
+	var doc interface{}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	doc, err = jsonValue(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(doc)
+}
+
+// jsonValue converts a value decoded from YAML, whose maps can have keys of any type, into one that can be encoded as JSON.
+func jsonValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			s, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("key %v isn't a string", key)
+			}
+
+			converted, err := jsonValue(val)
+			if err != nil {
+				return nil, err
+			}
+
+			m[s] = converted
+		}
+
+		return m, nil
+	case []interface{}:
+		for i := range v {
+			converted, err := jsonValue(v[i])
+			if err != nil {
+				return nil, err
+			}
+
+			v[i] = converted
+		}
+
+		return v, nil
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,69 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/byuoitav/common/structs"
+)
+
+func writeFile(t *testing.T, path, contents string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to make directory for %s: %s", path, err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("failed to write %s: %s", path, err)
+	}
+}
+
+func TestNewDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bundle")
+	if err != nil {
+		t.Fatalf("failed to make bundle directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile(t, filepath.Join(dir, "buildings.yaml"), `
+- _id: ITB
+  name: Information Technology Building
+`)
+
+	writeFile(t, filepath.Join(dir, "rooms", "ITB-1101.yml"), `
+_id: ITB-1101
+name: ITB-1101
+designation: production
+configuration:
+  _id: Default
+`)
+
+	writeFile(t, filepath.Join(dir, "rooms", "ITB-1102.json"), `{"_id": "ITB-1102", "name": "ITB-1102", "designation": "production", "configuration": {"_id": "Default"}}`)
+
+	d, err := NewDB(dir)
+	if err != nil {
+		t.Fatalf("failed to load bundle: %s", err)
+	}
+
+	rooms, err := d.GetRoomsByBuilding("ITB")
+	if err != nil {
+		t.Fatalf("failed to get rooms: %s", err)
+	}
+
+	if len(rooms) != 2 || rooms[0].ID != "ITB-1101" || rooms[0].Configuration.ID != "Default" {
+		t.Fatalf("unexpected rooms: %+v", rooms)
+	}
+
+	if _, err := d.CreateBuilding(structs.Building{ID: "JET"}); err != ErrReadOnly {
+		t.Fatalf("expected the bundle to be read-only, got %v", err)
+	}
+
+	if err := d.LoadFixture("buildings", []byte(`{"_id": "JET"}`)); err != ErrReadOnly {
+		t.Fatalf("expected the bundle to be read-only, got %v", err)
+	}
+
+	if _, err := NewDB(filepath.Join(dir, "missing")); err == nil {
+		t.Fatalf("expected loading a missing bundle to fail")
+	}
+}
@@ -0,0 +1,380 @@
+package file
+
+import (
+	"errors"
+
+	sd "github.com/byuoitav/common/state/statedefinition"
+	"github.com/byuoitav/common/structs"
+)
+
+// ErrReadOnly is returned by everything that would change a document in a file database.
+var ErrReadOnly = errors.New("the file database is read-only")
+
+// readOnly returns a failed response for each of ids.
+func readOnly(ids []string) []structs.BulkUpdateResponse {
+	var toReturn []structs.BulkUpdateResponse
+	for _, id := range ids {
+		toReturn = append(toReturn, structs.BulkUpdateResponse{ID: id, Message: ErrReadOnly.Error()})
+	}
+
+	return toReturn
+}
+
+// LoadFixtures returns ErrReadOnly; the documents in a file database come from its bundle.
+func (d *DB) LoadFixtures(dir string) error {
+	return ErrReadOnly
+}
+
+// LoadFixture returns ErrReadOnly; the documents in a file database come from its bundle.
+func (d *DB) LoadFixture(kind string, data []byte) error {
+	return ErrReadOnly
+}
+
+// CreateBuilding returns ErrReadOnly.
+func (d *DB) CreateBuilding(building structs.Building) (structs.Building, error) {
+	return structs.Building{}, ErrReadOnly
+}
+
+// UpdateBuilding returns ErrReadOnly.
+func (d *DB) UpdateBuilding(id string, building structs.Building) (structs.Building, error) {
+	return structs.Building{}, ErrReadOnly
+}
+
+// DeleteBuilding returns ErrReadOnly.
+func (d *DB) DeleteBuilding(id string) error {
+	return ErrReadOnly
+}
+
+// RenameBuilding returns ErrReadOnly.
+func (d *DB) RenameBuilding(oldID, newID string) (structs.RenameResult, error) {
+	return structs.RenameResult{OldID: oldID, NewID: newID}, ErrReadOnly
+}
+
+// DeleteBuildingCascade returns ErrReadOnly, unless it's a dry run.
+func (d *DB) DeleteBuildingCascade(id string, dryRun bool) (structs.CascadeDeleteResult, error) {
+	if dryRun {
+		return d.MemoryDB.DeleteBuildingCascade(id, dryRun)
+	}
+
+	return structs.CascadeDeleteResult{ID: id}, ErrReadOnly
+}
+
+// CreateRoom returns ErrReadOnly.
+func (d *DB) CreateRoom(room structs.Room) (structs.Room, error) {
+	return structs.Room{}, ErrReadOnly
+}
+
+// UpdateRoom returns ErrReadOnly.
+func (d *DB) UpdateRoom(id string, room structs.Room) (structs.Room, error) {
+	return structs.Room{}, ErrReadOnly
+}
+
+// DeleteRoom returns ErrReadOnly.
+func (d *DB) DeleteRoom(id string) error {
+	return ErrReadOnly
+}
+
+// RenameRoom returns ErrReadOnly.
+func (d *DB) RenameRoom(oldID, newID string) (structs.RenameResult, error) {
+	return structs.RenameResult{OldID: oldID, NewID: newID}, ErrReadOnly
+}
+
+// DeleteRoomCascade returns ErrReadOnly, unless it's a dry run.
+func (d *DB) DeleteRoomCascade(id string, dryRun bool) (structs.CascadeDeleteResult, error) {
+	if dryRun {
+		return d.MemoryDB.DeleteRoomCascade(id, dryRun)
+	}
+
+	return structs.CascadeDeleteResult{ID: id}, ErrReadOnly
+}
+
+// CreateDevice returns ErrReadOnly.
+func (d *DB) CreateDevice(device structs.Device) (structs.Device, error) {
+	return structs.Device{}, ErrReadOnly
+}
+
+// UpdateDevice returns ErrReadOnly.
+func (d *DB) UpdateDevice(id string, device structs.Device) (structs.Device, error) {
+	return structs.Device{}, ErrReadOnly
+}
+
+// DeleteDevice returns ErrReadOnly.
+func (d *DB) DeleteDevice(id string) error {
+	return ErrReadOnly
+}
+
+// UpsertDeviceState returns ErrReadOnly.
+func (d *DB) UpsertDeviceState(state sd.StaticDevice) (sd.StaticDevice, bool, error) {
+	return sd.StaticDevice{}, false, ErrReadOnly
+}
+
+// UpsertBulkDeviceStates fails for each of the states.
+func (d *DB) UpsertBulkDeviceStates(states []sd.StaticDevice) []structs.BulkUpdateResponse {
+	var ids []string
+	for _, state := range states {
+		ids = append(ids, state.DeviceID)
+	}
+
+	return readOnly(ids)
+}
+
+// UpsertRoomState returns ErrReadOnly.
+func (d *DB) UpsertRoomState(state sd.StaticRoom) (sd.StaticRoom, bool, error) {
+	return sd.StaticRoom{}, false, ErrReadOnly
+}
+
+// UpsertBulkRoomStates fails for each of the states.
+func (d *DB) UpsertBulkRoomStates(states []sd.StaticRoom) []structs.BulkUpdateResponse {
+	var ids []string
+	for _, state := range states {
+		ids = append(ids, state.RoomID)
+	}
+
+	return readOnly(ids)
+}
+
+// CreateDeviceType returns ErrReadOnly.
+func (d *DB) CreateDeviceType(dt structs.DeviceType) (structs.DeviceType, error) {
+	return structs.DeviceType{}, ErrReadOnly
+}
+
+// UpdateDeviceType returns ErrReadOnly.
+func (d *DB) UpdateDeviceType(id string, dt structs.DeviceType) (structs.DeviceType, error) {
+	return structs.DeviceType{}, ErrReadOnly
+}
+
+// DeleteDeviceType returns ErrReadOnly.
+func (d *DB) DeleteDeviceType(id string) error {
+	return ErrReadOnly
+}
+
+// CreateRoomConfiguration returns ErrReadOnly.
+func (d *DB) CreateRoomConfiguration(rc structs.RoomConfiguration) (structs.RoomConfiguration, error) {
+	return structs.RoomConfiguration{}, ErrReadOnly
+}
+
+// UpdateRoomConfiguration returns ErrReadOnly.
+func (d *DB) UpdateRoomConfiguration(id string, rc structs.RoomConfiguration) (structs.RoomConfiguration, error) {
+	return structs.RoomConfiguration{}, ErrReadOnly
+}
+
+// DeleteRoomConfiguration returns ErrReadOnly.
+func (d *DB) DeleteRoomConfiguration(id string) error {
+	return ErrReadOnly
+}
+
+// CreateUIConfig returns ErrReadOnly.
+func (d *DB) CreateUIConfig(roomID string, ui structs.UIConfig) (structs.UIConfig, error) {
+	return structs.UIConfig{}, ErrReadOnly
+}
+
+// UpdateUIConfig returns ErrReadOnly.
+func (d *DB) UpdateUIConfig(id string, ui structs.UIConfig) (structs.UIConfig, error) {
+	return structs.UIConfig{}, ErrReadOnly
+}
+
+// DeleteUIConfig returns ErrReadOnly.
+func (d *DB) DeleteUIConfig(id string) error {
+	return ErrReadOnly
+}
+
+// CreateBulkDevices fails for each of the devices.
+func (d *DB) CreateBulkDevices(devices []structs.Device) []structs.BulkUpdateResponse {
+	var ids []string
+	for _, device := range devices {
+		ids = append(ids, device.ID)
+	}
+
+	return readOnly(ids)
+}
+
+// UpdateBulkDevices fails for each of the devices.
+func (d *DB) UpdateBulkDevices(devices []structs.Device) []structs.BulkUpdateResponse {
+	return d.CreateBulkDevices(devices)
+}
+
+// DeleteBulkDevices fails for each of the devices.
+func (d *DB) DeleteBulkDevices(ids []string) []structs.BulkUpdateResponse {
+	return readOnly(ids)
+}
+
+// CreateBulkRooms fails for each of the rooms.
+func (d *DB) CreateBulkRooms(rooms []structs.Room) []structs.BulkUpdateResponse {
+	var ids []string
+	for _, room := range rooms {
+		ids = append(ids, room.ID)
+	}
+
+	return readOnly(ids)
+}
+
+// UpdateBulkRooms fails for each of the rooms.
+func (d *DB) UpdateBulkRooms(rooms []structs.Room) []structs.BulkUpdateResponse {
+	return d.CreateBulkRooms(rooms)
+}
+
+// DeleteBulkRooms fails for each of the rooms.
+func (d *DB) DeleteBulkRooms(ids []string) []structs.BulkUpdateResponse {
+	return readOnly(ids)
+}
+
+// CreateBulkDeviceTypes fails for each of the device types.
+func (d *DB) CreateBulkDeviceTypes(types []structs.DeviceType) []structs.BulkUpdateResponse {
+	var ids []string
+	for _, dt := range types {
+		ids = append(ids, dt.ID)
+	}
+
+	return readOnly(ids)
+}
+
+// UpdateBulkDeviceTypes fails for each of the device types.
+func (d *DB) UpdateBulkDeviceTypes(types []structs.DeviceType) []structs.BulkUpdateResponse {
+	return d.CreateBulkDeviceTypes(types)
+}
+
+// DeleteBulkDeviceTypes fails for each of the device types.
+func (d *DB) DeleteBulkDeviceTypes(ids []string) []structs.BulkUpdateResponse {
+	return readOnly(ids)
+}
+
+// UpdateTemplate returns ErrReadOnly.
+func (d *DB) UpdateTemplate(id string, newTemp structs.UIConfig) (structs.UIConfig, error) {
+	return structs.UIConfig{}, ErrReadOnly
+}
+
+// UpdateIcons returns ErrReadOnly.
+func (d *DB) UpdateIcons(iconList []string) ([]string, error) {
+	return nil, ErrReadOnly
+}
+
+// UpdateDeviceRoles returns ErrReadOnly.
+func (d *DB) UpdateDeviceRoles(roles []structs.Role) ([]structs.Role, error) {
+	return nil, ErrReadOnly
+}
+
+// UpdateRoomDesignations returns ErrReadOnly.
+func (d *DB) UpdateRoomDesignations(desigs []string) ([]string, error) {
+	return nil, ErrReadOnly
+}
+
+// UpdateRoomAttributeSchemas returns ErrReadOnly.
+func (d *DB) UpdateRoomAttributeSchemas(schemas map[string][]structs.AttributeSchema) (map[string][]structs.AttributeSchema, error) {
+	return nil, ErrReadOnly
+}
+
+// UpdateClosureCodes returns ErrReadOnly.
+func (d *DB) UpdateClosureCodes(codes []string) ([]string, error) {
+	return nil, ErrReadOnly
+}
+
+// UpdateTags returns ErrReadOnly.
+func (d *DB) UpdateTags(newTags []string) ([]string, error) {
+	return nil, ErrReadOnly
+}
+
+// UpdateMenuTree returns ErrReadOnly.
+func (d *DB) UpdateMenuTree(order []string) ([]string, error) {
+	return nil, ErrReadOnly
+}
+
+// CreateAttributeGroup returns ErrReadOnly.
+func (d *DB) CreateAttributeGroup(group structs.Group) (structs.Group, error) {
+	return structs.Group{}, ErrReadOnly
+}
+
+// UpdateAttributeGroup returns ErrReadOnly.
+func (d *DB) UpdateAttributeGroup(id string, group structs.Group) (structs.Group, error) {
+	return structs.Group{}, ErrReadOnly
+}
+
+// DeleteAttributeGroup returns ErrReadOnly.
+func (d *DB) DeleteAttributeGroup(id string) error {
+	return ErrReadOnly
+}
+
+// CreateLabConfig returns ErrReadOnly.
+func (d *DB) CreateLabConfig(config structs.LabConfig) (structs.LabConfig, error) {
+	return structs.LabConfig{}, ErrReadOnly
+}
+
+// UpdateLabConfig returns ErrReadOnly.
+func (d *DB) UpdateLabConfig(roomID string, config structs.LabConfig) (structs.LabConfig, error) {
+	return structs.LabConfig{}, ErrReadOnly
+}
+
+// DeleteLabConfig returns ErrReadOnly.
+func (d *DB) DeleteLabConfig(roomID string) error {
+	return ErrReadOnly
+}
+
+// CreateScheduleConfig returns ErrReadOnly.
+func (d *DB) CreateScheduleConfig(config structs.ScheduleConfig) (structs.ScheduleConfig, error) {
+	return structs.ScheduleConfig{}, ErrReadOnly
+}
+
+// UpdateScheduleConfig returns ErrReadOnly.
+func (d *DB) UpdateScheduleConfig(roomID string, config structs.ScheduleConfig) (structs.ScheduleConfig, error) {
+	return structs.ScheduleConfig{}, ErrReadOnly
+}
+
+// DeleteScheduleConfig returns ErrReadOnly.
+func (d *DB) DeleteScheduleConfig(roomID string) error {
+	return ErrReadOnly
+}
+
+// CreateDMPS returns ErrReadOnly.
+func (d *DB) CreateDMPS(dmps structs.DMPS) (structs.DMPSList, error) {
+	return structs.DMPSList{}, ErrReadOnly
+}
+
+// UpdateDMPS returns ErrReadOnly.
+func (d *DB) UpdateDMPS(hostname string, dmps structs.DMPS) (structs.DMPSList, error) {
+	return structs.DMPSList{}, ErrReadOnly
+}
+
+// DeleteDMPS returns ErrReadOnly.
+func (d *DB) DeleteDMPS(hostname string) (structs.DMPSList, error) {
+	return structs.DMPSList{}, ErrReadOnly
+}
+
+// CreatePermission returns ErrReadOnly.
+func (d *DB) CreatePermission(permission structs.Permission) (structs.Auth, error) {
+	return structs.Auth{}, ErrReadOnly
+}
+
+// UpdatePermission returns ErrReadOnly.
+func (d *DB) UpdatePermission(group string, permission structs.Permission) (structs.Auth, error) {
+	return structs.Auth{}, ErrReadOnly
+}
+
+// DeletePermission returns ErrReadOnly.
+func (d *DB) DeletePermission(group string) (structs.Auth, error) {
+	return structs.Auth{}, ErrReadOnly
+}
+
+// ImportSnapshot returns ErrReadOnly.
+func (d *DB) ImportSnapshot(snapshot structs.Snapshot, strategy structs.ConflictStrategy) (structs.ImportResult, error) {
+	return structs.ImportResult{Strategy: strategy}, ErrReadOnly
+}
+
+// PutSchemaVersion returns ErrReadOnly.
+func (d *DB) PutSchemaVersion(database string, version structs.SchemaVersion) (structs.SchemaVersion, error) {
+	return version, ErrReadOnly
+}
+
+// PutRawDocuments fails for each of the documents.
+func (d *DB) PutRawDocuments(database string, docs []map[string]interface{}) []structs.BulkUpdateResponse {
+	var ids []string
+	for _, doc := range docs {
+		id, _ := doc["_id"].(string)
+		ids = append(ids, id)
+	}
+
+	return readOnly(ids)
+}
+
+// AddAuditEntry returns ErrReadOnly.
+func (d *DB) AddAuditEntry(entry structs.AuditEntry) error {
+	return ErrReadOnly
+}
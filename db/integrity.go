@@ -0,0 +1,333 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/byuoitav/common/db/couch"
+	"github.com/byuoitav/common/structs"
+)
+
+// integrityCheck is the state of a running CheckIntegrity.
+type integrityCheck struct {
+	report structs.IntegrityReport
+
+	buildings   map[string]bool
+	rooms       map[string]bool
+	roomConfigs map[string]bool
+	deviceTypes map[string]bool
+	devices     map[string]*structs.Device
+	uiConfigs   map[string]*structs.UIConfig
+
+	// device names by room, which is how ui configs reference devices
+	deviceNames map[string]map[string]bool
+
+	// fixes are applied to the documents above, then each document with a fix is written once
+	fixes []integrityFix
+}
+
+// integrityFix is how to fix an issue in report.Issues.
+type integrityFix struct {
+	issue int
+	apply func()
+}
+
+/*
+CheckIntegrity checks every building, room, device, device type, room configuration, and ui
+config for references to documents that don't exist, documents whose building or room doesn't
+exist, and ids that don't match the naming scheme.
+
+If fix is true, the issues that can be fixed safely are fixed: a port's reference to a device
+that doesn't exist is cleared, and names of devices (or presets) that don't exist are removed
+from the lists in a ui config's presets. Nothing is ever deleted, and every other issue is only
+reported.
+*/
+func CheckIntegrity(d DB, fix bool) (structs.IntegrityReport, error) {
+	c := &integrityCheck{
+		report: structs.IntegrityReport{
+			CheckedAt: time.Now(),
+			Fix:       fix,
+			Checked:   make(map[string]int),
+		},
+		buildings:   make(map[string]bool),
+		rooms:       make(map[string]bool),
+		roomConfigs: make(map[string]bool),
+		deviceTypes: make(map[string]bool),
+		devices:     make(map[string]*structs.Device),
+		uiConfigs:   make(map[string]*structs.UIConfig),
+		deviceNames: make(map[string]map[string]bool),
+	}
+
+	buildings, err := d.GetAllBuildings()
+	if err != nil {
+		return c.report, fmt.Errorf("unable to check integrity: %s", err)
+	}
+
+	rooms, err := d.GetAllRooms()
+	if err != nil {
+		return c.report, fmt.Errorf("unable to check integrity: %s", err)
+	}
+
+	devices, err := d.GetAllDevices()
+	if err != nil {
+		return c.report, fmt.Errorf("unable to check integrity: %s", err)
+	}
+
+	types, err := d.GetAllDeviceTypes()
+	if err != nil {
+		return c.report, fmt.Errorf("unable to check integrity: %s", err)
+	}
+
+	configs, err := d.GetAllRoomConfigurations()
+	if err != nil {
+		return c.report, fmt.Errorf("unable to check integrity: %s", err)
+	}
+
+	uiConfigs, err := d.GetAllUIConfigs()
+	if err != nil {
+		return c.report, fmt.Errorf("unable to check integrity: %s", err)
+	}
+
+	for _, b := range buildings {
+		c.buildings[b.ID] = true
+	}
+
+	for _, r := range rooms {
+		c.rooms[r.ID] = true
+	}
+
+	for _, rc := range configs {
+		c.roomConfigs[rc.ID] = true
+	}
+
+	for _, dt := range types {
+		c.deviceTypes[dt.ID] = true
+	}
+
+	for i := range devices {
+		c.devices[devices[i].ID] = &devices[i]
+
+		roomID := devices[i].GetDeviceRoomID()
+		if c.deviceNames[roomID] == nil {
+			c.deviceNames[roomID] = make(map[string]bool)
+		}
+
+		c.deviceNames[roomID][devices[i].Name] = true
+	}
+
+	for i := range uiConfigs {
+		c.uiConfigs[uiConfigs[i].ID] = &uiConfigs[i]
+	}
+
+	c.report.Checked[couch.BUILDINGS] = len(buildings)
+	c.report.Checked[couch.ROOMS] = len(rooms)
+	c.report.Checked[couch.DEVICES] = len(devices)
+	c.report.Checked[couch.DEVICE_TYPES] = len(types)
+	c.report.Checked[couch.ROOM_CONFIGURATIONS] = len(configs)
+	c.report.Checked[couch.UI_CONFIGS] = len(uiConfigs)
+
+	for _, b := range buildings {
+		if err := b.Validate(); err != nil {
+			c.add(structs.IntegrityInvalidID, couch.BUILDINGS, b.ID, "_id", "", err.Error(), nil)
+		}
+	}
+
+	for _, r := range rooms {
+		c.checkRoom(r)
+	}
+
+	for i := range devices {
+		c.checkDevice(c.devices[devices[i].ID])
+	}
+
+	for i := range uiConfigs {
+		c.checkUIConfig(c.uiConfigs[uiConfigs[i].ID])
+	}
+
+	if fix {
+		c.fix(d)
+	}
+
+	return c.report, nil
+}
+
+func (c *integrityCheck) checkRoom(r structs.Room) {
+	if !structs.IsRoomIDValid(r.ID) {
+		c.add(structs.IntegrityInvalidID, couch.ROOMS, r.ID, "_id", "", "room id must match `([A-z,0-9]{2,})-[A-z,0-9]+`", nil)
+	}
+
+	if buildingID := strings.SplitN(r.ID, "-", 2)[0]; !c.buildings[buildingID] {
+		c.add(structs.IntegrityOrphanedDocument, couch.ROOMS, r.ID, "", buildingID, fmt.Sprintf("building %s doesn't exist", buildingID), nil)
+	}
+
+	if !c.roomConfigs[r.Configuration.ID] {
+		c.add(structs.IntegrityDanglingReference, couch.ROOMS, r.ID, "configuration._id", r.Configuration.ID, fmt.Sprintf("room configuration %q doesn't exist", r.Configuration.ID), nil)
+	}
+}
+
+func (c *integrityCheck) checkDevice(device *structs.Device) {
+	if !structs.IsDeviceIDValid(device.ID) {
+		c.add(structs.IntegrityInvalidID, couch.DEVICES, device.ID, "_id", "", "device id must match `([A-z,0-9]{2,}-[A-z,0-9]+)-[A-z]+[0-9]+`", nil)
+	}
+
+	if roomID := device.GetDeviceRoomID(); !c.rooms[roomID] {
+		c.add(structs.IntegrityOrphanedDocument, couch.DEVICES, device.ID, "", roomID, fmt.Sprintf("room %s doesn't exist", roomID), nil)
+	}
+
+	if !c.deviceTypes[device.Type.ID] {
+		c.add(structs.IntegrityDanglingReference, couch.DEVICES, device.ID, "type._id", device.Type.ID, fmt.Sprintf("device type %q doesn't exist", device.Type.ID), nil)
+	}
+
+	for i := range device.Ports {
+		port := &device.Ports[i]
+
+		if len(port.SourceDevice) > 0 && c.devices[port.SourceDevice] == nil {
+			c.add(structs.IntegrityDanglingReference, couch.DEVICES, device.ID, fmt.Sprintf("ports[%d].source_device", i), port.SourceDevice,
+				fmt.Sprintf("source device %s of port %s doesn't exist", port.SourceDevice, port.ID), func() { port.SourceDevice = "" })
+		}
+
+		if len(port.DestinationDevice) > 0 && c.devices[port.DestinationDevice] == nil {
+			c.add(structs.IntegrityDanglingReference, couch.DEVICES, device.ID, fmt.Sprintf("ports[%d].destination_device", i), port.DestinationDevice,
+				fmt.Sprintf("destination device %s of port %s doesn't exist", port.DestinationDevice, port.ID), func() { port.DestinationDevice = "" })
+		}
+	}
+}
+
+func (c *integrityCheck) checkUIConfig(ui *structs.UIConfig) {
+	if !c.rooms[ui.ID] {
+		c.add(structs.IntegrityOrphanedDocument, couch.UI_CONFIGS, ui.ID, "", ui.ID, fmt.Sprintf("room %s doesn't exist", ui.ID), nil)
+	}
+
+	names := c.deviceNames[ui.ID]
+
+	presets := make(map[string]bool)
+	for _, preset := range ui.Presets {
+		presets[preset.Name] = true
+	}
+
+	for i := range ui.Panels {
+		panel := ui.Panels[i]
+
+		if c.devices[panel.Hostname] == nil {
+			c.add(structs.IntegrityDanglingReference, couch.UI_CONFIGS, ui.ID, fmt.Sprintf("panels[%d].hostname", i), panel.Hostname, fmt.Sprintf("panel %s doesn't exist", panel.Hostname), nil)
+		}
+
+		if !presets[panel.Preset] {
+			c.add(structs.IntegrityDanglingReference, couch.UI_CONFIGS, ui.ID, fmt.Sprintf("panels[%d].preset", i), panel.Preset, fmt.Sprintf("preset %q of panel %s doesn't exist", panel.Preset, panel.Hostname), nil)
+		}
+	}
+
+	for i := range ui.Presets {
+		preset := &ui.Presets[i]
+
+		lists := []struct {
+			field string
+			list  *[]string
+			valid map[string]bool
+		}{
+			{"displays", &preset.Displays, names},
+			{"shareableDisplays", &preset.ShareableDisplays, names},
+			{"audioDevices", &preset.AudioDevices, names},
+			{"inputs", &preset.Inputs, names},
+			{"independentAudioDevices", &preset.IndependentAudioDevices, names},
+			{"screens", &preset.Screens, names},
+			{"shareablePresets", &preset.ShareablePresets, presets},
+		}
+
+		for _, l := range lists {
+			c.checkNames(ui.ID, fmt.Sprintf("presets[%d].%s", i, l.field), l.list, l.valid, preset.Name)
+		}
+	}
+
+	for i := range ui.AudioConfiguration {
+		audio := &ui.AudioConfiguration[i]
+
+		if !names[audio.Display] {
+			c.add(structs.IntegrityDanglingReference, couch.UI_CONFIGS, ui.ID, fmt.Sprintf("audioConfiguration[%d].display", i), audio.Display, fmt.Sprintf("display %q doesn't exist", audio.Display), nil)
+		}
+
+		c.checkNames(ui.ID, fmt.Sprintf("audioConfiguration[%d].audioDevices", i), &audio.AudioDevices, names, audio.Display)
+	}
+}
+
+// checkNames adds a fixable issue for each name in list (at field) that isn't valid. owner is the preset (or display) the list belongs to.
+func (c *integrityCheck) checkNames(id, field string, list *[]string, valid map[string]bool, owner string) {
+	for i, name := range *list {
+		if valid[name] {
+			continue
+		}
+
+		name := name
+		c.add(structs.IntegrityDanglingReference, couch.UI_CONFIGS, id, fmt.Sprintf("%s[%d]", field, i), name, fmt.Sprintf("%s of %q references %q, which doesn't exist", field[strings.LastIndex(field, ".")+1:], owner, name), func() {
+			var kept []string
+			for _, n := range *list {
+				if n != name {
+					kept = append(kept, n)
+				}
+			}
+
+			*list = kept
+		})
+	}
+}
+
+// add adds an issue to the report. fix is how to fix it, or nil if it can't be fixed safely.
+func (c *integrityCheck) add(kind structs.IntegrityIssueKind, database, id, field, reference, message string, fix func()) {
+	c.report.Issues = append(c.report.Issues, structs.IntegrityIssue{
+		Kind:      kind,
+		Database:  database,
+		ID:        id,
+		Field:     field,
+		Reference: reference,
+		Message:   message,
+		Fixable:   fix != nil,
+	})
+
+	if fix != nil {
+		c.fixes = append(c.fixes, integrityFix{issue: len(c.report.Issues) - 1, apply: fix})
+	}
+}
+
+// fix applies each fix, and writes each document that was fixed.
+func (c *integrityCheck) fix(d DB) {
+	// the issues fixed in each document, in the order the documents were first fixed
+	var order []string
+	fixed := make(map[string][]int)
+
+	for _, f := range c.fixes {
+		f.apply()
+
+		issue := c.report.Issues[f.issue]
+		key := issue.Database + "/" + issue.ID
+
+		if _, ok := fixed[key]; !ok {
+			order = append(order, key)
+		}
+
+		fixed[key] = append(fixed[key], f.issue)
+	}
+
+	for _, key := range order {
+		issue := c.report.Issues[fixed[key][0]]
+
+		var err error
+		switch issue.Database {
+		case couch.DEVICES:
+			_, err = d.UpdateDevice(issue.ID, *c.devices[issue.ID])
+		case couch.UI_CONFIGS:
+			_, err = d.UpdateUIConfig(issue.ID, *c.uiConfigs[issue.ID])
+		default:
+			err = fmt.Errorf("%s can't be fixed", issue.Database)
+		}
+
+		for _, i := range fixed[key] {
+			if err != nil {
+				c.report.Issues[i].FixError = err.Error()
+				continue
+			}
+
+			c.report.Issues[i].Fixed = true
+		}
+	}
+}
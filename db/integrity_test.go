@@ -0,0 +1,78 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/byuoitav/common/structs"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	m := newSeededMemoryDB(t)
+
+	device := structs.Device{
+		ID:    "CCC-AAA-D1",
+		Name:  "D1",
+		Type:  structs.DeviceType{ID: "non-controllable"},
+		Roles: []structs.Role{{ID: "VideoOut"}},
+		Ports: []structs.Port{{ID: "HDMI1", SourceDevice: "CCC-AAA-VIA1", DestinationDevice: "CCC-AAA-D1"}},
+	}
+
+	if _, err := m.CreateDevice(device); err != nil {
+		t.Fatalf("failed to create device: %s", err)
+	}
+
+	ui := structs.UIConfig{
+		Panels:  []structs.Panel{{Hostname: "CCC-AAA-D1", Preset: "main"}},
+		Presets: []structs.Preset{{Name: "main", Displays: []string{"D1", "D2"}}},
+	}
+
+	if _, err := m.CreateUIConfig("CCC-AAA", ui); err != nil {
+		t.Fatalf("failed to create ui config: %s", err)
+	}
+
+	report, err := CheckIntegrity(m, false)
+	if err != nil {
+		t.Fatalf("failed to check integrity: %s", err)
+	}
+
+	fixable := make(map[string]structs.IntegrityIssue)
+	for _, issue := range report.Issues {
+		if issue.Fixable {
+			fixable[issue.Field] = issue
+		}
+	}
+
+	if len(fixable) != 2 || fixable["ports[0].source_device"].Reference != "CCC-AAA-VIA1" || fixable["presets[0].displays[1]"].Reference != "D2" {
+		t.Fatalf("unexpected fixable issues: %+v", fixable)
+	}
+
+	report, err = CheckIntegrity(m, true)
+	if err != nil {
+		t.Fatalf("failed to fix integrity: %s", err)
+	}
+
+	for _, issue := range report.Issues {
+		if issue.Fixable && !issue.Fixed {
+			t.Fatalf("issue wasn't fixed: %+v", issue)
+		}
+	}
+
+	if d, _ := m.GetDevice("CCC-AAA-D1"); d.Ports[0].SourceDevice != "" || d.Ports[0].DestinationDevice != "CCC-AAA-D1" {
+		t.Fatalf("port wasn't fixed: %+v", d.Ports)
+	}
+
+	if u, _ := m.GetUIConfig("CCC-AAA"); len(u.Presets[0].Displays) != 1 || u.Presets[0].Displays[0] != "D1" {
+		t.Fatalf("preset wasn't fixed: %+v", u.Presets)
+	}
+
+	report, err = CheckIntegrity(m, false)
+	if err != nil {
+		t.Fatalf("failed to check integrity: %s", err)
+	}
+
+	for _, issue := range report.Issues {
+		if issue.Fixable {
+			t.Fatalf("issue wasn't fixed: %+v", issue)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 
 	"github.com/byuoitav/common/db/couch"
+	"github.com/byuoitav/common/db/memory"
 	"github.com/byuoitav/common/log"
 	"github.com/byuoitav/common/nerr"
 	"github.com/byuoitav/common/state/statedefinition"
@@ -18,12 +19,16 @@ type DB interface {
 	GetBuilding(id string) (structs.Building, error)
 	UpdateBuilding(id string, building structs.Building) (structs.Building, error)
 	DeleteBuilding(id string) error
+	RenameBuilding(oldID, newID string) (structs.RenameResult, error)
+	DeleteBuildingCascade(id string, dryRun bool) (structs.CascadeDeleteResult, error)
 
 	// room
 	CreateRoom(room structs.Room) (structs.Room, error)
 	GetRoom(id string) (structs.Room, error)
 	UpdateRoom(id string, room structs.Room) (structs.Room, error)
 	DeleteRoom(id string) error
+	RenameRoom(oldID, newID string) (structs.RenameResult, error)
+	DeleteRoomCascade(id string, dryRun bool) (structs.CascadeDeleteResult, error)
 	GetRoomAttachments(room string) ([]string, error)
 
 	// device
@@ -34,6 +39,13 @@ type DB interface {
 
 	// device state
 	GetDeviceState(string) (statedefinition.StaticDevice, error)
+	UpsertDeviceState(state statedefinition.StaticDevice) (statedefinition.StaticDevice, bool, error)
+	UpsertBulkDeviceStates(states []statedefinition.StaticDevice) []structs.BulkUpdateResponse
+
+	// room state
+	GetRoomState(roomID string) (statedefinition.StaticRoom, error)
+	UpsertRoomState(state statedefinition.StaticRoom) (statedefinition.StaticRoom, bool, error)
+	UpsertBulkRoomStates(states []statedefinition.StaticRoom) []structs.BulkUpdateResponse
 
 	// device type
 	CreateDeviceType(dt structs.DeviceType) (structs.DeviceType, error)
@@ -55,10 +67,18 @@ type DB interface {
 	GetUIAttachment(ui, attachment string) (string, []byte, error)
 
 	// lab configs
+	CreateLabConfig(config structs.LabConfig) (structs.LabConfig, error)
 	GetLabConfig(roomID string) (structs.LabConfig, error)
+	UpdateLabConfig(roomID string, config structs.LabConfig) (structs.LabConfig, error)
+	DeleteLabConfig(roomID string) error
+	GetAllLabConfigs() ([]structs.LabConfig, error)
 
 	// schedule configs
+	CreateScheduleConfig(config structs.ScheduleConfig) (structs.ScheduleConfig, error)
 	GetScheduleConfig(roomID string) (structs.ScheduleConfig, error)
+	UpdateScheduleConfig(roomID string, config structs.ScheduleConfig) (structs.ScheduleConfig, error)
+	DeleteScheduleConfig(roomID string) error
+	GetAllScheduleConfigs() ([]structs.ScheduleConfig, error)
 
 	/* bulk functions */
 	GetAllBuildings() ([]structs.Building, error)
@@ -69,21 +89,46 @@ type DB interface {
 	GetAllRoomConfigurations() ([]structs.RoomConfiguration, error)
 	GetAllUIConfigs() ([]structs.UIConfig, error)
 	CreateBulkDevices([]structs.Device) []structs.BulkUpdateResponse // TODO change the response struct
+	UpdateBulkDevices([]structs.Device) []structs.BulkUpdateResponse
+	DeleteBulkDevices(ids []string) []structs.BulkUpdateResponse
+	CreateBulkRooms([]structs.Room) []structs.BulkUpdateResponse
+	UpdateBulkRooms([]structs.Room) []structs.BulkUpdateResponse
+	DeleteBulkRooms(ids []string) []structs.BulkUpdateResponse
+	CreateBulkDeviceTypes([]structs.DeviceType) []structs.BulkUpdateResponse
+	UpdateBulkDeviceTypes([]structs.DeviceType) []structs.BulkUpdateResponse
+	DeleteBulkDeviceTypes(ids []string) []structs.BulkUpdateResponse
+
+	/* snapshot functions */
+	ExportSnapshot() (structs.Snapshot, error)
+	ImportSnapshot(snapshot structs.Snapshot, strategy structs.ConflictStrategy) (structs.ImportResult, error)
+
+	/* paged functions */
+	// each returns a page of up to pageSize documents starting at bookmark, and the bookmark for the next page (empty once there are no more)
+	ListBuildings(pageSize int, bookmark string) ([]structs.Building, string, error)
+	ListRooms(pageSize int, bookmark string) ([]structs.Room, string, error)
+	ListDevices(pageSize int, bookmark string) ([]structs.Device, string, error)
+	ListDeviceStates(pageSize int, bookmark string) ([]statedefinition.StaticDevice, string, error)
+	ListUIConfigs(pageSize int, bookmark string) ([]structs.UIConfig, string, error)
 
 	/* Specialty functions */
 	GetDevicesByRoom(roomID string) ([]structs.Device, error)
 	GetDeviceStatesByRoom(roomID string) ([]statedefinition.StaticDevice, error)
 	GetDeviceStatesByBuilding(buildingID string) ([]statedefinition.StaticDevice, error)
+	GetDevicesByType(typeID string) ([]structs.Device, error)
 	GetDevicesByRoomAndType(roomID, typeID string) ([]structs.Device, error)
 	GetDevicesByRoomAndRole(roomID, roleID string) ([]structs.Device, error)
 	GetDevicesByRoleAndType(roleID, typeID string) ([]structs.Device, *nerr.E)
 	GetDevicesByRoleAndTypeAndDesignation(roleID, typeID, designation string) ([]structs.Device, *nerr.E)
+	FindDevices(filter structs.DeviceFilter) ([]structs.Device, error)
 
 	GetRoomsByBuilding(id string) ([]structs.Room, error)
 	GetRoomsByDesignation(designation string) ([]structs.Room, *nerr.E)
 
 	/* dmps functions */
 	GetDMPSList() (structs.DMPSList, error)
+	CreateDMPS(dmps structs.DMPS) (structs.DMPSList, error)
+	UpdateDMPS(hostname string, dmps structs.DMPS) (structs.DMPSList, error)
+	DeleteDMPS(hostname string) (structs.DMPSList, error)
 
 	/* Options Functions */
 	GetTemplate(id string) (structs.UIConfig, error)
@@ -95,13 +140,19 @@ type DB interface {
 	UpdateDeviceRoles(roles []structs.Role) ([]structs.Role, error)
 	GetRoomDesignations() ([]string, error)
 	UpdateRoomDesignations(desigs []string) ([]string, error)
+	GetRoomAttributeSchemas() (map[string][]structs.AttributeSchema, error)
+	UpdateRoomAttributeSchemas(schemas map[string][]structs.AttributeSchema) (map[string][]structs.AttributeSchema, error)
 	GetClosureCodes() ([]string, error)
 	UpdateClosureCodes(desigs []string) ([]string, error)
 	GetTags() ([]string, error)
 	UpdateTags(newTags []string) ([]string, error)
 	GetMenuTree() ([]string, error)
+	UpdateMenuTree(order []string) ([]string, error)
 
+	CreateAttributeGroup(group structs.Group) (structs.Group, error)
 	GetAttributeGroup(groupID string) (structs.Group, error)
+	UpdateAttributeGroup(id string, group structs.Group) (structs.Group, error)
+	DeleteAttributeGroup(id string) error
 	GetAllAttributeGroups() ([]structs.Group, error)
 
 	/* Deployment Info Functions  */
@@ -115,6 +166,9 @@ type DB interface {
 	// GetDMActions(deviceID string) ([]*actions.Actions, error)
 
 	GetAuth() (structs.Auth, error)
+	CreatePermission(permission structs.Permission) (structs.Auth, error)
+	UpdatePermission(group string, permission structs.Permission) (structs.Auth, error)
+	DeletePermission(group string) (structs.Auth, error)
 
 	//Get the state (replication/readiness) of the database
 	GetStatus() (string, error)
@@ -123,6 +177,7 @@ type DB interface {
 var address string
 var username string
 var password string
+var backend string
 
 var database DB
 
@@ -130,17 +185,34 @@ func init() {
 	address = os.Getenv("DB_ADDRESS")
 	username = os.Getenv("DB_USERNAME")
 	password = os.Getenv("DB_PASSWORD")
+	backend = os.Getenv("DB_BACKEND")
 }
 
-// GetDB returns the instance of the database to use.
+/*
+GetDB returns the instance of the database to use. The backend is picked by the scheme of
+DB_ADDRESS (see Open), so an address like http://couch:5984 is still couch. DB_BACKEND can
+name the backend instead, for an address without a scheme, e.g. DB_BACKEND=file with
+DB_ADDRESS=/srv/bundle. If the backend can't be opened, the error is logged and couch is used,
+as it was before backends could be picked.
+*/
 func GetDB() DB {
-	if len(address) == 0 {
+	if len(address) == 0 && len(backend) == 0 {
 		log.L.Errorf("DB_ADDRESS is not set.")
 	}
 
-	// TODO add logic to "pick" which db to create
 	if database == nil {
-		database = couch.NewDB(address, username, password)
+		if len(address) == 0 && len(backend) == 0 {
+			database = couch.NewDB(address, username, password)
+			return database
+		}
+
+		d, err := Open(backendURL())
+		if err != nil {
+			log.L.Errorf("unable to open the database, so using couch at %s instead: %s", address, err)
+			d = couch.NewDB(address, username, password)
+		}
+
+		database = d
 	}
 
 	return database
@@ -150,3 +222,8 @@ func GetDB() DB {
 func GetDBWithCustomAuth(address, username, password string) DB {
 	return couch.NewDB(address, username, password)
 }
+
+// NewMemoryDB returns an empty in-memory database, for use in tests and offline development.
+func NewMemoryDB() DB {
+	return memory.NewDB()
+}
@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/byuoitav/common/structs"
+)
+
+// GetAttributeGroup returns an attribute group.
+func (m *MemoryDB) GetAttributeGroup(groupID string) (structs.Group, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn structs.Group
+
+	group, ok := m.attributeGroups[groupID]
+	if !ok {
+		return toReturn, notFound("failed to get attribute group %s: attribute group %s not found", groupID, groupID)
+	}
+
+	clone(group, &toReturn)
+	return toReturn, nil
+}
+
+// GetAllAttributeGroups returns every attribute group.
+func (m *MemoryDB) GetAllAttributeGroups() ([]structs.Group, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn []structs.Group
+	for _, id := range sortedKeys(m.attributeGroups) {
+		var group structs.Group
+		clone(m.attributeGroups[id], &group)
+		toReturn = append(toReturn, group)
+	}
+
+	return toReturn, nil
+}
+
+// CreateAttributeGroup adds an attribute group.
+func (m *MemoryDB) CreateAttributeGroup(group structs.Group) (structs.Group, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var toReturn structs.Group
+
+	if err := group.Validate(); err != nil {
+		return toReturn, err
+	}
+
+	if _, ok := m.attributeGroups[group.ID]; ok {
+		return toReturn, fmt.Errorf("unable to create attribute group, because it already exists. error: attribute group %s already exists", group.ID)
+	}
+
+	clone(group, &toReturn)
+	toReturn.Rev = nextRev("")
+	m.attributeGroups[group.ID] = toReturn
+
+	return toReturn, nil
+}
+
+// UpdateAttributeGroup updates an attribute group. If group.Rev is set, it is only updated if it hasn't changed since that revision.
+func (m *MemoryDB) UpdateAttributeGroup(id string, group structs.Group) (structs.Group, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var toReturn structs.Group
+
+	if err := group.Validate(); err != nil {
+		return toReturn, err
+	}
+
+	if group.ID != id {
+		return toReturn, fmt.Errorf("unable to update attribute group %s: the id of an attribute group can't be changed", id)
+	}
+
+	current, ok := m.attributeGroups[id]
+	if !ok {
+		return toReturn, notFound("failed to update attribute group %s: attribute group %s not found", id, id)
+	}
+
+	if err := checkRev(id, group.Rev, current.Rev); err != nil {
+		return toReturn, err
+	}
+
+	clone(group, &toReturn)
+	toReturn.Rev = nextRev(current.Rev)
+	m.attributeGroups[id] = toReturn
+
+	return toReturn, nil
+}
+
+// DeleteAttributeGroup deletes an attribute group. Deletion is refused while the group is in the menu tree.
+func (m *MemoryDB) DeleteAttributeGroup(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.attributeGroups[id]; !ok {
+		return notFound("unable to get attribute group %s to delete: attribute group %s not found", id, id)
+	}
+
+	for _, groupID := range m.menuTree {
+		if groupID == id {
+			return fmt.Errorf("attribute group %s is still in the menu tree. remove it from the menu tree first.", id)
+		}
+	}
+
+	delete(m.attributeGroups, id)
+	return nil
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/byuoitav/common/structs"
+)
+
+// AddAuditEntry adds entry to the audit log.
+func (m *MemoryDB) AddAuditEntry(entry structs.AuditEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.auditEntries[entry.ID]; ok {
+		return fmt.Errorf("unable to add audit entry: %s already exists", entry.ID)
+	}
+
+	var toStore structs.AuditEntry
+	clone(entry, &toStore)
+	toStore.Rev = nextRev("")
+
+	m.auditEntries[entry.ID] = toStore
+	return nil
+}
+
+// GetAuditEntry returns a single audit entry.
+func (m *MemoryDB) GetAuditEntry(id string) (structs.AuditEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn structs.AuditEntry
+
+	entry, ok := m.auditEntries[id]
+	if !ok {
+		return toReturn, notFound("failed to get audit entry %s: audit entry %s not found", id, id)
+	}
+
+	clone(entry, &toReturn)
+	return toReturn, nil
+}
+
+// GetDocumentHistory returns each change made to a document in database, including the rename that gave it its id, in the order they were made.
+func (m *MemoryDB) GetDocumentHistory(database, id string) ([]structs.AuditEntry, error) {
+	return m.findAuditEntries(func(entry structs.AuditEntry) bool {
+		return entry.Database == database && (entry.DocumentID == id || entry.PreviousID == id)
+	}), nil
+}
+
+// GetRoomHistory returns each change made to a room, its devices, or its ui config since since, in the order they were made.
+func (m *MemoryDB) GetRoomHistory(roomID string, since time.Time) ([]structs.AuditEntry, error) {
+	return m.findAuditEntries(func(entry structs.AuditEntry) bool {
+		return entry.RoomID == roomID && !entry.Timestamp.Before(since)
+	}), nil
+}
+
+func (m *MemoryDB) findAuditEntries(match func(structs.AuditEntry) bool) []structs.AuditEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn []structs.AuditEntry
+
+	// audit entry ids start with the time they were made
+	for _, id := range sortedKeys(m.auditEntries) {
+		if match(m.auditEntries[id]) {
+			var entry structs.AuditEntry
+			clone(m.auditEntries[id], &entry)
+			toReturn = append(toReturn, entry)
+		}
+	}
+
+	return toReturn
+}
@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/byuoitav/common/structs"
+)
+
+// GetBuilding returns the building with the given id.
+func (m *MemoryDB) GetBuilding(id string) (structs.Building, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.getBuilding(id)
+}
+
+func (m *MemoryDB) getBuilding(id string) (structs.Building, error) {
+	var toReturn structs.Building
+
+	b, ok := m.buildings[id]
+	if !ok {
+		return toReturn, notFound("failed to get building %s: building %s not found", id, id)
+	}
+
+	clone(b, &toReturn)
+	return toReturn, nil
+}
+
+// GetAllBuildings returns every building, sorted by id.
+func (m *MemoryDB) GetAllBuildings() ([]structs.Building, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn []structs.Building
+	for _, id := range sortedKeys(m.buildings) {
+		var b structs.Building
+		clone(m.buildings[id], &b)
+		toReturn = append(toReturn, b)
+	}
+
+	return toReturn, nil
+}
+
+// CreateBuilding adds a building. The building must pass validation and its id must not already be in use.
+func (m *MemoryDB) CreateBuilding(toAdd structs.Building) (structs.Building, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.createBuilding(toAdd)
+}
+
+func (m *MemoryDB) createBuilding(toAdd structs.Building) (structs.Building, error) {
+	var toReturn structs.Building
+
+	if err := toAdd.Validate(); err != nil {
+		return toReturn, err
+	}
+
+	if _, ok := m.buildings[toAdd.ID]; ok {
+		return toReturn, fmt.Errorf("building already exists, please update this building or change id's. error: building %s already exists", toAdd.ID)
+	}
+
+	clone(toAdd, &toReturn)
+	toReturn.Rev = nextRev("")
+	m.buildings[toAdd.ID] = toReturn
+
+	return m.getBuilding(toAdd.ID)
+}
+
+// DeleteBuilding deletes a building. Deletion is refused while rooms still exist in the building.
+func (m *MemoryDB) DeleteBuilding(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.buildings[id]; !ok {
+		return notFound("unable to get building %s to delete: building %s not found", id, id)
+	}
+
+	if len(m.getRoomsByBuilding(id)) > 0 {
+		return fmt.Errorf("there are still rooms associated with the building %s. delete all rooms from it first.", id)
+	}
+
+	delete(m.buildings, id)
+	return nil
+}
+
+// UpdateBuilding updates a building. If the id of the building changes, each of its rooms is moved into the new building.
+func (m *MemoryDB) UpdateBuilding(id string, building structs.Building) (structs.Building, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var toReturn structs.Building
+
+	if err := building.Validate(); err != nil {
+		return toReturn, err
+	}
+
+	if _, ok := m.buildings[id]; !ok {
+		return toReturn, notFound("unable to get building %s to update: building %s not found", id, id)
+	}
+
+	if err := checkRev(id, building.Rev, m.buildings[id].Rev); err != nil {
+		return toReturn, err
+	}
+
+	if id == building.ID {
+		clone(building, &toReturn)
+		toReturn.Rev = nextRev(m.buildings[id].Rev)
+		m.buildings[id] = toReturn
+		return m.getBuilding(id)
+	}
+
+	// the building ID is changing; move everything in it, then apply any other changes
+	if _, err := m.renameBuilding(id, building.ID); err != nil {
+		return toReturn, fmt.Errorf("unable to move building %s to %s: %s", id, building.ID, err)
+	}
+
+	clone(building, &toReturn)
+	toReturn.Rev = nextRev(m.buildings[building.ID].Rev)
+	m.buildings[building.ID] = toReturn
+
+	return m.getBuilding(building.ID)
+}
@@ -0,0 +1,219 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/byuoitav/common/structs"
+)
+
+// UpdateBulkDevices validates and updates each of the devices, which must already exist.
+func (m *MemoryDB) UpdateBulkDevices(devices []structs.Device) []structs.BulkUpdateResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var toReturn []structs.BulkUpdateResponse
+
+	for _, device := range devices {
+		response := structs.BulkUpdateResponse{ID: device.ID}
+
+		if err := m.replaceDevice(device); err != nil {
+			response.Message = err.Error()
+		} else {
+			response.Success = true
+		}
+
+		toReturn = append(toReturn, response)
+	}
+
+	return toReturn
+}
+
+// replaceDevice validates device, and replaces the existing device with the same id.
+func (m *MemoryDB) replaceDevice(device structs.Device) error {
+	current, ok := m.devices[device.ID]
+	if !ok {
+		return fmt.Errorf("unable to update device %s, because it doesn't exist", device.ID)
+	}
+
+	if err := checkRev(device.ID, device.Rev, current.Rev); err != nil {
+		return err
+	}
+
+	if err := device.Validate(); err != nil {
+		return err
+	}
+
+	if _, ok := m.deviceTypes[device.Type.ID]; !ok {
+		if _, err := m.createDeviceType(device.Type); err != nil {
+			return fmt.Errorf("device type %s doesn't exist yet, and not enough information was included to create it. (error: %s)", device.Type.ID, err)
+		}
+	}
+
+	if err := device.ApplyAttributeSchema(m.deviceTypes[device.Type.ID].AttributeSchema); err != nil {
+		return err
+	}
+
+	m.putDevice(device)
+	return nil
+}
+
+// DeleteBulkDevices deletes each of the devices.
+func (m *MemoryDB) DeleteBulkDevices(ids []string) []structs.BulkUpdateResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return bulkResponses(ids, m.deleteDevice)
+}
+
+// CreateBulkRooms validates and creates each of the rooms. Any devices included in a room are created after the room; their responses follow the rooms'.
+func (m *MemoryDB) CreateBulkRooms(rooms []structs.Room) []structs.BulkUpdateResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var toReturn []structs.BulkUpdateResponse
+	var devices []structs.Device
+
+	for _, room := range rooms {
+		response := structs.BulkUpdateResponse{ID: room.ID}
+
+		toCreate := room
+		toCreate.Devices = nil
+
+		if _, err := m.createRoom(toCreate); err != nil {
+			response.Message = err.Error()
+		} else {
+			response.Success = true
+			devices = append(devices, room.Devices...)
+		}
+
+		toReturn = append(toReturn, response)
+	}
+
+	if len(devices) > 0 {
+		toReturn = append(toReturn, m.createBulkDevices(devices)...)
+	}
+
+	return toReturn
+}
+
+// UpdateBulkRooms validates and updates each of the rooms, which must already exist. The devices in each room are not changed.
+func (m *MemoryDB) UpdateBulkRooms(rooms []structs.Room) []structs.BulkUpdateResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var toReturn []structs.BulkUpdateResponse
+
+	for _, room := range rooms {
+		response := structs.BulkUpdateResponse{ID: room.ID}
+
+		if _, err := m.updateRoom(room.ID, room); err != nil {
+			response.Message = err.Error()
+		} else {
+			response.Success = true
+		}
+
+		toReturn = append(toReturn, response)
+	}
+
+	return toReturn
+}
+
+// DeleteBulkRooms deletes each of the rooms, along with their devices.
+func (m *MemoryDB) DeleteBulkRooms(ids []string) []structs.BulkUpdateResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return bulkResponses(ids, m.deleteRoom)
+}
+
+// CreateBulkDeviceTypes validates and creates each of the device types.
+func (m *MemoryDB) CreateBulkDeviceTypes(types []structs.DeviceType) []structs.BulkUpdateResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var toReturn []structs.BulkUpdateResponse
+
+	for _, dt := range types {
+		response := structs.BulkUpdateResponse{ID: dt.ID}
+
+		if _, err := m.createDeviceType(dt); err != nil {
+			response.Message = err.Error()
+		} else {
+			response.Success = true
+		}
+
+		toReturn = append(toReturn, response)
+	}
+
+	return toReturn
+}
+
+// UpdateBulkDeviceTypes validates and updates each of the device types, which must already exist.
+func (m *MemoryDB) UpdateBulkDeviceTypes(types []structs.DeviceType) []structs.BulkUpdateResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var toReturn []structs.BulkUpdateResponse
+
+	for _, dt := range types {
+		response := structs.BulkUpdateResponse{ID: dt.ID}
+
+		current, ok := m.deviceTypes[dt.ID]
+		if !ok {
+			response.Message = fmt.Sprintf("unable to update device type %s, because it doesn't exist", dt.ID)
+		} else if err := checkRev(dt.ID, dt.Rev, current.Rev); err != nil {
+			response.Message = err.Error()
+		} else if err := dt.Validate(true); err != nil {
+			response.Message = err.Error()
+		} else {
+			var toStore structs.DeviceType
+			clone(dt, &toStore)
+			toStore.Rev = nextRev(current.Rev)
+			m.deviceTypes[dt.ID] = toStore
+
+			response.Success = true
+		}
+
+		toReturn = append(toReturn, response)
+	}
+
+	return toReturn
+}
+
+// DeleteBulkDeviceTypes deletes each of the device types. A type can't be deleted while devices still depend on it.
+func (m *MemoryDB) DeleteBulkDeviceTypes(ids []string) []structs.BulkUpdateResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return bulkResponses(ids, func(id string) error {
+		if devices := m.getDevicesByType(id); len(devices) != 0 {
+			return fmt.Errorf("can't delete device type %s. %v devices still depend on it.", id, len(devices))
+		}
+
+		if _, ok := m.deviceTypes[id]; !ok {
+			return notFound("failed to get device type %s to delete. does it exist? (error: device type %s not found)", id, id)
+		}
+
+		delete(m.deviceTypes, id)
+		return nil
+	})
+}
+
+// bulkResponses calls fn with each of ids, and returns a response for each of them.
+func bulkResponses(ids []string, fn func(id string) error) []structs.BulkUpdateResponse {
+	var toReturn []structs.BulkUpdateResponse
+
+	for _, id := range ids {
+		response := structs.BulkUpdateResponse{ID: id}
+
+		if err := fn(id); err != nil {
+			response.Message = err.Error()
+		} else {
+			response.Success = true
+		}
+
+		toReturn = append(toReturn, response)
+	}
+
+	return toReturn
+}
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"strings"
+
+	"github.com/byuoitav/common/db/couch"
+	"github.com/byuoitav/common/structs"
+)
+
+// DeleteRoomCascade deletes a room along with its devices, their device states, its ui config, and its room attachments.
+// If dryRun is true, nothing is deleted, and the result is the plan of every document that would be.
+func (m *MemoryDB) DeleteRoomCascade(id string, dryRun bool) (structs.CascadeDeleteResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := structs.CascadeDeleteResult{ID: id, DryRun: dryRun}
+
+	if _, ok := m.rooms[id]; !ok {
+		return result, notFound("unable to plan delete of room %s: room %s not found", id, id)
+	}
+
+	m.deleteRoomCascade(&result, id)
+	return result, nil
+}
+
+// DeleteBuildingCascade deletes a building along with each of its rooms, and everything in them.
+// If dryRun is true, nothing is deleted, and the result is the plan of every document that would be.
+func (m *MemoryDB) DeleteBuildingCascade(id string, dryRun bool) (structs.CascadeDeleteResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := structs.CascadeDeleteResult{ID: id, DryRun: dryRun}
+
+	if _, ok := m.buildings[id]; !ok {
+		return result, notFound("unable to get building %s to delete: building %s not found", id, id)
+	}
+
+	for _, room := range m.getRoomsByBuilding(id) {
+		m.deleteRoomCascade(&result, room.ID)
+	}
+
+	m.cascadeDelete(&result, couch.BUILDINGS, id, func() { delete(m.buildings, id) })
+	return result, nil
+}
+
+// deleteRoomCascade deletes (or plans to delete) room id and everything in it, in the same order as couch.
+func (m *MemoryDB) deleteRoomCascade(result *structs.CascadeDeleteResult, id string) {
+	for _, deviceID := range sortedKeys(m.deviceStates) {
+		if strings.HasPrefix(deviceID, id+"-") {
+			m.cascadeDelete(result, couch.DEVICE_STATES, deviceID, func() { delete(m.deviceStates, deviceID) })
+		}
+	}
+
+	for _, deviceID := range sortedKeys(m.devices) {
+		if strings.HasPrefix(deviceID, id+"-") {
+			m.cascadeDelete(result, couch.DEVICES, deviceID, func() { delete(m.devices, deviceID) })
+		}
+	}
+
+	if _, ok := m.uiConfigs[id]; ok {
+		m.cascadeDelete(result, couch.UI_CONFIGS, id, func() {
+			delete(m.uiConfigs, id)
+
+			for key := range m.uiAttachments {
+				if strings.HasPrefix(key, id+"/") {
+					delete(m.uiAttachments, key)
+				}
+			}
+		})
+	}
+
+	if _, ok := m.roomAttachments[id]; ok {
+		m.cascadeDelete(result, couch.ROOM_ATTACHMENTS, id, func() { delete(m.roomAttachments, id) })
+	}
+
+	m.cascadeDelete(result, couch.ROOMS, id, func() { delete(m.rooms, id) })
+}
+
+// cascadeDelete adds a document to result, and deletes it unless result is a dry run.
+func (m *MemoryDB) cascadeDelete(result *structs.CascadeDeleteResult, database, id string, del func()) {
+	doc := structs.DeletedDocument{
+		Database: database,
+		ID:       id,
+	}
+
+	if result.DryRun {
+		doc.Message = "would be deleted"
+	} else {
+		del()
+		doc.Success = true
+	}
+
+	result.Documents = append(result.Documents, doc)
+}
@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/byuoitav/common/structs"
+)
+
+// GetDeploymentInfo returns the deployment config for a service.
+func (m *MemoryDB) GetDeploymentInfo(serviceID string) (structs.FullConfig, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn structs.FullConfig
+
+	config, ok := m.deploymentInfo[serviceID]
+	if !ok {
+		return toReturn, notFound("deployment info %s not found", serviceID)
+	}
+
+	clone(config, &toReturn)
+	return toReturn, nil
+}
+
+// GetDeviceDeploymentInfo returns the deployment config for a device type.
+func (m *MemoryDB) GetDeviceDeploymentInfo(deviceType string) (structs.DeviceDeploymentConfig, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn structs.DeviceDeploymentConfig
+
+	config, ok := m.deviceDeploy[deviceType]
+	if !ok {
+		return toReturn, notFound("device deployment info %s not found", deviceType)
+	}
+
+	clone(config, &toReturn)
+	return toReturn, nil
+}
+
+// GetServiceInfo returns the config for a service.
+func (m *MemoryDB) GetServiceInfo(serviceID string) (structs.ServiceConfigWrapper, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn structs.ServiceConfigWrapper
+
+	config, ok := m.serviceInfo[serviceID]
+	if !ok {
+		return toReturn, notFound("service info %s not found", serviceID)
+	}
+
+	clone(config, &toReturn)
+	return toReturn, nil
+}
+
+// GetServiceAttachment returns the attachment for a service and designation.
+func (m *MemoryDB) GetServiceAttachment(service, designation string) ([]byte, error) {
+	return m.getServiceAttachment(service, fmt.Sprintf("%v-%v", service, designation))
+}
+
+// GetServiceZip returns the tarball for a service and designation.
+func (m *MemoryDB) GetServiceZip(service, designation string) ([]byte, error) {
+	return m.getServiceAttachment(service, fmt.Sprintf("%v.tar.gz", designation))
+}
+
+func (m *MemoryDB) getServiceAttachment(service, name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	data, ok := m.serviceAttachments[service+"/"+name]
+	if !ok {
+		return nil, notFound("attachment %s not found", service+"/"+name)
+	}
+
+	toReturn := make([]byte, len(data))
+	copy(toReturn, data)
+	return toReturn, nil
+}
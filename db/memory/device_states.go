@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"sort"
+	"strings"
+
+	sd "github.com/byuoitav/common/state/statedefinition"
+)
+
+// GetDeviceState returns the state document for a device.
+func (m *MemoryDB) GetDeviceState(id string) (sd.StaticDevice, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn sd.StaticDevice
+
+	state, ok := m.deviceStates[id]
+	if !ok {
+		return toReturn, notFound("failed to get device state for %s: device state %s not found", id, id)
+	}
+
+	clone(state, &toReturn)
+	return toReturn, nil
+}
+
+// GetAllDeviceStates returns every device state document.
+func (m *MemoryDB) GetAllDeviceStates() ([]sd.StaticDevice, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.getDeviceStatesByPrefix(""), nil
+}
+
+// GetDeviceStatesByRoom returns the state documents for each of the devices in a room.
+func (m *MemoryDB) GetDeviceStatesByRoom(roomID string) ([]sd.StaticDevice, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.getDeviceStatesByPrefix(roomID), nil
+}
+
+// GetDeviceStatesByBuilding returns the state documents for each of the devices in a building.
+func (m *MemoryDB) GetDeviceStatesByBuilding(buildingID string) ([]sd.StaticDevice, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.getDeviceStatesByPrefix(buildingID), nil
+}
+
+func (m *MemoryDB) getDeviceStatesByPrefix(prefix string) []sd.StaticDevice {
+	var ids []string
+	for id := range m.deviceStates {
+		if strings.HasPrefix(id, prefix) {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Strings(ids)
+
+	var toReturn []sd.StaticDevice
+	for _, id := range ids {
+		var state sd.StaticDevice
+		clone(m.deviceStates[id], &state)
+		toReturn = append(toReturn, state)
+	}
+
+	return toReturn
+}
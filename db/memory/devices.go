@@ -0,0 +1,376 @@
+package memory
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/byuoitav/common/nerr"
+	"github.com/byuoitav/common/structs"
+)
+
+// GetDevice returns a device, including its full device type.
+func (m *MemoryDB) GetDevice(id string) (structs.Device, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.getDevice(id)
+}
+
+func (m *MemoryDB) getDevice(id string) (structs.Device, error) {
+	var toReturn structs.Device
+
+	device, ok := m.devices[id]
+	if !ok {
+		return toReturn, notFound("failed to get device %s: device %s not found", id, id)
+	}
+
+	clone(device, &toReturn)
+
+	dt, ok := m.deviceTypes[device.Type.ID]
+	if !ok {
+		return toReturn, notFound("failed to get device type (%s) to get device %s: device type %s not found", device.Type.ID, id, device.Type.ID)
+	}
+
+	clone(dt, &toReturn.Type)
+	return toReturn, nil
+}
+
+// GetAllDevices returns every device. Like couch, the devices only include the id of their type.
+func (m *MemoryDB) GetAllDevices() ([]structs.Device, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn []structs.Device
+	for _, id := range sortedKeys(m.devices) {
+		var device structs.Device
+		clone(m.devices[id], &device)
+		toReturn = append(toReturn, device)
+	}
+
+	return toReturn, nil
+}
+
+// GetDevicesByRoom returns each of the devices in a room, including their full device types.
+func (m *MemoryDB) GetDevicesByRoom(roomID string) ([]structs.Device, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.getDevicesByRoom(roomID), nil
+}
+
+func (m *MemoryDB) getDevicesByRoom(roomID string) []structs.Device {
+	var toReturn []structs.Device
+
+	for _, id := range sortedKeys(m.devices) {
+		if !strings.HasPrefix(id, roomID+"-") {
+			continue
+		}
+
+		var device structs.Device
+		clone(m.devices[id], &device)
+
+		if dt, ok := m.deviceTypes[device.Type.ID]; ok {
+			clone(dt, &device.Type)
+		}
+
+		toReturn = append(toReturn, device)
+	}
+
+	return toReturn
+}
+
+/*
+CreateDevice creates a device. As with couch:
+	1. The device must pass validation, and the room portion of its id must be an existing room.
+	2. If the device type doesn't exist yet, it is created from the device's type.
+	3. Only the id of the device type is stored with the device.
+*/
+func (m *MemoryDB) CreateDevice(toAdd structs.Device) (structs.Device, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.createDevice(toAdd)
+}
+
+func (m *MemoryDB) createDevice(toAdd structs.Device) (structs.Device, error) {
+	var toReturn structs.Device
+
+	if err := toAdd.Validate(); err != nil {
+		return toReturn, err
+	}
+
+	// validate room is real
+	roomID := roomIDFromDevice(toAdd.ID)
+	if _, ok := m.rooms[roomID]; !ok {
+		return toReturn, fmt.Errorf("unable to create device %s: room %s doesn't exist", toAdd.ID, roomID)
+	}
+
+	if _, ok := m.devices[toAdd.ID]; ok {
+		return toReturn, fmt.Errorf("unable to create device, because it already exists. error: device %s already exists", toAdd.ID)
+	}
+
+	// validate device type
+	if _, ok := m.deviceTypes[toAdd.Type.ID]; !ok {
+		if _, err := m.createDeviceType(toAdd.Type); err != nil {
+			return toReturn, fmt.Errorf("attempting to create a device with a non-existant device type, but not enough information is included to create the type. (error: %s)", err)
+		}
+	}
+
+	if err := toAdd.ApplyAttributeSchema(m.deviceTypes[toAdd.Type.ID].AttributeSchema); err != nil {
+		return toReturn, err
+	}
+
+	m.putDevice(toAdd)
+	return m.getDevice(toAdd.ID)
+}
+
+// putDevice stores a copy of device, keeping only the id of its type.
+func (m *MemoryDB) putDevice(device structs.Device) {
+	var toStore structs.Device
+	clone(device, &toStore)
+	toStore.Type = structs.DeviceType{ID: device.Type.ID}
+	toStore.Rev = nextRev(m.devices[device.ID].Rev)
+
+	m.devices[toStore.ID] = toStore
+}
+
+// DeleteDevice deletes a device.
+func (m *MemoryDB) DeleteDevice(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.deleteDevice(id)
+}
+
+func (m *MemoryDB) deleteDevice(id string) error {
+	if _, ok := m.devices[id]; !ok {
+		return notFound("failed to get device %s to delete: device %s not found", id, id)
+	}
+
+	delete(m.devices, id)
+	return nil
+}
+
+// UpdateDevice updates a device. If the id of the device changes, the old device is deleted and the new one is created.
+func (m *MemoryDB) UpdateDevice(id string, device structs.Device) (structs.Device, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var toReturn structs.Device
+
+	if err := device.Validate(); err != nil {
+		return toReturn, err
+	}
+
+	current, ok := m.devices[id]
+	if !ok {
+		return toReturn, notFound("unable to get device %s to update: device %s not found", id, id)
+	}
+
+	if err := checkRev(id, device.Rev, current.Rev); err != nil {
+		return toReturn, err
+	}
+
+	if id == device.ID {
+		dt, ok := m.deviceTypes[device.Type.ID]
+		if !ok {
+			return toReturn, notFound("failed to update device %s: device type %s not found", id, device.Type.ID)
+		}
+
+		if err := device.ApplyAttributeSchema(dt.AttributeSchema); err != nil {
+			return toReturn, err
+		}
+
+		m.putDevice(device)
+		return m.getDevice(id)
+	}
+
+	device.Rev = ""
+
+	// the new device is created first, so that nothing changes if it can't be
+	toReturn, err := m.createDevice(device)
+	if err != nil {
+		return toReturn, fmt.Errorf("failed to update device %s: %s", device.ID, err)
+	}
+
+	delete(m.devices, id)
+	return toReturn, nil
+}
+
+// GetDevicesByRoomAndRole returns each of the devices in a room with the given role.
+func (m *MemoryDB) GetDevicesByRoomAndRole(roomID, role string) ([]structs.Device, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	toReturn := []structs.Device{}
+	for _, d := range m.getDevicesByRoom(roomID) {
+		if structs.HasRole(d, role) {
+			toReturn = append(toReturn, d)
+		}
+	}
+
+	return toReturn, nil
+}
+
+// GetDevicesByRoomAndType returns each of the devices in a room with the given type.
+func (m *MemoryDB) GetDevicesByRoomAndType(roomID, typeID string) ([]structs.Device, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	toReturn := []structs.Device{}
+	for _, d := range m.getDevicesByRoom(roomID) {
+		if strings.EqualFold(d.Type.ID, typeID) {
+			toReturn = append(toReturn, d)
+		}
+	}
+
+	return toReturn, nil
+}
+
+// GetDevicesByType returns each of the devices with the given type.
+func (m *MemoryDB) GetDevicesByType(deviceType string) ([]structs.Device, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.getDevicesByType(deviceType), nil
+}
+
+func (m *MemoryDB) getDevicesByType(deviceType string) []structs.Device {
+	var toReturn []structs.Device
+
+	for _, id := range sortedKeys(m.devices) {
+		if strings.EqualFold(m.devices[id].Type.ID, deviceType) {
+			var device structs.Device
+			clone(m.devices[id], &device)
+			toReturn = append(toReturn, device)
+		}
+	}
+
+	return toReturn
+}
+
+// GetDevicesByRoleAndType returns each of the devices with the given role and type.
+func (m *MemoryDB) GetDevicesByRoleAndType(role, deviceType string) ([]structs.Device, *nerr.E) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn []structs.Device
+	for _, d := range m.getDevicesByType(deviceType) {
+		if structs.HasRole(d, role) {
+			toReturn = append(toReturn, d)
+		}
+	}
+
+	return toReturn, nil
+}
+
+// GetDevicesByRoleAndTypeAndDesignation returns each of the devices with the given role and type, that are in a room with the given designation.
+func (m *MemoryDB) GetDevicesByRoleAndTypeAndDesignation(role, deviceType, designation string) ([]structs.Device, *nerr.E) {
+	devs, err := m.GetDevicesByRoleAndType(role, deviceType)
+	if err != nil {
+		return devs, err.Addf("Couldn't get device by role and type and designation")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn []structs.Device
+	for _, d := range devs {
+		room, ok := m.rooms[roomIDFromDevice(d.ID)]
+		if ok && strings.EqualFold(room.Designation, designation) {
+			toReturn = append(toReturn, d)
+		}
+	}
+
+	return toReturn, nil
+}
+
+// FindDevices returns each of the devices matching filter. Only the id of each device's type is included.
+func (m *MemoryDB) FindDevices(filter structs.DeviceFilter) ([]structs.Device, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn []structs.Device
+	for _, id := range sortedKeys(m.devices) {
+		if !filter.Matches(m.devices[id]) {
+			continue
+		}
+
+		if len(filter.Designation) > 0 && !filter.MatchesDesignation(m.rooms[roomIDFromDevice(id)].Designation) {
+			continue
+		}
+
+		var device structs.Device
+		clone(m.devices[id], &device)
+		toReturn = append(toReturn, device)
+	}
+
+	return toReturn, nil
+}
+
+// CreateBulkDevices validates and creates each of the devices, returning a response for each of them.
+func (m *MemoryDB) CreateBulkDevices(devices []structs.Device) []structs.BulkUpdateResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.createBulkDevices(devices)
+}
+
+func (m *MemoryDB) createBulkDevices(devices []structs.Device) []structs.BulkUpdateResponse {
+	var toReturn []structs.BulkUpdateResponse
+
+	validPortIDs := make(map[string]bool)
+	for i := range devices {
+		validPortIDs[devices[i].ID] = true
+	}
+
+	for _, device := range devices {
+		response := structs.BulkUpdateResponse{
+			ID:      device.ID,
+			Success: false,
+		}
+
+		// check that the ports contain valid devices
+		for _, port := range device.Ports {
+			if len(port.SourceDevice) > 0 && !validPortIDs[port.SourceDevice] {
+				if _, ok := m.devices[port.SourceDevice]; !ok {
+					response.Message = fmt.Sprintf("invalid port %v. source device %s doesn't exist, create it before adding it to a port.", port.ID, port.SourceDevice)
+					break
+				}
+			}
+
+			if len(port.DestinationDevice) > 0 && !validPortIDs[port.DestinationDevice] {
+				if _, ok := m.devices[port.DestinationDevice]; !ok {
+					response.Message = fmt.Sprintf("invalid port %v. destination device %s doesn't exist, create it before adding it to a port.", port.ID, port.DestinationDevice)
+					break
+				}
+			}
+		}
+
+		if len(response.Message) > 0 {
+			toReturn = append(toReturn, response)
+			continue
+		}
+
+		if _, err := m.createDevice(device); err != nil {
+			response.Message = err.Error()
+			toReturn = append(toReturn, response)
+			continue
+		}
+
+		response.Success = true
+		toReturn = append(toReturn, response)
+	}
+
+	return toReturn
+}
+
+// roomIDFromDevice returns the room portion of a device id, the same way couch does when creating a device.
+func roomIDFromDevice(id string) string {
+	split := strings.Split(id, "-")
+	if len(split) < 2 {
+		return id
+	}
+
+	return split[0] + "-" + split[1]
+}
@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/byuoitav/common/structs"
+)
+
+// GetDeviceType returns a device type.
+func (m *MemoryDB) GetDeviceType(id string) (structs.DeviceType, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.getDeviceType(id)
+}
+
+func (m *MemoryDB) getDeviceType(id string) (structs.DeviceType, error) {
+	var toReturn structs.DeviceType
+
+	dt, ok := m.deviceTypes[id]
+	if !ok {
+		return toReturn, notFound("failed to get device type %s: device type %s not found", id, id)
+	}
+
+	clone(dt, &toReturn)
+	return toReturn, nil
+}
+
+// GetAllDeviceTypes returns every device type.
+func (m *MemoryDB) GetAllDeviceTypes() ([]structs.DeviceType, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn []structs.DeviceType
+	for _, id := range sortedKeys(m.deviceTypes) {
+		var dt structs.DeviceType
+		clone(m.deviceTypes[id], &dt)
+		toReturn = append(toReturn, dt)
+	}
+
+	return toReturn, nil
+}
+
+// CreateDeviceType creates a device type. The device type must pass a deep validation.
+func (m *MemoryDB) CreateDeviceType(toAdd structs.DeviceType) (structs.DeviceType, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.createDeviceType(toAdd)
+}
+
+func (m *MemoryDB) createDeviceType(toAdd structs.DeviceType) (structs.DeviceType, error) {
+	var toReturn structs.DeviceType
+
+	if err := toAdd.Validate(true); err != nil {
+		return toReturn, err
+	}
+
+	if _, ok := m.deviceTypes[toAdd.ID]; ok {
+		return toReturn, fmt.Errorf("device type already exists, please update this type or change id's. error: device type %s already exists", toAdd.ID)
+	}
+
+	clone(toAdd, &toReturn)
+	toReturn.Rev = nextRev("")
+	m.deviceTypes[toAdd.ID] = toReturn
+
+	return m.getDeviceType(toAdd.ID)
+}
+
+// DeleteDeviceType deletes a device type. Deletion is refused while devices still depend on the type.
+func (m *MemoryDB) DeleteDeviceType(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if devices := m.getDevicesByType(id); len(devices) != 0 {
+		return fmt.Errorf("can't delete device type %s. %v devices still depend on it.", id, len(devices))
+	}
+
+	if _, ok := m.deviceTypes[id]; !ok {
+		return notFound("failed to get device type %s to delete. does it exist? (error: device type %s not found)", id, id)
+	}
+
+	delete(m.deviceTypes, id)
+	return nil
+}
+
+// UpdateDeviceType updates a device type. The id of a device type cannot be changed while devices depend on it.
+func (m *MemoryDB) UpdateDeviceType(id string, dt structs.DeviceType) (structs.DeviceType, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var toReturn structs.DeviceType
+
+	if err := dt.Validate(true); err != nil {
+		return toReturn, err
+	}
+
+	current, ok := m.deviceTypes[id]
+	if !ok {
+		return toReturn, notFound("unable to get device type %s to update: device type %s not found", id, id)
+	}
+
+	if err := checkRev(id, dt.Rev, current.Rev); err != nil {
+		return toReturn, err
+	}
+
+	if id != dt.ID {
+		if devices := m.getDevicesByType(id); len(devices) != 0 {
+			return toReturn, fmt.Errorf("can't change the id of device type %s. %v devices still depend on it.", id, len(devices))
+		}
+
+		if _, ok := m.deviceTypes[dt.ID]; ok {
+			return toReturn, fmt.Errorf("device type %s already exists", dt.ID)
+		}
+
+		delete(m.deviceTypes, id)
+		current.Rev = ""
+	}
+
+	clone(dt, &toReturn)
+	toReturn.Rev = nextRev(current.Rev)
+	m.deviceTypes[dt.ID] = toReturn
+
+	return m.getDeviceType(dt.ID)
+}
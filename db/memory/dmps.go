@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/byuoitav/common/db/couch"
+	"github.com/byuoitav/common/structs"
+)
+
+// GetDMPSList returns the list of DMPSes to pull events from.
+func (m *MemoryDB) GetDMPSList() (structs.DMPSList, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn structs.DMPSList
+	clone(m.dmps, &toReturn)
+	return toReturn, nil
+}
+
+// CreateDMPS adds a DMPS to the list of DMPSes to pull events from, and returns the new list.
+func (m *MemoryDB) CreateDMPS(dmps structs.DMPS) (structs.DMPSList, error) {
+	return m.updateDMPSList(func(list *structs.DMPSList) error {
+		for _, d := range list.List {
+			if d.Hostname == dmps.Hostname {
+				return fmt.Errorf("%s is already in the list", dmps.Hostname)
+			}
+		}
+
+		list.List = append(list.List, dmps)
+		return nil
+	})
+}
+
+// UpdateDMPS replaces the DMPS hostname in the list of DMPSes to pull events from, and returns the new list.
+func (m *MemoryDB) UpdateDMPS(hostname string, dmps structs.DMPS) (structs.DMPSList, error) {
+	return m.updateDMPSList(func(list *structs.DMPSList) error {
+		for i := range list.List {
+			if list.List[i].Hostname == hostname {
+				list.List[i] = dmps
+				return nil
+			}
+		}
+
+		return notFound("dmps %s not found", hostname)
+	})
+}
+
+// DeleteDMPS removes the DMPS hostname from the list of DMPSes to pull events from, and returns the new list.
+func (m *MemoryDB) DeleteDMPS(hostname string) (structs.DMPSList, error) {
+	return m.updateDMPSList(func(list *structs.DMPSList) error {
+		for i := range list.List {
+			if list.List[i].Hostname == hostname {
+				list.List = append(list.List[:i], list.List[i+1:]...)
+				return nil
+			}
+		}
+
+		return notFound("dmps %s not found", hostname)
+	})
+}
+
+func (m *MemoryDB) updateDMPSList(change func(list *structs.DMPSList) error) (structs.DMPSList, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var list structs.DMPSList
+	clone(m.dmps, &list)
+	list.ID = couch.DMPS_LIST_ID
+
+	if err := change(&list); err != nil {
+		return list, fmt.Errorf("unable to update DMPSList: %s", err)
+	}
+
+	if err := list.Validate(); err != nil {
+		return list, err
+	}
+
+	list.Rev = nextRev(m.dmps.Rev)
+	m.dmps = list
+
+	var toReturn structs.DMPSList
+	clone(m.dmps, &toReturn)
+	return toReturn, nil
+}
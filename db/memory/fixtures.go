@@ -0,0 +1,307 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	sd "github.com/byuoitav/common/state/statedefinition"
+	"github.com/byuoitav/common/structs"
+)
+
+// fixture kinds, in the order they must be loaded so that references resolve.
+var fixtureKinds = []string{
+	"buildings",
+	"roomconfigs",
+	"devicetypes",
+	"rooms",
+	"devices",
+	"uiconfigs",
+	"devicestates",
+	"roomstates",
+	"templates",
+	"attributegroups",
+	"labconfigs",
+	"scheduleconfigs",
+}
+
+// FixtureKinds returns the kinds of documents LoadFixture accepts, in the order they must be loaded so that references resolve.
+func FixtureKinds() []string {
+	return append([]string(nil), fixtureKinds...)
+}
+
+var fixtureRegex = regexp.MustCompile(`^setup_([A-Za-z]+)`)
+
+/*
+LoadFixtures seeds the database from each of the setup_<kind>_*.json files in dir, which is
+the same layout used by the couch package's test-data directory. Each file may hold a single
+document or an array of documents. Files are loaded kind by kind (buildings before rooms,
+rooms before devices, etc.) so that references between them resolve.
+*/
+func (m *MemoryDB) LoadFixtures(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("unable to read fixture directory %s: %s", dir, err)
+	}
+
+	byKind := make(map[string][]string)
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+
+		matches := fixtureRegex.FindStringSubmatch(f.Name())
+		if len(matches) == 0 {
+			continue
+		}
+
+		byKind[strings.ToLower(matches[1])] = append(byKind[strings.ToLower(matches[1])], f.Name())
+	}
+
+	for _, kind := range fixtureKinds {
+		names := byKind[kind]
+		sort.Strings(names)
+
+		for _, name := range names {
+			b, err := ioutil.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				return fmt.Errorf("unable to read fixture %s: %s", name, err)
+			}
+
+			if err := m.LoadFixture(kind, b); err != nil {
+				return fmt.Errorf("unable to load fixture %s: %s", name, err)
+			}
+		}
+
+		delete(byKind, kind)
+	}
+
+	for kind := range byKind {
+		return fmt.Errorf("unknown fixture kind %q", kind)
+	}
+
+	return nil
+}
+
+/*
+LoadFixture seeds the database with the document(s) in data. Documents are checked with
+the same Validate functions and building/room existence rules as the Create functions,
+but room configurations and device types embedded in rooms and devices are stored as
+given if they don't exist yet, so fixtures don't need to be complete.
+*/
+func (m *MemoryDB) LoadFixture(kind string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// accept either a single document or an array of them
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '[' {
+		data = append(append([]byte{'['}, data...), ']')
+	}
+
+	var docs []json.RawMessage
+	if err := json.Unmarshal(data, &docs); err != nil {
+		return err
+	}
+
+	for _, doc := range docs {
+		if err := m.seed(kind, doc); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// seedRev returns rev, or the first revision if the fixture doesn't have one.
+func seedRev(rev string) string {
+	if len(rev) > 0 {
+		return rev
+	}
+
+	return nextRev("")
+}
+
+func (m *MemoryDB) seed(kind string, doc json.RawMessage) error {
+	switch kind {
+	case "buildings":
+		var b structs.Building
+		if err := json.Unmarshal(doc, &b); err != nil {
+			return err
+		}
+
+		if err := b.Validate(); err != nil {
+			return err
+		}
+
+		b.Rev = seedRev(b.Rev)
+		m.buildings[b.ID] = b
+	case "roomconfigs":
+		var rc structs.RoomConfiguration
+		if err := json.Unmarshal(doc, &rc); err != nil {
+			return err
+		}
+
+		if err := rc.Validate(false); err != nil {
+			return err
+		}
+
+		rc.Rev = seedRev(rc.Rev)
+		m.roomConfigs[rc.ID] = rc
+	case "devicetypes":
+		var dt structs.DeviceType
+		if err := json.Unmarshal(doc, &dt); err != nil {
+			return err
+		}
+
+		if err := dt.Validate(false); err != nil {
+			return err
+		}
+
+		dt.Rev = seedRev(dt.Rev)
+		m.deviceTypes[dt.ID] = dt
+	case "rooms":
+		var room structs.Room
+		if err := json.Unmarshal(doc, &room); err != nil {
+			return err
+		}
+
+		return m.seedRoom(room)
+	case "devices":
+		var device structs.Device
+		if err := json.Unmarshal(doc, &device); err != nil {
+			return err
+		}
+
+		return m.seedDevice(device)
+	case "uiconfigs":
+		var config structs.UIConfig
+		if err := json.Unmarshal(doc, &config); err != nil {
+			return err
+		}
+
+		if len(config.ID) == 0 {
+			return fmt.Errorf("ui config is missing an _id")
+		}
+
+		config.Rev = seedRev(config.Rev)
+		m.uiConfigs[config.ID] = config
+	case "devicestates":
+		var state sd.StaticDevice
+		if err := json.Unmarshal(doc, &state); err != nil {
+			return err
+		}
+
+		if len(state.DeviceID) == 0 {
+			return fmt.Errorf("device state is missing a deviceID")
+		}
+
+		m.deviceStates[state.DeviceID] = state
+	case "roomstates":
+		var state sd.StaticRoom
+		if err := json.Unmarshal(doc, &state); err != nil {
+			return err
+		}
+
+		if len(state.RoomID) == 0 {
+			return fmt.Errorf("room state is missing a roomID")
+		}
+
+		m.roomStates[state.RoomID] = state
+	case "templates":
+		var t structs.Template
+		if err := json.Unmarshal(doc, &t); err != nil {
+			return err
+		}
+
+		if len(t.ID) == 0 {
+			return fmt.Errorf("template is missing an _id")
+		}
+
+		m.templates[t.ID] = t
+	case "attributegroups":
+		var group structs.Group
+		if err := json.Unmarshal(doc, &group); err != nil {
+			return err
+		}
+
+		if len(group.ID) == 0 {
+			return fmt.Errorf("attribute group is missing an _id")
+		}
+
+		group.Rev = seedRev(group.Rev)
+		m.attributeGroups[group.ID] = group
+	case "labconfigs":
+		var config structs.LabConfig
+		if err := json.Unmarshal(doc, &config); err != nil {
+			return err
+		}
+
+		config.Rev = seedRev(config.Rev)
+		m.labConfigs[config.ID] = config
+	case "scheduleconfigs":
+		var config structs.ScheduleConfig
+		if err := json.Unmarshal(doc, &config); err != nil {
+			return err
+		}
+
+		config.Rev = seedRev(config.Rev)
+		m.scheduleConfigs[config.ID] = config
+	default:
+		return fmt.Errorf("unknown fixture kind %q", kind)
+	}
+
+	return nil
+}
+
+func (m *MemoryDB) seedRoom(room structs.Room) error {
+	if err := room.Validate(); err != nil {
+		return err
+	}
+
+	buildingID := strings.Split(room.ID, "-")[0]
+	if _, ok := m.buildings[buildingID]; !ok {
+		return fmt.Errorf("unable to seed room %s: building %s doesn't exist", room.ID, buildingID)
+	}
+
+	if _, ok := m.roomConfigs[room.Configuration.ID]; !ok {
+		m.roomConfigs[room.Configuration.ID] = room.Configuration
+	}
+
+	devices := room.Devices
+	room.Devices = nil
+	room.Configuration = structs.RoomConfiguration{ID: room.Configuration.ID}
+	room.Rev = seedRev(room.Rev)
+	m.rooms[room.ID] = room
+
+	for _, device := range devices {
+		if err := m.seedDevice(device); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryDB) seedDevice(device structs.Device) error {
+	if err := device.Validate(); err != nil {
+		return err
+	}
+
+	roomID := roomIDFromDevice(device.ID)
+	if _, ok := m.rooms[roomID]; !ok {
+		return fmt.Errorf("unable to seed device %s: room %s doesn't exist", device.ID, roomID)
+	}
+
+	if _, ok := m.deviceTypes[device.Type.ID]; !ok {
+		m.deviceTypes[device.Type.ID] = device.Type
+	}
+
+	m.putDevice(device)
+	return nil
+}
@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/byuoitav/common/structs"
+)
+
+// GetLabConfig returns the lab configuration for a room.
+func (m *MemoryDB) GetLabConfig(roomID string) (structs.LabConfig, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var config structs.LabConfig
+
+	c, ok := m.labConfigs[roomID]
+	if !ok {
+		return config, notFound("Error while getting Lab Config from DB for room %s: lab config %s not found", roomID, roomID)
+	}
+
+	clone(c, &config)
+	return config, nil
+}
+
+// GetAllLabConfigs returns the lab attendance configuration of every room that has one.
+func (m *MemoryDB) GetAllLabConfigs() ([]structs.LabConfig, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn []structs.LabConfig
+	for _, id := range sortedKeys(m.labConfigs) {
+		var config structs.LabConfig
+		clone(m.labConfigs[id], &config)
+		toReturn = append(toReturn, config)
+	}
+
+	return toReturn, nil
+}
+
+// CreateLabConfig adds the lab attendance configuration for a room.
+func (m *MemoryDB) CreateLabConfig(config structs.LabConfig) (structs.LabConfig, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var toReturn structs.LabConfig
+
+	if err := config.Validate(); err != nil {
+		return toReturn, err
+	}
+
+	if _, ok := m.labConfigs[config.ID]; ok {
+		return toReturn, fmt.Errorf("unable to create lab config, because it already exists. error: lab config %s already exists", config.ID)
+	}
+
+	clone(config, &toReturn)
+	toReturn.Rev = nextRev("")
+	m.labConfigs[config.ID] = toReturn
+
+	return toReturn, nil
+}
+
+// UpdateLabConfig updates the lab attendance configuration for a room. If config.Rev is set, it is only updated if it hasn't changed since that revision.
+func (m *MemoryDB) UpdateLabConfig(roomID string, config structs.LabConfig) (structs.LabConfig, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var toReturn structs.LabConfig
+
+	if err := config.Validate(); err != nil {
+		return toReturn, err
+	}
+
+	if config.ID != roomID {
+		return toReturn, fmt.Errorf("unable to update lab config for %s: the room of a lab config can't be changed", roomID)
+	}
+
+	current, ok := m.labConfigs[roomID]
+	if !ok {
+		return toReturn, notFound("failed to update lab config for %s: lab config %s not found", roomID, roomID)
+	}
+
+	if err := checkRev(roomID, config.Rev, current.Rev); err != nil {
+		return toReturn, err
+	}
+
+	clone(config, &toReturn)
+	toReturn.Rev = nextRev(current.Rev)
+	m.labConfigs[roomID] = toReturn
+
+	return toReturn, nil
+}
+
+// DeleteLabConfig deletes the lab attendance configuration for a room.
+func (m *MemoryDB) DeleteLabConfig(roomID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.labConfigs[roomID]; !ok {
+		return notFound("failed to get lab config %s to delete: lab config %s not found", roomID, roomID)
+	}
+
+	delete(m.labConfigs, roomID)
+	return nil
+}
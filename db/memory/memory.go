@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/byuoitav/common/db/couch"
+	sd "github.com/byuoitav/common/state/statedefinition"
+	"github.com/byuoitav/common/structs"
+)
+
+// MemoryDB is an in-memory implementation of the database. It enforces the same
+// validation and cascade rules as the couch package, which makes it useful for
+// unit tests and for development without a running CouchDB.
+type MemoryDB struct {
+	mu sync.RWMutex
+
+	buildings       map[string]structs.Building
+	rooms           map[string]structs.Room
+	devices         map[string]structs.Device
+	deviceTypes     map[string]structs.DeviceType
+	roomConfigs     map[string]structs.RoomConfiguration
+	uiConfigs       map[string]structs.UIConfig
+	deviceStates    map[string]sd.StaticDevice
+	roomStates      map[string]sd.StaticRoom
+	labConfigs      map[string]structs.LabConfig
+	scheduleConfigs map[string]structs.ScheduleConfig
+	templates       map[string]structs.Template
+	attributeGroups map[string]structs.Group
+
+	deploymentInfo map[string]structs.FullConfig
+	deviceDeploy   map[string]structs.DeviceDeploymentConfig
+	serviceInfo    map[string]structs.ServiceConfigWrapper
+
+	schemaVersions map[string]structs.SchemaVersion
+	auditEntries   map[string]structs.AuditEntry
+
+	// attachments are keyed by <doc id>/<attachment name>
+	uiAttachments      map[string]attachment
+	roomAttachments    map[string][]string
+	serviceAttachments map[string][]byte
+
+	icons        []string
+	roles        []structs.Role
+	designations []string
+	closureCodes []string
+	tags         []string
+	menuTree     []string
+	roomSchemas  map[string][]structs.AttributeSchema
+	dmps         structs.DMPSList
+	auth         structs.Auth
+}
+
+type attachment struct {
+	contentType string
+	data        []byte
+}
+
+// NewDB returns an empty in-memory database.
+func NewDB() *MemoryDB {
+	return &MemoryDB{
+		buildings:          make(map[string]structs.Building),
+		rooms:              make(map[string]structs.Room),
+		devices:            make(map[string]structs.Device),
+		deviceTypes:        make(map[string]structs.DeviceType),
+		roomConfigs:        make(map[string]structs.RoomConfiguration),
+		uiConfigs:          make(map[string]structs.UIConfig),
+		deviceStates:       make(map[string]sd.StaticDevice),
+		roomStates:         make(map[string]sd.StaticRoom),
+		labConfigs:         make(map[string]structs.LabConfig),
+		scheduleConfigs:    make(map[string]structs.ScheduleConfig),
+		templates:          make(map[string]structs.Template),
+		attributeGroups:    make(map[string]structs.Group),
+		deploymentInfo:     make(map[string]structs.FullConfig),
+		deviceDeploy:       make(map[string]structs.DeviceDeploymentConfig),
+		serviceInfo:        make(map[string]structs.ServiceConfigWrapper),
+		schemaVersions:     make(map[string]structs.SchemaVersion),
+		auditEntries:       make(map[string]structs.AuditEntry),
+		uiAttachments:      make(map[string]attachment),
+		roomAttachments:    make(map[string][]string),
+		serviceAttachments: make(map[string][]byte),
+	}
+}
+
+// nextRev returns the revision that follows rev. Revisions have the same "<generation>-<hash>" form as couch's.
+func nextRev(rev string) string {
+	generation, _ := strconv.Atoi(strings.SplitN(rev, "-", 2)[0])
+	return fmt.Sprintf("%d-%08x", generation+1, rand.Uint32())
+}
+
+// checkRev returns a conflict if rev is set, and isn't the current revision of id.
+func checkRev(id, rev, current string) error {
+	if len(rev) > 0 && rev != current {
+		return couch.NewConflict(id, rev, current)
+	}
+
+	return nil
+}
+
+// GetStatus always reports the in-memory database as ready.
+func (m *MemoryDB) GetStatus() (string, error) {
+	return "completed", nil
+}
+
+// clone deep copies src into dst by round tripping it through json, the same way
+// a document is copied on its way in and out of couch.
+func clone(src, dst interface{}) {
+	b, err := json.Marshal(src)
+	if err != nil {
+		panic(fmt.Sprintf("unable to clone %T: %s", src, err))
+	}
+
+	if err := json.Unmarshal(b, dst); err != nil {
+		panic(fmt.Sprintf("unable to clone %T: %s", src, err))
+	}
+}
+
+// notFound returns the *couch.NotFound that couch would return for a missing document, so that callers can check for it the same way with either backend.
+func notFound(format string, a ...interface{}) error {
+	return couch.NewNotFound(fmt.Sprintf(format, a...))
+}
+
+// sortedKeys returns the keys of a map[string]T in sorted order, so that the bulk
+// functions return documents in the same order as couch.
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, k.String())
+	}
+
+	sort.Strings(keys)
+	return keys
+}
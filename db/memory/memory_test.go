@@ -0,0 +1,645 @@
+package memory
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/byuoitav/common/db/couch"
+	"github.com/byuoitav/common/structs"
+)
+
+// the same fixtures that the couch tests use
+var testDir = `../couch/test-data`
+
+func unmarshalFromFile(t *testing.T, filename string, toFill interface{}) {
+	b, err := ioutil.ReadFile(testDir + "/" + filename)
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", filename, err)
+	}
+
+	if err := json.Unmarshal(b, toFill); err != nil {
+		t.Fatalf("failed to unmarshal %s: %s", filename, err)
+	}
+}
+
+func newSeededDB(t *testing.T) *MemoryDB {
+	db := NewDB()
+	if err := db.LoadFixtures(testDir); err != nil {
+		t.Fatalf("failed to load fixtures: %s", err)
+	}
+
+	return db
+}
+
+func TestLoadFixtures(t *testing.T) {
+	db := newSeededDB(t)
+
+	buildings, err := db.GetAllBuildings()
+	if err != nil {
+		t.Fatalf("failed to get all buildings: %s", err)
+	}
+
+	if len(buildings) != 3 {
+		t.Fatalf("expected 3 buildings, got %v", len(buildings))
+	}
+
+	room, err := db.GetRoom("CCC-AAA")
+	if err != nil {
+		t.Fatalf("failed to get room: %s", err)
+	}
+
+	if room.Configuration.ID != "ABC" || len(room.Configuration.Evaluators) != 4 {
+		t.Fatalf("room configuration wasn't filled in: %+v", room.Configuration)
+	}
+}
+
+func TestBuilding(t *testing.T) {
+	db := NewDB()
+
+	var building structs.Building
+	unmarshalFromFile(t, "new_building.json", &building)
+
+	if _, err := db.CreateBuilding(building); err != nil {
+		t.Fatalf("failed to create building: %s", err)
+	}
+
+	if _, err := db.CreateBuilding(building); err == nil {
+		t.Fatalf("created a duplicate building")
+	}
+
+	b, err := db.GetBuilding(building.ID)
+	if err != nil {
+		t.Fatalf("failed to get building %s: %s", building.ID, err)
+	}
+
+	if b.Name != building.Name || len(b.Tags) != len(building.Tags) {
+		t.Fatalf("got a different building than expected...\ngot: %+v\nexpected: %+v", b, building)
+	}
+
+	// a building can't be deleted while it has rooms
+	var room structs.Room
+	unmarshalFromFile(t, "new_room_a.json", &room)
+
+	if err := db.LoadFixture("rooms", mustMarshal(t, room)); err != nil {
+		t.Fatalf("failed to seed room: %s", err)
+	}
+
+	if err := db.DeleteBuilding(building.ID); err == nil {
+		t.Fatalf("deleted building %s while it still had rooms", building.ID)
+	}
+
+	if err := db.DeleteRoom(room.ID); err != nil {
+		t.Fatalf("failed to delete room: %s", err)
+	}
+
+	if err := db.DeleteBuilding(building.ID); err != nil {
+		t.Fatalf("failed to delete building %s: %s", building.ID, err)
+	}
+
+	if _, err := db.GetBuilding(building.ID); err == nil {
+		t.Fatalf("building %s didn't really get deleted", building.ID)
+	}
+}
+
+func TestRoom(t *testing.T) {
+	db := NewDB()
+
+	var room structs.Room
+	unmarshalFromFile(t, "new_room_b.json", &room)
+
+	if _, err := db.CreateRoom(room); err == nil {
+		t.Fatalf("successfully created room when I shouldn't have (there was no building matching the room)")
+	}
+
+	db = newSeededDB(t)
+
+	// the room configuration doesn't exist, and there isn't enough information to create it
+	room.Configuration = structs.RoomConfiguration{ID: "UNKNOWN"}
+	if _, err := db.CreateRoom(room); err == nil {
+		t.Fatalf("successfully created room with an incomplete room configuration")
+	}
+
+	// the seeded rooms created the room configuration
+	room.Configuration = structs.RoomConfiguration{ID: "AAA"}
+	created, err := db.CreateRoom(room)
+	if err != nil {
+		t.Fatalf("failed to create room: %s", err)
+	}
+
+	if len(created.Configuration.Evaluators) == 0 {
+		t.Fatalf("created room didn't include its room configuration")
+	}
+}
+
+func TestDevice(t *testing.T) {
+	db := newSeededDB(t)
+
+	var device structs.Device
+	unmarshalFromFile(t, "new_device.json", &device)
+
+	if _, err := db.CreateDevice(device); err == nil {
+		t.Fatalf("should have failed to create this device, because it didn't have a room for it, but I succeeded")
+	}
+
+	var room structs.Room
+	unmarshalFromFile(t, "new_room_a.json", &room)
+
+	if err := db.LoadFixture("rooms", mustMarshal(t, room)); err != nil {
+		t.Fatalf("failed to seed room: %s", err)
+	}
+
+	if _, err := db.CreateDevice(device); err != nil {
+		t.Fatalf("failed to create device: %s", err)
+	}
+
+	// the device type should have been created along with the device
+	if _, err := db.GetDeviceType(device.Type.ID); err != nil {
+		t.Fatalf("device type wasn't created with the device: %s", err)
+	}
+
+	if err := db.DeleteDeviceType(device.Type.ID); err == nil {
+		t.Fatalf("deleted a device type that devices still depend on")
+	}
+
+	devices, err := db.GetDevicesByRoom(room.ID)
+	if err != nil {
+		t.Fatalf("failed to get devices in room: %s", err)
+	}
+
+	if len(devices) != 1 || devices[0].ID != device.ID {
+		t.Fatalf("expected to find device %s in room %s, got %+v", device.ID, room.ID, devices)
+	}
+
+	device.Description = "updated description"
+	if _, err := db.UpdateDevice(device.ID, device); err != nil {
+		t.Fatalf("failed to update device: %s", err)
+	}
+
+	d, err := db.GetDevice(device.ID)
+	if err != nil {
+		t.Fatalf("failed to get device: %s", err)
+	}
+
+	if d.Description != device.Description {
+		t.Fatalf("device wasn't updated. got description %q", d.Description)
+	}
+
+	// an update with a type that doesn't exist doesn't change anything
+	bad := d
+	bad.Description = "bad type"
+	bad.Type = structs.DeviceType{ID: "missing-type"}
+
+	if _, err := db.UpdateDevice(bad.ID, bad); err == nil {
+		t.Fatalf("updated a device to a type that doesn't exist")
+	}
+
+	if d, err := db.GetDevice(device.ID); err != nil || d.Description != device.Description || d.Type.ID != device.Type.ID {
+		t.Fatalf("device was changed by a failed update (err: %v): %+v", err, d)
+	}
+
+	// deleting the room should delete its devices
+	if err := db.DeleteRoom(room.ID); err != nil {
+		t.Fatalf("failed to delete room: %s", err)
+	}
+
+	if _, err := db.GetDevice(device.ID); err == nil {
+		t.Fatalf("device %s wasn't deleted with its room", device.ID)
+	} else if _, ok := err.(*couch.NotFound); !ok {
+		t.Fatalf("expected a *couch.NotFound for a missing device, got %T: %s", err, err)
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to marshal %T: %s", v, err)
+	}
+
+	return b
+}
+
+func TestListRooms(t *testing.T) {
+	db := newSeededDB(t)
+
+	all, err := db.GetAllRooms()
+	if err != nil {
+		t.Fatalf("failed to get all rooms: %s", err)
+	}
+
+	var listed []structs.Room
+	bookmark := ""
+	for {
+		rooms, next, err := db.ListRooms(3, bookmark)
+		if err != nil {
+			t.Fatalf("failed to list rooms: %s", err)
+		}
+
+		if len(rooms) > 3 {
+			t.Fatalf("got a page of %v rooms, expected at most 3", len(rooms))
+		}
+
+		listed = append(listed, rooms...)
+		if len(next) == 0 {
+			break
+		}
+
+		bookmark = next
+	}
+
+	if len(listed) != len(all) {
+		t.Fatalf("listed %v rooms, but there are %v", len(listed), len(all))
+	}
+
+	for i := range all {
+		if listed[i].ID != all[i].ID {
+			t.Fatalf("room %v was %s, expected %s", i, listed[i].ID, all[i].ID)
+		}
+	}
+}
+
+func TestFindDevices(t *testing.T) {
+	db := newSeededDB(t)
+
+	var room structs.Room
+	unmarshalFromFile(t, "new_room_a.json", &room)
+
+	var d structs.Device
+	unmarshalFromFile(t, "new_device.json", &d)
+
+	if err := db.LoadFixture("rooms", mustMarshal(t, room)); err != nil {
+		t.Fatalf("failed to seed room: %s", err)
+	}
+
+	if err := db.LoadFixture("devices", mustMarshal(t, d)); err != nil {
+		t.Fatalf("failed to seed device: %s", err)
+	}
+
+	filter := structs.DeviceFilter{
+		RoomID:      room.ID,
+		Type:        d.Type.ID,
+		Roles:       []string{d.Roles[0].ID},
+		Designation: room.Designation,
+	}
+
+	found, err := db.FindDevices(filter)
+	if err != nil {
+		t.Fatalf("failed to find devices: %s", err)
+	}
+
+	if len(found) != 1 || found[0].ID != d.ID {
+		t.Fatalf("expected to find %s, got %+v", d.ID, found)
+	}
+
+	filter.Roles = []string{"not-a-role"}
+	if found, _ = db.FindDevices(filter); len(found) != 0 {
+		t.Fatalf("found devices without the role: %+v", found)
+	}
+}
+
+func TestRenameBuilding(t *testing.T) {
+	db := newSeededDB(t)
+
+	var room structs.Room
+	unmarshalFromFile(t, "new_room_a.json", &room)
+
+	var d structs.Device
+	unmarshalFromFile(t, "new_device.json", &d)
+	d.Ports[0].SourceDevice = d.ID
+	d.Ports[0].DestinationDevice = room.ID + "-D2"
+
+	ui := structs.UIConfig{
+		ID:     room.ID,
+		Panels: []structs.Panel{{Hostname: room.ID + "-CP1"}},
+	}
+
+	if err := db.LoadFixture("rooms", mustMarshal(t, room)); err != nil {
+		t.Fatalf("failed to seed room: %s", err)
+	}
+
+	if err := db.LoadFixture("devices", mustMarshal(t, d)); err != nil {
+		t.Fatalf("failed to seed device: %s", err)
+	}
+
+	if err := db.LoadFixture("uiconfigs", mustMarshal(t, ui)); err != nil {
+		t.Fatalf("failed to seed ui config: %s", err)
+	}
+
+	// renaming to a building that already exists changes nothing
+	if _, err := db.RenameBuilding("AAA", "BBB"); err == nil {
+		t.Fatalf("renamed building to one that already exists")
+	}
+
+	if _, err := db.GetRoom(room.ID); err != nil {
+		t.Fatalf("failed rename moved room %s: %s", room.ID, err)
+	}
+
+	result, err := db.RenameBuilding("AAA", "DDD")
+	if err != nil {
+		t.Fatalf("failed to rename building: %s", err)
+	}
+
+	for _, doc := range result.Documents {
+		if !doc.Success {
+			t.Fatalf("failed to move %s %s: %s", doc.Kind, doc.OldID, doc.Message)
+		}
+	}
+
+	if _, err := db.GetBuilding("AAA"); err == nil {
+		t.Fatalf("old building still exists")
+	}
+
+	if _, err := db.GetRoom(room.ID); err == nil {
+		t.Fatalf("old room %s still exists", room.ID)
+	}
+
+	device, err := db.GetDevice("DDD-ZZZ-D1")
+	if err != nil {
+		t.Fatalf("failed to get moved device: %s", err)
+	}
+
+	if device.Ports[0].SourceDevice != "DDD-ZZZ-D1" || device.Ports[0].DestinationDevice != "DDD-ZZZ-D2" {
+		t.Fatalf("port references weren't moved: %+v", device.Ports[0])
+	}
+
+	config, err := db.GetUIConfig("DDD-ZZZ")
+	if err != nil {
+		t.Fatalf("failed to get moved ui config: %s", err)
+	}
+
+	if config.Panels[0].Hostname != "DDD-ZZZ-CP1" {
+		t.Fatalf("panel hostname wasn't moved: %s", config.Panels[0].Hostname)
+	}
+
+	// rooms can't be moved into a building that doesn't exist
+	if _, err := db.RenameRoom("DDD-ZZZ", "EEE-ZZZ"); err == nil {
+		t.Fatalf("renamed room into a building that doesn't exist")
+	}
+}
+
+func TestDeleteBuildingCascade(t *testing.T) {
+	db := newSeededDB(t)
+
+	var room structs.Room
+	unmarshalFromFile(t, "new_room_a.json", &room)
+
+	var d structs.Device
+	unmarshalFromFile(t, "new_device.json", &d)
+
+	if err := db.LoadFixture("rooms", mustMarshal(t, room)); err != nil {
+		t.Fatalf("failed to seed room: %s", err)
+	}
+
+	if err := db.LoadFixture("devices", mustMarshal(t, d)); err != nil {
+		t.Fatalf("failed to seed device: %s", err)
+	}
+
+	if err := db.LoadFixture("uiconfigs", mustMarshal(t, structs.UIConfig{ID: room.ID})); err != nil {
+		t.Fatalf("failed to seed ui config: %s", err)
+	}
+
+	plan, err := db.DeleteBuildingCascade("AAA", true)
+	if err != nil {
+		t.Fatalf("failed to plan delete: %s", err)
+	}
+
+	// the building is deleted last
+	last := plan.Documents[len(plan.Documents)-1]
+	if last.Database != "buildings" || last.ID != "AAA" {
+		t.Fatalf("expected the building to be deleted last, got %+v", last)
+	}
+
+	planned := make(map[string]bool)
+	for _, doc := range plan.Documents {
+		planned[doc.Database+"/"+doc.ID] = true
+	}
+
+	for _, expected := range []string{"rooms/" + room.ID, "devices/" + d.ID, "ui-configuration/" + room.ID} {
+		if !planned[expected] {
+			t.Fatalf("plan doesn't include %s: %+v", expected, plan.Documents)
+		}
+	}
+
+	// a dry run doesn't delete anything
+	if _, err := db.GetDevice(d.ID); err != nil {
+		t.Fatalf("dry run deleted device %s: %s", d.ID, err)
+	}
+
+	result, err := db.DeleteBuildingCascade("AAA", false)
+	if err != nil {
+		t.Fatalf("failed to delete building: %s", err)
+	}
+
+	if len(result.Documents) != len(plan.Documents) || len(result.Failed()) != 0 {
+		t.Fatalf("delete didn't match the plan: %+v", result.Documents)
+	}
+
+	if _, err := db.GetDevice(d.ID); err == nil {
+		t.Fatalf("device %s wasn't deleted", d.ID)
+	}
+
+	if _, err := db.GetUIConfig(room.ID); err == nil {
+		t.Fatalf("ui config %s wasn't deleted", room.ID)
+	}
+
+	if _, err := db.GetBuilding("AAA"); err == nil {
+		t.Fatalf("building wasn't deleted")
+	}
+}
+
+func TestBulkRooms(t *testing.T) {
+	db := newSeededDB(t)
+
+	var room structs.Room
+	unmarshalFromFile(t, "new_room_a.json", &room)
+
+	var d structs.Device
+	unmarshalFromFile(t, "new_device.json", &d)
+	room.Devices = []structs.Device{d}
+	room.Configuration = structs.RoomConfiguration{ID: "AAA"}
+
+	responses := db.CreateBulkRooms([]structs.Room{room, room})
+	if len(responses) != 3 {
+		t.Fatalf("expected a response for each room and device, got %+v", responses)
+	}
+
+	if !responses[0].Success || responses[1].Success || !responses[2].Success || responses[2].ID != d.ID {
+		t.Fatalf("expected the first room and its device to be created: %+v", responses)
+	}
+
+	// the device type can't be deleted while the device uses it
+	if responses := db.DeleteBulkDeviceTypes([]string{d.Type.ID}); responses[0].Success {
+		t.Fatalf("deleted a device type that was still in use")
+	}
+
+	if responses := db.DeleteBulkRooms([]string{room.ID, "AAA-NOPE"}); !responses[0].Success || responses[1].Success {
+		t.Fatalf("expected only the existing room to be deleted: %+v", responses)
+	}
+
+	if _, err := db.GetDevice(d.ID); err == nil {
+		t.Fatalf("device %s wasn't deleted with its room", d.ID)
+	}
+}
+
+func TestUpdateBuildingConflict(t *testing.T) {
+	db := newSeededDB(t)
+
+	building, err := db.GetBuilding("AAA")
+	if err != nil {
+		t.Fatalf("failed to get building: %s", err)
+	}
+
+	stale := building
+
+	building.Description = "first update"
+	updated, err := db.UpdateBuilding(building.ID, building)
+	if err != nil {
+		t.Fatalf("failed to update building: %s", err)
+	}
+
+	if len(updated.Rev) == 0 || updated.Rev == stale.Rev {
+		t.Fatalf("expected a new rev, got %q (was %q)", updated.Rev, stale.Rev)
+	}
+
+	stale.Description = "stale update"
+	_, err = db.UpdateBuilding(stale.ID, stale)
+
+	conflict, ok := err.(*couch.Conflict)
+	if !ok {
+		t.Fatalf("expected a conflict updating with a stale rev, got %v", err)
+	}
+
+	if conflict.ExpectedRev != stale.Rev || conflict.CurrentRev != updated.Rev {
+		t.Fatalf("unexpected conflict: %+v", conflict)
+	}
+
+	// an update without a rev always wins
+	updated.Rev = ""
+	if _, err := db.UpdateBuilding(updated.ID, updated); err != nil {
+		t.Fatalf("failed to update building without a rev: %s", err)
+	}
+}
+
+func TestPermissions(t *testing.T) {
+	db := newSeededDB(t)
+
+	if _, err := db.CreatePermission(structs.Permission{Group: "av-(admins", Roles: []string{"write"}}); err == nil {
+		t.Fatalf("expected a group that isn't a valid regular expression to be rejected")
+	}
+
+	if _, err := db.CreatePermission(structs.Permission{Group: "av-.*", Roles: []string{"read"}}); err != nil {
+		t.Fatalf("failed to create permission: %s", err)
+	}
+
+	auth, err := db.UpdatePermission("av-.*", structs.Permission{Group: "av-.*", Roles: []string{"read", "write"}})
+	if err != nil {
+		t.Fatalf("failed to update permission: %s", err)
+	}
+
+	if len(auth.Permissions) != 1 || len(auth.Permissions[0].Roles) != 2 {
+		t.Fatalf("unexpected permissions: %+v", auth.Permissions)
+	}
+
+	if auth, err = db.DeletePermission("av-.*"); err != nil || len(auth.Permissions) != 0 {
+		t.Fatalf("failed to delete permission (err: %v): %+v", err, auth.Permissions)
+	}
+
+	if _, err := db.DeletePermission("av-.*"); err == nil {
+		t.Fatalf("expected deleting a missing permission to fail")
+	}
+}
+
+func TestScheduleConfig(t *testing.T) {
+	db := newSeededDB(t)
+
+	config := structs.ScheduleConfig{ID: "CCC-AAA", Resource: "ccc-aaa@byu.edu", Name: "CCC AAA"}
+
+	created, err := db.CreateScheduleConfig(config)
+	if err != nil {
+		t.Fatalf("failed to create schedule config: %s", err)
+	}
+
+	if _, err := db.CreateScheduleConfig(config); err == nil {
+		t.Fatalf("expected creating an existing schedule config to fail")
+	}
+
+	stale := created
+	created.BookNow = true
+	if _, err := db.UpdateScheduleConfig(created.ID, created); err != nil {
+		t.Fatalf("failed to update schedule config: %s", err)
+	}
+
+	if _, err := db.UpdateScheduleConfig(stale.ID, stale); err == nil {
+		t.Fatalf("expected a conflict updating with a stale rev")
+	}
+
+	if configs, _ := db.GetAllScheduleConfigs(); len(configs) != 1 || !configs[0].BookNow {
+		t.Fatalf("unexpected schedule configs: %+v", configs)
+	}
+
+	if err := db.DeleteScheduleConfig(created.ID); err != nil {
+		t.Fatalf("failed to delete schedule config: %s", err)
+	}
+}
+
+func TestAttributeSchema(t *testing.T) {
+	db := newSeededDB(t)
+
+	dt := structs.DeviceType{
+		ID: "schema-display",
+		AttributeSchema: []structs.AttributeSchema{
+			{Name: "inputs", Type: structs.AttributeInteger, Required: true},
+			{Name: "brand", Type: structs.AttributeString, Default: "sony", Allowed: []interface{}{"sony", "nec"}},
+		},
+	}
+
+	if _, err := db.CreateDeviceType(dt); err != nil {
+		t.Fatalf("failed to create device type: %s", err)
+	}
+
+	device := structs.Device{ID: "CCC-AAA-D1", Name: "D1", Address: "ccc-aaa-d1.byu.edu", Type: structs.DeviceType{ID: dt.ID}, Roles: []structs.Role{{ID: "VideoOut"}}}
+	if _, err := db.CreateDevice(device); err == nil {
+		t.Fatalf("created a device without a required attribute")
+	}
+
+	device.Attributes = map[string]interface{}{"inputs": 2.5}
+	if _, err := db.CreateDevice(device); err == nil {
+		t.Fatalf("created a device with an attribute of the wrong type")
+	}
+
+	device.Attributes = map[string]interface{}{"inputs": 4}
+	created, err := db.CreateDevice(device)
+	if err != nil {
+		t.Fatalf("failed to create device: %s", err)
+	}
+
+	if brand, err := structs.GetStringAttribute(created.Attributes, "brand"); err != nil || brand != "sony" {
+		t.Fatalf("expected the default brand to be applied, got %q (%v)", brand, err)
+	}
+
+	if _, err := structs.GetBoolAttribute(created.Attributes, "inputs"); err == nil || err.Error() != `attribute "inputs" (4) has type integer, not bool` {
+		t.Fatalf("unexpected error getting inputs as a bool: %v", err)
+	}
+
+	created.Attributes["brand"] = "lg"
+	if _, err := db.UpdateDevice(created.ID, created); err == nil {
+		t.Fatalf("updated a device with an attribute that isn't allowed")
+	}
+
+	if _, err := db.UpdateRoomAttributeSchemas(map[string][]structs.AttributeSchema{"development": {{Name: "capacity", Type: "size"}}}); err == nil {
+		t.Fatalf("updated room attribute schemas with an unknown attribute type")
+	}
+
+	if _, err := db.UpdateRoomAttributeSchemas(map[string][]structs.AttributeSchema{"development": {{Name: "capacity", Type: structs.AttributeInteger, Required: true}}}); err != nil {
+		t.Fatalf("failed to update room attribute schemas: %s", err)
+	}
+
+	room, err := db.GetRoom("CCC-AAA")
+	if err != nil {
+		t.Fatalf("failed to get room: %s", err)
+	}
+
+	if _, err := db.UpdateRoom(room.ID, room); err == nil {
+		t.Fatalf("updated a room without a required attribute")
+	}
+}
@@ -0,0 +1,166 @@
+package memory
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/byuoitav/common/db/couch"
+	"github.com/byuoitav/common/structs"
+)
+
+// GetSchemaVersion returns the schema version of database. A database that has never been migrated is at version 0.
+func (m *MemoryDB) GetSchemaVersion(database string) (structs.SchemaVersion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	version, ok := m.schemaVersions[database]
+	if !ok {
+		return structs.SchemaVersion{Database: database}, nil
+	}
+
+	var toReturn structs.SchemaVersion
+	clone(version, &toReturn)
+
+	return toReturn, nil
+}
+
+// PutSchemaVersion writes the schema version of database. version.Rev must be the rev returned by GetSchemaVersion.
+func (m *MemoryDB) PutSchemaVersion(database string, version structs.SchemaVersion) (structs.SchemaVersion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := m.schemaVersions[database].Rev
+	if version.Rev != current {
+		return version, couch.NewConflict(database+"/"+couch.SCHEMA_VERSION, version.Rev, current)
+	}
+
+	var toStore structs.SchemaVersion
+	clone(version, &toStore)
+	toStore.Database = database
+	toStore.Rev = nextRev(current)
+
+	m.schemaVersions[database] = toStore
+	return toStore, nil
+}
+
+// rawTable returns the map holding the documents in database.
+func (m *MemoryDB) rawTable(database string) (interface{}, error) {
+	switch database {
+	case couch.BUILDINGS:
+		return m.buildings, nil
+	case couch.ROOMS:
+		return m.rooms, nil
+	case couch.DEVICES:
+		return m.devices, nil
+	case couch.DEVICE_TYPES:
+		return m.deviceTypes, nil
+	case couch.ROOM_CONFIGURATIONS:
+		return m.roomConfigs, nil
+	case couch.UI_CONFIGS:
+		return m.uiConfigs, nil
+	default:
+		return nil, fmt.Errorf("%s can't be migrated in memory", database)
+	}
+}
+
+/*
+GetRawDocuments returns every document in database, decoded as plain JSON.
+
+Documents are stored in memory as their structs, so (unlike in couch) a field that isn't part of
+the struct is dropped when the document is written back.
+*/
+func (m *MemoryDB) GetRawDocuments(database string) ([]map[string]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	table, err := m.rawTable(database)
+	if err != nil {
+		return nil, err
+	}
+
+	var toReturn []map[string]interface{}
+	for _, id := range sortedKeys(table) {
+		var doc map[string]interface{}
+		clone(reflect.ValueOf(table).MapIndex(reflect.ValueOf(id)).Interface(), &doc)
+
+		toReturn = append(toReturn, doc)
+	}
+
+	return toReturn, nil
+}
+
+// PutRawDocuments writes each of docs back to database. Each document must have the _rev it was read with.
+func (m *MemoryDB) PutRawDocuments(database string, docs []map[string]interface{}) []structs.BulkUpdateResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var toReturn []structs.BulkUpdateResponse
+
+	for _, doc := range docs {
+		id, _ := doc["_id"].(string)
+		rev, _ := doc["_rev"].(string)
+
+		response := structs.BulkUpdateResponse{ID: id}
+		if err := m.putRawDocument(database, id, rev, doc); err != nil {
+			response.Message = err.Error()
+		} else {
+			response.Success = true
+		}
+
+		toReturn = append(toReturn, response)
+	}
+
+	return toReturn
+}
+
+func (m *MemoryDB) putRawDocument(database, id, rev string, doc map[string]interface{}) error {
+	table, err := m.rawTable(database)
+	if err != nil {
+		return err
+	}
+
+	var current string
+	if existing := reflect.ValueOf(table).MapIndex(reflect.ValueOf(id)); existing.IsValid() {
+		current = existing.FieldByName("Rev").String()
+	}
+
+	if len(current) == 0 || rev != current {
+		return couch.NewConflict(id, rev, current)
+	}
+
+	switch database {
+	case couch.BUILDINGS:
+		var b structs.Building
+		clone(doc, &b)
+		b.Rev = nextRev(current)
+		m.buildings[id] = b
+	case couch.ROOMS:
+		var r structs.Room
+		clone(doc, &r)
+		r.Devices = nil
+		r.Configuration = structs.RoomConfiguration{ID: r.Configuration.ID}
+		r.Rev = nextRev(current)
+		m.rooms[id] = r
+	case couch.DEVICES:
+		var d structs.Device
+		clone(doc, &d)
+		d.ID = id
+		m.putDevice(d)
+	case couch.DEVICE_TYPES:
+		var dt structs.DeviceType
+		clone(doc, &dt)
+		dt.Rev = nextRev(current)
+		m.deviceTypes[id] = dt
+	case couch.ROOM_CONFIGURATIONS:
+		var rc structs.RoomConfiguration
+		clone(doc, &rc)
+		rc.Rev = nextRev(current)
+		m.roomConfigs[id] = rc
+	case couch.UI_CONFIGS:
+		var ui structs.UIConfig
+		clone(doc, &ui)
+		m.putUIConfig(id, ui)
+	}
+
+	return nil
+}
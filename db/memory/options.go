@@ -0,0 +1,244 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/byuoitav/common/structs"
+)
+
+// TEMPLATES
+
+// GetAllTemplates returns each of the room templates that have a ui config.
+func (m *MemoryDB) GetAllTemplates() ([]structs.Template, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn []structs.Template
+	for _, id := range sortedKeys(m.templates) {
+		if len(m.templates[id].UIConfig.Api) == 0 {
+			continue
+		}
+
+		var t structs.Template
+		clone(m.templates[id], &t)
+		toReturn = append(toReturn, t)
+	}
+
+	return toReturn, nil
+}
+
+// GetTemplate returns the ui config of a template.
+func (m *MemoryDB) GetTemplate(id string) (structs.UIConfig, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn structs.UIConfig
+
+	t, ok := m.templates[id]
+	if !ok {
+		return toReturn, notFound("failed to get template %s: template %s not found", id, id)
+	}
+
+	clone(t.UIConfig, &toReturn)
+	return toReturn, nil
+}
+
+// UpdateTemplate updates the ui config of a template. If the id changes, the template is moved to the new id.
+func (m *MemoryDB) UpdateTemplate(id string, newTemp structs.UIConfig) (structs.UIConfig, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var toReturn structs.UIConfig
+
+	t, ok := m.templates[id]
+	if !ok {
+		return toReturn, notFound("unable to get template %s to update: template %s not found", id, id)
+	}
+
+	if id != newTemp.ID {
+		if _, ok := m.templates[newTemp.ID]; ok {
+			return toReturn, fmt.Errorf("template already exists, please update this template or change IDs. error: template %s already exists", newTemp.ID)
+		}
+
+		delete(m.templates, id)
+		t.ID = newTemp.ID
+	}
+
+	clone(newTemp, &t.UIConfig)
+	m.templates[t.ID] = t
+
+	clone(t.UIConfig, &toReturn)
+	return toReturn, nil
+}
+
+// ICONS
+
+// GetIcons returns the list of icons.
+func (m *MemoryDB) GetIcons() ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return copyStrings(m.icons), nil
+}
+
+// UpdateIcons replaces the list of icons.
+func (m *MemoryDB) UpdateIcons(iconList []string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.icons = copyStrings(iconList)
+	return copyStrings(m.icons), nil
+}
+
+// ROLES
+
+// GetDeviceRoles returns the list of device roles.
+func (m *MemoryDB) GetDeviceRoles() ([]structs.Role, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn []structs.Role
+	clone(m.roles, &toReturn)
+	return toReturn, nil
+}
+
+// UpdateDeviceRoles replaces the list of device roles.
+func (m *MemoryDB) UpdateDeviceRoles(roles []structs.Role) ([]structs.Role, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.roles = nil
+	clone(roles, &m.roles)
+
+	var toReturn []structs.Role
+	clone(m.roles, &toReturn)
+	return toReturn, nil
+}
+
+// DESIGNATIONS
+
+// GetRoomDesignations returns the list of room designations.
+func (m *MemoryDB) GetRoomDesignations() ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return copyStrings(m.designations), nil
+}
+
+// UpdateRoomDesignations replaces the list of room designations.
+func (m *MemoryDB) UpdateRoomDesignations(desigs []string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.designations = copyStrings(desigs)
+	return copyStrings(m.designations), nil
+}
+
+// ROOM ATTRIBUTE SCHEMAS
+
+// GetRoomAttributeSchemas returns the attribute schema of the rooms with each designation.
+func (m *MemoryDB) GetRoomAttributeSchemas() (map[string][]structs.AttributeSchema, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn map[string][]structs.AttributeSchema
+	clone(m.roomSchemas, &toReturn)
+	return toReturn, nil
+}
+
+// UpdateRoomAttributeSchemas replaces the attribute schema of the rooms with each designation.
+func (m *MemoryDB) UpdateRoomAttributeSchemas(schemas map[string][]structs.AttributeSchema) (map[string][]structs.AttributeSchema, error) {
+	for designation, schema := range schemas {
+		if err := structs.ValidateAttributeSchemas(schema, designation).Err("room attribute schemas"); err != nil {
+			return nil, err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.roomSchemas = nil
+	clone(schemas, &m.roomSchemas)
+
+	var toReturn map[string][]structs.AttributeSchema
+	clone(m.roomSchemas, &toReturn)
+	return toReturn, nil
+}
+
+// CLOSURE CODES
+
+// GetClosureCodes returns the list of closure codes.
+func (m *MemoryDB) GetClosureCodes() ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return copyStrings(m.closureCodes), nil
+}
+
+// UpdateClosureCodes replaces the list of closure codes.
+func (m *MemoryDB) UpdateClosureCodes(codes []string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closureCodes = copyStrings(codes)
+	return copyStrings(m.closureCodes), nil
+}
+
+// TAGS
+
+// GetTags returns the list of tags.
+func (m *MemoryDB) GetTags() ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return copyStrings(m.tags), nil
+}
+
+// UpdateTags replaces the list of tags.
+func (m *MemoryDB) UpdateTags(newTags []string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tags = copyStrings(newTags)
+	return copyStrings(m.tags), nil
+}
+
+// GetMenuTree returns the order of the attribute groups.
+func (m *MemoryDB) GetMenuTree() ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return copyStrings(m.menuTree), nil
+}
+
+// UpdateMenuTree replaces the order of the attribute groups in the menu tree. Each group must exist, and can only be in it once.
+func (m *MemoryDB) UpdateMenuTree(order []string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, id := range order {
+		if seen[id] {
+			return nil, fmt.Errorf("invalid menu tree: %s is in it more than once", id)
+		}
+
+		seen[id] = true
+
+		if _, ok := m.attributeGroups[id]; !ok {
+			return nil, notFound("invalid menu tree: failed to get attribute group %s: attribute group %s not found", id, id)
+		}
+	}
+
+	m.menuTree = copyStrings(order)
+	return copyStrings(m.menuTree), nil
+}
+
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+
+	toReturn := make([]string, len(s))
+	copy(toReturn, s)
+	return toReturn
+}
@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"sort"
+
+	"github.com/byuoitav/common/db/couch"
+	sd "github.com/byuoitav/common/state/statedefinition"
+	"github.com/byuoitav/common/structs"
+)
+
+/*
+page returns the sorted keys of m that belong on the page starting at bookmark, and the
+bookmark for the next page. Bookmarks are the last id on the previous page, so, like
+couch's, they stay valid while documents are added and removed.
+*/
+func page(m interface{}, pageSize int, bookmark string) ([]string, string) {
+	if pageSize <= 0 {
+		pageSize = couch.DefaultPageSize
+	}
+
+	keys := sortedKeys(m)
+	start := sort.Search(len(keys), func(i int) bool {
+		return keys[i] > bookmark
+	})
+
+	keys = keys[start:]
+	if len(keys) <= pageSize {
+		return keys, ""
+	}
+
+	keys = keys[:pageSize]
+	return keys, keys[len(keys)-1]
+}
+
+// ListBuildings returns a page of buildings, and the bookmark for the next page.
+func (m *MemoryDB) ListBuildings(pageSize int, bookmark string) ([]structs.Building, string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn []structs.Building
+
+	ids, next := page(m.buildings, pageSize, bookmark)
+	for _, id := range ids {
+		var building structs.Building
+		clone(m.buildings[id], &building)
+		toReturn = append(toReturn, building)
+	}
+
+	return toReturn, next, nil
+}
+
+// ListRooms returns a page of rooms, and the bookmark for the next page.
+func (m *MemoryDB) ListRooms(pageSize int, bookmark string) ([]structs.Room, string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn []structs.Room
+
+	ids, next := page(m.rooms, pageSize, bookmark)
+	for _, id := range ids {
+		var room structs.Room
+		clone(m.rooms[id], &room)
+		toReturn = append(toReturn, room)
+	}
+
+	return toReturn, next, nil
+}
+
+// ListDevices returns a page of devices, and the bookmark for the next page. Only the id of each device's type is included.
+func (m *MemoryDB) ListDevices(pageSize int, bookmark string) ([]structs.Device, string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn []structs.Device
+
+	ids, next := page(m.devices, pageSize, bookmark)
+	for _, id := range ids {
+		var device structs.Device
+		clone(m.devices[id], &device)
+		toReturn = append(toReturn, device)
+	}
+
+	return toReturn, next, nil
+}
+
+// ListDeviceStates returns a page of device states, and the bookmark for the next page.
+func (m *MemoryDB) ListDeviceStates(pageSize int, bookmark string) ([]sd.StaticDevice, string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn []sd.StaticDevice
+
+	ids, next := page(m.deviceStates, pageSize, bookmark)
+	for _, id := range ids {
+		var state sd.StaticDevice
+		clone(m.deviceStates[id], &state)
+		toReturn = append(toReturn, state)
+	}
+
+	return toReturn, next, nil
+}
+
+// ListUIConfigs returns a page of ui configs, and the bookmark for the next page.
+func (m *MemoryDB) ListUIConfigs(pageSize int, bookmark string) ([]structs.UIConfig, string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn []structs.UIConfig
+
+	ids, next := page(m.uiConfigs, pageSize, bookmark)
+	for _, id := range ids {
+		var config structs.UIConfig
+		clone(m.uiConfigs[id], &config)
+		toReturn = append(toReturn, config)
+	}
+
+	return toReturn, next, nil
+}
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/byuoitav/common/db/couch"
+	"github.com/byuoitav/common/structs"
+)
+
+// GetAuth returns the permissions document.
+func (m *MemoryDB) GetAuth() (structs.Auth, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn structs.Auth
+	clone(m.auth, &toReturn)
+	return toReturn, nil
+}
+
+// CreatePermission adds a permission, and returns the updated permissions.
+func (m *MemoryDB) CreatePermission(permission structs.Permission) (structs.Auth, error) {
+	return m.updateAuth(func(auth *structs.Auth) error {
+		for _, p := range auth.Permissions {
+			if p.Group == permission.Group {
+				return fmt.Errorf("there is already a permission for %s", permission.Group)
+			}
+		}
+
+		auth.Permissions = append(auth.Permissions, permission)
+		return nil
+	})
+}
+
+// UpdatePermission replaces the permission for group, and returns the updated permissions.
+func (m *MemoryDB) UpdatePermission(group string, permission structs.Permission) (structs.Auth, error) {
+	return m.updateAuth(func(auth *structs.Auth) error {
+		for i := range auth.Permissions {
+			if auth.Permissions[i].Group == group {
+				auth.Permissions[i] = permission
+				return nil
+			}
+		}
+
+		return notFound("permission %s not found", group)
+	})
+}
+
+// DeletePermission removes the permission for group, and returns the updated permissions.
+func (m *MemoryDB) DeletePermission(group string) (structs.Auth, error) {
+	return m.updateAuth(func(auth *structs.Auth) error {
+		for i := range auth.Permissions {
+			if auth.Permissions[i].Group == group {
+				auth.Permissions = append(auth.Permissions[:i], auth.Permissions[i+1:]...)
+				return nil
+			}
+		}
+
+		return notFound("permission %s not found", group)
+	})
+}
+
+func (m *MemoryDB) updateAuth(change func(auth *structs.Auth) error) (structs.Auth, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var auth structs.Auth
+	clone(m.auth, &auth)
+	auth.ID = couch.AUTH
+
+	if err := change(&auth); err != nil {
+		return auth, fmt.Errorf("unable to update permissions: %s", err)
+	}
+
+	if err := auth.Validate(); err != nil {
+		return auth, err
+	}
+
+	auth.Rev = nextRev(m.auth.Rev)
+	m.auth = auth
+
+	var toReturn structs.Auth
+	clone(m.auth, &toReturn)
+	return toReturn, nil
+}
@@ -0,0 +1,154 @@
+package memory
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/byuoitav/common/structs"
+)
+
+// RenameBuilding moves building oldID to newID, along with each of its rooms, their devices, and their ui configs.
+// Everything is checked before anything is moved, so a rename either fully succeeds or changes nothing.
+func (m *MemoryDB) RenameBuilding(oldID, newID string) (structs.RenameResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.renameBuilding(oldID, newID)
+}
+
+func (m *MemoryDB) renameBuilding(oldID, newID string) (structs.RenameResult, error) {
+	result := structs.RenameResult{OldID: oldID, NewID: newID}
+
+	old, ok := m.buildings[oldID]
+	if !ok {
+		return result, notFound("unable to get building %s to rename: building %s not found", oldID, oldID)
+	}
+
+	var renamed structs.Building
+	clone(old, &renamed)
+	renamed.ID = newID
+	renamed.Rev = nextRev("")
+
+	if err := renamed.Validate(); err != nil {
+		return result, fmt.Errorf("unable to rename building %s: %s", oldID, err)
+	}
+
+	if _, ok := m.buildings[newID]; ok {
+		return result, fmt.Errorf("unable to rename to %s: it already exists", newID)
+	}
+
+	// rooms in the new building can't already exist if the new building doesn't
+	var roomIDs []string
+	for _, id := range sortedKeys(m.rooms) {
+		if strings.HasPrefix(id, oldID+"-") {
+			roomIDs = append(roomIDs, id)
+		}
+	}
+
+	delete(m.buildings, oldID)
+	m.buildings[newID] = renamed
+	result.Documents = append(result.Documents, renamedDocument("building", oldID, newID))
+
+	for _, id := range roomIDs {
+		result.Documents = append(result.Documents, m.moveRoom(id, oldID, newID)...)
+	}
+
+	return result, nil
+}
+
+// RenameRoom moves room oldID to newID, along with its devices and its ui config. The building of newID must already exist.
+func (m *MemoryDB) RenameRoom(oldID, newID string) (structs.RenameResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.renameRoom(oldID, newID)
+}
+
+func (m *MemoryDB) renameRoom(oldID, newID string) (structs.RenameResult, error) {
+	result := structs.RenameResult{OldID: oldID, NewID: newID}
+
+	old, ok := m.rooms[oldID]
+	if !ok {
+		return result, notFound("unable to get room %s to rename: room %s not found", oldID, oldID)
+	}
+
+	var renamed structs.Room
+	clone(old, &renamed)
+	renamed.ID = newID
+
+	if err := renamed.Validate(); err != nil {
+		return result, fmt.Errorf("unable to rename room %s: %s", oldID, err)
+	}
+
+	buildingID := strings.Split(newID, "-")[0]
+	if _, ok := m.buildings[buildingID]; !ok {
+		return result, fmt.Errorf("unable to rename room %s: building %s doesn't exist", oldID, buildingID)
+	}
+
+	if _, ok := m.rooms[newID]; ok {
+		return result, fmt.Errorf("unable to rename to %s: it already exists", newID)
+	}
+
+	for id := range m.devices {
+		if strings.HasPrefix(id, newID+"-") {
+			return result, fmt.Errorf("unable to rename to %s: device %s already exists", newID, id)
+		}
+	}
+
+	if _, ok := m.uiConfigs[newID]; ok {
+		return result, fmt.Errorf("unable to rename to %s: ui config %s already exists", newID, newID)
+	}
+
+	result.Documents = m.moveRoom(oldID, oldID, newID)
+	return result, nil
+}
+
+// moveRoom moves room id, its devices, and its ui config, for oldID (the room, or its building) being renamed to newID.
+func (m *MemoryDB) moveRoom(id, oldID, newID string) []structs.RenamedDocument {
+	var docs []structs.RenamedDocument
+
+	room := m.rooms[id]
+	room.ID = structs.RenameID(id, oldID, newID)
+	room.Rev = nextRev("")
+
+	delete(m.rooms, id)
+	m.rooms[room.ID] = room
+	docs = append(docs, renamedDocument("room", id, room.ID))
+
+	for _, deviceID := range sortedKeys(m.devices) {
+		if !strings.HasPrefix(deviceID, id+"-") {
+			continue
+		}
+
+		var device structs.Device
+		clone(m.devices[deviceID], &device)
+		device.Rename(oldID, newID)
+		device.Rev = nextRev("")
+
+		delete(m.devices, deviceID)
+		m.devices[device.ID] = device
+		docs = append(docs, renamedDocument("device", deviceID, device.ID))
+	}
+
+	if config, ok := m.uiConfigs[id]; ok {
+		var renamed structs.UIConfig
+		clone(config, &renamed)
+		renamed.Rename(oldID, newID)
+		renamed.Rev = nextRev("")
+
+		delete(m.uiConfigs, id)
+		m.uiConfigs[renamed.ID] = renamed
+		docs = append(docs, renamedDocument("ui-config", id, renamed.ID))
+	}
+
+	return docs
+}
+
+func renamedDocument(kind, oldID, newID string) structs.RenamedDocument {
+	return structs.RenamedDocument{
+		Kind:    kind,
+		OldID:   oldID,
+		NewID:   newID,
+		Success: true,
+	}
+}
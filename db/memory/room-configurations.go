@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/byuoitav/common/structs"
+)
+
+// GetRoomConfiguration returns a room configuration.
+func (m *MemoryDB) GetRoomConfiguration(id string) (structs.RoomConfiguration, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.getRoomConfiguration(id)
+}
+
+func (m *MemoryDB) getRoomConfiguration(id string) (structs.RoomConfiguration, error) {
+	var toReturn structs.RoomConfiguration
+
+	rc, ok := m.roomConfigs[id]
+	if !ok {
+		return toReturn, notFound("failed to get room configuration %s: room configuration %s not found", id, id)
+	}
+
+	clone(rc, &toReturn)
+	return toReturn, nil
+}
+
+// GetAllRoomConfigurations returns every room configuration.
+func (m *MemoryDB) GetAllRoomConfigurations() ([]structs.RoomConfiguration, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn []structs.RoomConfiguration
+	for _, id := range sortedKeys(m.roomConfigs) {
+		var rc structs.RoomConfiguration
+		clone(m.roomConfigs[id], &rc)
+		toReturn = append(toReturn, rc)
+	}
+
+	return toReturn, nil
+}
+
+// CreateRoomConfiguration creates a room configuration. The room configuration must pass a deep validation.
+func (m *MemoryDB) CreateRoomConfiguration(toAdd structs.RoomConfiguration) (structs.RoomConfiguration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.createRoomConfiguration(toAdd)
+}
+
+func (m *MemoryDB) createRoomConfiguration(toAdd structs.RoomConfiguration) (structs.RoomConfiguration, error) {
+	var toReturn structs.RoomConfiguration
+
+	if err := toAdd.Validate(true); err != nil {
+		return toReturn, err
+	}
+
+	if _, ok := m.roomConfigs[toAdd.ID]; ok {
+		return toReturn, fmt.Errorf("room configuration already exists; please update this configuration or change id's. error: room configuration %s already exists", toAdd.ID)
+	}
+
+	clone(toAdd, &toReturn)
+	toReturn.Rev = nextRev("")
+	m.roomConfigs[toAdd.ID] = toReturn
+
+	return m.getRoomConfiguration(toAdd.ID)
+}
+
+// DeleteRoomConfiguration deletes a room configuration. Deletion is refused while rooms still depend on it.
+func (m *MemoryDB) DeleteRoomConfiguration(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if rooms := m.getRoomsByRoomConfiguration(id); len(rooms) != 0 {
+		return fmt.Errorf("can't delete room configuration %s. %v rooms still depend on it.", id, len(rooms))
+	}
+
+	if _, ok := m.roomConfigs[id]; !ok {
+		return notFound("failed to get room configuration %s to delete. does it exist? (error: room configuration %s not found)", id, id)
+	}
+
+	delete(m.roomConfigs, id)
+	return nil
+}
+
+// UpdateRoomConfiguration updates a room configuration. The id of a room configuration cannot be changed while rooms depend on it.
+func (m *MemoryDB) UpdateRoomConfiguration(id string, rc structs.RoomConfiguration) (structs.RoomConfiguration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var toReturn structs.RoomConfiguration
+
+	if err := rc.Validate(true); err != nil {
+		return toReturn, err
+	}
+
+	current, ok := m.roomConfigs[id]
+	if !ok {
+		return toReturn, notFound("unable to get room configuration %s to update: room configuration %s not found", id, id)
+	}
+
+	if err := checkRev(id, rc.Rev, current.Rev); err != nil {
+		return toReturn, err
+	}
+
+	if id != rc.ID {
+		if rooms := m.getRoomsByRoomConfiguration(id); len(rooms) != 0 {
+			return toReturn, fmt.Errorf("can't change the id of room configuration %s. %v rooms still depend on it.", id, len(rooms))
+		}
+
+		if _, ok := m.roomConfigs[rc.ID]; ok {
+			return toReturn, fmt.Errorf("room configuration %s already exists", rc.ID)
+		}
+
+		delete(m.roomConfigs, id)
+		current.Rev = ""
+	}
+
+	clone(rc, &toReturn)
+	toReturn.Rev = nextRev(current.Rev)
+	m.roomConfigs[rc.ID] = toReturn
+
+	return m.getRoomConfiguration(rc.ID)
+}
@@ -0,0 +1,276 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/byuoitav/common/nerr"
+	"github.com/byuoitav/common/structs"
+)
+
+// GetRoom returns a room, including its devices and full room configuration.
+func (m *MemoryDB) GetRoom(id string) (structs.Room, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.getRoom(id)
+}
+
+func (m *MemoryDB) getRoom(id string) (structs.Room, error) {
+	var toReturn structs.Room
+
+	room, ok := m.rooms[id]
+	if !ok {
+		return toReturn, notFound("failed to get room %s: room %s not found", id, id)
+	}
+
+	clone(room, &toReturn)
+
+	// fill in the devices and configuration
+	toReturn.Devices = m.getDevicesByRoom(id)
+
+	config, ok := m.roomConfigs[room.Configuration.ID]
+	if !ok {
+		return toReturn, notFound("failed to get room configuration %s for room %s: room configuration %s not found", room.Configuration.ID, id, room.Configuration.ID)
+	}
+
+	clone(config, &toReturn.Configuration)
+	return toReturn, nil
+}
+
+// GetAllRooms returns every room. Like couch, the rooms only include the id of their configuration.
+func (m *MemoryDB) GetAllRooms() ([]structs.Room, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn []structs.Room
+	for _, id := range sortedKeys(m.rooms) {
+		var room structs.Room
+		clone(m.rooms[id], &room)
+		toReturn = append(toReturn, room)
+	}
+
+	return toReturn, nil
+}
+
+// GetRoomsByBuilding returns each of the rooms in a building.
+func (m *MemoryDB) GetRoomsByBuilding(id string) ([]structs.Room, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.getRoomsByBuilding(id), nil
+}
+
+func (m *MemoryDB) getRoomsByBuilding(id string) []structs.Room {
+	var toReturn []structs.Room
+
+	for _, roomID := range sortedKeys(m.rooms) {
+		if strings.HasPrefix(roomID, id+"-") {
+			var room structs.Room
+			clone(m.rooms[roomID], &room)
+			toReturn = append(toReturn, room)
+		}
+	}
+
+	return toReturn
+}
+
+// GetRoomsByDesignation returns each of the rooms with the given designation.
+func (m *MemoryDB) GetRoomsByDesignation(designation string) ([]structs.Room, *nerr.E) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn []structs.Room
+	for _, id := range sortedKeys(m.rooms) {
+		if strings.EqualFold(m.rooms[id].Designation, designation) {
+			var room structs.Room
+			clone(m.rooms[id], &room)
+			toReturn = append(toReturn, room)
+		}
+	}
+
+	return toReturn, nil
+}
+
+// GetRoomsByRoomConfiguration returns each of the rooms using the given room configuration.
+func (m *MemoryDB) GetRoomsByRoomConfiguration(configID string) ([]structs.Room, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.getRoomsByRoomConfiguration(configID), nil
+}
+
+func (m *MemoryDB) getRoomsByRoomConfiguration(configID string) []structs.Room {
+	var toReturn []structs.Room
+
+	for _, id := range sortedKeys(m.rooms) {
+		if strings.EqualFold(m.rooms[id].Configuration.ID, configID) {
+			var room structs.Room
+			clone(m.rooms[id], &room)
+			toReturn = append(toReturn, room)
+		}
+	}
+
+	return toReturn
+}
+
+/*
+CreateRoom creates a room. As with couch:
+	1. The room must pass validation, and the building portion of its id must be an existing building.
+	2. If the room configuration doesn't exist yet, it is created from the room's configuration.
+	3. Any devices included in the room are created after the room. A device failing to be created does not roll back the room.
+*/
+func (m *MemoryDB) CreateRoom(toAdd structs.Room) (structs.Room, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.createRoom(toAdd)
+}
+
+func (m *MemoryDB) createRoom(toAdd structs.Room) (structs.Room, error) {
+	var toReturn structs.Room
+
+	if err := toAdd.Validate(); err != nil {
+		return toReturn, err
+	}
+
+	if err := toAdd.ApplyAttributeSchema(m.roomSchemas[toAdd.Designation]); err != nil {
+		return toReturn, err
+	}
+
+	// ensure it's in a real building
+	buildingID := strings.Split(toAdd.ID, "-")[0]
+	if _, ok := m.buildings[buildingID]; !ok {
+		return toReturn, fmt.Errorf("unable to create room %s: building %s doesn't exist.", toAdd.ID, buildingID)
+	}
+
+	if _, ok := m.rooms[toAdd.ID]; ok {
+		return toReturn, fmt.Errorf("unable to create new room, because it already exists. error: room %s already exists", toAdd.ID)
+	}
+
+	configID, err := m.ensureRoomConfiguration(toAdd.Configuration)
+	if err != nil {
+		return toReturn, fmt.Errorf("unable to create room %s: %s", toAdd.ID, err)
+	}
+
+	devices := toAdd.Devices
+
+	var room structs.Room
+	clone(toAdd, &room)
+	room.Configuration = structs.RoomConfiguration{ID: configID}
+	room.Devices = nil
+	room.Rev = nextRev("")
+	m.rooms[room.ID] = room
+
+	for i := range devices {
+		m.createDevice(devices[i])
+	}
+
+	return m.getRoom(room.ID)
+}
+
+// ensureRoomConfiguration creates config if it doesn't already exist, and returns its id.
+func (m *MemoryDB) ensureRoomConfiguration(config structs.RoomConfiguration) (string, error) {
+	if _, ok := m.roomConfigs[config.ID]; ok {
+		return config.ID, nil
+	}
+
+	created, err := m.createRoomConfiguration(config)
+	if err != nil {
+		return "", err
+	}
+
+	return created.ID, nil
+}
+
+// DeleteRoom deletes a room and each of the devices in it.
+func (m *MemoryDB) DeleteRoom(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.deleteRoom(id)
+}
+
+func (m *MemoryDB) deleteRoom(id string) error {
+	if _, ok := m.rooms[id]; !ok {
+		return notFound("unable to get room %s to delete: room %s not found", id, id)
+	}
+
+	for _, device := range m.getDevicesByRoom(id) {
+		delete(m.devices, device.ID)
+	}
+
+	delete(m.rooms, id)
+	return nil
+}
+
+// UpdateRoom updates a room. If the id of the room changes, the room's devices are moved into the new room.
+func (m *MemoryDB) UpdateRoom(id string, room structs.Room) (structs.Room, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.updateRoom(id, room)
+}
+
+func (m *MemoryDB) updateRoom(id string, room structs.Room) (structs.Room, error) {
+	var toReturn structs.Room
+
+	if err := room.Validate(); err != nil {
+		return toReturn, err
+	}
+
+	if err := room.ApplyAttributeSchema(m.roomSchemas[room.Designation]); err != nil {
+		return toReturn, err
+	}
+
+	configID, err := m.ensureRoomConfiguration(room.Configuration)
+	if err != nil {
+		return toReturn, fmt.Errorf("unable to create room %s: %s", room.ID, err)
+	}
+
+	if _, ok := m.rooms[id]; !ok {
+		return toReturn, notFound("unable to get room %s to update: room %s not found", id, id)
+	}
+
+	if err := checkRev(id, room.Rev, m.rooms[id].Rev); err != nil {
+		return toReturn, err
+	}
+
+	room.Configuration = structs.RoomConfiguration{ID: configID}
+
+	if id == room.ID {
+		var updated structs.Room
+		clone(room, &updated)
+		updated.Devices = nil
+		updated.Rev = nextRev(m.rooms[id].Rev)
+		m.rooms[id] = updated
+
+		return m.getRoom(id)
+	}
+
+	// the room ID is changing; move its devices and ui config with it, then apply any other changes
+	if _, err := m.renameRoom(id, room.ID); err != nil {
+		return toReturn, fmt.Errorf("failed to move room %s to %s: %s", id, room.ID, err)
+	}
+
+	room.Rev = ""
+	return m.updateRoom(room.ID, room)
+}
+
+// GetRoomAttachments returns the names of the attachments for a room.
+func (m *MemoryDB) GetRoomAttachments(room string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	attachments, ok := m.roomAttachments[room]
+	if !ok {
+		return []string{}, notFound("failed to get room %s: room attachments %s not found", room, room)
+	}
+
+	toReturn := make([]string, len(attachments))
+	copy(toReturn, attachments)
+	sort.Strings(toReturn)
+
+	return toReturn, nil
+}
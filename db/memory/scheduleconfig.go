@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/byuoitav/common/structs"
+)
+
+// GetScheduleConfig returns the scheduling panel configuration for a room.
+func (m *MemoryDB) GetScheduleConfig(roomID string) (structs.ScheduleConfig, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var config structs.ScheduleConfig
+
+	c, ok := m.scheduleConfigs[roomID]
+	if !ok {
+		return config, notFound("Error while getting Scheduling Config from DB for room %s: schedule config %s not found", roomID, roomID)
+	}
+
+	clone(c, &config)
+	return config, nil
+}
+
+// GetAllScheduleConfigs returns the scheduling panel configuration of every room that has one.
+func (m *MemoryDB) GetAllScheduleConfigs() ([]structs.ScheduleConfig, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn []structs.ScheduleConfig
+	for _, id := range sortedKeys(m.scheduleConfigs) {
+		var config structs.ScheduleConfig
+		clone(m.scheduleConfigs[id], &config)
+		toReturn = append(toReturn, config)
+	}
+
+	return toReturn, nil
+}
+
+// CreateScheduleConfig adds the scheduling panel configuration for a room.
+func (m *MemoryDB) CreateScheduleConfig(config structs.ScheduleConfig) (structs.ScheduleConfig, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var toReturn structs.ScheduleConfig
+
+	if err := config.Validate(); err != nil {
+		return toReturn, err
+	}
+
+	if _, ok := m.scheduleConfigs[config.ID]; ok {
+		return toReturn, fmt.Errorf("unable to create schedule config, because it already exists. error: schedule config %s already exists", config.ID)
+	}
+
+	clone(config, &toReturn)
+	toReturn.Rev = nextRev("")
+	m.scheduleConfigs[config.ID] = toReturn
+
+	return toReturn, nil
+}
+
+// UpdateScheduleConfig updates the scheduling panel configuration for a room. If config.Rev is set, it is only updated if it hasn't changed since that revision.
+func (m *MemoryDB) UpdateScheduleConfig(roomID string, config structs.ScheduleConfig) (structs.ScheduleConfig, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var toReturn structs.ScheduleConfig
+
+	if err := config.Validate(); err != nil {
+		return toReturn, err
+	}
+
+	if config.ID != roomID {
+		return toReturn, fmt.Errorf("unable to update schedule config for %s: the room of a schedule config can't be changed", roomID)
+	}
+
+	current, ok := m.scheduleConfigs[roomID]
+	if !ok {
+		return toReturn, notFound("failed to update schedule config for %s: schedule config %s not found", roomID, roomID)
+	}
+
+	if err := checkRev(roomID, config.Rev, current.Rev); err != nil {
+		return toReturn, err
+	}
+
+	clone(config, &toReturn)
+	toReturn.Rev = nextRev(current.Rev)
+	m.scheduleConfigs[roomID] = toReturn
+
+	return toReturn, nil
+}
+
+// DeleteScheduleConfig deletes the scheduling panel configuration for a room.
+func (m *MemoryDB) DeleteScheduleConfig(roomID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.scheduleConfigs[roomID]; !ok {
+		return notFound("failed to get schedule config %s to delete: schedule config %s not found", roomID, roomID)
+	}
+
+	delete(m.scheduleConfigs, roomID)
+	return nil
+}
@@ -0,0 +1,305 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/byuoitav/common/db/couch"
+	"github.com/byuoitav/common/structs"
+)
+
+// ExportSnapshot returns every configuration document in the database. Revisions aren't included, so the snapshot can be imported into any database.
+func (m *MemoryDB) ExportSnapshot() (structs.Snapshot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshot := structs.Snapshot{
+		Version:   structs.SnapshotVersion,
+		CreatedAt: time.Now(),
+	}
+
+	for _, id := range sortedKeys(m.buildings) {
+		var b structs.Building
+		clone(m.buildings[id], &b)
+		b.Rev = ""
+		snapshot.Buildings = append(snapshot.Buildings, b)
+	}
+
+	for _, id := range sortedKeys(m.rooms) {
+		var r structs.Room
+		clone(m.rooms[id], &r)
+		r.Rev = ""
+		snapshot.Rooms = append(snapshot.Rooms, r)
+	}
+
+	for _, id := range sortedKeys(m.devices) {
+		var d structs.Device
+		clone(m.devices[id], &d)
+		d.Rev = ""
+		snapshot.Devices = append(snapshot.Devices, d)
+	}
+
+	for _, id := range sortedKeys(m.deviceTypes) {
+		var dt structs.DeviceType
+		clone(m.deviceTypes[id], &dt)
+		dt.Rev = ""
+		snapshot.DeviceTypes = append(snapshot.DeviceTypes, dt)
+	}
+
+	for _, id := range sortedKeys(m.roomConfigs) {
+		var rc structs.RoomConfiguration
+		clone(m.roomConfigs[id], &rc)
+		rc.Rev = ""
+		snapshot.RoomConfigurations = append(snapshot.RoomConfigurations, rc)
+	}
+
+	for _, id := range sortedKeys(m.uiConfigs) {
+		var ui structs.UIConfig
+		clone(m.uiConfigs[id], &ui)
+		ui.Rev = ""
+		snapshot.UIConfigs = append(snapshot.UIConfigs, ui)
+	}
+
+	for _, id := range sortedKeys(m.templates) {
+		var t structs.Template
+		clone(m.templates[id], &t)
+		snapshot.Options.Templates = append(snapshot.Options.Templates, t)
+	}
+
+	clone(m.icons, &snapshot.Options.Icons)
+	clone(m.roles, &snapshot.Options.Roles)
+	clone(m.designations, &snapshot.Options.RoomDesignations)
+	clone(m.closureCodes, &snapshot.Options.ClosureCodes)
+	clone(m.tags, &snapshot.Options.Tags)
+	clone(m.menuTree, &snapshot.Options.MenuTree)
+	clone(m.roomSchemas, &snapshot.Options.RoomAttributeSchemas)
+
+	for _, id := range sortedKeys(m.attributeGroups) {
+		var g structs.Group
+		clone(m.attributeGroups[id], &g)
+		g.Rev = ""
+		snapshot.AttributeGroups = append(snapshot.AttributeGroups, g)
+	}
+
+	// the deployment config and service config of a service are the same document in couch
+	ids := sortedKeys(m.deploymentInfo)
+	for _, id := range sortedKeys(m.serviceInfo) {
+		if _, ok := m.deploymentInfo[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+
+	for _, id := range ids {
+		doc := structs.DeploymentDocument{ID: id}
+		clone(m.deploymentInfo[id].AWSConfig, &doc.AWSConfig)
+		clone(m.deploymentInfo[id].CampusConfig, &doc.CampusConfig)
+		clone(m.serviceInfo[id].Designations, &doc.Designations)
+		snapshot.DeploymentInfo = append(snapshot.DeploymentInfo, doc)
+	}
+
+	for _, id := range sortedKeys(m.deviceDeploy) {
+		var d structs.DeviceDeploymentConfig
+		clone(m.deviceDeploy[id], &d)
+		snapshot.DeviceDeploymentInfo = append(snapshot.DeviceDeploymentInfo, d)
+	}
+
+	return snapshot, nil
+}
+
+// snapshotDoc is a document to import, whether it already exists, and how to store it.
+type snapshotDoc struct {
+	database string
+	id       string
+	exists   bool
+	put      func()
+}
+
+// ImportSnapshot writes each of the documents in snapshot, in the same way as couch's ImportSnapshot.
+func (m *MemoryDB) ImportSnapshot(snapshot structs.Snapshot, strategy structs.ConflictStrategy) (structs.ImportResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := structs.ImportResult{Strategy: strategy}
+
+	if err := snapshot.Check(strategy); err != nil {
+		return result, err
+	}
+
+	docs := m.snapshotDocs(snapshot)
+
+	if strategy == structs.ConflictFail {
+		for _, doc := range docs {
+			if doc.exists {
+				result.Documents = append(result.Documents, structs.ImportedDocument{
+					Database: doc.database,
+					ID:       doc.id,
+					Action:   structs.ImportFailed,
+					Message:  "already exists",
+				})
+			}
+		}
+
+		if len(result.Documents) > 0 {
+			return result, fmt.Errorf("unable to import snapshot: %v documents already exist", len(result.Documents))
+		}
+	}
+
+	for _, doc := range docs {
+		imported := structs.ImportedDocument{
+			Database: doc.database,
+			ID:       doc.id,
+			Action:   structs.ImportCreated,
+		}
+
+		switch {
+		case doc.exists && strategy == structs.ConflictSkip:
+			imported.Action = structs.ImportSkipped
+		case doc.exists:
+			imported.Action = structs.ImportOverwritten
+			doc.put()
+		default:
+			doc.put()
+		}
+
+		result.Documents = append(result.Documents, imported)
+	}
+
+	return result, nil
+}
+
+// snapshotDocs returns each of the documents in snapshot, in the order they should be imported.
+func (m *MemoryDB) snapshotDocs(snapshot structs.Snapshot) []snapshotDoc {
+	var docs []snapshotDoc
+
+	for i := range snapshot.RoomConfigurations {
+		var rc structs.RoomConfiguration
+		clone(snapshot.RoomConfigurations[i], &rc)
+		_, exists := m.roomConfigs[rc.ID]
+
+		docs = append(docs, snapshotDoc{couch.ROOM_CONFIGURATIONS, rc.ID, exists, func() {
+			rc.Rev = nextRev(m.roomConfigs[rc.ID].Rev)
+			m.roomConfigs[rc.ID] = rc
+		}})
+	}
+
+	for i := range snapshot.DeviceTypes {
+		var dt structs.DeviceType
+		clone(snapshot.DeviceTypes[i], &dt)
+		_, exists := m.deviceTypes[dt.ID]
+
+		docs = append(docs, snapshotDoc{couch.DEVICE_TYPES, dt.ID, exists, func() {
+			dt.Rev = nextRev(m.deviceTypes[dt.ID].Rev)
+			m.deviceTypes[dt.ID] = dt
+		}})
+	}
+
+	for i := range snapshot.Buildings {
+		var b structs.Building
+		clone(snapshot.Buildings[i], &b)
+		_, exists := m.buildings[b.ID]
+
+		docs = append(docs, snapshotDoc{couch.BUILDINGS, b.ID, exists, func() {
+			b.Rev = nextRev(m.buildings[b.ID].Rev)
+			m.buildings[b.ID] = b
+		}})
+	}
+
+	for i := range snapshot.Rooms {
+		var r structs.Room
+		clone(snapshot.Rooms[i], &r)
+		_, exists := m.rooms[r.ID]
+
+		docs = append(docs, snapshotDoc{couch.ROOMS, r.ID, exists, func() {
+			r.Devices = nil
+			r.Configuration = structs.RoomConfiguration{ID: r.Configuration.ID}
+			r.Rev = nextRev(m.rooms[r.ID].Rev)
+			m.rooms[r.ID] = r
+		}})
+	}
+
+	for i := range snapshot.Devices {
+		d := snapshot.Devices[i]
+		_, exists := m.devices[d.ID]
+
+		docs = append(docs, snapshotDoc{couch.DEVICES, d.ID, exists, func() {
+			m.putDevice(d)
+		}})
+	}
+
+	for i := range snapshot.UIConfigs {
+		ui := snapshot.UIConfigs[i]
+		_, exists := m.uiConfigs[ui.ID]
+
+		docs = append(docs, snapshotDoc{couch.UI_CONFIGS, ui.ID, exists, func() {
+			m.putUIConfig(ui.ID, ui)
+		}})
+	}
+
+	for i := range snapshot.Options.Templates {
+		var t structs.Template
+		clone(snapshot.Options.Templates[i], &t)
+		_, exists := m.templates[t.ID]
+
+		docs = append(docs, snapshotDoc{couch.OPTIONS, t.ID, exists, func() {
+			m.templates[t.ID] = t
+		}})
+	}
+
+	options := []struct {
+		id     string
+		length int
+		exists bool
+		put    func()
+	}{
+		{couch.ICONS, len(snapshot.Options.Icons), len(m.icons) > 0, func() { clone(snapshot.Options.Icons, &m.icons) }},
+		{couch.ROLES, len(snapshot.Options.Roles), len(m.roles) > 0, func() { clone(snapshot.Options.Roles, &m.roles) }},
+		{couch.ROOM_DESIGNATIONS, len(snapshot.Options.RoomDesignations), len(m.designations) > 0, func() { clone(snapshot.Options.RoomDesignations, &m.designations) }},
+		{couch.CLOSURE_CODES, len(snapshot.Options.ClosureCodes), len(m.closureCodes) > 0, func() { clone(snapshot.Options.ClosureCodes, &m.closureCodes) }},
+		{couch.TAGS, len(snapshot.Options.Tags), len(m.tags) > 0, func() { clone(snapshot.Options.Tags, &m.tags) }},
+		{couch.MENUTREE, len(snapshot.Options.MenuTree), len(m.menuTree) > 0, func() { clone(snapshot.Options.MenuTree, &m.menuTree) }},
+		{couch.ROOM_SCHEMAS, len(snapshot.Options.RoomAttributeSchemas), len(m.roomSchemas) > 0, func() { clone(snapshot.Options.RoomAttributeSchemas, &m.roomSchemas) }},
+	}
+
+	// an empty list isn't imported, so it doesn't replace the existing one
+	for _, option := range options {
+		if option.length > 0 {
+			docs = append(docs, snapshotDoc{couch.OPTIONS, option.id, option.exists, option.put})
+		}
+	}
+
+	for i := range snapshot.AttributeGroups {
+		var g structs.Group
+		clone(snapshot.AttributeGroups[i], &g)
+		_, exists := m.attributeGroups[g.ID]
+
+		docs = append(docs, snapshotDoc{couch.ATTRIBUTES, g.ID, exists, func() {
+			m.attributeGroups[g.ID] = g
+		}})
+	}
+
+	for i := range snapshot.DeploymentInfo {
+		var d structs.DeploymentDocument
+		clone(snapshot.DeploymentInfo[i], &d)
+		_, exists := m.deploymentInfo[d.ID]
+		if _, ok := m.serviceInfo[d.ID]; ok {
+			exists = true
+		}
+
+		docs = append(docs, snapshotDoc{couch.DEPLOY, d.ID, exists, func() {
+			m.deploymentInfo[d.ID] = structs.FullConfig{ID: d.ID, AWSConfig: d.AWSConfig, CampusConfig: d.CampusConfig}
+			m.serviceInfo[d.ID] = structs.ServiceConfigWrapper{ID: d.ID, Designations: d.Designations}
+		}})
+	}
+
+	for i := range snapshot.DeviceDeploymentInfo {
+		var d structs.DeviceDeploymentConfig
+		clone(snapshot.DeviceDeploymentInfo[i], &d)
+		_, exists := m.deviceDeploy[d.ID]
+
+		docs = append(docs, snapshotDoc{couch.CAMPUS, d.ID, exists, func() {
+			m.deviceDeploy[d.ID] = d
+		}})
+	}
+
+	return docs
+}
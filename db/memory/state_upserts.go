@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"errors"
+
+	sd "github.com/byuoitav/common/state/statedefinition"
+	"github.com/byuoitav/common/structs"
+)
+
+// UpsertDeviceState merges state into the current state of its device, creating it if the device doesn't have one yet, in the same way as couch's UpsertDeviceState.
+func (m *MemoryDB) UpsertDeviceState(state sd.StaticDevice) (sd.StaticDevice, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.upsertDeviceState(state)
+}
+
+func (m *MemoryDB) upsertDeviceState(state sd.StaticDevice) (sd.StaticDevice, bool, error) {
+	var toReturn sd.StaticDevice
+
+	if len(state.DeviceID) == 0 {
+		return toReturn, false, errors.New("unable to upsert device state: missing deviceID")
+	}
+
+	current, exists := m.deviceStates[state.DeviceID]
+
+	merged, changed := sd.MergeDevices(current, state)
+	if changed || !exists {
+		var toStore sd.StaticDevice
+		clone(merged, &toStore)
+		m.deviceStates[state.DeviceID] = toStore
+	}
+
+	clone(merged, &toReturn)
+	return toReturn, changed || !exists, nil
+}
+
+// UpsertBulkDeviceStates upserts each of states. A state that didn't change anything is successful, with the message "unchanged".
+func (m *MemoryDB) UpsertBulkDeviceStates(states []sd.StaticDevice) []structs.BulkUpdateResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var toReturn []structs.BulkUpdateResponse
+	for _, state := range states {
+		_, changed, err := m.upsertDeviceState(state)
+		toReturn = append(toReturn, upsertResponse(state.DeviceID, changed, err))
+	}
+
+	return toReturn
+}
+
+// GetRoomState returns the state document of a room.
+func (m *MemoryDB) GetRoomState(roomID string) (sd.StaticRoom, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn sd.StaticRoom
+
+	state, ok := m.roomStates[roomID]
+	if !ok {
+		return toReturn, notFound("failed to get room state for %s: room state %s not found", roomID, roomID)
+	}
+
+	clone(state, &toReturn)
+	return toReturn, nil
+}
+
+// UpsertRoomState merges state into the current state of its room, in the same way as couch's UpsertRoomState.
+func (m *MemoryDB) UpsertRoomState(state sd.StaticRoom) (sd.StaticRoom, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.upsertRoomState(state)
+}
+
+func (m *MemoryDB) upsertRoomState(state sd.StaticRoom) (sd.StaticRoom, bool, error) {
+	var toReturn sd.StaticRoom
+
+	if len(state.RoomID) == 0 {
+		return toReturn, false, errors.New("unable to upsert room state: missing roomID")
+	}
+
+	current, exists := m.roomStates[state.RoomID]
+
+	merged, changed := sd.MergeRooms(current, state)
+	if changed || !exists {
+		var toStore sd.StaticRoom
+		clone(merged, &toStore)
+		m.roomStates[state.RoomID] = toStore
+	}
+
+	clone(merged, &toReturn)
+	return toReturn, changed || !exists, nil
+}
+
+// UpsertBulkRoomStates upserts each of states. A state that didn't change anything is successful, with the message "unchanged".
+func (m *MemoryDB) UpsertBulkRoomStates(states []sd.StaticRoom) []structs.BulkUpdateResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var toReturn []structs.BulkUpdateResponse
+	for _, state := range states {
+		_, changed, err := m.upsertRoomState(state)
+		toReturn = append(toReturn, upsertResponse(state.RoomID, changed, err))
+	}
+
+	return toReturn
+}
+
+func upsertResponse(id string, changed bool, err error) structs.BulkUpdateResponse {
+	response := structs.BulkUpdateResponse{ID: id, Success: err == nil}
+
+	switch {
+	case err != nil:
+		response.Message = err.Error()
+	case !changed:
+		response.Message = "unchanged"
+	}
+
+	return response
+}
@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/byuoitav/common/structs"
+)
+
+// GetUIConfig returns the ui config for a room.
+func (m *MemoryDB) GetUIConfig(roomID string) (structs.UIConfig, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.getUIConfig(roomID)
+}
+
+func (m *MemoryDB) getUIConfig(roomID string) (structs.UIConfig, error) {
+	var toReturn structs.UIConfig
+
+	config, ok := m.uiConfigs[roomID]
+	if !ok {
+		return toReturn, notFound("failed to get ui config %s: ui config %s not found", roomID, roomID)
+	}
+
+	clone(config, &toReturn)
+	return toReturn, nil
+}
+
+// GetAllUIConfigs returns every ui config.
+func (m *MemoryDB) GetAllUIConfigs() ([]structs.UIConfig, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var toReturn []structs.UIConfig
+	for _, id := range sortedKeys(m.uiConfigs) {
+		var config structs.UIConfig
+		clone(m.uiConfigs[id], &config)
+		toReturn = append(toReturn, config)
+	}
+
+	return toReturn, nil
+}
+
+// CreateUIConfig adds the ui config for a room.
+func (m *MemoryDB) CreateUIConfig(roomID string, toAdd structs.UIConfig) (structs.UIConfig, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.uiConfigs[roomID]; ok {
+		return structs.UIConfig{}, fmt.Errorf("unable to create ui config, because it already exists. error: ui config %s already exists", roomID)
+	}
+
+	m.putUIConfig(roomID, toAdd)
+	return m.getUIConfig(roomID)
+}
+
+// putUIConfig stores a copy of config, using roomID as its id.
+func (m *MemoryDB) putUIConfig(roomID string, config structs.UIConfig) {
+	var toStore structs.UIConfig
+	clone(config, &toStore)
+	toStore.ID = roomID
+	toStore.Rev = nextRev(m.uiConfigs[roomID].Rev)
+
+	m.uiConfigs[roomID] = toStore
+}
+
+// DeleteUIConfig deletes the ui config for a room.
+func (m *MemoryDB) DeleteUIConfig(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.uiConfigs[id]; !ok {
+		return notFound("failed to get ui config %s to delete: ui config %s not found", id, id)
+	}
+
+	delete(m.uiConfigs, id)
+	return nil
+}
+
+// UpdateUIConfig updates the ui config for a room. If the id of the config changes, it is moved to the new id.
+func (m *MemoryDB) UpdateUIConfig(id string, update structs.UIConfig) (structs.UIConfig, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, ok := m.uiConfigs[id]
+	if !ok {
+		return structs.UIConfig{}, notFound("unable to get ui config %s to update: ui config %s not found", id, id)
+	}
+
+	if err := checkRev(id, update.Rev, current.Rev); err != nil {
+		return structs.UIConfig{}, err
+	}
+
+	newID := id
+	if len(update.ID) > 0 && update.ID != id {
+		if _, ok := m.uiConfigs[update.ID]; ok {
+			return structs.UIConfig{}, fmt.Errorf("ui config already exists, please update this ui config or change IDs. error: ui config %s already exists", update.ID)
+		}
+
+		delete(m.uiConfigs, id)
+		newID = update.ID
+	}
+
+	m.putUIConfig(newID, update)
+	return m.getUIConfig(newID)
+}
+
+// GetUIAttachment returns the content type and contents of an attachment on a ui config.
+func (m *MemoryDB) GetUIAttachment(ui, attachment string) (string, []byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	a, ok := m.uiAttachments[ui+"/"+attachment]
+	if !ok {
+		return "", nil, notFound("attachment %s not found", ui+"/"+attachment)
+	}
+
+	data := make([]byte, len(a.data))
+	copy(data, a.data)
+
+	return a.contentType, data, nil
+}
@@ -0,0 +1,339 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/common/structs"
+)
+
+/*
+Migration moves the documents in a database from one schema version to the next. Migrate is
+called with each document in the database (as decoded JSON, including its _id and _rev), and
+changes it in place. It returns whether it changed anything, so that unchanged documents aren't
+written. Migrations should leave documents that are already in the new shape alone.
+
+Migrations are registered with RegisterMigration, usually from an init function:
+
+	func init() {
+		db.RegisterMigration(db.Migration{
+			Database: couch.DEVICES,
+			Version:  2,
+			Name:     "move proxy into attributes",
+			Migrate: func(doc map[string]interface{}) (bool, error) {
+				...
+			},
+		})
+	}
+*/
+type Migration struct {
+	Database string
+	Version  int
+	Name     string
+	Migrate  func(doc map[string]interface{}) (bool, error)
+}
+
+/*
+MigrationStore is implemented by databases that can be migrated. The couch and in-memory
+databases both implement it.
+
+The schema version of a database is stored alongside its documents, but isn't returned as one
+of them.
+*/
+type MigrationStore interface {
+	GetSchemaVersion(database string) (structs.SchemaVersion, error)
+	PutSchemaVersion(database string, version structs.SchemaVersion) (structs.SchemaVersion, error)
+	GetRawDocuments(database string) ([]map[string]interface{}, error)
+	PutRawDocuments(database string, docs []map[string]interface{}) []structs.BulkUpdateResponse
+}
+
+var (
+	migrationsMu sync.RWMutex
+	migrations   = make(map[string][]Migration)
+)
+
+// RegisterMigration adds m to the migrations for its database. It panics if the database already has a migration for m's version.
+func RegisterMigration(m Migration) {
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+
+	if m.Version < 1 {
+		panic(fmt.Sprintf("migration %q has invalid version %v", m.Name, m.Version))
+	}
+
+	for _, existing := range migrations[m.Database] {
+		if existing.Version == m.Version {
+			panic(fmt.Sprintf("%s already has a migration for version %v (%q)", m.Database, m.Version, existing.Name))
+		}
+	}
+
+	migrations[m.Database] = append(migrations[m.Database], m)
+	sort.Slice(migrations[m.Database], func(i, j int) bool {
+		return migrations[m.Database][i].Version < migrations[m.Database][j].Version
+	})
+}
+
+// resetMigrations removes every registered migration, so that tests don't leak them into each other.
+func resetMigrations() {
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+
+	migrations = make(map[string][]Migration)
+}
+
+// Migrations returns the migrations registered for database, in order.
+func Migrations(database string) []Migration {
+	migrationsMu.RLock()
+	defer migrationsMu.RUnlock()
+
+	return append([]Migration(nil), migrations[database]...)
+}
+
+// unwrapper is a DB that wraps another one, e.g. a CachedDB or an AuditedDB.
+type unwrapper interface {
+	Unwrap() DB
+}
+
+// unwrap returns d, and each DB wrapped by it, outermost first.
+func unwrap(d DB) []DB {
+	toReturn := []DB{d}
+
+	for {
+		w, ok := d.(unwrapper)
+		if !ok {
+			return toReturn
+		}
+
+		d = w.Unwrap()
+		toReturn = append(toReturn, d)
+	}
+}
+
+// migrationStore returns the MigrationStore behind d.
+func migrationStore(d DB) (MigrationStore, error) {
+	for _, inner := range unwrap(d) {
+		if store, ok := inner.(MigrationStore); ok {
+			return store, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%T can't be migrated", d)
+}
+
+/*
+Migrate runs each of the migrations for database newer than its schema version. If dryRun is
+true, nothing is written, and the result is the change each migration would make to each
+document.
+
+If any document can't be written, the schema version isn't changed, so Migrate can be run again
+once the problem is fixed.
+*/
+func Migrate(d DB, database string, dryRun bool) (structs.MigrationResult, error) {
+	result := structs.MigrationResult{Database: database, DryRun: dryRun}
+
+	store, err := migrationStore(d)
+	if err != nil {
+		return result, err
+	}
+
+	// documents are written straight to the store, so any cache in front of it is stale afterwards
+	for _, inner := range unwrap(d) {
+		if c, ok := inner.(*CachedDB); ok && !dryRun {
+			defer c.Flush()
+		}
+	}
+
+	schema, err := store.GetSchemaVersion(database)
+	if err != nil {
+		return result, fmt.Errorf("unable to get schema version of %s: %s", database, err)
+	}
+
+	result.FromVersion = schema.Version
+	result.ToVersion = schema.Version
+
+	var pending []Migration
+	for _, m := range Migrations(database) {
+		if m.Version > schema.Version {
+			pending = append(pending, m)
+		}
+	}
+
+	if len(pending) == 0 {
+		return result, nil
+	}
+
+	result.ToVersion = pending[len(pending)-1].Version
+
+	docs, err := store.GetRawDocuments(database)
+	if err != nil {
+		return result, fmt.Errorf("unable to get documents in %s: %s", database, err)
+	}
+
+	counts := make(map[int]int)
+	var changed []map[string]interface{}
+
+	for _, doc := range docs {
+		original := copyValue(doc).(map[string]interface{})
+		migrated := structs.MigratedDocument{}
+		migrated.ID, _ = doc["_id"].(string)
+
+		for _, m := range pending {
+			ok, err := m.Migrate(doc)
+			if err != nil {
+				return result, fmt.Errorf("migration %v (%s) failed on %s: %s", m.Version, m.Name, migrated.ID, err)
+			}
+
+			if ok {
+				counts[m.Version]++
+				migrated.Migrations = append(migrated.Migrations, m.Name)
+			}
+		}
+
+		if len(migrated.Migrations) == 0 {
+			continue
+		}
+
+		migrated.Changes = diffValues("", original, doc)
+		result.Documents = append(result.Documents, migrated)
+		changed = append(changed, doc)
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	responses := store.PutRawDocuments(database, changed)
+	for i := range result.Documents {
+		result.Documents[i].Success = responses[i].Success
+		result.Documents[i].Message = responses[i].Message
+	}
+
+	if failed := result.Failed(); len(failed) > 0 {
+		return result, fmt.Errorf("unable to write %v of the %v migrated documents in %s", len(failed), len(result.Documents), database)
+	}
+
+	for _, m := range pending {
+		schema.Applied = append(schema.Applied, structs.AppliedMigration{
+			Version:   m.Version,
+			Name:      m.Name,
+			AppliedAt: time.Now(),
+			Documents: counts[m.Version],
+		})
+	}
+
+	schema.Database = database
+	schema.Version = result.ToVersion
+
+	if _, err := store.PutSchemaVersion(database, schema); err != nil {
+		return result, fmt.Errorf("migrated %s, but unable to record its schema version: %s", database, err)
+	}
+
+	return result, nil
+}
+
+// MigrateAll runs Migrate for each database that has migrations registered, stopping at the first one that fails.
+func MigrateAll(d DB, dryRun bool) ([]structs.MigrationResult, error) {
+	migrationsMu.RLock()
+	var databases []string
+	for database := range migrations {
+		databases = append(databases, database)
+	}
+	migrationsMu.RUnlock()
+
+	sort.Strings(databases)
+
+	var results []structs.MigrationResult
+	for _, database := range databases {
+		result, err := Migrate(d, database, dryRun)
+		results = append(results, result)
+
+		if err != nil {
+			return results, err
+		}
+	}
+
+	return results, nil
+}
+
+// copyValue returns a deep copy of a decoded JSON value.
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			m[key] = copyValue(val)
+		}
+
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i := range v {
+			s[i] = copyValue(v[i])
+		}
+
+		return s
+	default:
+		return v
+	}
+}
+
+// diffValues returns each of the fields that differ between old and new, two decoded JSON values, with paths starting at path.
+func diffValues(path string, old, new interface{}) []structs.FieldChange {
+	oldMap, oldIsMap := old.(map[string]interface{})
+	newMap, newIsMap := new.(map[string]interface{})
+
+	if oldIsMap && newIsMap {
+		keys := make(map[string]bool)
+		for key := range oldMap {
+			keys[key] = true
+		}
+
+		for key := range newMap {
+			keys[key] = true
+		}
+
+		var sorted []string
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+
+		sort.Strings(sorted)
+
+		var changes []structs.FieldChange
+		for _, key := range sorted {
+			changes = append(changes, diffValues(joinPath(path, key), oldMap[key], newMap[key])...)
+		}
+
+		return changes
+	}
+
+	oldSlice, oldIsSlice := old.([]interface{})
+	newSlice, newIsSlice := new.([]interface{})
+
+	if oldIsSlice && newIsSlice && len(oldSlice) == len(newSlice) {
+		var changes []structs.FieldChange
+		for i := range oldSlice {
+			changes = append(changes, diffValues(joinPath(path, strconv.Itoa(i)), oldSlice[i], newSlice[i])...)
+		}
+
+		return changes
+	}
+
+	if reflect.DeepEqual(old, new) {
+		return nil
+	}
+
+	return []structs.FieldChange{{Path: path, Old: old, New: new}}
+}
+
+func joinPath(path, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+
+	return path + "." + key
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/byuoitav/common/db/couch"
+)
+
+func TestMigrate(t *testing.T) {
+	defer resetMigrations()
+
+	RegisterMigration(Migration{
+		Database: couch.BUILDINGS,
+		Version:  1,
+		Name:     "add migrated tag",
+		Migrate: func(doc map[string]interface{}) (bool, error) {
+			tags, _ := doc["tags"].([]interface{})
+			for _, tag := range tags {
+				if tag == "migrated" {
+					return false, nil
+				}
+			}
+
+			doc["tags"] = append(tags, "migrated")
+			return true, nil
+		},
+	})
+
+	d := newSeededMemoryDB(t)
+
+	plan, err := Migrate(d, couch.BUILDINGS, true)
+	if err != nil {
+		t.Fatalf("failed to plan migration: %s", err)
+	}
+
+	if plan.FromVersion != 0 || plan.ToVersion != 1 || len(plan.Documents) != 3 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+
+	change := plan.Documents[0].Changes
+	if len(change) != 1 || change[0].Path != "tags" || change[0].New == nil {
+		t.Fatalf("unexpected changes: %+v", change)
+	}
+
+	if b, _ := d.GetBuilding("AAA"); len(b.Tags) != 2 {
+		t.Fatalf("dry run changed building: %+v", b)
+	}
+
+	// migrations go through any wrappers to the database behind them
+	audited := NewAuditedDB(NewCachedDB(d, CacheOptions{}), d)
+
+	result, err := Migrate(audited, couch.BUILDINGS, false)
+	if err != nil {
+		t.Fatalf("failed to migrate: %s", err)
+	}
+
+	if len(result.Failed()) != 0 {
+		t.Fatalf("failed to migrate documents: %+v", result.Failed())
+	}
+
+	if b, _ := d.GetBuilding("AAA"); len(b.Tags) != 3 || b.Tags[2] != "migrated" {
+		t.Fatalf("building wasn't migrated: %+v", b)
+	}
+
+	schema, err := d.GetSchemaVersion(couch.BUILDINGS)
+	if err != nil {
+		t.Fatalf("failed to get schema version: %s", err)
+	}
+
+	if schema.Version != 1 || len(schema.Applied) != 1 || schema.Applied[0].Documents != 3 {
+		t.Fatalf("unexpected schema version: %+v", schema)
+	}
+
+	again, err := Migrate(d, couch.BUILDINGS, false)
+	if err != nil {
+		t.Fatalf("failed to migrate again: %s", err)
+	}
+
+	if len(again.Documents) != 0 || again.FromVersion != 1 {
+		t.Fatalf("second migration wasn't a no-op: %+v", again)
+	}
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/byuoitav/common/structs"
+)
+
+// WriteSnapshot writes snapshot to w as an archive: gzipped JSON.
+func WriteSnapshot(w io.Writer, snapshot structs.Snapshot) error {
+	gz := gzip.NewWriter(w)
+
+	enc := json.NewEncoder(gz)
+	enc.SetIndent("", "\t")
+
+	if err := enc.Encode(snapshot); err != nil {
+		return fmt.Errorf("unable to write snapshot: %s", err)
+	}
+
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("unable to write snapshot: %s", err)
+	}
+
+	return nil
+}
+
+// ReadSnapshot reads a snapshot archive written by WriteSnapshot. Plain (not gzipped) JSON is also accepted.
+func ReadSnapshot(r io.Reader) (structs.Snapshot, error) {
+	var snapshot structs.Snapshot
+
+	br := bufio.NewReader(r)
+	in := io.Reader(br)
+
+	// gzip streams start with 0x1f 0x8b
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return snapshot, fmt.Errorf("unable to read snapshot: %s", err)
+		}
+		defer gz.Close()
+
+		in = gz
+	}
+
+	if err := json.NewDecoder(in).Decode(&snapshot); err != nil {
+		return snapshot, fmt.Errorf("unable to read snapshot: %s", err)
+	}
+
+	if snapshot.Version < 1 || snapshot.Version > structs.SnapshotVersion {
+		return snapshot, fmt.Errorf("unsupported snapshot version %v (expected 1 through %v)", snapshot.Version, structs.SnapshotVersion)
+	}
+
+	return snapshot, nil
+}
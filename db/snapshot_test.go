@@ -0,0 +1,85 @@
+package db
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/byuoitav/common/db/memory"
+	"github.com/byuoitav/common/structs"
+)
+
+func TestSnapshot(t *testing.T) {
+	src := newSeededMemoryDB(t)
+
+	snapshot, err := src.ExportSnapshot()
+	if err != nil {
+		t.Fatalf("failed to export snapshot: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSnapshot(&buf, snapshot); err != nil {
+		t.Fatalf("failed to write snapshot: %s", err)
+	}
+
+	read, err := ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("failed to read snapshot: %s", err)
+	}
+
+	if len(read.Buildings) != 3 || len(read.Rooms) != len(snapshot.Rooms) || len(read.RoomConfigurations) != len(snapshot.RoomConfigurations) {
+		t.Fatalf("snapshot didn't survive being written: %+v", read)
+	}
+
+	dst := memory.NewDB()
+	if _, err := dst.ImportSnapshot(read, structs.ConflictFail); err != nil {
+		t.Fatalf("failed to import snapshot: %s", err)
+	}
+
+	room, err := dst.GetRoom("CCC-AAA")
+	if err != nil {
+		t.Fatalf("room wasn't imported: %s", err)
+	}
+
+	if len(room.Configuration.Evaluators) != 4 {
+		t.Fatalf("room configuration wasn't imported with the room: %+v", room.Configuration)
+	}
+
+	// importing it again conflicts with every document
+	building := structs.Building{ID: "AAA", Name: "changed"}
+	if _, err := dst.UpdateBuilding(building.ID, building); err != nil {
+		t.Fatalf("failed to update building: %s", err)
+	}
+
+	result, err := dst.ImportSnapshot(read, structs.ConflictFail)
+	if err == nil || len(result.Failed()) == 0 {
+		t.Fatalf("expected the import to fail, got %+v", result)
+	}
+
+	result, err = dst.ImportSnapshot(read, structs.ConflictSkip)
+	if err != nil {
+		t.Fatalf("failed to import snapshot: %s", err)
+	}
+
+	for _, doc := range result.Documents {
+		if doc.Action != structs.ImportSkipped {
+			t.Fatalf("expected every document to be skipped, got %+v", doc)
+		}
+	}
+
+	if b, _ := dst.GetBuilding("AAA"); b.Name != "changed" {
+		t.Fatalf("skipped building was overwritten")
+	}
+
+	if _, err := dst.ImportSnapshot(read, structs.ConflictOverwrite); err != nil {
+		t.Fatalf("failed to import snapshot: %s", err)
+	}
+
+	if b, _ := dst.GetBuilding("AAA"); b.Name == "changed" {
+		t.Fatalf("building wasn't overwritten")
+	}
+
+	read.Version = structs.SnapshotVersion + 1
+	if _, err := dst.ImportSnapshot(read, structs.ConflictOverwrite); err == nil {
+		t.Fatalf("expected a snapshot from a newer version to be refused")
+	}
+}
@@ -0,0 +1,276 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/byuoitav/common/structs"
+)
+
+/*
+CreateRoomFromTemplate creates the room roomID from the template templateID: the room itself,
+a device for each of the template's base types, and the template's ui config for the room.
+
+Each device is named with its type's default name, numbered in order (two base types with the
+default name "D" become D1 and D2). A port on a device type whose source or destination device
+is another of the template's base types (or the name of one of the new devices) is wired to
+that device; other references are cleared, and a port with only a source is wired to the
+device itself. Panel and api hostnames in the ui config are moved into the new room.
+
+Everything is built and validated before anything is written. If writing any of it fails, what
+was written is deleted again.
+*/
+func CreateRoomFromTemplate(d DB, templateID, roomID string, params structs.TemplateParams) (structs.TemplateInstance, error) {
+	instance := structs.TemplateInstance{TemplateID: templateID}
+
+	if err := params.Validate(); err != nil {
+		return instance, fmt.Errorf("unable to create room from template %s: %s", templateID, err)
+	}
+
+	if !structs.IsRoomIDValid(roomID) {
+		return instance, fmt.Errorf("unable to create room from template %s: invalid room id %q", templateID, roomID)
+	}
+
+	templates, err := d.GetAllTemplates()
+	if err != nil {
+		return instance, fmt.Errorf("unable to get template %s: %s", templateID, err)
+	}
+
+	var template *structs.Template
+	for i := range templates {
+		if templates[i].ID == templateID {
+			template = &templates[i]
+		}
+	}
+
+	if template == nil {
+		return instance, fmt.Errorf("unable to create room from template %s: template doesn't exist", templateID)
+	}
+
+	if _, err := d.GetRoom(roomID); err == nil {
+		return instance, fmt.Errorf("unable to create room from template %s: room %s already exists", templateID, roomID)
+	}
+
+	buildingID := strings.SplitN(roomID, "-", 2)[0]
+	if _, err := d.GetBuilding(buildingID); err != nil {
+		return instance, fmt.Errorf("unable to create room from template %s: unable to get building %s: %s", templateID, buildingID, err)
+	}
+
+	if len(params.Configuration) == 0 {
+		params.Configuration = "Default"
+	}
+
+	config, err := d.GetRoomConfiguration(params.Configuration)
+	if err != nil {
+		return instance, fmt.Errorf("unable to create room from template %s: unable to get room configuration %s: %s", templateID, params.Configuration, err)
+	}
+
+	types := make(map[string]structs.DeviceType)
+	for _, id := range template.BaseTypes {
+		if _, ok := types[id]; ok {
+			continue
+		}
+
+		dt, err := d.GetDeviceType(id)
+		if err != nil {
+			return instance, fmt.Errorf("unable to create room from template %s: unable to get device type %s: %s", templateID, id, err)
+		}
+
+		types[id] = dt
+	}
+
+	instance, err = instantiateTemplate(*template, types, roomID, params)
+	if err != nil {
+		return instance, fmt.Errorf("unable to create room from template %s: %s", templateID, err)
+	}
+
+	instance.Room.Configuration = config
+
+	return instance, createTemplateInstance(d, instance)
+}
+
+// instantiateTemplate builds and validates everything for roomID from template, without writing any of it. types are the template's base types.
+func instantiateTemplate(template structs.Template, types map[string]structs.DeviceType, roomID string, params structs.TemplateParams) (structs.TemplateInstance, error) {
+	instance := structs.TemplateInstance{
+		TemplateID: template.ID,
+		Room: structs.Room{
+			ID:            roomID,
+			Name:          params.Name,
+			Description:   params.Description,
+			Designation:   params.Designation,
+			Configuration: structs.RoomConfiguration{ID: params.Configuration},
+		},
+	}
+
+	if err := instance.Room.Validate(); err != nil {
+		return instance, err
+	}
+
+	// name each device, and find the device created for each type
+	taken := make(map[string]bool)
+	byType := make(map[string]string)
+	byName := make(map[string]string)
+
+	for _, typeID := range template.BaseTypes {
+		dt := types[typeID]
+
+		name, err := nextDeviceName(dt.DefaultName, taken)
+		if err != nil {
+			return instance, fmt.Errorf("unable to name a device of type %s: %s", typeID, err)
+		}
+
+		id := roomID + "-" + name
+		taken[name] = true
+		byName[name] = id
+
+		if _, ok := byType[typeID]; !ok {
+			byType[typeID] = id
+		}
+
+		instance.Devices = append(instance.Devices, structs.Device{
+			ID:          id,
+			Name:        name,
+			Address:     params.DeviceAddress(id, name),
+			DisplayName: dt.DisplayName,
+			Type:        structs.DeviceType{ID: dt.ID},
+			Roles:       append([]structs.Role(nil), dt.Roles...),
+		})
+	}
+
+	resolve := func(ref string) string {
+		if id, ok := byType[ref]; ok {
+			return id
+		}
+
+		return byName[ref]
+	}
+
+	for i, typeID := range template.BaseTypes {
+		device := &instance.Devices[i]
+
+		for _, port := range types[typeID].Ports {
+			port.SourceDevice = resolve(port.SourceDevice)
+			port.DestinationDevice = resolve(port.DestinationDevice)
+
+			if len(port.SourceDevice) > 0 && len(port.DestinationDevice) == 0 {
+				port.DestinationDevice = device.ID
+			}
+
+			port.Tags = append([]string(nil), port.Tags...)
+			device.Ports = append(device.Ports, port)
+		}
+
+		if err := device.Validate(); err != nil {
+			return instance, err
+		}
+	}
+
+	ui, err := templateUIConfig(template.UIConfig, roomID, byName)
+	if err != nil {
+		return instance, err
+	}
+
+	instance.UIConfig = ui
+	return instance, nil
+}
+
+// templateUIConfig returns a copy of ui for roomID. Each panel must be one of devices (by name).
+func templateUIConfig(ui structs.UIConfig, roomID string, devices map[string]string) (structs.UIConfig, error) {
+	var toReturn structs.UIConfig
+
+	b, err := json.Marshal(ui)
+	if err != nil {
+		return toReturn, fmt.Errorf("unable to copy ui config: %s", err)
+	}
+
+	if err := json.Unmarshal(b, &toReturn); err != nil {
+		return toReturn, fmt.Errorf("unable to copy ui config: %s", err)
+	}
+
+	toReturn.ID = roomID
+	toReturn.Rev = ""
+
+	for i := range toReturn.Api {
+		toReturn.Api[i] = templateHostname(toReturn.Api[i], roomID)
+	}
+
+	for i := range toReturn.Panels {
+		panel := &toReturn.Panels[i]
+		panel.Hostname = templateHostname(panel.Hostname, roomID)
+
+		name := strings.TrimPrefix(panel.Hostname, roomID+"-")
+		if _, ok := devices[name]; !ok {
+			return toReturn, fmt.Errorf("invalid ui config: panel %s isn't one of the template's devices", panel.Hostname)
+		}
+	}
+
+	return toReturn, nil
+}
+
+var deviceNameRegex = regexp.MustCompile(`^[A-Za-z]+[0-9]+$`)
+
+// templateHostname returns hostname moved into roomID: a device id has its room replaced, and a bare device name gets roomID as a prefix. Anything else (e.g. localhost) is unchanged.
+func templateHostname(hostname, roomID string) string {
+	switch {
+	case structs.IsDeviceIDValid(hostname):
+		parts := strings.Split(hostname, "-")
+		return roomID + "-" + strings.Join(parts[2:], "-")
+	case deviceNameRegex.MatchString(hostname):
+		return roomID + "-" + hostname
+	default:
+		return hostname
+	}
+}
+
+/*
+nextDeviceName returns the first name not in taken made from defaultName: any trailing number
+is dropped from defaultName, and the lowest number (starting at 1) that isn't taken is added.
+*/
+func nextDeviceName(defaultName string, taken map[string]bool) (string, error) {
+	prefix := strings.TrimRight(defaultName, "0123456789")
+	if len(prefix) == 0 {
+		return "", errors.New("missing default name")
+	}
+
+	for n := 1; ; n++ {
+		name := prefix + strconv.Itoa(n)
+		if !taken[name] {
+			return name, nil
+		}
+	}
+}
+
+// createTemplateInstance writes instance, deleting what was written if any of it fails.
+func createTemplateInstance(d DB, instance structs.TemplateInstance) error {
+	if _, err := d.CreateRoom(instance.Room); err != nil {
+		return fmt.Errorf("unable to create room %s: %s", instance.Room.ID, err)
+	}
+
+	err := func() error {
+		for _, resp := range d.CreateBulkDevices(instance.Devices) {
+			if !resp.Success {
+				return fmt.Errorf("unable to create device %s: %s", resp.ID, resp.Message)
+			}
+		}
+
+		if _, err := d.CreateUIConfig(instance.Room.ID, instance.UIConfig); err != nil {
+			return fmt.Errorf("unable to create ui config: %s", err)
+		}
+
+		return nil
+	}()
+
+	if err != nil {
+		if _, rerr := d.DeleteRoomCascade(instance.Room.ID, false); rerr != nil {
+			return fmt.Errorf("%s (and unable to delete what was created: %s)", err, rerr)
+		}
+
+		return err
+	}
+
+	return nil
+}
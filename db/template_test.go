@@ -0,0 +1,71 @@
+package db
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/byuoitav/common/structs"
+)
+
+func TestCreateRoomFromTemplate(t *testing.T) {
+	m := newSeededMemoryDB(t)
+
+	types := []structs.DeviceType{
+		{ID: "test-display", DefaultName: "D", Roles: []structs.Role{{ID: "VideoOut"}}, Ports: []structs.Port{{ID: "hdmi!1", SourceDevice: "test-pi"}}},
+		{ID: "test-pi", DefaultName: "CP1", Roles: []structs.Role{{ID: "ControlProcessor"}}},
+	}
+
+	for _, dt := range types {
+		if _, err := m.CreateDeviceType(dt); err != nil {
+			t.Fatalf("failed to create device type: %s", err)
+		}
+	}
+
+	template, _ := json.Marshal(structs.Template{
+		ID:        "two-displays",
+		BaseTypes: []string{"test-display", "test-display", "test-pi"},
+		UIConfig: structs.UIConfig{
+			Api:     []string{"localhost"},
+			Panels:  []structs.Panel{{Hostname: "ITB-1101-CP1", Preset: "main"}},
+			Presets: []structs.Preset{{Name: "main", Displays: []string{"D1", "D2"}}},
+		},
+	})
+
+	if err := m.LoadFixture("templates", template); err != nil {
+		t.Fatalf("failed to load template: %s", err)
+	}
+
+	params := structs.TemplateParams{Name: "Test Room", Designation: "production", Configuration: "ABC", AddressPattern: "{id}.byu.edu"}
+
+	instance, err := CreateRoomFromTemplate(m, "two-displays", "CCC-TMP", params)
+	if err != nil {
+		t.Fatalf("failed to create room from template: %s", err)
+	}
+
+	devices, err := m.GetDevicesByRoom("CCC-TMP")
+	if err != nil || len(devices) != 3 || len(instance.Devices) != 3 {
+		t.Fatalf("expected 3 devices (err: %v): %+v", err, devices)
+	}
+
+	d2, err := m.GetDevice("CCC-TMP-D2")
+	if err != nil {
+		t.Fatalf("failed to get device: %s", err)
+	}
+
+	if d2.Address != "CCC-TMP-D2.byu.edu" || len(d2.Ports) != 1 || d2.Ports[0].SourceDevice != "CCC-TMP-CP1" || d2.Ports[0].DestinationDevice != "CCC-TMP-D2" {
+		t.Fatalf("unexpected device: %+v", d2)
+	}
+
+	ui, err := m.GetUIConfig("CCC-TMP")
+	if err != nil {
+		t.Fatalf("failed to get ui config: %s", err)
+	}
+
+	if ui.Panels[0].Hostname != "CCC-TMP-CP1" || ui.Api[0] != "localhost" {
+		t.Fatalf("unexpected ui config: %+v", ui)
+	}
+
+	if _, err := CreateRoomFromTemplate(m, "two-displays", "CCC-TMP", params); err == nil {
+		t.Fatalf("expected creating an existing room to fail")
+	}
+}
@@ -0,0 +1,269 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/byuoitav/common/inputgraph"
+	"github.com/byuoitav/common/structs"
+)
+
+// uiConfigCheck is the state of a running ValidateUIConfig.
+type uiConfigCheck struct {
+	result structs.ValidationResult
+	ui     *structs.UIConfig
+
+	// devices in the room, by name, which is how ui configs reference them
+	devices map[string]structs.Device
+	presets map[string]bool
+	icons   map[string]bool
+
+	// the room's video signal path, or nil if the room doesn't have any devices
+	graph *inputgraph.InputGraph
+}
+
+/*
+ValidateUIConfig checks ui against devices, which are the devices in its room. It reports:
+  - device names (in presets, input/output/audio configurations, and pseudo inputs) and panel hostnames that aren't devices in the room
+  - panels and shareable presets that reference presets that don't exist
+  - inputs of a preset that can't reach any of the preset's displays, according to the room's video signal path
+  - icons that aren't in icons (if icons is empty, icons aren't checked)
+*/
+func ValidateUIConfig(ui structs.UIConfig, devices []structs.Device, icons []string) structs.ValidationResult {
+	c := &uiConfigCheck{
+		ui:      &ui,
+		devices: make(map[string]structs.Device),
+		presets: make(map[string]bool),
+	}
+
+	for _, device := range devices {
+		c.devices[device.Name] = device
+	}
+
+	for _, preset := range ui.Presets {
+		c.presets[preset.Name] = true
+	}
+
+	if len(icons) > 0 {
+		c.icons = make(map[string]bool)
+		for _, icon := range icons {
+			c.icons[icon] = true
+		}
+	}
+
+	if len(devices) > 0 {
+		if graph, err := inputgraph.BuildGraph(devices, "video"); err == nil {
+			pruneGraph(&graph)
+			c.graph = &graph
+		}
+	}
+
+	for i, panel := range ui.Panels {
+		path := fmt.Sprintf("panels[%d]", i)
+
+		if !c.hasDeviceID(panel.Hostname) {
+			c.errorf(path+".hostname", structs.ValidationUnknownDevice, "%s isn't a device in %s", panel.Hostname, ui.ID)
+		}
+
+		c.checkPreset(path+".preset", panel.Preset)
+	}
+
+	for i := range ui.Presets {
+		c.checkUIPreset(i)
+	}
+
+	c.checkIOConfigurations("inputConfiguration", ui.InputConfiguration)
+	c.checkIOConfigurations("outputConfiguration", ui.OutputConfiguration)
+
+	for i, audio := range ui.AudioConfiguration {
+		path := fmt.Sprintf("audioConfiguration[%d]", i)
+
+		c.checkDevice(path+".display", audio.Display)
+		c.checkDevices(path+".audioDevices", audio.AudioDevices)
+	}
+
+	for i, pseudo := range ui.PseudoInputs {
+		for j, config := range pseudo.Config {
+			path := fmt.Sprintf("pseudoInputs[%d].config[%d]", i, j)
+
+			c.checkDevice(path+".input", config.Input)
+			c.checkDevices(path+".outputs", config.Outputs)
+		}
+	}
+
+	return c.result
+}
+
+// ValidateRoomUIConfig checks the ui config for roomID against the devices in the room and the available icons. See ValidateUIConfig.
+func ValidateRoomUIConfig(d DB, roomID string) (structs.ValidationResult, error) {
+	var result structs.ValidationResult
+
+	ui, err := d.GetUIConfig(roomID)
+	if err != nil {
+		return result, fmt.Errorf("unable to get ui config for %s: %s", roomID, err)
+	}
+
+	devices, err := d.GetDevicesByRoom(roomID)
+	if err != nil {
+		return result, fmt.Errorf("unable to get devices in %s: %s", roomID, err)
+	}
+
+	icons, err := d.GetIcons()
+	if err != nil {
+		return result, fmt.Errorf("unable to get icons: %s", err)
+	}
+
+	return ValidateUIConfig(ui, devices, icons), nil
+}
+
+func (c *uiConfigCheck) checkUIPreset(i int) {
+	preset := c.ui.Presets[i]
+	path := fmt.Sprintf("presets[%d]", i)
+
+	c.checkIcon(path+".icon", preset.Icon)
+
+	lists := []struct {
+		field string
+		names []string
+	}{
+		{"displays", preset.Displays},
+		{"shareableDisplays", preset.ShareableDisplays},
+		{"audioDevices", preset.AudioDevices},
+		{"inputs", preset.Inputs},
+		{"independentAudioDevices", preset.IndependentAudioDevices},
+		{"screens", preset.Screens},
+	}
+
+	for _, l := range lists {
+		c.checkDevices(path+"."+l.field, l.names)
+	}
+
+	var groups []string
+	for group := range preset.AudioGroups {
+		groups = append(groups, group)
+	}
+
+	sort.Strings(groups)
+
+	for _, group := range groups {
+		c.checkDevices(fmt.Sprintf("%s.audioGroups.%s", path, group), preset.AudioGroups[group])
+	}
+
+	for j, name := range preset.ShareablePresets {
+		c.checkPreset(fmt.Sprintf("%s.shareablePresets[%d]", path, j), name)
+	}
+
+	c.checkReachability(path, preset)
+}
+
+// pruneGraph drops the edges to and from devices that aren't in graph (e.g. ports with a source in another room). CheckReachability's queue only has room for the devices in the graph, so it blocks forever if it follows too many of them.
+func pruneGraph(graph *inputgraph.InputGraph) {
+	for id, sources := range graph.AdjacencyMap {
+		if _, ok := graph.DeviceMap[id]; !ok {
+			delete(graph.AdjacencyMap, id)
+			continue
+		}
+
+		var known []string
+		for _, source := range sources {
+			if _, ok := graph.DeviceMap[source]; ok {
+				known = append(known, source)
+			}
+		}
+
+		graph.AdjacencyMap[id] = known
+	}
+}
+
+// checkReachability reports each input of preset that can't reach any of its displays.
+func (c *uiConfigCheck) checkReachability(path string, preset structs.Preset) {
+	if c.graph == nil {
+		return
+	}
+
+	var displays []string
+	for _, name := range preset.Displays {
+		if display, ok := c.devices[name]; ok {
+			displays = append(displays, display.ID)
+		}
+	}
+
+	if len(displays) == 0 {
+		return
+	}
+
+	for j, name := range preset.Inputs {
+		input, ok := c.devices[name]
+		if !ok {
+			continue
+		}
+
+		reachable := false
+		for _, display := range displays {
+			if ok, _, err := inputgraph.CheckReachability(display, input.ID, *c.graph); err == nil && ok {
+				reachable = true
+				break
+			}
+		}
+
+		if !reachable {
+			c.errorf(fmt.Sprintf("%s.inputs[%d]", path, j), structs.ValidationUnreachable, "%s can't reach any of the displays of preset %q", name, preset.Name)
+		}
+	}
+}
+
+func (c *uiConfigCheck) checkIOConfigurations(path string, configs []structs.IOConfiguration) {
+	for i, config := range configs {
+		p := fmt.Sprintf("%s[%d]", path, i)
+
+		c.checkDevice(p+".name", config.Name)
+		c.checkIcon(p+".icon", config.Icon)
+		c.checkIOConfigurations(p+".subInputs", config.SubInputs)
+	}
+}
+
+func (c *uiConfigCheck) checkDevices(path string, names []string) {
+	for i, name := range names {
+		c.checkDevice(fmt.Sprintf("%s[%d]", path, i), name)
+	}
+}
+
+func (c *uiConfigCheck) checkDevice(path, name string) {
+	if _, ok := c.devices[name]; !ok {
+		c.errorf(path, structs.ValidationUnknownDevice, "%q isn't a device in %s", name, c.ui.ID)
+	}
+}
+
+func (c *uiConfigCheck) hasDeviceID(id string) bool {
+	for _, device := range c.devices {
+		if device.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *uiConfigCheck) checkPreset(path, name string) {
+	if !c.presets[name] {
+		c.errorf(path, structs.ValidationUnknownPreset, "preset %q doesn't exist", name)
+	}
+}
+
+func (c *uiConfigCheck) checkIcon(path, icon string) {
+	if c.icons == nil || len(icon) == 0 {
+		return
+	}
+
+	if !c.icons[icon] {
+		c.warnf(path, structs.ValidationUnknownIcon, "%q isn't one of the available icons", icon)
+	}
+}
+
+func (c *uiConfigCheck) errorf(path string, code structs.ValidationCode, format string, a ...interface{}) {
+	c.result.Issues = append(c.result.Issues, structs.ValidationIssue{Path: path, Severity: structs.SeverityError, Code: code, Message: fmt.Sprintf(format, a...)})
+}
+
+func (c *uiConfigCheck) warnf(path string, code structs.ValidationCode, format string, a ...interface{}) {
+	c.result.Issues = append(c.result.Issues, structs.ValidationIssue{Path: path, Severity: structs.SeverityWarning, Code: code, Message: fmt.Sprintf(format, a...)})
+}
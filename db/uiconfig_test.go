@@ -0,0 +1,87 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/byuoitav/common/structs"
+)
+
+func TestValidateUIConfig(t *testing.T) {
+	devices := []structs.Device{
+		{ID: "CCC-AAA-D1", Name: "D1", Roles: []structs.Role{{ID: "VideoOut"}}, Ports: []structs.Port{{ID: "hdmi!1", SourceDevice: "CCC-AAA-VIA1", DestinationDevice: "CCC-AAA-D1"}}},
+		{ID: "CCC-AAA-VIA1", Name: "VIA1", Roles: []structs.Role{{ID: "VideoIn"}}},
+		{ID: "CCC-AAA-HDMI1", Name: "HDMI1", Roles: []structs.Role{{ID: "VideoIn"}}},
+		{ID: "CCC-AAA-CP1", Name: "CP1", Roles: []structs.Role{{ID: "ControlProcessor"}}},
+	}
+
+	ui := structs.UIConfig{
+		ID: "CCC-AAA",
+		Panels: []structs.Panel{
+			{Hostname: "CCC-AAA-CP1", Preset: "main"},
+			{Hostname: "CCC-AAA-CP2", Preset: "other"},
+		},
+		Presets: []structs.Preset{{
+			Name:     "main",
+			Icon:     "tv",
+			Displays: []string{"D1"},
+			Inputs:   []string{"VIA1", "HDMI1", "HDMI2"},
+		}},
+		InputConfiguration: []structs.IOConfiguration{{Name: "VIA1", Icon: "settings_input_antenna"}},
+	}
+
+	expected := map[string]structs.ValidationCode{
+		"panels[1].hostname":         structs.ValidationUnknownDevice,
+		"panels[1].preset":           structs.ValidationUnknownPreset,
+		"presets[0].inputs[2]":       structs.ValidationUnknownDevice,
+		"presets[0].inputs[1]":       structs.ValidationUnreachable,
+		"inputConfiguration[0].icon": structs.ValidationUnknownIcon,
+	}
+
+	result := ValidateUIConfig(ui, devices, []string{"tv"})
+
+	if len(result.Issues) != len(expected) {
+		t.Fatalf("expected %d issues, got %+v", len(expected), result.Issues)
+	}
+
+	for _, issue := range result.Issues {
+		if expected[issue.Path] != issue.Code {
+			t.Fatalf("unexpected issue: %+v", issue)
+		}
+	}
+
+	if len(result.Warnings()) != 1 {
+		t.Fatalf("expected only the unknown icon to be a warning, got %+v", result.Warnings())
+	}
+}
+
+func TestValidateUIConfigForeignPorts(t *testing.T) {
+	// the display's sources are in another room, so there are more of them than devices in the graph
+	devices := []structs.Device{
+		{ID: "AAA-1-D1", Name: "D1", Roles: []structs.Role{{ID: "VideoOut"}}, Ports: []structs.Port{
+			{ID: "hdmi!1", SourceDevice: "BBB-1-PC1", DestinationDevice: "AAA-1-D1"},
+			{ID: "hdmi!2", SourceDevice: "BBB-1-PC2", DestinationDevice: "AAA-1-D1"},
+			{ID: "hdmi!3", SourceDevice: "BBB-1-PC3", DestinationDevice: "AAA-1-D1"},
+		}},
+		{ID: "AAA-1-PC1", Name: "PC1", Roles: []structs.Role{{ID: "VideoIn"}}},
+	}
+
+	ui := structs.UIConfig{
+		ID:      "AAA-1",
+		Presets: []structs.Preset{{Name: "main", Displays: []string{"D1"}, Inputs: []string{"PC1"}}},
+	}
+
+	done := make(chan structs.ValidationResult, 1)
+	go func() {
+		done <- ValidateUIConfig(ui, devices, nil)
+	}()
+
+	select {
+	case result := <-done:
+		if len(result.Issues) != 1 || result.Issues[0].Code != structs.ValidationUnreachable {
+			t.Fatalf("expected PC1 to be unreachable, got %+v", result.Issues)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("validating the ui config didn't finish")
+	}
+}
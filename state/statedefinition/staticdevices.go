@@ -313,6 +313,23 @@ func CompareDevices(base, new StaticDevice) (diff StaticDevice, merged StaticDev
 	return
 }
 
+//MergeDevices merges new into base with the same per-field UpdateTimes semantics as CompareDevices, without changing base. Bool denotes if the merged device is different from base.
+func MergeDevices(base, new StaticDevice) (merged StaticDevice, changes bool) {
+	base.UpdateTimes = copyUpdateTimes(base.UpdateTimes)
+
+	_, merged, changes, _ = CompareDevices(base, new)
+	return merged, changes
+}
+
+func copyUpdateTimes(times map[string]time.Time) map[string]time.Time {
+	copied := make(map[string]time.Time, len(times))
+	for k, v := range times {
+		copied[k] = v
+	}
+
+	return copied
+}
+
 func compareString(base, new string, changes bool) (string, string, bool) {
 	if new != "" {
 		if base != new {
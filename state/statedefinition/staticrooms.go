@@ -83,6 +83,20 @@ func CompareRooms(base, new StaticRoom) (diff, merged StaticRoom, changes bool,
 	return
 }
 
+//MergeRooms merges new into base with the same per-field UpdateTimes semantics as CompareRooms, without changing base. The merged room's UpdateTimes are the later of base's and new's for each field. Bool denotes if the merged room is different from base.
+func MergeRooms(base, new StaticRoom) (merged StaticRoom, changes bool) {
+	_, merged, changes, _ = CompareRooms(base, new)
+
+	merged.UpdateTimes = copyUpdateTimes(base.UpdateTimes)
+	for k, v := range new.UpdateTimes {
+		if v.After(merged.UpdateTimes[k]) {
+			merged.UpdateTimes[k] = v
+		}
+	}
+
+	return merged, changes
+}
+
 func (r *StaticRoom) HasSystemType(s string) bool {
 	for i := range r.SystemType {
 		if r.SystemType[i] == s {
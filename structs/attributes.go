@@ -8,11 +8,64 @@ type MenuTree struct {
 // Group is a collection of attribute presets to create devices that fall into this group
 type Group struct {
 	ID        string         `json:"_id"`
+	Rev       string         `json:"_rev,omitempty"`
 	Icon      string         `json:"icon,omitempty"`
 	Subgroups []Group        `json:"sub-groups,omitempty"`
 	Presets   []AttributeSet `json:"presets,omitempty"`
 }
 
+// Validate checks to make sure that the Group, its presets, and each of its subgroups are valid, and that no two presets in it have the same name.
+func (g *Group) Validate() error {
+	return g.ValidateAll().Err("attribute group")
+}
+
+// ValidateAll returns every problem with the Group, its presets, and each of its subgroups.
+func (g *Group) ValidateAll() ValidationResult {
+	var r ValidationResult
+	g.validate(&r, "", make(map[string]bool))
+	return r
+}
+
+func (g *Group) validate(r *ValidationResult, path string, names map[string]bool) {
+	if len(g.ID) == 0 {
+		r.errorf(fieldPath(path, "_id"), ValidationMissing, "missing _id")
+	}
+
+	for i := range g.Presets {
+		preset := &g.Presets[i]
+		presetPath := indexPath(fieldPath(path, "presets"), i)
+
+		preset.validate(r, presetPath)
+
+		if names[preset.Name] {
+			r.errorf(fieldPath(presetPath, "name"), ValidationDuplicate, "there is more than one preset named %q", preset.Name)
+		}
+
+		names[preset.Name] = true
+	}
+
+	for i := range g.Subgroups {
+		g.Subgroups[i].validate(r, indexPath(fieldPath(path, "sub-groups"), i), names)
+	}
+}
+
+// FindPreset returns the preset named name in the group or any of its subgroups.
+func (g *Group) FindPreset(name string) (AttributeSet, bool) {
+	for _, preset := range g.Presets {
+		if preset.Name == name {
+			return preset, true
+		}
+	}
+
+	for _, sub := range g.Subgroups {
+		if preset, ok := sub.FindPreset(name); ok {
+			return preset, true
+		}
+	}
+
+	return AttributeSet{}, false
+}
+
 // AttributeSet is an object that contains a set of attributes and an identifier for this set
 type AttributeSet struct {
 	Name       string                 `json:"name"`
@@ -21,3 +74,23 @@ type AttributeSet struct {
 	DeviceIcon string                 `json:"device-icon,omitempty"`
 	Attributes map[string]interface{} `json:"attributes"`
 }
+
+// Validate checks to make sure that the AttributeSet's values are valid.
+func (a *AttributeSet) Validate() error {
+	var r ValidationResult
+	a.validate(&r, "")
+	return r.Err("preset")
+}
+
+func (a *AttributeSet) validate(r *ValidationResult, path string) {
+	if len(a.Name) == 0 {
+		r.errorf(fieldPath(path, "name"), ValidationMissing, "missing name")
+	}
+
+	if len(a.DeviceType) == 0 {
+		r.errorf(fieldPath(path, "device-type"), ValidationMissing, "missing device-type")
+	}
+}
+
+// DeviceIconAttribute is the attribute a device's icon is kept in.
+const DeviceIconAttribute = "icon"
@@ -0,0 +1,355 @@
+package structs
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// AttributeType is the type of the value of an attribute.
+type AttributeType string
+
+// Attribute types
+const (
+	AttributeString     AttributeType = "string"
+	AttributeNumber     AttributeType = "number"
+	AttributeInteger    AttributeType = "integer"
+	AttributeBool       AttributeType = "bool"
+	AttributeStringList AttributeType = "string-list"
+	AttributeObject     AttributeType = "object"
+)
+
+/*
+AttributeSchema describes one attribute that a device type's devices (or the rooms with a
+designation) can have. A required attribute without a Default must be set; one with a Default
+gets it when it isn't set. If Allowed isn't empty, the value (or, for a string-list, each value
+in it) must be one of Allowed.
+*/
+type AttributeSchema struct {
+	Name        string        `json:"name"`
+	Type        AttributeType `json:"type"`
+	Description string        `json:"description,omitempty"`
+	Required    bool          `json:"required,omitempty"`
+	Default     interface{}   `json:"default,omitempty"`
+	Allowed     []interface{} `json:"allowed,omitempty"`
+}
+
+// ValidateAttributeSchemas checks that each of schemas is valid, and that no two are for the same attribute. path is the JSON path of the list of schemas.
+func ValidateAttributeSchemas(schemas []AttributeSchema, path string) ValidationResult {
+	var r ValidationResult
+	validateAttributeSchemas(&r, path, schemas)
+	return r
+}
+
+func validateAttributeSchemas(r *ValidationResult, path string, schemas []AttributeSchema) {
+	names := make(map[string]bool)
+
+	for i := range schemas {
+		p := indexPath(path, i)
+		schemas[i].validate(r, p)
+
+		if names[schemas[i].Name] {
+			r.errorf(fieldPath(p, "name"), ValidationDuplicate, "there is more than one schema for %q", schemas[i].Name)
+		}
+
+		names[schemas[i].Name] = true
+	}
+}
+
+func (s *AttributeSchema) validate(r *ValidationResult, path string) {
+	if len(s.Name) == 0 {
+		r.errorf(fieldPath(path, "name"), ValidationMissing, "missing name")
+	}
+
+	switch s.Type {
+	case AttributeString, AttributeNumber, AttributeInteger, AttributeBool, AttributeStringList, AttributeObject:
+	default:
+		r.errorf(fieldPath(path, "type"), ValidationInvalidFormat, "unknown attribute type %q", s.Type)
+		return
+	}
+
+	if s.Default != nil {
+		s.validateValue(r, fieldPath(path, "default"), s.Default)
+	}
+
+	for i, allowed := range s.Allowed {
+		elem := s.Type
+		if elem == AttributeStringList {
+			elem = AttributeString
+		}
+
+		if !isAttributeType(allowed, elem) {
+			r.errorf(indexPath(fieldPath(path, "allowed"), i), ValidationWrongType, "%v has type %s, not %s", allowed, attributeTypeOf(allowed), elem)
+		}
+	}
+}
+
+// validateValue checks that value has the schema's type, and is allowed.
+func (s *AttributeSchema) validateValue(r *ValidationResult, path string, value interface{}) {
+	if !isAttributeType(value, s.Type) {
+		r.errorf(path, ValidationWrongType, "has type %s, not %s", attributeTypeOf(value), s.Type)
+		return
+	}
+
+	if len(s.Allowed) == 0 {
+		return
+	}
+
+	values := []interface{}{value}
+	if s.Type == AttributeStringList {
+		values = toInterfaces(value)
+	}
+
+	for _, v := range values {
+		if !s.allows(v) {
+			r.errorf(path, ValidationNotAllowed, "%v isn't one of %v", v, s.Allowed)
+		}
+	}
+}
+
+func (s *AttributeSchema) allows(value interface{}) bool {
+	for _, allowed := range s.Allowed {
+		if reflect.DeepEqual(normalizeAttribute(allowed), normalizeAttribute(value)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+ValidateAttributes checks attributes against schemas: each attribute with a schema must have its
+type and be allowed, and each required attribute without a default must be set. Attributes
+without a schema are warnings, since they are probably typos. Nothing is checked if schemas is
+empty. path is the JSON path of the attributes (e.g. "attributes").
+*/
+func ValidateAttributes(schemas []AttributeSchema, attributes map[string]interface{}, path string) ValidationResult {
+	var r ValidationResult
+	validateAttributes(&r, path, schemas, attributes)
+	return r
+}
+
+func validateAttributes(r *ValidationResult, path string, schemas []AttributeSchema, attributes map[string]interface{}) {
+	if len(schemas) == 0 {
+		return
+	}
+
+	known := make(map[string]bool)
+
+	for i := range schemas {
+		s := &schemas[i]
+		known[s.Name] = true
+
+		value, ok := attributes[s.Name]
+		if !ok || value == nil {
+			if s.Required && s.Default == nil {
+				r.errorf(fieldPath(path, s.Name), ValidationMissing, "missing required attribute %q", s.Name)
+			}
+
+			continue
+		}
+
+		s.validateValue(r, fieldPath(path, s.Name), value)
+	}
+
+	var names []string
+	for name := range attributes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !known[name] {
+			r.warnf(fieldPath(path, name), ValidationUnknownAttribute, "%q isn't in the attribute schema", name)
+		}
+	}
+}
+
+// ApplyAttributeDefaults returns attributes with the default of each schema that has one set, if that attribute isn't already set. attributes itself isn't changed.
+func ApplyAttributeDefaults(schemas []AttributeSchema, attributes map[string]interface{}) map[string]interface{} {
+	toReturn := attributes
+	copied := false
+
+	for _, s := range schemas {
+		if s.Default == nil {
+			continue
+		}
+
+		if value, ok := attributes[s.Name]; ok && value != nil {
+			continue
+		}
+
+		if !copied {
+			toReturn = make(map[string]interface{}, len(attributes)+1)
+			for k, v := range attributes {
+				toReturn[k] = v
+			}
+
+			copied = true
+		}
+
+		toReturn[s.Name] = s.Default
+	}
+
+	return toReturn
+}
+
+// ApplyAttributeSchema sets each attribute of the device that has a default in schemas and isn't set, then checks its attributes against schemas.
+func (d *Device) ApplyAttributeSchema(schemas []AttributeSchema) error {
+	d.Attributes = ApplyAttributeDefaults(schemas, d.Attributes)
+	return ValidateAttributes(schemas, d.Attributes, "attributes").Err("device")
+}
+
+// ApplyAttributeSchema sets each attribute of the room that has a default in schemas and isn't set, then checks its attributes against schemas.
+func (r *Room) ApplyAttributeSchema(schemas []AttributeSchema) error {
+	r.Attributes = ApplyAttributeDefaults(schemas, r.Attributes)
+	return ValidateAttributes(schemas, r.Attributes, "attributes").Err("room")
+}
+
+func isAttributeType(value interface{}, t AttributeType) bool {
+	switch t {
+	case AttributeString:
+		_, ok := value.(string)
+		return ok
+	case AttributeNumber:
+		_, ok := normalizeAttribute(value).(float64)
+		return ok
+	case AttributeInteger:
+		f, ok := normalizeAttribute(value).(float64)
+		return ok && f == math.Trunc(f)
+	case AttributeBool:
+		_, ok := value.(bool)
+		return ok
+	case AttributeStringList:
+		if _, ok := value.([]string); ok {
+			return true
+		}
+
+		list, ok := value.([]interface{})
+		if !ok {
+			return false
+		}
+
+		for _, v := range list {
+			if _, ok := v.(string); !ok {
+				return false
+			}
+		}
+
+		return true
+	case AttributeObject:
+		_, ok := value.(map[string]interface{})
+		return ok
+	default:
+		return false
+	}
+}
+
+// attributeTypeOf returns the attribute type of value, for error messages.
+func attributeTypeOf(value interface{}) string {
+	for _, t := range []AttributeType{AttributeString, AttributeInteger, AttributeNumber, AttributeBool, AttributeStringList, AttributeObject} {
+		if isAttributeType(value, t) {
+			return string(t)
+		}
+	}
+
+	return fmt.Sprintf("%T", value)
+}
+
+func toInterfaces(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case []string:
+		var toReturn []interface{}
+		for _, s := range v {
+			toReturn = append(toReturn, s)
+		}
+
+		return toReturn
+	default:
+		return nil
+	}
+}
+
+// AttributeError is returned when an attribute isn't set, or isn't the type it was asked for.
+type AttributeError struct {
+	Name     string
+	Expected AttributeType
+
+	// Value is the attribute's value, or nil if it isn't set.
+	Value interface{}
+}
+
+func (e *AttributeError) Error() string {
+	if e.Value == nil {
+		return fmt.Sprintf("attribute %q isn't set", e.Name)
+	}
+
+	return fmt.Sprintf("attribute %q (%v) has type %s, not %s", e.Name, e.Value, attributeTypeOf(e.Value), e.Expected)
+}
+
+// GetStringAttribute returns the attribute name in attributes, which must be a string.
+func GetStringAttribute(attributes map[string]interface{}, name string) (string, error) {
+	value := attributes[name]
+
+	s, ok := value.(string)
+	if !ok {
+		return "", &AttributeError{Name: name, Expected: AttributeString, Value: value}
+	}
+
+	return s, nil
+}
+
+// GetNumberAttribute returns the attribute name in attributes, which must be a number.
+func GetNumberAttribute(attributes map[string]interface{}, name string) (float64, error) {
+	value := attributes[name]
+
+	f, ok := normalizeAttribute(value).(float64)
+	if !ok {
+		return 0, &AttributeError{Name: name, Expected: AttributeNumber, Value: value}
+	}
+
+	return f, nil
+}
+
+// GetIntAttribute returns the attribute name in attributes, which must be a whole number.
+func GetIntAttribute(attributes map[string]interface{}, name string) (int, error) {
+	value := attributes[name]
+
+	if !isAttributeType(value, AttributeInteger) {
+		return 0, &AttributeError{Name: name, Expected: AttributeInteger, Value: value}
+	}
+
+	return int(normalizeAttribute(value).(float64)), nil
+}
+
+// GetBoolAttribute returns the attribute name in attributes, which must be a bool.
+func GetBoolAttribute(attributes map[string]interface{}, name string) (bool, error) {
+	value := attributes[name]
+
+	b, ok := value.(bool)
+	if !ok {
+		return false, &AttributeError{Name: name, Expected: AttributeBool, Value: value}
+	}
+
+	return b, nil
+}
+
+// GetStringListAttribute returns the attribute name in attributes, which must be a list of strings.
+func GetStringListAttribute(attributes map[string]interface{}, name string) ([]string, error) {
+	value := attributes[name]
+
+	if !isAttributeType(value, AttributeStringList) {
+		return nil, &AttributeError{Name: name, Expected: AttributeStringList, Value: value}
+	}
+
+	var toReturn []string
+	for _, v := range toInterfaces(value) {
+		toReturn = append(toReturn, v.(string))
+	}
+
+	return toReturn, nil
+}
@@ -0,0 +1,41 @@
+package structs
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditTimeFormat is the layout of the time that starts the ID of each audit entry, so that entries sort in the order they were made.
+const AuditTimeFormat = "20060102T150405.000000000Z"
+
+// AuditAction is what was done to a document.
+type AuditAction string
+
+// Audit actions
+const (
+	AuditCreated AuditAction = "created"
+	AuditUpdated AuditAction = "updated"
+	AuditDeleted AuditAction = "deleted"
+	AuditRenamed AuditAction = "renamed"
+)
+
+/*
+AuditEntry is a single change to a configuration document. Before and After are the whole
+document on either side of the change; Before is empty for a document that was created, and
+After is empty for one that was deleted. Changes is the difference between them, for documents
+that were updated or renamed.
+*/
+type AuditEntry struct {
+	ID         string          `json:"_id"`
+	Rev        string          `json:"_rev,omitempty"`
+	Database   string          `json:"database"`
+	DocumentID string          `json:"document-id"`
+	PreviousID string          `json:"previous-id,omitempty"`
+	RoomID     string          `json:"room-id,omitempty"`
+	Action     AuditAction     `json:"action"`
+	Actor      string          `json:"actor"`
+	Timestamp  time.Time       `json:"timestamp"`
+	Changes    []FieldChange   `json:"changes,omitempty"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+}
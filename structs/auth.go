@@ -1,11 +1,60 @@
 package structs
 
+import "regexp"
+
 // Auth - our authentication struct.
 type Auth struct {
-	ID          string   `json:"_id"`
-	Roles       []string `json:"roles"`
-	Permissions []struct {
-		Group string   `json:"group"`
-		Roles []string `json:"roles"`
-	} `json:"permissions"`
+	ID          string       `json:"_id"`
+	Rev         string       `json:"_rev,omitempty"`
+	Roles       []string     `json:"roles"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// Permission - the roles given to each member of the groups matching Group, which is a regular expression.
+type Permission struct {
+	Group string   `json:"group"`
+	Roles []string `json:"roles"`
+}
+
+// Validate checks to make sure that each of the Auth's permissions is valid, and that no two are for the same group.
+func (a *Auth) Validate() error {
+	return a.ValidateAll().Err("auth")
+}
+
+// ValidateAll returns every problem with the Auth's permissions.
+func (a *Auth) ValidateAll() ValidationResult {
+	var r ValidationResult
+	groups := make(map[string]bool)
+
+	for i := range a.Permissions {
+		path := indexPath("permissions", i)
+		a.Permissions[i].validate(&r, path)
+
+		if groups[a.Permissions[i].Group] {
+			r.errorf(fieldPath(path, "group"), ValidationDuplicate, "there is more than one permission for %s", a.Permissions[i].Group)
+		}
+
+		groups[a.Permissions[i].Group] = true
+	}
+
+	return r
+}
+
+// Validate checks to make sure that the Permission's group is a valid regular expression, and that it gives at least one role.
+func (p *Permission) Validate() error {
+	var r ValidationResult
+	p.validate(&r, "")
+	return r.Err("permission")
+}
+
+func (p *Permission) validate(r *ValidationResult, path string) {
+	if len(p.Group) == 0 {
+		r.errorf(fieldPath(path, "group"), ValidationMissing, "missing group")
+	} else if _, err := regexp.Compile(p.Group); err != nil {
+		r.errorf(fieldPath(path, "group"), ValidationInvalidFormat, "%q isn't a valid regular expression: %s", p.Group, err)
+	}
+
+	if len(p.Roles) == 0 {
+		r.errorf(fieldPath(path, "roles"), ValidationMissing, "must have at least one role")
+	}
 }
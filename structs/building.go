@@ -1,10 +1,9 @@
 package structs
 
-import "errors"
-
 // Building - the representation about a building containing a TEC Pi system.
 type Building struct {
 	ID          string   `json:"_id"`
+	Rev         string   `json:"_rev,omitempty"`
 	Name        string   `json:"name"`
 	Description string   `json:"description"`
 	Tags        []string `json:"tags,omitempty"`
@@ -12,8 +11,15 @@ type Building struct {
 
 // Validate determines if the current values for the building's attributes are valid or not.
 func (b *Building) Validate() error {
+	return b.ValidateAll().Err("building")
+}
+
+// ValidateAll returns every problem with the current values for the building's attributes.
+func (b *Building) ValidateAll() ValidationResult {
+	var r ValidationResult
 	if len(b.ID) < 2 {
-		return errors.New("invalid building: id must be at least 2 characters long")
+		r.errorf("_id", ValidationTooShort, "must be at least 2 characters long")
 	}
-	return nil
+
+	return r
 }
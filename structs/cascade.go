@@ -0,0 +1,33 @@
+package structs
+
+// CascadeDeleteResult is the plan for (and, unless it was a dry run, the result of) deleting a building or room along with everything in it.
+type CascadeDeleteResult struct {
+	ID        string            `json:"_id"`
+	DryRun    bool              `json:"dryRun"`
+	Documents []DeletedDocument `json:"documents"`
+}
+
+// DeletedDocument is a single document removed as part of a cascading delete. In a dry run, Success is always false.
+type DeletedDocument struct {
+	Database string `json:"database"`
+	ID       string `json:"_id"`
+	Success  bool   `json:"success"`
+	Message  string `json:"message,omitempty"`
+}
+
+// Failed returns each of the documents that couldn't be deleted.
+func (r CascadeDeleteResult) Failed() []DeletedDocument {
+	var toReturn []DeletedDocument
+
+	if r.DryRun {
+		return toReturn
+	}
+
+	for _, doc := range r.Documents {
+		if !doc.Success {
+			toReturn = append(toReturn, doc)
+		}
+	}
+
+	return toReturn
+}
@@ -1,7 +1,6 @@
 package structs
 
 import (
-	"errors"
 	"fmt"
 	"net/url"
 	"regexp"
@@ -13,6 +12,7 @@ import (
 // Device - a representation of a device involved in a TEC Pi system.
 type Device struct {
 	ID          string                 `json:"_id"`
+	Rev         string                 `json:"_rev,omitempty"`
 	Name        string                 `json:"name"`
 	Address     string                 `json:"address"`
 	Description string                 `json:"description"`
@@ -43,38 +43,44 @@ func IsDeviceIDValid(id string) bool {
 
 // Validate checks to see if the device's information is valid or not.
 func (d *Device) Validate() error {
-	vals := deviceIDValidationRegex.FindStringSubmatch(d.ID)
-	if len(vals) == 0 {
-		return errors.New("invalid device: inproper id. must match `([A-z,0-9]{2,}-[A-z,0-9]+)-[A-z]+[0-9]+`")
+	return d.ValidateAll().Err("device")
+}
+
+// ValidateAll returns every problem with the device's information.
+func (d *Device) ValidateAll() ValidationResult {
+	var r ValidationResult
+	d.validate(&r, "")
+	return r
+}
+
+func (d *Device) validate(r *ValidationResult, path string) {
+	if len(deviceIDValidationRegex.FindStringSubmatch(d.ID)) == 0 {
+		r.errorf(fieldPath(path, "_id"), ValidationInvalidFormat, "must match `([A-z,0-9]{2,}-[A-z,0-9]+)-[A-z]+[0-9]+`")
 	}
 
 	if len(d.Name) < 2 {
-		return errors.New("invalid device: name must be at least 3 characters long")
+		r.errorf(fieldPath(path, "name"), ValidationTooShort, "must be at least 2 characters long")
 	}
 
-	// validate device type
-	if err := d.Type.Validate(false); err != nil {
-		return fmt.Errorf("invalid device: %s", err)
+	if len(d.Address) == 0 {
+		r.warnf(fieldPath(path, "address"), ValidationMissing, "missing address")
 	}
 
+	// validate device type
+	d.Type.validate(r, fieldPath(path, "type"), false)
+
 	// validate roles
 	if len(d.Roles) == 0 {
-		return errors.New("invalid device: must include at least 1 role")
+		r.errorf(fieldPath(path, "roles"), ValidationMissing, "must include at least 1 role")
 	}
-	for _, role := range d.Roles {
-		if err := role.Validate(); err != nil {
-			return fmt.Errorf("invalid device: %s", err)
-		}
+	for i := range d.Roles {
+		d.Roles[i].validate(r, indexPath(fieldPath(path, "roles"), i))
 	}
 
 	// validate ports
-	for _, port := range d.Ports {
-		if err := port.Validate(); err != nil {
-			return fmt.Errorf("invalid device: %s", err)
-		}
+	for i := range d.Ports {
+		d.Ports[i].validate(r, indexPath(fieldPath(path, "ports"), i))
 	}
-
-	return nil
 }
 
 // GetDeviceRoomID returns the room ID portion of the device ID.
@@ -137,6 +143,7 @@ func (d *Device) GetPortFromSrcAndDest(src, dest string) *Port {
 // DeviceType - a representation of a type (or category) of devices.
 type DeviceType struct {
 	ID          string       `json:"_id"`
+	Rev         string       `json:"_rev,omitempty"`
 	Description string       `json:"description,omitempty"`
 	DisplayName string       `json:"display_name,omitempty"`
 	Input       bool         `json:"input,omitempty"`
@@ -150,30 +157,41 @@ type DeviceType struct {
 	DefaultName string       `json:"default-name,omitempty"`
 	DefaultIcon string       `json:"default-icon,omitempty"`
 	Tags        []string     `json:"tags,omitempty"`
+
+	// AttributeSchema describes the attributes of devices of this type.
+	AttributeSchema []AttributeSchema `json:"attribute_schema,omitempty"`
 }
 
 // Validate checks to make sure that the values of the DeviceType are valid.
 func (dt *DeviceType) Validate(deepCheck bool) error {
+	return dt.ValidateAll(deepCheck).Err("device type")
+}
+
+// ValidateAll returns every problem with the values of the DeviceType. Its ports, commands, and attribute schema are only checked if deepCheck is true.
+func (dt *DeviceType) ValidateAll(deepCheck bool) ValidationResult {
+	var r ValidationResult
+	dt.validate(&r, "", deepCheck)
+	return r
+}
+
+func (dt *DeviceType) validate(r *ValidationResult, path string, deepCheck bool) {
 	if len(dt.ID) == 0 {
-		return errors.New("invalid device type: missing id")
+		r.errorf(fieldPath(path, "_id"), ValidationMissing, "missing id")
 	}
 
 	if deepCheck {
 		// check all of the ports
-		for _, port := range dt.Ports {
-			if err := port.Validate(); err != nil {
-				return fmt.Errorf("invalid device type: %s", err)
-			}
+		for i := range dt.Ports {
+			dt.Ports[i].validate(r, indexPath(fieldPath(path, "ports"), i))
 		}
 
 		// check all of the commands
-		for _, command := range dt.Commands {
-			if err := command.Validate(); err != nil {
-				return fmt.Errorf("invalid device type: %s", err)
-			}
+		for i := range dt.Commands {
+			dt.Commands[i].validate(r, indexPath(fieldPath(path, "commands"), i))
 		}
+
+		validateAttributeSchemas(r, fieldPath(path, "attribute_schema"), dt.AttributeSchema)
 	}
-	return nil
 }
 
 // PowerState - a representation of a device's power state.
@@ -185,10 +203,15 @@ type PowerState struct {
 
 // Validate checks to make sure that the PowerState's values are valid.
 func (ps *PowerState) Validate() error {
+	var r ValidationResult
+	ps.validate(&r, "")
+	return r.Err("power state")
+}
+
+func (ps *PowerState) validate(r *ValidationResult, path string) {
 	if len(ps.ID) < 3 {
-		return errors.New("invalid power state: id must be at least 3 characters long")
+		r.errorf(fieldPath(path, "_id"), ValidationTooShort, "must be at least 3 characters long")
 	}
-	return nil
 }
 
 // Port - a representation of an input/output port on a device.
@@ -204,10 +227,19 @@ type Port struct {
 
 // Validate checks to make sure that the Port's values are valid.
 func (p *Port) Validate() error {
-	if len(p.ID) < 1 {
-		return errors.New("invalid port: id must be at least 3 characters long")
+	var r ValidationResult
+	p.validate(&r, "")
+	return r.Err("port")
+}
+
+func (p *Port) validate(r *ValidationResult, path string) {
+	if len(p.ID) == 0 {
+		r.errorf(fieldPath(path, "_id"), ValidationMissing, "missing id")
+	}
+
+	if len(p.SourceDevice) > 0 && len(p.DestinationDevice) == 0 {
+		r.warnf(fieldPath(path, "destination_device"), ValidationUnconnected, "has a source device (%s), but no destination device", p.SourceDevice)
 	}
-	return nil
 }
 
 // Role - a representation of a role that a device plays in the overall system.
@@ -219,10 +251,15 @@ type Role struct {
 
 // Validate checks to make sure that the Role's values are valid.
 func (r *Role) Validate() error {
+	var result ValidationResult
+	r.validate(&result, "")
+	return result.Err("role")
+}
+
+func (r *Role) validate(result *ValidationResult, path string) {
 	if len(r.ID) < 3 {
-		return errors.New("invalid role: id must at least 3 characters long")
+		result.errorf(fieldPath(path, "_id"), ValidationTooShort, "must be at least 3 characters long")
 	}
-	return nil
 }
 
 // Command - a representation of an API command to be executed.
@@ -237,20 +274,18 @@ type Command struct {
 
 // Validate checks to make sure that the Command's values are valid.
 func (c *Command) Validate() error {
-	if len(c.ID) < 3 {
-		return errors.New("invalid command: id must be at least 3 characters long")
-	}
+	var r ValidationResult
+	c.validate(&r, "")
+	return r.Err("command")
+}
 
-	// validate microservice
-	if err := c.Microservice.Validate(); err != nil {
-		return fmt.Errorf("invalid command: %s", err)
+func (c *Command) validate(r *ValidationResult, path string) {
+	if len(c.ID) < 3 {
+		r.errorf(fieldPath(path, "_id"), ValidationTooShort, "must be at least 3 characters long")
 	}
 
-	// validate endpoint
-	if err := c.Endpoint.Validate(); err != nil {
-		return fmt.Errorf("invalid command: %s", err)
-	}
-	return nil
+	c.Microservice.validate(r, fieldPath(path, "microservice"))
+	c.Endpoint.validate(r, fieldPath(path, "endpoint"))
 }
 
 // BuildCommandAddress builds the full address for a command based off it's the microservice and endpoint
@@ -272,15 +307,24 @@ type Microservice struct {
 
 // Validate checks to make sure that the Microservice's values are valid.
 func (m *Microservice) Validate() error {
+	var r ValidationResult
+	m.validate(&r, "")
+	return r.Err("microservice")
+}
+
+func (m *Microservice) validate(r *ValidationResult, path string) {
 	if len(m.ID) < 3 {
-		return errors.New("invalid microservice: id must be at least 3 characters long")
+		r.errorf(fieldPath(path, "_id"), ValidationTooShort, "must be at least 3 characters long")
 	}
 
 	// validate address
-	if _, err := url.ParseRequestURI(m.Address); err != nil {
-		return fmt.Errorf("invalid microservice: %s", err)
+	u, err := url.ParseRequestURI(m.Address)
+	switch {
+	case err != nil:
+		r.errorf(fieldPath(path, "address"), ValidationInvalidFormat, "%s", err)
+	case u.Scheme != "http" && u.Scheme != "https":
+		r.warnf(fieldPath(path, "address"), ValidationUnexpectedScheme, "scheme %q isn't http or https", u.Scheme)
 	}
-	return nil
 }
 
 // Endpoint - a representation of an API endpoint.
@@ -293,15 +337,20 @@ type Endpoint struct {
 
 // Validate checks to make sure that the Endpoint's values are valid.
 func (e *Endpoint) Validate() error {
+	var r ValidationResult
+	e.validate(&r, "")
+	return r.Err("endpoint")
+}
+
+func (e *Endpoint) validate(r *ValidationResult, path string) {
 	if len(e.ID) < 3 {
-		return errors.New("invalid endpoint: id must be at least 3 characters long")
+		r.errorf(fieldPath(path, "_id"), ValidationTooShort, "must be at least 3 characters long")
 	}
 
 	// validate path
 	if _, err := url.ParseRequestURI(e.Path); err != nil {
-		return fmt.Errorf("invalid endpoint: %s", err)
+		r.errorf(fieldPath(path, "path"), ValidationInvalidFormat, "%s", err)
 	}
-	return nil
 }
 
 // HasRole checks to see if the given device has the given role.
@@ -3,9 +3,34 @@ package structs
 // DMPSList - the list of DMPSes to connect to and pull events
 type DMPSList struct {
 	ID   string `json:"_id"`
+	Rev  string `json:"_rev,omitempty"`
 	List []DMPS `json:"list"`
 }
 
+// Validate checks to make sure that each DMPS in the list is valid, and that no two have the same hostname.
+func (l *DMPSList) Validate() error {
+	return l.ValidateAll().Err("dmps list")
+}
+
+// ValidateAll returns every problem with the DMPSes in the list.
+func (l *DMPSList) ValidateAll() ValidationResult {
+	var r ValidationResult
+	hostnames := make(map[string]bool)
+
+	for i := range l.List {
+		path := indexPath("list", i)
+		l.List[i].validate(&r, path)
+
+		if hostnames[l.List[i].Hostname] {
+			r.errorf(fieldPath(path, "hostname"), ValidationDuplicate, "%s is in the list more than once", l.List[i].Hostname)
+		}
+
+		hostnames[l.List[i].Hostname] = true
+	}
+
+	return r
+}
+
 // DMPS - a single DMPS to connect to and pull events
 type DMPS struct {
 	Hostname       string `json:"hostname"`
@@ -13,3 +38,20 @@ type DMPS struct {
 	CommandToQuery string `json:"commandToQuery,omitempty"`
 	Port           string `json:"port,omitempty"`
 }
+
+// Validate checks to make sure that the DMPS's values are valid.
+func (d *DMPS) Validate() error {
+	var r ValidationResult
+	d.validate(&r, "")
+	return r.Err("dmps")
+}
+
+func (d *DMPS) validate(r *ValidationResult, path string) {
+	if len(d.Hostname) == 0 {
+		r.errorf(fieldPath(path, "hostname"), ValidationMissing, "missing hostname")
+	}
+
+	if len(d.Address) == 0 {
+		r.errorf(fieldPath(path, "address"), ValidationMissing, "missing address")
+	}
+}
@@ -0,0 +1,119 @@
+package structs
+
+import (
+	"reflect"
+	"strings"
+)
+
+/*
+DeviceFilter describes a set of devices to find. Fields that are left empty match every
+device, and a device must match every field that is set.
+
+By default values are matched exactly; set IgnoreCase to match Type, Roles and Designation
+the way HasRole does. Backends may not be able to use an index for case-insensitive matches.
+*/
+type DeviceFilter struct {
+	// RoomID matches devices in this room.
+	RoomID string `json:"roomID,omitempty"`
+
+	// BuildingID matches devices in this building.
+	BuildingID string `json:"buildingID,omitempty"`
+
+	// Type matches devices with this device type id.
+	Type string `json:"type,omitempty"`
+
+	// Roles matches devices that have all of these role ids.
+	Roles []string `json:"roles,omitempty"`
+
+	// Tags matches devices that have all of these tags.
+	Tags []string `json:"tags,omitempty"`
+
+	// Attributes matches devices whose attributes have each of these keys set to the given value.
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+
+	// Designation matches devices in rooms with this designation.
+	Designation string `json:"designation,omitempty"`
+
+	IgnoreCase bool `json:"ignoreCase,omitempty"`
+}
+
+// Matches reports whether d matches every field of the filter, except for Designation, which depends on d's room.
+func (f DeviceFilter) Matches(d Device) bool {
+	if len(f.RoomID) > 0 && !strings.HasPrefix(d.ID, f.RoomID+"-") {
+		return false
+	}
+
+	if len(f.BuildingID) > 0 && !strings.HasPrefix(d.ID, f.BuildingID+"-") {
+		return false
+	}
+
+	if len(f.Type) > 0 && !f.equal(d.Type.ID, f.Type) {
+		return false
+	}
+
+	for _, role := range f.Roles {
+		found := false
+		for i := range d.Roles {
+			if f.equal(d.Roles[i].ID, role) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	for _, tag := range f.Tags {
+		found := false
+		for i := range d.Tags {
+			if d.Tags[i] == tag {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	for key, val := range f.Attributes {
+		attr, ok := d.Attributes[key]
+		if !ok || !reflect.DeepEqual(normalizeAttribute(attr), normalizeAttribute(val)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MatchesDesignation reports whether a room's designation matches the filter's Designation.
+func (f DeviceFilter) MatchesDesignation(designation string) bool {
+	return len(f.Designation) == 0 || f.equal(designation, f.Designation)
+}
+
+func (f DeviceFilter) equal(a, b string) bool {
+	if f.IgnoreCase {
+		return strings.EqualFold(a, b)
+	}
+
+	return a == b
+}
+
+// normalizeAttribute converts numbers to float64, so that an attribute read from json matches one set in code.
+func normalizeAttribute(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float32:
+		return float64(n)
+	default:
+		return v
+	}
+}
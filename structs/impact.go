@@ -0,0 +1,169 @@
+package structs
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// DeviceTypeChanges are the ids of the items of one kind (e.g. commands) that were added to, removed from, or changed in a device type.
+type DeviceTypeChanges struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+}
+
+// Empty returns true if nothing was added, removed, or changed.
+func (c DeviceTypeChanges) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Changed) == 0
+}
+
+// DeviceTypeDiff is the difference between two versions of a device type.
+type DeviceTypeDiff struct {
+	ID          string            `json:"_id"`
+	Commands    DeviceTypeChanges `json:"commands"`
+	Ports       DeviceTypeChanges `json:"ports"`
+	Roles       DeviceTypeChanges `json:"roles"`
+	PowerStates DeviceTypeChanges `json:"power_states"`
+}
+
+// Empty returns true if none of the type's commands, ports, roles, or power states changed.
+func (d DeviceTypeDiff) Empty() bool {
+	return d.Commands.Empty() && d.Ports.Empty() && d.Roles.Empty() && d.PowerStates.Empty()
+}
+
+// Removes returns true if any command, port, role, or power state was removed.
+func (d DeviceTypeDiff) Removes() bool {
+	return len(d.Commands.Removed) > 0 || len(d.Ports.Removed) > 0 || len(d.Roles.Removed) > 0 || len(d.PowerStates.Removed) > 0
+}
+
+// DiffDeviceTypes returns what was added, removed, and changed between old and new. Items are matched by id.
+func DiffDeviceTypes(old, new DeviceType) DeviceTypeDiff {
+	diff := DeviceTypeDiff{ID: old.ID}
+
+	var oldItems, newItems []idItem
+
+	for i := range old.Commands {
+		oldItems = append(oldItems, idItem{old.Commands[i].ID, old.Commands[i]})
+	}
+	for i := range new.Commands {
+		newItems = append(newItems, idItem{new.Commands[i].ID, new.Commands[i]})
+	}
+	diff.Commands = diffItems(oldItems, newItems)
+
+	oldItems, newItems = nil, nil
+	for i := range old.Ports {
+		oldItems = append(oldItems, idItem{old.Ports[i].ID, old.Ports[i]})
+	}
+	for i := range new.Ports {
+		newItems = append(newItems, idItem{new.Ports[i].ID, new.Ports[i]})
+	}
+	diff.Ports = diffItems(oldItems, newItems)
+
+	oldItems, newItems = nil, nil
+	for i := range old.Roles {
+		oldItems = append(oldItems, idItem{old.Roles[i].ID, old.Roles[i]})
+	}
+	for i := range new.Roles {
+		newItems = append(newItems, idItem{new.Roles[i].ID, new.Roles[i]})
+	}
+	diff.Roles = diffItems(oldItems, newItems)
+
+	oldItems, newItems = nil, nil
+	for i := range old.PowerStates {
+		oldItems = append(oldItems, idItem{old.PowerStates[i].ID, old.PowerStates[i]})
+	}
+	for i := range new.PowerStates {
+		newItems = append(newItems, idItem{new.PowerStates[i].ID, new.PowerStates[i]})
+	}
+	diff.PowerStates = diffItems(oldItems, newItems)
+
+	return diff
+}
+
+type idItem struct {
+	id   string
+	item interface{}
+}
+
+func diffItems(old, new []idItem) DeviceTypeChanges {
+	var changes DeviceTypeChanges
+
+	oldByID := make(map[string]interface{})
+	for _, o := range old {
+		oldByID[o.id] = o.item
+	}
+
+	newByID := make(map[string]interface{})
+	for _, n := range new {
+		newByID[n.id] = n.item
+
+		o, ok := oldByID[n.id]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, n.id)
+		case !reflect.DeepEqual(o, n.item):
+			changes.Changed = append(changes.Changed, n.id)
+		}
+	}
+
+	for _, o := range old {
+		if _, ok := newByID[o.id]; !ok {
+			changes.Removed = append(changes.Removed, o.id)
+		}
+	}
+
+	return changes
+}
+
+/*
+DeviceTypeReference is something that depends on an item removed from a device type: a device
+of the type (which loses the item), or a ui config that names such a device. Field is the JSON
+path of the field with the reference (e.g. "ports[0]._id" or "presets[1].displays[0]"), and Removed
+is the removed item the reference depends on.
+*/
+type DeviceTypeReference struct {
+	Database string `json:"database"`
+	ID       string `json:"_id"`
+	Field    string `json:"field,omitempty"`
+	Removed  string `json:"removed"`
+	Message  string `json:"message"`
+}
+
+// DeviceTypeImpact is what an update to a device type would change, and what depends on anything it removes.
+type DeviceTypeImpact struct {
+	Diff DeviceTypeDiff `json:"diff"`
+
+	// Devices are the ids of each device of the type.
+	Devices    []string              `json:"devices"`
+	References []DeviceTypeReference `json:"references"`
+}
+
+// Breaking returns true if the update removes anything that something still depends on.
+func (i DeviceTypeImpact) Breaking() bool {
+	return len(i.References) > 0
+}
+
+// BreakingChange is returned when an update to a device type removes something that is still depended on.
+type BreakingChange struct {
+	Impact DeviceTypeImpact
+}
+
+func (e *BreakingChange) Error() string {
+	var removed []string
+	for _, c := range []struct {
+		kind string
+		ids  []string
+	}{
+		{"commands", e.Impact.Diff.Commands.Removed},
+		{"ports", e.Impact.Diff.Ports.Removed},
+		{"roles", e.Impact.Diff.Roles.Removed},
+		{"power states", e.Impact.Diff.PowerStates.Removed},
+	} {
+		if len(c.ids) > 0 {
+			removed = append(removed, fmt.Sprintf("%s %s", c.kind, strings.Join(c.ids, ", ")))
+		}
+	}
+
+	return fmt.Sprintf("update to device type %s is breaking: it removes %s, which %d references depend on", e.Impact.Diff.ID, strings.Join(removed, "; "), len(e.Impact.References))
+}
@@ -0,0 +1,59 @@
+package structs
+
+import "time"
+
+// IntegrityIssueKind is the kind of problem found by an integrity check.
+type IntegrityIssueKind string
+
+// Integrity issue kinds
+const (
+	// IntegrityInvalidID is a document whose id doesn't match the naming scheme for its kind.
+	IntegrityInvalidID IntegrityIssueKind = "invalid-id"
+
+	// IntegrityDanglingReference is a field that references a document (or, in a ui config, a device or preset name) that doesn't exist.
+	IntegrityDanglingReference IntegrityIssueKind = "dangling-reference"
+
+	// IntegrityOrphanedDocument is a document whose building or room doesn't exist.
+	IntegrityOrphanedDocument IntegrityIssueKind = "orphaned-document"
+)
+
+/*
+IntegrityIssue is a single problem found by an integrity check. Field is the JSON path of the
+field with the problem (e.g. "ports[0].source_device"), and is empty for problems with the whole
+document.
+
+Fixable issues are those that can be fixed without losing anything but the broken reference
+itself; Fixed is whether that was done.
+*/
+type IntegrityIssue struct {
+	Kind      IntegrityIssueKind `json:"kind"`
+	Database  string             `json:"database"`
+	ID        string             `json:"_id"`
+	Field     string             `json:"field,omitempty"`
+	Reference string             `json:"reference,omitempty"`
+	Message   string             `json:"message"`
+	Fixable   bool               `json:"fixable"`
+	Fixed     bool               `json:"fixed"`
+	FixError  string             `json:"fix-error,omitempty"`
+}
+
+// IntegrityReport is the result of checking the integrity of every configuration document.
+type IntegrityReport struct {
+	CheckedAt time.Time        `json:"checked-at"`
+	Fix       bool             `json:"fix"`
+	Checked   map[string]int   `json:"checked"`
+	Issues    []IntegrityIssue `json:"issues"`
+}
+
+// Unfixed returns each of the issues that haven't been fixed.
+func (r IntegrityReport) Unfixed() []IntegrityIssue {
+	var toReturn []IntegrityIssue
+
+	for _, issue := range r.Issues {
+		if !issue.Fixed {
+			toReturn = append(toReturn, issue)
+		}
+	}
+
+	return toReturn
+}
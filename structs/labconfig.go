@@ -3,6 +3,31 @@ package structs
 // LabConfig represents the configuration values neccessary for a Lab Attendance system to function properly
 type LabConfig struct {
 	ID      string `json:"_id"`
+	Rev     string `json:"_rev,omitempty"`
 	LabName string `json:"lab_name"`
 	LabID   string `json:"lab_id"`
 }
+
+// Validate checks to make sure that the LabConfig's values are valid.
+func (l *LabConfig) Validate() error {
+	return l.ValidateAll().Err("lab config")
+}
+
+// ValidateAll returns every problem with the LabConfig's values.
+func (l *LabConfig) ValidateAll() ValidationResult {
+	var r ValidationResult
+
+	if !IsRoomIDValid(l.ID) {
+		r.errorf("_id", ValidationInvalidFormat, "must be a room id")
+	}
+
+	if len(l.LabName) == 0 {
+		r.errorf("lab_name", ValidationMissing, "missing lab_name")
+	}
+
+	if len(l.LabID) == 0 {
+		r.errorf("lab_id", ValidationMissing, "missing lab_id")
+	}
+
+	return r
+}
@@ -0,0 +1,61 @@
+package structs
+
+import "time"
+
+// SchemaVersion is the schema version of a database, and the migrations that brought it there.
+type SchemaVersion struct {
+	Rev      string             `json:"_rev,omitempty"`
+	Database string             `json:"database"`
+	Version  int                `json:"version"`
+	Applied  []AppliedMigration `json:"applied,omitempty"`
+}
+
+// AppliedMigration is a migration that has been run against a database.
+type AppliedMigration struct {
+	Version   int       `json:"version"`
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"applied-at"`
+	Documents int       `json:"documents"`
+}
+
+// MigrationResult is the plan for (and, unless it was a dry run, the result of) migrating a database.
+type MigrationResult struct {
+	Database    string             `json:"database"`
+	DryRun      bool               `json:"dryRun"`
+	FromVersion int                `json:"from-version"`
+	ToVersion   int                `json:"to-version"`
+	Documents   []MigratedDocument `json:"documents"`
+}
+
+// MigratedDocument is a single document changed by a migration, and how it was changed.
+type MigratedDocument struct {
+	ID         string        `json:"_id"`
+	Migrations []string      `json:"migrations"`
+	Changes    []FieldChange `json:"changes"`
+	Success    bool          `json:"success"`
+	Message    string        `json:"message,omitempty"`
+}
+
+// FieldChange is a field of a document that was changed. Path is the JSON path of the field (e.g. "ports.0.tags"). Old is nil for an added field, and New is nil for a removed one.
+type FieldChange struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// Failed returns each of the documents that couldn't be migrated.
+func (r MigrationResult) Failed() []MigratedDocument {
+	var toReturn []MigratedDocument
+
+	if r.DryRun {
+		return toReturn
+	}
+
+	for _, doc := range r.Documents {
+		if !doc.Success {
+			toReturn = append(toReturn, doc)
+		}
+	}
+
+	return toReturn
+}
@@ -0,0 +1,51 @@
+package structs
+
+import "strings"
+
+// RenameResult is the result of renaming a building or a room, along with everything in it.
+type RenameResult struct {
+	OldID      string            `json:"oldID"`
+	NewID      string            `json:"newID"`
+	Documents  []RenamedDocument `json:"documents"`
+	RolledBack bool              `json:"rolledBack,omitempty"`
+}
+
+// RenamedDocument is the result of moving a single document as part of a rename.
+type RenamedDocument struct {
+	Kind    string `json:"kind"`
+	OldID   string `json:"oldID"`
+	NewID   string `json:"newID"`
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+}
+
+// RenameID returns id with the building or room oldID replaced by newID. If id isn't oldID, or something in it, id is returned unchanged.
+func RenameID(id, oldID, newID string) string {
+	switch {
+	case id == oldID:
+		return newID
+	case strings.HasPrefix(id, oldID+"-"):
+		return newID + id[len(oldID):]
+	default:
+		return id
+	}
+}
+
+// Rename updates the device's ID, and the devices its ports reference, for the building or room oldID being renamed to newID.
+func (d *Device) Rename(oldID, newID string) {
+	d.ID = RenameID(d.ID, oldID, newID)
+
+	for i := range d.Ports {
+		d.Ports[i].SourceDevice = RenameID(d.Ports[i].SourceDevice, oldID, newID)
+		d.Ports[i].DestinationDevice = RenameID(d.Ports[i].DestinationDevice, oldID, newID)
+	}
+}
+
+// Rename updates the ui config's ID, and the hostnames of its panels, for the building or room oldID being renamed to newID.
+func (u *UIConfig) Rename(oldID, newID string) {
+	u.ID = RenameID(u.ID, oldID, newID)
+
+	for i := range u.Panels {
+		u.Panels[i].Hostname = RenameID(u.Panels[i].Hostname, oldID, newID)
+	}
+}
@@ -1,14 +1,11 @@
 package structs
 
-import (
-	"errors"
-	"fmt"
-	"regexp"
-)
+import "regexp"
 
 // Room - a representation of a room containing a TEC Pi system.
 type Room struct {
 	ID            string                 `json:"_id"`
+	Rev           string                 `json:"_rev,omitempty"`
 	Name          string                 `json:"name"`
 	Description   string                 `json:"description"`
 	Configuration RoomConfiguration      `json:"configuration"`
@@ -20,31 +17,43 @@ type Room struct {
 
 var roomValidationRegex = regexp.MustCompile(`([A-z,0-9]{2,})-[A-z,0-9]+`)
 
+// IsRoomIDValid takes a room id and tells you whether or not it is valid.
+func IsRoomIDValid(id string) bool {
+	return len(roomValidationRegex.FindStringSubmatch(id)) > 0
+}
+
 // Validate checks to make sure that the Room's values are valid.
 func (r *Room) Validate() error {
-	vals := roomValidationRegex.FindStringSubmatch(r.ID)
-	if len(vals) == 0 {
-		return errors.New("invalid room: _id must match `([A-z,0-9]{2,})-[A-z,0-9]+`")
+	return r.ValidateAll().Err("room")
+}
+
+// ValidateAll returns every problem with the Room's values.
+func (r *Room) ValidateAll() ValidationResult {
+	var result ValidationResult
+	r.validate(&result, "")
+	return result
+}
+
+func (r *Room) validate(result *ValidationResult, path string) {
+	if len(roomValidationRegex.FindStringSubmatch(r.ID)) == 0 {
+		result.errorf(fieldPath(path, "_id"), ValidationInvalidFormat, "must match `([A-z,0-9]{2,})-[A-z,0-9]+`")
 	}
 
 	if len(r.Name) == 0 {
-		return errors.New("invalid room: missing name")
+		result.errorf(fieldPath(path, "name"), ValidationMissing, "missing name")
 	}
 
 	if len(r.Designation) == 0 {
-		return errors.New("invalid room: missing designation")
-	}
-
-	if err := r.Configuration.Validate(false); err != nil {
-		return fmt.Errorf("invalid room: %s", err)
+		result.errorf(fieldPath(path, "designation"), ValidationMissing, "missing designation")
 	}
 
-	return nil
+	r.Configuration.validate(result, fieldPath(path, "configuration"), false)
 }
 
 // RoomConfiguration - a representation of the configuration of a room.
 type RoomConfiguration struct {
 	ID          string      `json:"_id"`
+	Rev         string      `json:"_rev,omitempty"`
 	Evaluators  []Evaluator `json:"evaluators,omitempty"`
 	Description string      `json:"description,omitempty"`
 	Tags        []string    `json:"tags,omitempty"`
@@ -52,23 +61,30 @@ type RoomConfiguration struct {
 
 // Validate checks to make sure that the RoomConfiguration's values are valid.
 func (rc *RoomConfiguration) Validate(deepCheck bool) error {
+	return rc.ValidateAll(deepCheck).Err("room configuration")
+}
+
+// ValidateAll returns every problem with the RoomConfiguration's values. Its evaluators are only checked if deepCheck is true.
+func (rc *RoomConfiguration) ValidateAll(deepCheck bool) ValidationResult {
+	var r ValidationResult
+	rc.validate(&r, "", deepCheck)
+	return r
+}
+
+func (rc *RoomConfiguration) validate(r *ValidationResult, path string, deepCheck bool) {
 	if len(rc.ID) == 0 {
-		return errors.New("invalid room configuration: missing _id")
+		r.errorf(fieldPath(path, "_id"), ValidationMissing, "missing _id")
 	}
 
 	if deepCheck {
 		if len(rc.Evaluators) == 0 {
-			return errors.New("invalid room configuration: at least one evaluator is required")
+			r.errorf(fieldPath(path, "evaluators"), ValidationMissing, "at least one evaluator is required")
 		}
 
-		for _, evaluator := range rc.Evaluators {
-			if err := evaluator.Validate(); err != nil {
-				return fmt.Errorf("invalid room configuration: %s", err)
-			}
+		for i := range rc.Evaluators {
+			rc.Evaluators[i].validate(r, indexPath(fieldPath(path, "evaluators"), i))
 		}
 	}
-
-	return nil
 }
 
 // Evaluator - a representation of a priority evaluator.
@@ -82,18 +98,22 @@ type Evaluator struct {
 
 // Validate checks to make sure that the Evaluator's values are valid.
 func (e *Evaluator) Validate() error {
+	var r ValidationResult
+	e.validate(&r, "")
+	return r.Err("evaluator")
+}
+
+func (e *Evaluator) validate(r *ValidationResult, path string) {
 	if len(e.ID) == 0 {
-		return errors.New("invalid evaluator: missing evaluator _id")
+		r.errorf(fieldPath(path, "_id"), ValidationMissing, "missing evaluator _id")
 	}
 
 	if len(e.CodeKey) == 0 {
-		return errors.New("invalid evaluator: missing codekey")
+		r.errorf(fieldPath(path, "codekey"), ValidationMissing, "missing codekey")
 	}
 
 	// default priority to 1000
 	if e.Priority == 0 {
 		e.Priority = 1000
 	}
-
-	return nil
 }
@@ -3,7 +3,7 @@ package structs
 // ScheduleConfig represents the configuration values necessary for the Calendar service to function properly
 type ScheduleConfig struct {
 	ID              string `json:"_id"`
-	Rev             string `json:"_rev"`
+	Rev             string `json:"_rev,omitempty"`
 	Resource        string `json:"resource"`
 	Name            string `json:"displayname"`
 	AutoDiscoverURL string `json:"autodiscover-url"`
@@ -14,3 +14,27 @@ type ScheduleConfig struct {
 	CalendarType    string `json:"calendar-type"`
 	CalendarName    string `json:"calendar-name"`
 }
+
+// Validate checks to make sure that the ScheduleConfig's values are valid.
+func (s *ScheduleConfig) Validate() error {
+	return s.ValidateAll().Err("schedule config")
+}
+
+// ValidateAll returns every problem with the ScheduleConfig's values.
+func (s *ScheduleConfig) ValidateAll() ValidationResult {
+	var r ValidationResult
+
+	if !IsRoomIDValid(s.ID) {
+		r.errorf("_id", ValidationInvalidFormat, "must be a room id")
+	}
+
+	if len(s.Resource) == 0 {
+		r.errorf("resource", ValidationMissing, "missing resource")
+	}
+
+	if len(s.Name) == 0 {
+		r.errorf("displayname", ValidationMissing, "missing displayname")
+	}
+
+	return r
+}
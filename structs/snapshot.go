@@ -0,0 +1,114 @@
+package structs
+
+import (
+	"fmt"
+	"time"
+)
+
+// SnapshotVersion is the version of the snapshot format. Snapshots from a newer version can't be imported.
+const SnapshotVersion = 1
+
+// Snapshot is every configuration document in a database, for backing it up, or for copying it into another one.
+type Snapshot struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created-at"`
+
+	Buildings          []Building          `json:"buildings,omitempty"`
+	Rooms              []Room              `json:"rooms,omitempty"`
+	Devices            []Device            `json:"devices,omitempty"`
+	DeviceTypes        []DeviceType        `json:"device-types,omitempty"`
+	RoomConfigurations []RoomConfiguration `json:"room-configurations,omitempty"`
+	UIConfigs          []UIConfig          `json:"ui-configs,omitempty"`
+	Options            SnapshotOptions     `json:"options"`
+	AttributeGroups    []Group             `json:"attribute-groups,omitempty"`
+
+	DeploymentInfo       []DeploymentDocument     `json:"deployment-info,omitempty"`
+	DeviceDeploymentInfo []DeviceDeploymentConfig `json:"device-deployment-info,omitempty"`
+}
+
+// SnapshotOptions are the documents from the options database. An empty list isn't imported, so it doesn't replace the existing one.
+type SnapshotOptions struct {
+	Templates        []Template `json:"templates,omitempty"`
+	Icons            []string   `json:"icons,omitempty"`
+	Roles            []Role     `json:"roles,omitempty"`
+	RoomDesignations []string   `json:"room-designations,omitempty"`
+	ClosureCodes     []string   `json:"closure-codes,omitempty"`
+	Tags             []string   `json:"tags,omitempty"`
+	MenuTree         []string   `json:"menu-tree,omitempty"`
+
+	RoomAttributeSchemas map[string][]AttributeSchema `json:"room-attribute-schemas,omitempty"`
+}
+
+// DeploymentDocument is a document from the deployment information database, which holds both the deployment config (see FullConfig) and the service config (see ServiceConfigWrapper) of a service. Attachments aren't included.
+type DeploymentDocument struct {
+	ID           string                       `json:"_id"`
+	AWSConfig    map[string]DesignationConfig `json:"aws-stages,omitempty"`
+	CampusConfig map[string]DesignationConfig `json:"campus-stages,omitempty"`
+	Designations map[string]ServiceConfig     `json:"designations,omitempty"`
+}
+
+// ConflictStrategy is what to do when a document being imported already exists.
+type ConflictStrategy string
+
+const (
+	// ConflictSkip leaves the existing document as it is.
+	ConflictSkip ConflictStrategy = "skip"
+
+	// ConflictOverwrite replaces the existing document.
+	ConflictOverwrite ConflictStrategy = "overwrite"
+
+	// ConflictFail imports nothing if any of the documents already exist.
+	ConflictFail ConflictStrategy = "fail"
+)
+
+// ImportAction is what happened to a document when a snapshot was imported.
+type ImportAction string
+
+const (
+	ImportCreated     ImportAction = "created"
+	ImportOverwritten ImportAction = "overwritten"
+	ImportSkipped     ImportAction = "skipped"
+	ImportFailed      ImportAction = "failed"
+)
+
+// ImportResult is the result of importing a snapshot.
+type ImportResult struct {
+	Strategy  ConflictStrategy   `json:"strategy"`
+	Documents []ImportedDocument `json:"documents"`
+}
+
+// ImportedDocument is a single document from an imported snapshot.
+type ImportedDocument struct {
+	Database string       `json:"database"`
+	ID       string       `json:"_id"`
+	Action   ImportAction `json:"action"`
+	Message  string       `json:"message,omitempty"`
+}
+
+// Failed returns each of the documents that couldn't be imported.
+func (r ImportResult) Failed() []ImportedDocument {
+	var toReturn []ImportedDocument
+
+	for _, doc := range r.Documents {
+		if doc.Action == ImportFailed {
+			toReturn = append(toReturn, doc)
+		}
+	}
+
+	return toReturn
+}
+
+// Check returns an error if the snapshot can't be imported with strategy.
+func (s Snapshot) Check(strategy ConflictStrategy) error {
+	switch strategy {
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+	default:
+		return fmt.Errorf("unknown conflict strategy %q", strategy)
+	}
+
+	if s.Version < 1 || s.Version > SnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %v (expected 1 through %v)", s.Version, SnapshotVersion)
+	}
+
+	return nil
+}
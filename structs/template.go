@@ -0,0 +1,54 @@
+package structs
+
+import "strings"
+
+// TemplateParams are the details of a room being created from a template that the template doesn't provide.
+type TemplateParams struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Designation string `json:"designation"`
+
+	// Configuration is the id of the room's configuration; it defaults to "Default".
+	Configuration string `json:"configuration,omitempty"`
+
+	// AddressPattern is the address of each device, with {id}, {name}, and {room} replaced by the device's id, name, and room id (e.g. "{id}.byu.edu"). It defaults to "{id}".
+	AddressPattern string `json:"address-pattern,omitempty"`
+}
+
+// Validate checks to make sure the params are valid.
+func (p *TemplateParams) Validate() error {
+	return p.ValidateAll().Err("template params")
+}
+
+// ValidateAll returns every problem with the params.
+func (p *TemplateParams) ValidateAll() ValidationResult {
+	var r ValidationResult
+
+	if len(p.Name) == 0 {
+		r.errorf("name", ValidationMissing, "missing name")
+	}
+
+	if len(p.Designation) == 0 {
+		r.errorf("designation", ValidationMissing, "missing designation")
+	}
+
+	return r
+}
+
+// DeviceAddress returns the address of the device id, named name, following p.AddressPattern.
+func (p *TemplateParams) DeviceAddress(id, name string) string {
+	pattern := p.AddressPattern
+	if len(pattern) == 0 {
+		pattern = "{id}"
+	}
+
+	return strings.NewReplacer("{id}", id, "{name}", name, "{room}", GetRoomIDFromDevice(id)).Replace(pattern)
+}
+
+// TemplateInstance is everything created for a room from a template.
+type TemplateInstance struct {
+	TemplateID string   `json:"template-id"`
+	Room       Room     `json:"room"`
+	Devices    []Device `json:"devices"`
+	UIConfig   UIConfig `json:"uiconfig"`
+}
@@ -3,6 +3,7 @@ package structs
 // UIConfig - a representation of all the information needed to configure the touchpanel UI.
 type UIConfig struct {
 	ID                  string               `json:"_id,omitempty"`
+	Rev                 string               `json:"_rev,omitempty"`
 	Api                 []string             `json:"api"`
 	Panels              []Panel              `json:"panels"`
 	Presets             []Preset             `json:"presets"`
@@ -0,0 +1,164 @@
+package structs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationSeverity is how serious a validation issue is.
+type ValidationSeverity string
+
+// Validation severities
+const (
+	// SeverityError is a problem that makes the document invalid.
+	SeverityError ValidationSeverity = "error"
+
+	// SeverityWarning is something that is probably a mistake, but doesn't make the document invalid.
+	SeverityWarning ValidationSeverity = "warning"
+)
+
+// ValidationCode is the kind of problem a validation issue is.
+type ValidationCode string
+
+// Validation codes
+const (
+	// ValidationMissing is a required field that is empty.
+	ValidationMissing ValidationCode = "missing"
+
+	// ValidationTooShort is a field that is shorter than it is allowed to be.
+	ValidationTooShort ValidationCode = "too-short"
+
+	// ValidationInvalidFormat is a field whose value doesn't match the format it must have (e.g. an id naming scheme, a url, or a regular expression).
+	ValidationInvalidFormat ValidationCode = "invalid-format"
+
+	// ValidationDuplicate is a field whose value must be unique, but isn't.
+	ValidationDuplicate ValidationCode = "duplicate"
+
+	// ValidationUnexpectedScheme is a url whose scheme isn't http or https.
+	ValidationUnexpectedScheme ValidationCode = "unexpected-scheme"
+
+	// ValidationUnconnected is a port with a source device, but no destination device.
+	ValidationUnconnected ValidationCode = "unconnected"
+
+	// ValidationUnknownDevice is a reference to a device that isn't in the room.
+	ValidationUnknownDevice ValidationCode = "unknown-device"
+
+	// ValidationUnknownPreset is a reference to a ui config preset that doesn't exist.
+	ValidationUnknownPreset ValidationCode = "unknown-preset"
+
+	// ValidationUnknownIcon is an icon that isn't one of the available icons.
+	ValidationUnknownIcon ValidationCode = "unknown-icon"
+
+	// ValidationUnreachable is an input that can't reach any of the displays it is meant to be shown on.
+	ValidationUnreachable ValidationCode = "unreachable"
+
+	// ValidationWrongType is an attribute whose value isn't the type its schema says it is.
+	ValidationWrongType ValidationCode = "wrong-type"
+
+	// ValidationNotAllowed is an attribute whose value isn't one of the values its schema allows.
+	ValidationNotAllowed ValidationCode = "not-allowed"
+
+	// ValidationUnknownAttribute is an attribute that isn't in the attribute schema.
+	ValidationUnknownAttribute ValidationCode = "unknown-attribute"
+)
+
+// ValidationIssue is a single problem found validating a document. Path is the JSON path of the field with the problem (e.g. "ports[2]._id").
+type ValidationIssue struct {
+	Path     string             `json:"path"`
+	Severity ValidationSeverity `json:"severity"`
+	Code     ValidationCode     `json:"code"`
+	Message  string             `json:"message"`
+}
+
+func (i ValidationIssue) String() string {
+	if len(i.Path) == 0 {
+		return i.Message
+	}
+
+	return fmt.Sprintf("%s: %s", i.Path, i.Message)
+}
+
+// ValidationResult is every problem found validating a document.
+type ValidationResult struct {
+	Issues []ValidationIssue `json:"issues"`
+}
+
+// Valid returns true if there aren't any errors (there may still be warnings).
+func (r ValidationResult) Valid() bool {
+	return len(r.Errors()) == 0
+}
+
+// Errors returns each of the issues that are errors.
+func (r ValidationResult) Errors() []ValidationIssue {
+	return r.bySeverity(SeverityError)
+}
+
+// Warnings returns each of the issues that are warnings.
+func (r ValidationResult) Warnings() []ValidationIssue {
+	return r.bySeverity(SeverityWarning)
+}
+
+func (r ValidationResult) bySeverity(severity ValidationSeverity) []ValidationIssue {
+	var toReturn []ValidationIssue
+
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			toReturn = append(toReturn, issue)
+		}
+	}
+
+	return toReturn
+}
+
+// Err returns a *ValidationError with each of the errors, or nil if there aren't any. kind is what was validated (e.g. "device").
+func (r ValidationResult) Err(kind string) error {
+	errs := r.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Kind: kind, Issues: errs}
+}
+
+func (r *ValidationResult) errorf(path string, code ValidationCode, format string, a ...interface{}) {
+	r.Issues = append(r.Issues, ValidationIssue{Path: path, Severity: SeverityError, Code: code, Message: fmt.Sprintf(format, a...)})
+}
+
+func (r *ValidationResult) warnf(path string, code ValidationCode, format string, a ...interface{}) {
+	r.Issues = append(r.Issues, ValidationIssue{Path: path, Severity: SeverityWarning, Code: code, Message: fmt.Sprintf(format, a...)})
+}
+
+// ValidationError is returned by Validate when a document is invalid. Issues are all of the errors found, not just the first.
+type ValidationError struct {
+	Kind   string            `json:"kind"`
+	Issues []ValidationIssue `json:"issues"`
+}
+
+func (e *ValidationError) Error() string {
+	msg := fmt.Sprintf("invalid %s: %s", e.Kind, e.Issues[0])
+
+	if len(e.Issues) > 1 {
+		var rest []string
+		for _, issue := range e.Issues[1:] {
+			rest = append(rest, issue.String())
+		}
+
+		msg += fmt.Sprintf(" (and %d more: %s)", len(rest), strings.Join(rest, "; "))
+	}
+
+	return msg
+}
+
+// fieldPath returns the path of field in the object at path.
+func fieldPath(path, field string) string {
+	if len(path) == 0 {
+		return field
+	}
+
+	return path + "." + field
+}
+
+// indexPath returns the path of element i of the list at path.
+func indexPath(path string, i int) string {
+	return fmt.Sprintf("%s[%d]", path, i)
+}
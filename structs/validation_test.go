@@ -0,0 +1,46 @@
+package structs
+
+import "testing"
+
+func TestDeviceValidateAll(t *testing.T) {
+	device := Device{
+		ID:    "ITB-1101-D1",
+		Name:  "D",
+		Type:  DeviceType{ID: "non-controllable"},
+		Roles: []Role{{ID: "VideoOut"}},
+		Ports: []Port{{ID: "hdmi!1", SourceDevice: "ITB-1101-VIA1"}, {}},
+	}
+
+	result := device.ValidateAll()
+
+	expected := map[string]ValidationCode{
+		"name":                        ValidationTooShort,
+		"address":                     ValidationMissing,
+		"ports[0].destination_device": ValidationUnconnected,
+		"ports[1]._id":                ValidationMissing,
+	}
+
+	if len(result.Issues) != len(expected) {
+		t.Fatalf("expected %d issues, got %+v", len(expected), result.Issues)
+	}
+
+	for _, issue := range result.Issues {
+		if expected[issue.Path] != issue.Code {
+			t.Fatalf("unexpected issue: %+v", issue)
+		}
+	}
+
+	if len(result.Errors()) != 2 || len(result.Warnings()) != 2 {
+		t.Fatalf("expected 2 errors and 2 warnings, got %+v", result.Issues)
+	}
+
+	err, ok := device.Validate().(*ValidationError)
+	if !ok || len(err.Issues) != 2 || err.Issues[0].Path != "name" {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	dt := DeviceType{ID: "sony", Commands: []Command{{ID: "PowerOn", Microservice: Microservice{ID: "sony-control", Address: "http://localhost:8007"}}}}
+	if issues := dt.ValidateAll(true).Errors(); len(issues) != 2 || issues[0].Path != "commands[0].endpoint._id" || issues[1].Path != "commands[0].endpoint.path" {
+		t.Fatalf("unexpected device type issues: %+v", issues)
+	}
+}